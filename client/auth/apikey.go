@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// 固定のAPIキー（またはBearerトークン）をヘッダーに付与するAuthorizer
+// OAuthとは異なり、サーバーに拒否された場合は認可をやり直さずにエラーを返す
+type APIKeyAuthorizer struct {
+	header string
+	prefix string
+
+	mu  sync.RWMutex
+	key string
+}
+
+// Authorization: Bearer <token> としてトークンを送るAuthorizerを返す
+func NewBearerTokenAuthorizer(token string) *APIKeyAuthorizer {
+	return &APIKeyAuthorizer{header: "Authorization", prefix: "Bearer ", key: token}
+}
+
+// headerで指定したヘッダー（例：X-API-Key）にキーを送るAuthorizerを返す
+func NewAPIKeyAuthorizer(header string, key string) *APIKeyAuthorizer {
+	return &APIKeyAuthorizer{header: header, key: key}
+}
+
+// 以降のリクエストで送るキーを入れ替える。キーのローテーションに使用する
+func (a *APIKeyAuthorizer) SetKey(key string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.key = key
+}
+
+func (a *APIKeyAuthorizer) SetAuthHeader(ctx context.Context, req *http.Request) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	req.Header.Set(a.header, a.prefix+a.key)
+	return nil
+}
+
+func (a *APIKeyAuthorizer) HandleUnauthorized(ctx context.Context, resp *http.Response) error {
+	return fmt.Errorf("api key was rejected by the server (status %d)", resp.StatusCode)
+}
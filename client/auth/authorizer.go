@@ -0,0 +1,15 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+)
+
+// HTTPのクライアントトランスポートがリクエストの認可に使用する
+type Authorizer interface {
+	// 送信するリクエストに認可のためのヘッダーを設定する
+	SetAuthHeader(ctx context.Context, req *http.Request) error
+	// サーバーが401（またはスコープ不足の403）を返した場合に呼ばれる
+	// 認可をやり直し、リクエストを再送できる状態になった場合はnilを返す
+	HandleUnauthorized(ctx context.Context, resp *http.Response) error
+}
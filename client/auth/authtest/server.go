@@ -0,0 +1,381 @@
+// authtestは、OAuthのクライアントをテストするための、ローカルで動作する認可サーバーを提供する
+package authtest
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/kakkky/mcp-sdk-go/shared/auth"
+)
+
+// 認可コードの有効期限
+const authorizationCodeLifetime = time.Minute
+
+// テスト用の認可サーバー
+// 認可エンドポイントはユーザーの操作を待たずに、Subjectとして即座に認可する
+// 発行するアクセストークンは不透明な文字列で、イントロスペクションエンドポイントで検証できる
+type AuthorizationServer struct {
+	*httptest.Server
+
+	mu sync.Mutex
+	// 認可するユーザー
+	subject string
+	// 発行するアクセストークンの有効期間
+	accessTokenLifetime time.Duration
+	// trueの場合、認可エンドポイントはaccess_deniedを返す
+	denyAuthorization bool
+	clients           map[string]auth.ClientInformation
+	codes             map[string]authorizationCode
+	accessTokens      map[string]issuedToken
+	refreshTokens     map[string]issuedToken
+	tokenRequests     map[string]int
+
+	// 公開するメタデータを書き換える関数
+	editMetadata func(*auth.AuthorizationServerMetadata)
+}
+
+type authorizationCode struct {
+	clientId      string
+	redirectURI   string
+	codeChallenge string
+	scope         string
+	resource      string
+	expiresAt     time.Time
+}
+
+type issuedToken struct {
+	clientId  string
+	subject   string
+	scope     string
+	resource  string
+	expiresAt time.Time
+}
+
+func NewAuthorizationServer() *AuthorizationServer {
+	s := &AuthorizationServer{
+		subject:             "test-user",
+		accessTokenLifetime: time.Hour,
+		clients:             make(map[string]auth.ClientInformation),
+		codes:               make(map[string]authorizationCode),
+		accessTokens:        make(map[string]issuedToken),
+		refreshTokens:       make(map[string]issuedToken),
+		tokenRequests:       make(map[string]int),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(auth.AUTHORIZATION_SERVER_METADATA_PATH, s.handleMetadata)
+	mux.HandleFunc("/register", s.handleRegister)
+	mux.HandleFunc("/authorize", s.handleAuthorize)
+	mux.HandleFunc("/token", s.handleToken)
+	mux.HandleFunc("/introspect", s.handleIntrospect)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+func (s *AuthorizationServer) Metadata() auth.AuthorizationServerMetadata {
+	return auth.AuthorizationServerMetadata{
+		Issuer:                            s.URL,
+		AuthorizationEndpoint:             s.URL + "/authorize",
+		TokenEndpoint:                     s.URL + "/token",
+		RegistrationEndpoint:              s.URL + "/register",
+		IntrospectionEndpoint:             s.URL + "/introspect",
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{"authorization_code", "refresh_token"},
+		TokenEndpointAuthMethodsSupported: []string{"none", "client_secret_basic", "client_secret_post"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+	}
+}
+
+// 認可するユーザーを変更する
+func (s *AuthorizationServer) SetSubject(subject string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subject = subject
+}
+
+// 以降に発行するアクセストークンの有効期間を変更する
+func (s *AuthorizationServer) SetAccessTokenLifetime(lifetime time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accessTokenLifetime = lifetime
+}
+
+// trueの場合、以降の認可リクエストをaccess_deniedで拒否する
+func (s *AuthorizationServer) SetDenyAuthorization(deny bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.denyAuthorization = deny
+}
+
+// 公開するメタデータを書き換える
+// 不正なメタデータを公開する認可サーバーを再現する場合に使用する
+func (s *AuthorizationServer) SetMetadataEditor(edit func(*auth.AuthorizationServerMetadata)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.editMetadata = edit
+}
+
+// 事前に登録したクライアントとして扱う
+func (s *AuthorizationServer) RegisterClient(info auth.ClientInformation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clients[info.ClientId] = info
+}
+
+// 発行済みの全てのアクセストークンを無効にする。リフレッシュトークンは引き続き使用できる
+func (s *AuthorizationServer) RevokeAccessTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accessTokens = make(map[string]issuedToken)
+}
+
+// 発行済みの全てのリフレッシュトークンを無効にする
+func (s *AuthorizationServer) RevokeRefreshTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refreshTokens = make(map[string]issuedToken)
+}
+
+// 動的クライアント登録で登録されたクライアントの数
+func (s *AuthorizationServer) RegisteredClients() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.clients)
+}
+
+// grant_typeごとの、トークンエンドポイントへのリクエスト数
+func (s *AuthorizationServer) TokenRequests(grantType string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tokenRequests[grantType]
+}
+
+func (s *AuthorizationServer) handleMetadata(w http.ResponseWriter, r *http.Request) {
+	metadata := s.Metadata()
+	s.mu.Lock()
+	edit := s.editMetadata
+	s.mu.Unlock()
+	if edit != nil {
+		edit(&metadata)
+	}
+	writeJSON(w, http.StatusOK, metadata)
+}
+
+func (s *AuthorizationServer) handleRegister(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var metadata auth.OAuthClientMetadata
+	if err := json.NewDecoder(r.Body).Decode(&metadata); err != nil || len(metadata.RedirectUris) == 0 {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_client_metadata", "redirect_uris is required")
+		return
+	}
+	info := auth.ClientInformation{
+		OAuthClientMetadata: metadata,
+		ClientId:            randomToken(),
+		ClientIdIssuedAt:    time.Now().Unix(),
+	}
+	if metadata.TokenEndpointAuthMethod != "none" {
+		info.ClientSecret = randomToken()
+	}
+	s.mu.Lock()
+	s.clients[info.ClientId] = info
+	s.mu.Unlock()
+	writeJSON(w, http.StatusCreated, info)
+}
+
+func (s *AuthorizationServer) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	client, ok := s.clients[query.Get("client_id")]
+	if !ok {
+		http.Error(w, "unknown client", http.StatusBadRequest)
+		return
+	}
+	redirectURI := query.Get("redirect_uri")
+	if !isRegisteredRedirectURI(client.RedirectUris, redirectURI) {
+		// 登録されていないリダイレクト先には、エラーであってもリダイレクトしない
+		http.Error(w, "unregistered redirect_uri", http.StatusBadRequest)
+		return
+	}
+	redirect, _ := url.Parse(redirectURI)
+	params := url.Values{"state": {query.Get("state")}}
+	switch {
+	case s.denyAuthorization:
+		params.Set("error", "access_denied")
+	case query.Get("response_type") != "code":
+		params.Set("error", "unsupported_response_type")
+	case query.Get("code_challenge") == "" || query.Get("code_challenge_method") != "S256":
+		params.Set("error", "invalid_request")
+		params.Set("error_description", "PKCE with S256 is required")
+	default:
+		code := randomToken()
+		s.codes[code] = authorizationCode{
+			clientId:      client.ClientId,
+			redirectURI:   redirectURI,
+			codeChallenge: query.Get("code_challenge"),
+			scope:         query.Get("scope"),
+			resource:      query.Get("resource"),
+			expiresAt:     time.Now().Add(authorizationCodeLifetime),
+		}
+		params.Set("code", code)
+	}
+	redirect.RawQuery = params.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+// ループバックアドレスのリダイレクト先は、ポート番号を問わずに一致させる
+// 参照：(https://datatracker.ietf.org/doc/html/rfc8252#section-7.3)
+func isRegisteredRedirectURI(registered []string, redirectURI string) bool {
+	requested, err := url.Parse(redirectURI)
+	if err != nil {
+		return false
+	}
+	for _, candidate := range registered {
+		if candidate == redirectURI {
+			return true
+		}
+		u, err := url.Parse(candidate)
+		if err != nil {
+			continue
+		}
+		ip := net.ParseIP(u.Hostname())
+		if ip != nil && ip.IsLoopback() && u.Scheme == requested.Scheme && u.Hostname() == requested.Hostname() && u.Path == requested.Path {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *AuthorizationServer) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.Method != http.MethodPost {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	grantType := r.PostForm.Get("grant_type")
+	s.tokenRequests[grantType]++
+	client, ok := s.authenticateClient(r)
+	if !ok {
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_client", "")
+		return
+	}
+	switch grantType {
+	case "authorization_code":
+		code, ok := s.codes[r.PostForm.Get("code")]
+		delete(s.codes, r.PostForm.Get("code"))
+		if !ok || code.clientId != client.ClientId || time.Now().After(code.expiresAt) || code.redirectURI != r.PostForm.Get("redirect_uri") {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "invalid authorization code")
+			return
+		}
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if base64.RawURLEncoding.EncodeToString(sum[:]) != code.codeChallenge {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "code_verifier does not match")
+			return
+		}
+		s.issueTokens(w, client.ClientId, code.scope, code.resource)
+	case "refresh_token":
+		token, ok := s.refreshTokens[r.PostForm.Get("refresh_token")]
+		if !ok || token.clientId != client.ClientId {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "invalid refresh token")
+			return
+		}
+		// リフレッシュトークンは1度だけ使用できる
+		delete(s.refreshTokens, r.PostForm.Get("refresh_token"))
+		s.issueTokens(w, client.ClientId, token.scope, token.resource)
+	default:
+		writeOAuthError(w, http.StatusBadRequest, "unsupported_grant_type", "")
+	}
+}
+
+// Basic認証、またはフォームのclient_id（とclient_secret）でクライアントを認証する
+func (s *AuthorizationServer) authenticateClient(r *http.Request) (auth.ClientInformation, bool) {
+	clientId, clientSecret, ok := r.BasicAuth()
+	if ok {
+		clientId, _ = url.QueryUnescape(clientId)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientId, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	client, found := s.clients[clientId]
+	if !found || client.ClientSecret != clientSecret {
+		return auth.ClientInformation{}, false
+	}
+	return client, true
+}
+
+func (s *AuthorizationServer) issueTokens(w http.ResponseWriter, clientId string, scope string, resource string) {
+	accessToken, refreshToken := randomToken(), randomToken()
+	s.accessTokens[accessToken] = issuedToken{
+		clientId:  clientId,
+		subject:   s.subject,
+		scope:     scope,
+		resource:  resource,
+		expiresAt: time.Now().Add(s.accessTokenLifetime),
+	}
+	s.refreshTokens[refreshToken] = issuedToken{clientId: clientId, scope: scope, resource: resource}
+	writeJSON(w, http.StatusOK, auth.Tokens{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(s.accessTokenLifetime / time.Second),
+		RefreshToken: refreshToken,
+		Scope:        scope,
+	})
+}
+
+// アクセストークンの情報を返す。リソースサーバーの認証は行わない
+func (s *AuthorizationServer) handleIntrospect(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token, ok := s.accessTokens[r.PostForm.Get("token")]
+	if !ok || time.Now().After(token.expiresAt) {
+		writeJSON(w, http.StatusOK, map[string]any{"active": false})
+		return
+	}
+	response := map[string]any{
+		"active":    true,
+		"client_id": token.clientId,
+		"sub":       token.subject,
+		"iss":       s.URL,
+		"exp":       token.expiresAt.Unix(),
+	}
+	if token.scope != "" {
+		response["scope"] = token.scope
+	}
+	if token.resource != "" {
+		response["aud"] = token.resource
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeOAuthError(w http.ResponseWriter, status int, code string, description string) {
+	writeJSON(w, status, auth.OAuthError{Code: code, Description: description})
+}
+
+func randomToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
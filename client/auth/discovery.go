@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/kakkky/mcp-sdk-go/shared/auth"
+)
+
+// メタデータとして読み込む最大バイト数
+const maxMetadataSize = 1 << 20
+
+// 取得したメタデータが、取得に使用した識別子と一致しない場合などに返すエラー
+// メタデータを取得できない場合と異なり、他の方法で探索を続けずに認可を中止する
+var errInvalidMetadata = errors.New("invalid metadata")
+
+// WWW-Authenticateヘッダーのチャレンジ（Bearer key="value", ...）からパラメータを取り出す
+// Bearer以外の認証方式のチャレンジは無視する
+func parseBearerChallenge(header string) map[string]string {
+	params := make(map[string]string)
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return params
+	}
+	for rest = strings.TrimSpace(rest); rest != ""; rest = strings.TrimSpace(rest) {
+		key, value, ok := strings.Cut(rest, "=")
+		if !ok {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		if strings.HasPrefix(value, `"`) {
+			// 引用符で囲まれた値は、エスケープを解除しながら閉じ引用符まで読む
+			var b strings.Builder
+			i := 1
+			for ; i < len(value) && value[i] != '"'; i++ {
+				if value[i] == '\\' && i+1 < len(value) {
+					i++
+				}
+				b.WriteByte(value[i])
+			}
+			params[key] = b.String()
+			rest = strings.TrimPrefix(strings.TrimSpace(value[min(i+1, len(value)):]), ",")
+			continue
+		}
+		value, rest, _ = strings.Cut(value, ",")
+		params[key] = strings.TrimSpace(value)
+	}
+	return params
+}
+
+// 保護されたリソースのメタデータを取得する
+// WWW-Authenticateヘッダーで場所が示されない場合は、MCPエンドポイントのURLから求めたwell-knownのURLを使う
+func (c *OAuthClient) discoverProtectedResource(ctx context.Context, serverURL string, challenge map[string]string) (*auth.ProtectedResourceMetadata, error) {
+	metadataURL := challenge["resource_metadata"]
+	if metadataURL == "" {
+		var err error
+		if metadataURL, err = auth.ProtectedResourceMetadataURL(serverURL); err != nil {
+			return nil, err
+		}
+	}
+	var metadata auth.ProtectedResourceMetadata
+	if err := c.getJSON(ctx, metadataURL, &metadata); err != nil {
+		return nil, err
+	}
+	// 他のリソースのメタデータを使って、別のリソース向けのトークンを要求しないようにする
+	// 参照：(https://datatracker.ietf.org/doc/html/rfc9728#section-3.3)
+	if !isResourceOf(metadata.Resource, serverURL) {
+		return nil, fmt.Errorf("%w: resource %q does not match the server url %q", errInvalidMetadata, metadata.Resource, serverURL)
+	}
+	return &metadata, nil
+}
+
+// resourceが、MCPサーバーのURLそのものか、同じオリジンでパスがその親であるかを判定する
+func isResourceOf(resource string, serverURL string) bool {
+	r, err := url.Parse(resource)
+	if err != nil || r.Scheme == "" || r.Host == "" || r.RawQuery != "" || r.Fragment != "" {
+		return false
+	}
+	s, err := url.Parse(serverURL)
+	if err != nil {
+		return false
+	}
+	if !strings.EqualFold(r.Scheme, s.Scheme) || !strings.EqualFold(r.Host, s.Host) {
+		return false
+	}
+	resourcePath := strings.TrimSuffix(r.Path, "/")
+	serverPath := strings.TrimSuffix(s.Path, "/")
+	return serverPath == resourcePath || strings.HasPrefix(serverPath, resourcePath+"/")
+}
+
+// 認可サーバーのメタデータを、RFC 8414、OpenID Connect Discoveryの順に取得する
+// どちらも公開されていない場合は、issuerの直下にあるデフォルトのエンドポイントを使用する
+// 参照：(https://modelcontextprotocol.io/specification/2025-03-26/basic/authorization#2-3-3-fallbacks-for-servers-without-metadata-discovery)
+func (c *OAuthClient) discoverAuthorizationServer(ctx context.Context, issuer string) (*auth.AuthorizationServerMetadata, error) {
+	metadataURL, err := auth.AuthorizationServerMetadataURL(issuer)
+	if err != nil {
+		return nil, err
+	}
+	openIdURL, _ := url.JoinPath(strings.TrimSuffix(issuer, "/"), auth.OPENID_CONFIGURATION_PATH)
+	for _, candidate := range []string{metadataURL, openIdURL} {
+		var metadata auth.AuthorizationServerMetadata
+		if err := c.getJSON(ctx, candidate, &metadata); err != nil {
+			continue
+		}
+		// 他の認可サーバーになりすましたメタデータを使わないようにする
+		// 参照：(https://datatracker.ietf.org/doc/html/rfc8414#section-3.3)
+		if strings.TrimSuffix(metadata.Issuer, "/") != strings.TrimSuffix(issuer, "/") {
+			return nil, fmt.Errorf("%w: issuer %q does not match %q", errInvalidMetadata, metadata.Issuer, issuer)
+		}
+		// PKCEのS256に対応していない認可サーバーでは認可しない
+		// 参照：(https://modelcontextprotocol.io/specification/2025-06-18/basic/authorization#authorization-code-protection)
+		if !slices.Contains(metadata.CodeChallengeMethodsSupported, "S256") {
+			return nil, fmt.Errorf("%w: authorization server %q does not support the S256 code challenge method", errInvalidMetadata, issuer)
+		}
+		return &metadata, nil
+	}
+	u, err := url.Parse(issuer)
+	if err != nil {
+		return nil, fmt.Errorf("invalid issuer %q: %w", issuer, err)
+	}
+	base := u.Scheme + "://" + u.Host
+	return &auth.AuthorizationServerMetadata{
+		Issuer:                base,
+		AuthorizationEndpoint: base + "/authorize",
+		TokenEndpoint:         base + "/token",
+		RegistrationEndpoint:  base + "/register",
+	}, nil
+}
+
+func (c *OAuthClient) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.options.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to get %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get %s: %s", url, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxMetadataSize))
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", url, err)
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", url, err)
+	}
+	return nil
+}
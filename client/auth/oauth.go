@@ -0,0 +1,369 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kakkky/mcp-sdk-go/shared/auth"
+)
+
+const (
+	// 認可を待つデフォルトの最大時間
+	defaultAuthorizationTimeout = 5 * time.Minute
+	// 有効期限のこの時間前になったアクセストークンは、送信前に更新する
+	tokenExpiryMargin = 30 * time.Second
+)
+
+type OAuthClientOptions struct {
+	// 事前に登録したクライアントの情報
+	// 指定しなかった場合は、TokenStoreに保存された情報を使用し、それも無い場合は動的クライアント登録を行う
+	ClientId     string
+	ClientSecret string
+	// 動的クライアント登録で送るメタデータ。RedirectUris、GrantTypes、ResponseTypesは自動で設定する
+	ClientMetadata auth.OAuthClientMetadata
+	// 要求するスコープ
+	// 指定しなかった場合は、サーバーがWWW-Authenticateヘッダー、またはメタデータで示すスコープとなる
+	Scopes []string
+	// 指定しなかった場合は、メモリに保存する
+	Store TokenStore
+	// 認可コードを受け取るリダイレクト先のアドレス。指定しなかった場合は"127.0.0.1:0"となる
+	RedirectAddress string
+	// 認可URLをユーザーに開いてもらう関数（ブラウザを起動する、URLを表示するなど）
+	// 認可コードフローを行うために必須
+	OpenURL func(authorizationURL string) error
+	// 指定しなかった場合はhttp.DefaultClientとなる
+	HTTPClient *http.Client
+	// ユーザーの認可を待つ最大時間。指定しなかった場合は5分となる
+	AuthorizationTimeout time.Duration
+	// 現在時刻を返す関数。指定しなかった場合はtime.Nowとなる
+	Now func() time.Time
+}
+
+// OAuth 2.1の認可コードフロー（PKCE）でアクセストークンを取得するAuthorizer
+// サーバーから401を受け取ると、メタデータの探索、動的クライアント登録、認可、トークンの取得を順に行う
+// リフレッシュトークンがある場合は、認可をやり直す前にトークンの更新を試みる
+// 参照：(https://modelcontextprotocol.io/specification/2025-06-18/basic/authorization)
+type OAuthClient struct {
+	options OAuthClientOptions
+
+	// 認可の手続きは同時に1つだけ行う
+	mu sync.Mutex
+	// 探索した認可サーバーのメタデータと、トークンを要求するリソース
+	metadata *auth.AuthorizationServerMetadata
+	resource string
+}
+
+func NewOAuthClient(options *OAuthClientOptions) *OAuthClient {
+	opts := OAuthClientOptions{}
+	if options != nil {
+		opts = *options
+	}
+	if opts.Store == nil {
+		opts.Store = NewMemoryTokenStore()
+	}
+	if opts.RedirectAddress == "" {
+		opts.RedirectAddress = "127.0.0.1:0"
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+	if opts.AuthorizationTimeout <= 0 {
+		opts.AuthorizationTimeout = defaultAuthorizationTimeout
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &OAuthClient{options: opts}
+}
+
+// 保存されたアクセストークンをAuthorizationヘッダーに設定する
+// 有効期限が近い場合は、先にトークンを更新する
+func (c *OAuthClient) SetAuthHeader(ctx context.Context, req *http.Request) error {
+	tokens, err := c.options.Store.Tokens()
+	if err != nil {
+		return err
+	}
+	if tokens == nil {
+		// 401を受け取ってから認可を開始する
+		return nil
+	}
+	if c.isExpiring(tokens) && tokens.RefreshToken != "" {
+		c.mu.Lock()
+		if c.metadata != nil {
+			if refreshed, err := c.refresh(ctx, tokens); err == nil {
+				tokens = refreshed
+			}
+		}
+		c.mu.Unlock()
+	}
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	return nil
+}
+
+func (c *OAuthClient) isExpiring(tokens *auth.Tokens) bool {
+	return !tokens.ExpiresAt.IsZero() && !c.options.Now().Add(tokenExpiryMargin).Before(tokens.ExpiresAt)
+}
+
+func (c *OAuthClient) HandleUnauthorized(ctx context.Context, resp *http.Response) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	tokens, err := c.options.Store.Tokens()
+	if err != nil {
+		return err
+	}
+	// 並行して送ったリクエストの401で、既に他のリクエストがトークンを取得し直している場合
+	if tokens != nil && resp.Request != nil && resp.Request.Header.Get("Authorization") != "Bearer "+tokens.AccessToken {
+		return nil
+	}
+
+	challenge := parseBearerChallenge(resp.Header.Get("WWW-Authenticate"))
+	serverURL := ""
+	if resp.Request != nil {
+		u := *resp.Request.URL
+		u.RawQuery, u.Fragment = "", ""
+		serverURL = u.String()
+	}
+	if err := c.discover(ctx, serverURL, challenge); err != nil {
+		return err
+	}
+	// スコープが足りない場合は、更新しても同じスコープのトークンしか得られないため認可をやり直す
+	if tokens != nil && tokens.RefreshToken != "" && challenge["error"] != "insufficient_scope" {
+		if _, err := c.refresh(ctx, tokens); err == nil {
+			return nil
+		}
+	}
+	return c.authorize(ctx, challenge["scope"])
+}
+
+// 認可サーバーのメタデータと、トークンを要求するリソースを探索する
+// 保護されたリソースのメタデータが無い場合は、MCPサーバーのオリジンを認可サーバーとみなす
+func (c *OAuthClient) discover(ctx context.Context, serverURL string, challenge map[string]string) error {
+	c.resource = serverURL
+	issuer := ""
+	resourceMetadata, err := c.discoverProtectedResource(ctx, serverURL, challenge)
+	if errors.Is(err, errInvalidMetadata) {
+		return err
+	}
+	if err == nil {
+		c.resource = resourceMetadata.Resource
+		if len(resourceMetadata.AuthorizationServers) > 0 {
+			issuer = resourceMetadata.AuthorizationServers[0]
+		}
+		if len(c.options.Scopes) == 0 && challenge["scope"] == "" && len(resourceMetadata.ScopesSupported) > 0 {
+			challenge["scope"] = strings.Join(resourceMetadata.ScopesSupported, " ")
+		}
+	}
+	if issuer == "" {
+		u, err := url.Parse(serverURL)
+		if err != nil {
+			return fmt.Errorf("invalid server url %q: %w", serverURL, err)
+		}
+		issuer = u.Scheme + "://" + u.Host
+	}
+	metadata, err := c.discoverAuthorizationServer(ctx, issuer)
+	if err != nil {
+		return err
+	}
+	c.metadata = metadata
+	return nil
+}
+
+// 認可コードフローでトークンを取得し、保存する
+func (c *OAuthClient) authorize(ctx context.Context, challengeScope string) error {
+	if c.options.OpenURL == nil {
+		return errors.New("OpenURL is required to authorize")
+	}
+	state := randomString(16)
+	redirect, err := newRedirectListener(c.options.RedirectAddress, state)
+	if err != nil {
+		return err
+	}
+	defer redirect.close()
+
+	clientInfo, err := c.clientInformation(ctx, redirect.redirectURI())
+	if err != nil {
+		return err
+	}
+	verifier, challenge := newPKCE()
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {clientInfo.ClientId},
+		"redirect_uri":          {redirect.redirectURI()},
+		"code_challenge":        {challenge},
+		"code_challenge_method": {"S256"},
+		"state":                 {state},
+	}
+	if scope := c.scope(challengeScope); scope != "" {
+		query.Set("scope", scope)
+	}
+	if c.resource != "" {
+		query.Set("resource", c.resource)
+	}
+	authorizationURL, err := url.Parse(c.metadata.AuthorizationEndpoint)
+	if err != nil {
+		return fmt.Errorf("invalid authorization endpoint: %w", err)
+	}
+	authorizationURL.RawQuery = query.Encode()
+	if err := c.options.OpenURL(authorizationURL.String()); err != nil {
+		return fmt.Errorf("failed to open authorization url: %w", err)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, c.options.AuthorizationTimeout)
+	defer cancel()
+	code, err := redirect.wait(waitCtx)
+	if err != nil {
+		return err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirect.redirectURI()},
+		"code_verifier": {verifier},
+	}
+	_, err = c.requestToken(ctx, clientInfo, form, nil)
+	return err
+}
+
+func (c *OAuthClient) scope(challengeScope string) string {
+	if len(c.options.Scopes) > 0 {
+		return strings.Join(c.options.Scopes, " ")
+	}
+	return challengeScope
+}
+
+// リフレッシュトークンでアクセストークンを更新し、保存する
+func (c *OAuthClient) refresh(ctx context.Context, tokens *auth.Tokens) (*auth.Tokens, error) {
+	clientInfo, err := c.clientInformation(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {tokens.RefreshToken},
+	}
+	return c.requestToken(ctx, clientInfo, form, tokens)
+}
+
+// 事前に登録したクライアント、保存したクライアントの順に使用し、どちらも無い場合は動的クライアント登録を行う
+// redirectURIが空の場合（トークンの更新時）は、登録を行わない
+func (c *OAuthClient) clientInformation(ctx context.Context, redirectURI string) (*auth.ClientInformation, error) {
+	if c.options.ClientId != "" {
+		return &auth.ClientInformation{ClientId: c.options.ClientId, ClientSecret: c.options.ClientSecret}, nil
+	}
+	info, err := c.options.Store.ClientInformation()
+	if err != nil {
+		return nil, err
+	}
+	if info != nil {
+		return info, nil
+	}
+	if redirectURI == "" {
+		return nil, errors.New("client is not registered")
+	}
+	return c.register(ctx, redirectURI)
+}
+
+// 動的クライアント登録
+// 参照：(https://datatracker.ietf.org/doc/html/rfc7591#section-3)
+func (c *OAuthClient) register(ctx context.Context, redirectURI string) (*auth.ClientInformation, error) {
+	if c.metadata.RegistrationEndpoint == "" {
+		return nil, errors.New("authorization server does not support dynamic client registration")
+	}
+	metadata := c.options.ClientMetadata
+	metadata.RedirectUris = []string{redirectURI}
+	metadata.GrantTypes = []string{"authorization_code", "refresh_token"}
+	metadata.ResponseTypes = []string{"code"}
+	if metadata.TokenEndpointAuthMethod == "" {
+		metadata.TokenEndpointAuthMethod = "none"
+	}
+	body, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal client metadata: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.metadata.RegistrationEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create registration request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	var info auth.ClientInformation
+	if err := c.doJSON(req, &info); err != nil {
+		return nil, fmt.Errorf("failed to register client: %w", err)
+	}
+	if err := c.options.Store.SaveClientInformation(&info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// トークンエンドポイントにリクエストし、得られたトークンを保存する
+// 更新時に新しいリフレッシュトークンが返されない場合は、以前のものを引き継ぐ
+func (c *OAuthClient) requestToken(ctx context.Context, clientInfo *auth.ClientInformation, form url.Values, previous *auth.Tokens) (*auth.Tokens, error) {
+	if c.resource != "" {
+		form.Set("resource", c.resource)
+	}
+	useBasicAuth := clientInfo.ClientSecret != "" && clientInfo.TokenEndpointAuthMethod != "client_secret_post"
+	if !useBasicAuth {
+		form.Set("client_id", clientInfo.ClientId)
+		if clientInfo.ClientSecret != "" {
+			form.Set("client_secret", clientInfo.ClientSecret)
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if useBasicAuth {
+		req.SetBasicAuth(url.QueryEscape(clientInfo.ClientId), url.QueryEscape(clientInfo.ClientSecret))
+	}
+	var tokens auth.Tokens
+	if err := c.doJSON(req, &tokens); err != nil {
+		return nil, fmt.Errorf("failed to request token: %w", err)
+	}
+	if tokens.AccessToken == "" {
+		return nil, errors.New("token response has no access token")
+	}
+	if tokens.ExpiresIn > 0 {
+		tokens.ExpiresAt = c.options.Now().Add(time.Duration(tokens.ExpiresIn) * time.Second)
+	}
+	if tokens.RefreshToken == "" && previous != nil {
+		tokens.RefreshToken = previous.RefreshToken
+	}
+	if err := c.options.Store.SaveTokens(&tokens); err != nil {
+		return nil, err
+	}
+	return &tokens, nil
+}
+
+// 2xxの場合はボディをvに読み込み、それ以外の場合はOAuthErrorを返す
+func (c *OAuthClient) doJSON(req *http.Request, v any) error {
+	resp, err := c.options.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxMetadataSize))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var oauthErr auth.OAuthError
+		if err := json.Unmarshal(body, &oauthErr); err == nil && oauthErr.Code != "" {
+			return &oauthErr
+		}
+		return fmt.Errorf("server returned %s", resp.Status)
+	}
+	return json.Unmarshal(body, v)
+}
@@ -0,0 +1,319 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kakkky/mcp-sdk-go/client/auth/authtest"
+	mcpauth "github.com/kakkky/mcp-sdk-go/mcp-server/auth"
+	"github.com/kakkky/mcp-sdk-go/shared/auth"
+)
+
+// イントロスペクションでトークンを検証する、保護されたリソース
+// advertisedResourceが空でない場合は、メタデータのresourceとして公開する
+func newProtectedResource(t *testing.T, authorizationServer *authtest.AuthorizationServer, advertisedResource string) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	resource := server.URL + "/mcp"
+	metadata := auth.ProtectedResourceMetadata{
+		Resource:             resource,
+		AuthorizationServers: []string{authorizationServer.URL},
+		ScopesSupported:      []string{"mcp"},
+	}
+	metadataURL, _ := auth.ProtectedResourceMetadataURL(resource)
+	u, _ := url.Parse(metadataURL)
+	advertised := metadata
+	if advertisedResource != "" {
+		advertised.Resource = advertisedResource
+	}
+	mux.Handle(u.Path, mcpauth.NewProtectedResourceMetadataHandler(advertised))
+	mux.Handle("/mcp", mcpauth.RequireBearerToken(mcpauth.ResourceServerOptions{
+		Verifier: mcpauth.NewIntrospectionVerifier(authorizationServer.Metadata().IntrospectionEndpoint, &mcpauth.IntrospectionVerifierOptions{
+			Audience: resource,
+		}),
+		Metadata: &metadata,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info, _ := auth.AuthInfoFromContext(r.Context())
+		_, _ = io.WriteString(w, info.Subject)
+	})))
+	return server
+}
+
+// トランスポートと同様に、401の場合は認可をやり直して1度だけ再送する
+func get(t *testing.T, sut Authorizer, url string) (int, string, error) {
+	t.Helper()
+	for retried := false; ; retried = true {
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		if err := sut.SetAuthHeader(context.Background(), req); err != nil {
+			return 0, "", err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("failed to send request: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized || retried {
+			return resp.StatusCode, string(body), nil
+		}
+		if err := sut.HandleUnauthorized(context.Background(), resp); err != nil {
+			return 0, "", err
+		}
+	}
+}
+
+// ユーザーの代わりに認可URLを開き、リダイレクトに従う
+type browser struct {
+	opened int
+}
+
+func (b *browser) open(authorizationURL string) error {
+	b.opened++
+	resp, err := http.Get(authorizationURL)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func TestOAuthClient(t *testing.T) {
+	type expected struct {
+		opened              int
+		registeredClients   int
+		authorizationCodes  int
+		refreshes           int
+		scope               string
+		wantAuthorizeDenied bool
+		wantInvalidMetadata bool
+	}
+	// 時刻を進めるテストで使用する
+	var clockOffset time.Duration
+	tests := []struct {
+		name               string
+		setup              func(as *authtest.AuthorizationServer) *OAuthClientOptions
+		scenario           func(as *authtest.AuthorizationServer)
+		advertisedResource string
+		expected           expected
+	}{
+		{
+			name: "normal : discovers metadata, registers the client and obtains a token with PKCE",
+			setup: func(as *authtest.AuthorizationServer) *OAuthClientOptions {
+				return &OAuthClientOptions{ClientMetadata: auth.OAuthClientMetadata{ClientName: "test-client"}}
+			},
+			expected: expected{opened: 1, registeredClients: 1, authorizationCodes: 1, scope: "mcp"},
+		},
+		{
+			name: "normal : refreshes the token when the server returns 401",
+			setup: func(as *authtest.AuthorizationServer) *OAuthClientOptions {
+				return &OAuthClientOptions{}
+			},
+			scenario: func(as *authtest.AuthorizationServer) {
+				as.RevokeAccessTokens()
+			},
+			expected: expected{opened: 1, registeredClients: 1, authorizationCodes: 1, refreshes: 1, scope: "mcp"},
+		},
+		{
+			name: "normal : refreshes the token before it expires",
+			setup: func(as *authtest.AuthorizationServer) *OAuthClientOptions {
+				clockOffset = 0
+				return &OAuthClientOptions{Now: func() time.Time { return time.Now().Add(clockOffset) }}
+			},
+			scenario: func(as *authtest.AuthorizationServer) {
+				clockOffset = time.Hour - 10*time.Second
+			},
+			expected: expected{opened: 1, registeredClients: 1, authorizationCodes: 1, refreshes: 1, scope: "mcp"},
+		},
+		{
+			name: "normal : authorizes again when the refresh token is revoked",
+			setup: func(as *authtest.AuthorizationServer) *OAuthClientOptions {
+				return &OAuthClientOptions{}
+			},
+			scenario: func(as *authtest.AuthorizationServer) {
+				as.RevokeAccessTokens()
+				as.RevokeRefreshTokens()
+			},
+			expected: expected{opened: 2, registeredClients: 1, authorizationCodes: 2, refreshes: 1, scope: "mcp"},
+		},
+		{
+			name: "normal : pre-registered confidential client with requested scopes",
+			setup: func(as *authtest.AuthorizationServer) *OAuthClientOptions {
+				as.RegisterClient(auth.ClientInformation{
+					OAuthClientMetadata: auth.OAuthClientMetadata{RedirectUris: []string{"http://127.0.0.1/callback"}},
+					ClientId:            "pre-registered",
+					ClientSecret:        "secret",
+				})
+				return &OAuthClientOptions{ClientId: "pre-registered", ClientSecret: "secret", Scopes: []string{"mcp", "profile"}}
+			},
+			expected: expected{opened: 1, registeredClients: 1, authorizationCodes: 1, scope: "mcp profile"},
+		},
+		{
+			name: "semi normal : user denies the authorization",
+			setup: func(as *authtest.AuthorizationServer) *OAuthClientOptions {
+				as.SetDenyAuthorization(true)
+				return &OAuthClientOptions{}
+			},
+			expected: expected{opened: 1, registeredClients: 1, wantAuthorizeDenied: true},
+		},
+		{
+			name: "semi normal : protected resource metadata is for another resource",
+			setup: func(as *authtest.AuthorizationServer) *OAuthClientOptions {
+				return &OAuthClientOptions{}
+			},
+			advertisedResource: "https://attacker.example.com/mcp",
+			expected:           expected{wantInvalidMetadata: true},
+		},
+		{
+			name: "semi normal : authorization server metadata has another issuer",
+			setup: func(as *authtest.AuthorizationServer) *OAuthClientOptions {
+				as.SetMetadataEditor(func(metadata *auth.AuthorizationServerMetadata) {
+					metadata.Issuer = "https://attacker.example.com"
+				})
+				return &OAuthClientOptions{}
+			},
+			expected: expected{wantInvalidMetadata: true},
+		},
+		{
+			name: "semi normal : authorization server does not support S256",
+			setup: func(as *authtest.AuthorizationServer) *OAuthClientOptions {
+				as.SetMetadataEditor(func(metadata *auth.AuthorizationServerMetadata) {
+					metadata.CodeChallengeMethodsSupported = []string{"plain"}
+				})
+				return &OAuthClientOptions{}
+			},
+			expected: expected{wantInvalidMetadata: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			as := authtest.NewAuthorizationServer()
+			defer as.Close()
+			resource := newProtectedResource(t, as, tt.advertisedResource)
+			defer resource.Close()
+			resourceURL := resource.URL + "/mcp"
+
+			b := &browser{}
+			options := tt.setup(as)
+			options.OpenURL = b.open
+			options.Store = NewMemoryTokenStore()
+			sut := NewOAuthClient(options)
+
+			status, body, err := get(t, sut, resourceURL)
+			switch {
+			case tt.expected.wantInvalidMetadata:
+				if !errors.Is(err, errInvalidMetadata) {
+					t.Fatalf("error = %v, want invalid metadata", err)
+				}
+			case tt.expected.wantAuthorizeDenied:
+				var oauthErr *auth.OAuthError
+				if !errors.As(err, &oauthErr) || oauthErr.Code != "access_denied" {
+					t.Fatalf("error = %v, want access_denied", err)
+				}
+			default:
+				if err != nil {
+					t.Fatalf("failed to access the protected resource: %v", err)
+				}
+				if status != http.StatusOK || body != "test-user" {
+					t.Fatalf("response = %d %q, want 200 %q", status, body, "test-user")
+				}
+				if tt.scenario != nil {
+					tt.scenario(as)
+				}
+				// 2回目のリクエストは、保存したトークン（または更新したトークン）で送る
+				if status, _, err := get(t, sut, resourceURL); err != nil || status != http.StatusOK {
+					t.Fatalf("second request = %d, %v, want 200", status, err)
+				}
+				tokens, _ := options.Store.Tokens()
+				if tokens == nil {
+					t.Fatal("tokens are not saved")
+				}
+				if diff := cmp.Diff(tt.expected.scope, tokens.Scope); diff != "" {
+					t.Errorf("scope mismatch (-want +got):\n%s", diff)
+				}
+			}
+
+			got := expected{
+				opened:              b.opened,
+				registeredClients:   as.RegisteredClients(),
+				authorizationCodes:  as.TokenRequests("authorization_code"),
+				refreshes:           as.TokenRequests("refresh_token"),
+				scope:               tt.expected.scope,
+				wantAuthorizeDenied: tt.expected.wantAuthorizeDenied,
+				wantInvalidMetadata: tt.expected.wantInvalidMetadata,
+			}
+			if diff := cmp.Diff(tt.expected, got, cmp.AllowUnexported(expected{})); diff != "" {
+				t.Errorf("authorization flow mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFileTokenStore(t *testing.T) {
+	as := authtest.NewAuthorizationServer()
+	defer as.Close()
+	resource := newProtectedResource(t, as, "")
+	defer resource.Close()
+	path := filepath.Join(t.TempDir(), "tokens.json")
+
+	b := &browser{}
+	first := NewOAuthClient(&OAuthClientOptions{Store: NewFileTokenStore(path), OpenURL: b.open})
+	if status, _, err := get(t, first, resource.URL+"/mcp"); err != nil || status != http.StatusOK {
+		t.Fatalf("first client = %d, %v, want 200", status, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("token store is not written: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("token store file mode = %v, want %v", info.Mode().Perm(), os.FileMode(0o600))
+	}
+
+	// 保存したトークンとクライアントの情報を、別のプロセスから再利用できる
+	second := NewOAuthClient(&OAuthClientOptions{Store: NewFileTokenStore(path), OpenURL: b.open})
+	if status, _, err := get(t, second, resource.URL+"/mcp"); err != nil || status != http.StatusOK {
+		t.Fatalf("second client = %d, %v, want 200", status, err)
+	}
+	if b.opened != 1 {
+		t.Errorf("authorization url was opened %d times, want 1", b.opened)
+	}
+}
+
+func TestParseBearerChallenge(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		expected map[string]string
+	}{
+		{
+			name:     "normal : quoted parameters",
+			header:   `Bearer error="invalid_token", error_description="the \"token\" expired", resource_metadata="https://example.com/.well-known/oauth-protected-resource"`,
+			expected: map[string]string{"error": "invalid_token", "error_description": `the "token" expired`, "resource_metadata": "https://example.com/.well-known/oauth-protected-resource"},
+		},
+		{
+			name:     "normal : token parameters",
+			header:   `bearer error=insufficient_scope, scope="mcp admin"`,
+			expected: map[string]string{"error": "insufficient_scope", "scope": "mcp admin"},
+		},
+		{
+			name:     "semi normal : other scheme",
+			header:   `Basic realm="example"`,
+			expected: map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.expected, parseBearerChallenge(tt.header)); diff != "" {
+				t.Errorf("parseBearerChallenge() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
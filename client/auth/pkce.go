@@ -0,0 +1,22 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// PKCEのcode_verifierと、S256で求めたcode_challengeを生成する
+// 参照：(https://datatracker.ietf.org/doc/html/rfc7636#section-4)
+func newPKCE() (verifier string, challenge string) {
+	verifier = randomString(32)
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// nバイトの乱数をbase64urlで符号化した文字列
+func randomString(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
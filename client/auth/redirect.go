@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/kakkky/mcp-sdk-go/shared/auth"
+)
+
+// 認可サーバーからリダイレクトされるパス
+const redirectPath = "/callback"
+
+const authorizationCompletedPage = `<!DOCTYPE html>
+<html><body><p>Authorization completed. You can close this window.</p></body></html>`
+
+const authorizationFailedPage = `<!DOCTYPE html>
+<html><body><p>Authorization failed. You can close this window.</p></body></html>`
+
+type authorizationResult struct {
+	code string
+	err  error
+}
+
+// 認可コードを受け取るためにループバックアドレスで待ち受けるサーバー
+// ネイティブアプリは、リダイレクト先にループバックアドレスの任意のポートを使用できる
+// 参照：(https://datatracker.ietf.org/doc/html/rfc8252#section-7.3)
+type redirectListener struct {
+	listener net.Listener
+	server   *http.Server
+	state    string
+	result   chan authorizationResult
+}
+
+func newRedirectListener(address string, state string) (*redirectListener, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for redirect: %w", err)
+	}
+	r := &redirectListener{
+		listener: listener,
+		state:    state,
+		result:   make(chan authorizationResult, 1),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(redirectPath, r.handleRedirect)
+	r.server = &http.Server{Handler: mux}
+	go func() { _ = r.server.Serve(listener) }()
+	return r, nil
+}
+
+func (r *redirectListener) redirectURI() string {
+	return "http://" + r.listener.Addr().String() + redirectPath
+}
+
+func (r *redirectListener) handleRedirect(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	// 他のサイトから送り込まれた認可コードを受け付けないよう、stateを検証する
+	if query.Get("state") != r.state {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(authorizationFailedPage))
+		return
+	}
+	result := authorizationResult{code: query.Get("code")}
+	if code := query.Get("error"); code != "" {
+		result = authorizationResult{err: &auth.OAuthError{Code: code, Description: query.Get("error_description")}}
+	} else if result.code == "" {
+		result = authorizationResult{err: errors.New("authorization code is missing")}
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if result.err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(authorizationFailedPage))
+	} else {
+		_, _ = w.Write([]byte(authorizationCompletedPage))
+	}
+	select {
+	case r.result <- result:
+	default:
+	}
+}
+
+// 認可サーバーからのリダイレクトを待つ
+func (r *redirectListener) wait(ctx context.Context) (string, error) {
+	select {
+	case result := <-r.result:
+		return result.code, result.err
+	case <-ctx.Done():
+		return "", fmt.Errorf("authorization was not completed: %w", ctx.Err())
+	}
+}
+
+func (r *redirectListener) close() {
+	_ = r.server.Close()
+}
@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/kakkky/mcp-sdk-go/shared/auth"
+)
+
+// 取得したトークンと、登録したクライアントの情報の保存先
+// 保存されていない場合、Tokens、ClientInformationはnilを返す
+type TokenStore interface {
+	Tokens() (*auth.Tokens, error)
+	SaveTokens(tokens *auth.Tokens) error
+	ClientInformation() (*auth.ClientInformation, error)
+	SaveClientInformation(info *auth.ClientInformation) error
+}
+
+// プロセスの終了とともに破棄されるTokenStore
+type MemoryTokenStore struct {
+	mu         sync.Mutex
+	tokens     *auth.Tokens
+	clientInfo *auth.ClientInformation
+}
+
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{}
+}
+
+func (m *MemoryTokenStore) Tokens() (*auth.Tokens, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.tokens, nil
+}
+
+func (m *MemoryTokenStore) SaveTokens(tokens *auth.Tokens) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tokens = tokens
+	return nil
+}
+
+func (m *MemoryTokenStore) ClientInformation() (*auth.ClientInformation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.clientInfo, nil
+}
+
+func (m *MemoryTokenStore) SaveClientInformation(info *auth.ClientInformation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clientInfo = info
+	return nil
+}
+
+// トークンとクライアントの情報をJSONファイルに保存するTokenStore
+// ファイルは所有者のみ読み書きできるパーミッション（0600）で作成する
+type FileTokenStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileTokenStore(path string) *FileTokenStore {
+	return &FileTokenStore{path: path}
+}
+
+type fileTokenStoreData struct {
+	Tokens            *auth.Tokens            `json:"tokens,omitempty"`
+	ClientInformation *auth.ClientInformation `json:"client_information,omitempty"`
+}
+
+func (f *FileTokenStore) Tokens() (*auth.Tokens, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, err := f.load()
+	return data.Tokens, err
+}
+
+func (f *FileTokenStore) SaveTokens(tokens *auth.Tokens) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, err := f.load()
+	if err != nil {
+		return err
+	}
+	data.Tokens = tokens
+	return f.save(data)
+}
+
+func (f *FileTokenStore) ClientInformation() (*auth.ClientInformation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, err := f.load()
+	return data.ClientInformation, err
+}
+
+func (f *FileTokenStore) SaveClientInformation(info *auth.ClientInformation) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, err := f.load()
+	if err != nil {
+		return err
+	}
+	data.ClientInformation = info
+	return f.save(data)
+}
+
+// ファイルが無い場合は空のデータを返す
+func (f *FileTokenStore) load() (fileTokenStoreData, error) {
+	var data fileTokenStoreData
+	content, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return data, nil
+	}
+	if err != nil {
+		return data, fmt.Errorf("failed to read token store: %w", err)
+	}
+	if err := json.Unmarshal(content, &data); err != nil {
+		return data, fmt.Errorf("failed to parse token store: %w", err)
+	}
+	return data, nil
+}
+
+// 書き込み途中の内容を読まれないよう、一時ファイルに書き込んでから置き換える
+func (f *FileTokenStore) save(data fileTokenStoreData) error {
+	content, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal token store: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create token store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0o600); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to set permission of token store: %w", err)
+	}
+	if _, err := tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write token store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write token store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return fmt.Errorf("failed to save token store: %w", err)
+	}
+	return nil
+}
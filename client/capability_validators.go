@@ -72,6 +72,10 @@ func (s *Client) validateRequestHandlerCapability(method string) error {
 		if s.capabilities.Roots == nil {
 			return fmt.Errorf("Client does not support roots (required for %s)", method)
 		}
+	case "elicitation/create":
+		if s.capabilities.Elicitation == nil {
+			return fmt.Errorf("Client does not support elicitation (required for %s)", method)
+		}
 	case "ping":
 		break
 	}
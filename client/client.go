@@ -3,7 +3,7 @@ package client
 import (
 	"errors"
 	"fmt"
-	"log"
+	"sync"
 
 	"github.com/kakkky/mcp-sdk-go/shared"
 	"github.com/kakkky/mcp-sdk-go/shared/protocol"
@@ -23,6 +23,9 @@ type Client struct {
 	capabilities       schema.ClientCapabilities
 	instruction        string
 	clientInfo         schema.Implementation
+	// ListToolsで受け取った、ツールごとのoutputSchema
+	toolOutputSchemasMu sync.Mutex
+	toolOutputSchemas   map[string]*schema.JsonSchema
 	shared.Protocol
 }
 
@@ -90,17 +93,10 @@ func (c *Client) Connect(transport protocol.Transport) error {
 	if transport == nil {
 		return errors.New("transport is required")
 	}
-	// transportに接続
-	go func() {
-		if err := c.Protocol.Connect(transport); err != nil {
-			if err := c.Close(); err != nil {
-				log.Fatalln("Failed to close protocol after connection error:", err)
-			}
-			log.Fatalf("failed to connect to transport: %v", err)
-		}
-	}()
-	// transportへの接続が確立後に後続のinitialiation phaseを開始する
-	<-TransportStartedNotify
+	// transportに接続し、接続が確立後に後続のinitialiation phaseを開始する
+	if err := c.startTransport(transport); err != nil {
+		return err
+	}
 	// initializeリクエスト
 	result, err := c.Request(&schema.InitializeRequestSchema{
 		MethodName: "initialize",
@@ -154,10 +150,36 @@ func (c *Client) Connect(transport protocol.Transport) error {
 		}
 		return fmt.Errorf("failed to send initialized notification: %w", err)
 	}
-	OperationPhaseStartedNotify <- struct{}{}
+	// 再接続によってConnectが複数回呼ばれても、通知を受け取る側がいなければブロックしないようにする
+	select {
+	case OperationPhaseStartedNotify <- struct{}{}:
+	default:
+	}
 	return nil
 }
 
+// TransportStartedNotifyは全てのトランスポートで共有されるため、
+// 複数のクライアントが同時に接続しても通知を取り違えないように、トランスポートの開始を直列化する
+var transportStartMu sync.Mutex
+
+// 接続に失敗した場合は、プロセスを終了させずに呼び出し元へエラーを返す（再接続できるようにするため）
+func (c *Client) startTransport(transport protocol.Transport) error {
+	transportStartMu.Lock()
+	defer transportStartMu.Unlock()
+	connectErrCh := make(chan error, 1)
+	go func() {
+		if err := c.Protocol.Connect(transport); err != nil {
+			connectErrCh <- err
+		}
+	}()
+	select {
+	case <-TransportStartedNotify:
+		return nil
+	case err := <-connectErrCh:
+		return fmt.Errorf("failed to connect to transport: %w", err)
+	}
+}
+
 func (c *Client) ServerCapabilities() schema.ServerCapabilities {
 	return c.serverCapabilities
 }
@@ -238,17 +260,34 @@ func (c *Client) UnsubscribeResource(params schema.UnsubscribeRequestParams) (sc
 	}, &schema.EmptyResultSchema{})
 }
 
+// ListToolsでoutputSchemaを受け取っているツールの場合は、結果のstructuredContentを検証する
 func (c *Client) CallTool(params schema.CallToolRequestParams) (schema.Result, error) {
-	return c.Request(&schema.CallToolRequestSchema{
+	result, err := c.Request(&schema.CallToolRequestSchema{
 		MethodName: "tools/call",
 		ParamsData: params,
 	}, &schema.CallToolResultSchema{})
+	if err != nil {
+		return nil, err
+	}
+	if callToolResult, ok := result.(*schema.CallToolResultSchema); ok {
+		if err := c.validateStructuredContent(params.Name, callToolResult); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
 }
 
 func (c *Client) ListTools() (schema.Result, error) {
-	return c.Request(&schema.ListToolsRequestSchema{
+	result, err := c.Request(&schema.ListToolsRequestSchema{
 		MethodName: "tools/list",
 	}, &schema.ListToolsResultSchema{})
+	if err != nil {
+		return nil, err
+	}
+	if listToolsResult, ok := result.(*schema.ListToolsResultSchema); ok {
+		c.cacheToolOutputSchemas(listToolsResult.Tools)
+	}
+	return result, nil
 }
 
 func (c *Client) SendRootsListChanged() error {
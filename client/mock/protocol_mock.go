@@ -10,6 +10,7 @@
 package mock
 
 import (
+	context "context"
 	reflect "reflect"
 
 	protocol "github.com/kakkky/mcp-sdk-go/shared/protocol"
@@ -98,6 +99,21 @@ func (mr *MockProtocolMockRecorder) Request(request, resultSchema any) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Request", reflect.TypeOf((*MockProtocol)(nil).Request), request, resultSchema)
 }
 
+// RequestWithContext mocks base method.
+func (m *MockProtocol) RequestWithContext(ctx context.Context, request schema.Request, resultSchema any) (schema.Result, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequestWithContext", ctx, request, resultSchema)
+	ret0, _ := ret[0].(schema.Result)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RequestWithContext indicates an expected call of RequestWithContext.
+func (mr *MockProtocolMockRecorder) RequestWithContext(ctx, request, resultSchema any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequestWithContext", reflect.TypeOf((*MockProtocol)(nil).RequestWithContext), ctx, request, resultSchema)
+}
+
 // SetNotificationHandler mocks base method.
 func (m *MockProtocol) SetNotificationHandler(arg0 schema.Notification, handler func(schema.JsonRpcNotification) error) {
 	m.ctrl.T.Helper()
@@ -0,0 +1,442 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kakkky/mcp-sdk-go/shared/protocol"
+	"github.com/kakkky/mcp-sdk-go/shared/schema"
+)
+
+// 再接続中に発行されたリクエストの取り扱い
+type ReconnectPolicy string
+
+const (
+	FAIL_WHILE_RECONNECTING ReconnectPolicy = "fail" // 即座にErrReconnectingを返す
+	HOLD_WHILE_RECONNECTING ReconnectPolicy = "hold" // 再接続が完了するまで待ってから送信する
+)
+
+const (
+	defaultInitialBackoff    = 500 * time.Millisecond
+	defaultMaxBackoff        = 30 * time.Second
+	defaultBackoffMultiplier = 2.0
+)
+
+// FAIL_WHILE_RECONNECTINGの場合に、再接続中に発行されたリクエストが返すエラー
+var ErrReconnecting = errors.New("client is reconnecting")
+
+// Closeされた後、または再接続を諦めた後に発行されたリクエストが返すエラー
+var ErrClientClosed = errors.New("client is closed")
+
+type ReconnectOptions struct {
+	// 1回目の再接続を試みるまでの待機時間。指定しなかった場合は500ミリ秒となる
+	InitialBackoff time.Duration
+	// 待機時間の上限。指定しなかった場合は30秒となる
+	MaxBackoff time.Duration
+	// 再接続に失敗するたびに待機時間に掛ける倍率。指定しなかった場合は2となる
+	Multiplier float64
+	// 1回の切断に対して再接続を試みる最大回数。0の場合は無制限
+	MaxRetries int
+	// 再接続中に発行されたリクエストの取り扱い。指定しなかった場合はFAIL_WHILE_RECONNECTINGとなる
+	Policy ReconnectPolicy
+	// 再接続が完了した時に呼ばれる
+	OnReconnect func()
+	// 再接続の試行に失敗した時、またはセッションの復元に失敗した時に呼ばれる
+	OnError func(error)
+}
+
+// 未指定の項目をデフォルト値で埋めたオプションを返す
+func (o *ReconnectOptions) withDefaults() ReconnectOptions {
+	opts := ReconnectOptions{}
+	if o != nil {
+		opts = *o
+	}
+	if opts.InitialBackoff <= 0 {
+		opts.InitialBackoff = defaultInitialBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = defaultMaxBackoff
+	}
+	if opts.Multiplier < 1 {
+		opts.Multiplier = defaultBackoffMultiplier
+	}
+	if opts.Policy == "" {
+		opts.Policy = FAIL_WHILE_RECONNECTING
+	}
+	return opts
+}
+
+// 切断を検知すると、新しいトランスポートで自動的に再接続するクライアント
+// 再接続後は、initializeをやり直した上で、ロギングレベル、リソースの購読、取得済みの一覧を復元する
+type ReconnectingClient struct {
+	client       *Client
+	newTransport func() (protocol.Transport, error)
+	options      ReconnectOptions
+
+	mu sync.Mutex
+	// 接続中のトランスポートの世代。古いトランスポートのクローズで再接続しないようにするため
+	generation   int
+	isConnected  bool
+	isClosed     bool
+	err          error
+	ready        chan struct{} // 接続中の間はcloseされている
+	done         chan struct{} // Closeされた、または再接続を諦めた時にcloseされる
+	loggingLevel *schema.LoggingLevelSchema
+	subscribed   map[string]struct{}
+	listCache    map[string]schema.Result
+}
+
+// newTransportは接続のたびに呼ばれ、新しいトランスポートを返す必要がある
+func NewReconnectingClient(client *Client, newTransport func() (protocol.Transport, error), options *ReconnectOptions) *ReconnectingClient {
+	return &ReconnectingClient{
+		client:       client,
+		newTransport: newTransport,
+		options:      options.withDefaults(),
+		ready:        make(chan struct{}),
+		done:         make(chan struct{}),
+		subscribed:   make(map[string]struct{}),
+		listCache:    make(map[string]schema.Result),
+	}
+}
+
+// 初回の接続を行う
+// 初回の接続に失敗した場合は再接続せずにエラーを返す
+func (r *ReconnectingClient) Connect() error {
+	if err := r.connect(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.isConnected = true
+	close(r.ready)
+	r.mu.Unlock()
+	return nil
+}
+
+// 新しいトランスポートを生成し、initializeまでを行う
+func (r *ReconnectingClient) connect() error {
+	t, err := r.newTransport()
+	if err != nil {
+		return fmt.Errorf("failed to create transport: %w", err)
+	}
+	r.mu.Lock()
+	r.generation++
+	generation := r.generation
+	r.mu.Unlock()
+	return r.client.Connect(&closeHookTransport{
+		Transport: t,
+		onClosed: func() {
+			r.handleClose(generation)
+		},
+	})
+}
+
+// トランスポートのクローズを検知した時に呼ばれる
+func (r *ReconnectingClient) handleClose(generation int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// Closeによる切断や、接続処理中の古いトランスポートのクローズでは再接続しない
+	if r.isClosed || !r.isConnected || generation != r.generation {
+		return
+	}
+	r.isConnected = false
+	r.ready = make(chan struct{})
+	go r.reconnectLoop()
+}
+
+func (r *ReconnectingClient) reconnectLoop() {
+	backoff := r.options.InitialBackoff
+	for attempt := 1; r.options.MaxRetries == 0 || attempt <= r.options.MaxRetries; attempt++ {
+		select {
+		case <-r.done:
+			return
+		case <-time.After(backoff):
+		}
+		err := r.connect()
+		if err == nil {
+			err = r.restoreSession()
+		}
+		if err == nil {
+			r.mu.Lock()
+			if r.isClosed {
+				r.mu.Unlock()
+				_ = r.client.Close()
+				return
+			}
+			r.isConnected = true
+			close(r.ready)
+			r.mu.Unlock()
+			if r.options.OnReconnect != nil {
+				r.options.OnReconnect()
+			}
+			return
+		}
+		r.onError(fmt.Errorf("reconnect attempt %d failed: %w", attempt, err))
+		backoff = min(time.Duration(float64(backoff)*r.options.Multiplier), r.options.MaxBackoff)
+	}
+	// 再接続を諦めた場合は、以降のリクエストを全てエラーにする
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.isClosed {
+		return
+	}
+	r.isClosed = true
+	r.err = fmt.Errorf("gave up reconnecting after %d attempts", r.options.MaxRetries)
+	close(r.done)
+}
+
+// 切断前のセッションの状態を復元する
+// 接続が再び切れた場合はエラーを返し、それ以外の失敗はOnErrorで通知して継続する
+func (r *ReconnectingClient) restoreSession() error {
+	r.mu.Lock()
+	loggingLevel := r.loggingLevel
+	uris := make([]string, 0, len(r.subscribed))
+	for uri := range r.subscribed {
+		uris = append(uris, uri)
+	}
+	methods := make([]string, 0, len(r.listCache))
+	for method := range r.listCache {
+		methods = append(methods, method)
+	}
+	r.mu.Unlock()
+
+	var steps []func() error
+	if loggingLevel != nil {
+		steps = append(steps, func() error {
+			_, err := r.client.SetLoggingLevel(*loggingLevel)
+			return err
+		})
+	}
+	for _, uri := range uris {
+		steps = append(steps, func() error {
+			_, err := r.client.SubscribeResource(schema.SubscribeRequestParams{Uri: uri})
+			return err
+		})
+	}
+	for _, method := range methods {
+		steps = append(steps, func() error {
+			result, err := r.fetchList(method)
+			if err != nil {
+				return err
+			}
+			r.mu.Lock()
+			r.listCache[method] = result
+			r.mu.Unlock()
+			return nil
+		})
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			// 接続が切れていれば、再接続の試行自体を失敗とする
+			if r.client.Transport() == nil {
+				return err
+			}
+			r.onError(fmt.Errorf("failed to restore session: %w", err))
+		}
+	}
+	return nil
+}
+
+func (r *ReconnectingClient) fetchList(method string) (schema.Result, error) {
+	switch method {
+	case "tools/list":
+		return r.client.ListTools()
+	case "prompts/list":
+		return r.client.ListPrompts()
+	case "resources/list":
+		return r.client.ListResources()
+	case "resources/templates/list":
+		return r.client.ListResourceTemplates()
+	default:
+		return nil, fmt.Errorf("unknown list method: %s", method)
+	}
+}
+
+func (r *ReconnectingClient) onError(err error) {
+	if r.options.OnError != nil {
+		r.options.OnError(err)
+	}
+}
+
+// 接続中であればすぐに、そうでなければポリシーに従ってリクエストの送信可否を判断する
+func (r *ReconnectingClient) waitReady() error {
+	r.mu.Lock()
+	ready := r.ready
+	isConnected := r.isConnected
+	isClosed := r.isClosed
+	err := r.err
+	r.mu.Unlock()
+	if isClosed {
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrClientClosed, err)
+		}
+		return ErrClientClosed
+	}
+	if isConnected {
+		return nil
+	}
+	if r.options.Policy == FAIL_WHILE_RECONNECTING {
+		return ErrReconnecting
+	}
+	select {
+	case <-ready:
+		return nil
+	case <-r.done:
+		return r.waitReady()
+	}
+}
+
+func (r *ReconnectingClient) do(request func() (schema.Result, error)) (schema.Result, error) {
+	if err := r.waitReady(); err != nil {
+		return nil, err
+	}
+	return request()
+}
+
+// 再接続をやめ、接続を閉じる
+func (r *ReconnectingClient) Close() error {
+	r.mu.Lock()
+	if r.isClosed {
+		r.mu.Unlock()
+		return ErrClientClosed
+	}
+	r.isClosed = true
+	isConnected := r.isConnected
+	close(r.done)
+	r.mu.Unlock()
+	if !isConnected {
+		return nil
+	}
+	return r.client.Close()
+}
+
+// Closeされた、または再接続を諦めた時にcloseされるチャネル
+func (r *ReconnectingClient) Done() <-chan struct{} {
+	return r.done
+}
+
+// 接続中であればtrueを返す。再接続中、Close後はfalseとなる
+func (r *ReconnectingClient) IsConnected() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.isConnected && !r.isClosed
+}
+
+// 再接続を諦めた場合は、その理由を返す
+func (r *ReconnectingClient) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// 内部のクライアントを返す
+// 内部のクライアントから直接送信したリクエストは、再接続の待機やセッションの復元の対象とならない
+func (r *ReconnectingClient) Client() *Client {
+	return r.client
+}
+
+// 一覧取得の結果のうち、最後に取得したものを返す
+// 再接続後は自動的に取得し直される
+func (r *ReconnectingClient) CachedList(method string) (schema.Result, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result, ok := r.listCache[method]
+	return result, ok
+}
+
+func (r *ReconnectingClient) Ping() (schema.Result, error) {
+	return r.do(r.client.Ping)
+}
+
+func (r *ReconnectingClient) Complete(params schema.CompleteRequestParams) (schema.Result, error) {
+	return r.do(func() (schema.Result, error) { return r.client.Complete(params) })
+}
+
+func (r *ReconnectingClient) SetLoggingLevel(level schema.LoggingLevelSchema) (schema.Result, error) {
+	result, err := r.do(func() (schema.Result, error) { return r.client.SetLoggingLevel(level) })
+	if err == nil {
+		r.mu.Lock()
+		r.loggingLevel = &level
+		r.mu.Unlock()
+	}
+	return result, err
+}
+
+func (r *ReconnectingClient) GetPrompt(params schema.GetPromptRequestParams) (schema.Result, error) {
+	return r.do(func() (schema.Result, error) { return r.client.GetPrompt(params) })
+}
+
+func (r *ReconnectingClient) ListPrompts() (schema.Result, error) {
+	return r.list("prompts/list")
+}
+
+func (r *ReconnectingClient) ListResources() (schema.Result, error) {
+	return r.list("resources/list")
+}
+
+func (r *ReconnectingClient) ListResourceTemplates() (schema.Result, error) {
+	return r.list("resources/templates/list")
+}
+
+func (r *ReconnectingClient) ListTools() (schema.Result, error) {
+	return r.list("tools/list")
+}
+
+// 一覧を取得し、再接続後に取得し直せるようにキャッシュする
+func (r *ReconnectingClient) list(method string) (schema.Result, error) {
+	result, err := r.do(func() (schema.Result, error) { return r.fetchList(method) })
+	if err == nil {
+		r.mu.Lock()
+		r.listCache[method] = result
+		r.mu.Unlock()
+	}
+	return result, err
+}
+
+func (r *ReconnectingClient) ReadResource(params schema.ReadResourceRequestParams) (schema.Result, error) {
+	return r.do(func() (schema.Result, error) { return r.client.ReadResource(params) })
+}
+
+func (r *ReconnectingClient) SubscribeResource(params schema.SubscribeRequestParams) (schema.Result, error) {
+	result, err := r.do(func() (schema.Result, error) { return r.client.SubscribeResource(params) })
+	if err == nil {
+		r.mu.Lock()
+		r.subscribed[params.Uri] = struct{}{}
+		r.mu.Unlock()
+	}
+	return result, err
+}
+
+func (r *ReconnectingClient) UnsubscribeResource(params schema.UnsubscribeRequestParams) (schema.Result, error) {
+	result, err := r.do(func() (schema.Result, error) { return r.client.UnsubscribeResource(params) })
+	if err == nil {
+		r.mu.Lock()
+		delete(r.subscribed, params.Uri)
+		r.mu.Unlock()
+	}
+	return result, err
+}
+
+func (r *ReconnectingClient) CallTool(params schema.CallToolRequestParams) (schema.Result, error) {
+	return r.do(func() (schema.Result, error) { return r.client.CallTool(params) })
+}
+
+func (r *ReconnectingClient) SendRootsListChanged() error {
+	_, err := r.do(func() (schema.Result, error) { return nil, r.client.SendRootsListChanged() })
+	return err
+}
+
+// トランスポートのクローズを横取りして通知するラッパー
+type closeHookTransport struct {
+	protocol.Transport
+	onClosed func()
+}
+
+func (t *closeHookTransport) SetOnClose(onClose func()) {
+	t.Transport.SetOnClose(func() {
+		// 応答待ちのリクエストがエラーで戻る前に、切断されたことを記録する
+		t.onClosed()
+		onClose()
+	})
+}
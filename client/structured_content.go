@@ -0,0 +1,88 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kakkky/mcp-sdk-go/shared/schema"
+)
+
+func (c *Client) cacheToolOutputSchemas(tools []schema.ToolSchema) {
+	c.toolOutputSchemasMu.Lock()
+	defer c.toolOutputSchemasMu.Unlock()
+	c.toolOutputSchemas = make(map[string]*schema.JsonSchema, len(tools))
+	for _, tool := range tools {
+		if tool.OutputSchema != nil {
+			c.toolOutputSchemas[tool.Name] = tool.OutputSchema
+		}
+	}
+}
+
+func (c *Client) toolOutputSchema(name string) *schema.JsonSchema {
+	c.toolOutputSchemasMu.Lock()
+	defer c.toolOutputSchemasMu.Unlock()
+	return c.toolOutputSchemas[name]
+}
+
+// outputSchemaを持つツールは、エラーの結果を除いてスキーマに一致するstructuredContentを返す必要がある
+func (c *Client) validateStructuredContent(name string, result *schema.CallToolResultSchema) error {
+	outputSchema := c.toolOutputSchema(name)
+	if outputSchema == nil || result.IsError {
+		return nil
+	}
+	if result.StructuredContent == nil {
+		return fmt.Errorf("tool %s has an output schema but did not return structured content", name)
+	}
+	if _, err := outputSchema.Validate(result.StructuredContent, nil); err != nil {
+		return fmt.Errorf("structured content of tool %s does not match the output schema: %w", name, err)
+	}
+	return nil
+}
+
+// ツールを呼び出し、結果のstructuredContentをOutにデコードして返す
+// ツールがエラーの結果を返した場合は、そのテキストをエラーとして返す
+func CallToolStructured[Out any](c *Client, params schema.CallToolRequestParams) (Out, error) {
+	var out Out
+	result, err := c.CallTool(params)
+	if err != nil {
+		return out, err
+	}
+	callToolResult, ok := result.(*schema.CallToolResultSchema)
+	if !ok {
+		return out, fmt.Errorf("unexpected result type: %T", result)
+	}
+	if callToolResult.IsError {
+		return out, fmt.Errorf("tool %s returned an error: %s", params.Name, textOf(callToolResult.Content))
+	}
+	if err := DecodeStructuredContent(callToolResult, &out); err != nil {
+		return out, fmt.Errorf("tool %s: %w", params.Name, err)
+	}
+	return out, nil
+}
+
+// 結果のstructuredContentをoutにデコードする
+func DecodeStructuredContent(result *schema.CallToolResultSchema, out any) error {
+	if result.StructuredContent == nil {
+		return errors.New("result has no structured content")
+	}
+	data, err := json.Marshal(result.StructuredContent)
+	if err != nil {
+		return fmt.Errorf("failed to encode structured content: %w", err)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode structured content: %w", err)
+	}
+	return nil
+}
+
+func textOf(contents []schema.ToolContentSchema) string {
+	var texts []string
+	for _, content := range contents {
+		if text, ok := content.(*schema.TextContentSchema); ok {
+			texts = append(texts, text.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
@@ -0,0 +1,60 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/kakkky/mcp-sdk-go/shared/schema"
+)
+
+func TestClient_ValidateStructuredContent(t *testing.T) {
+	sut := NewClient(schema.Implementation{}, nil)
+	outputSchema := schema.Object(schema.PropertySchema{
+		"temperature": schema.Number("temperature in celsius"),
+	}, "temperature")
+	sut.cacheToolOutputSchemas([]schema.ToolSchema{
+		{Name: "weather", OutputSchema: &outputSchema},
+		{Name: "echo"},
+	})
+	tests := []struct {
+		name          string
+		toolName      string
+		result        *schema.CallToolResultSchema
+		isExpectedErr bool
+	}{
+		{
+			name:     "normal : structured content matches the output schema",
+			toolName: "weather",
+			result:   &schema.CallToolResultSchema{StructuredContent: map[string]any{"temperature": 22.5}},
+		},
+		{
+			name:     "normal : error result is not validated",
+			toolName: "weather",
+			result:   &schema.CallToolResultSchema{IsError: true},
+		},
+		{
+			name:     "normal : tool without output schema is not validated",
+			toolName: "echo",
+			result:   &schema.CallToolResultSchema{StructuredContent: map[string]any{"any": "value"}},
+		},
+		{
+			name:          "semi normal : structured content does not match the output schema",
+			toolName:      "weather",
+			result:        &schema.CallToolResultSchema{StructuredContent: map[string]any{"temperature": "hot"}},
+			isExpectedErr: true,
+		},
+		{
+			name:          "semi normal : structured content is missing",
+			toolName:      "weather",
+			result:        &schema.CallToolResultSchema{},
+			isExpectedErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := sut.validateStructuredContent(tt.toolName, tt.result)
+			if (err != nil) != tt.isExpectedErr {
+				t.Errorf("validateStructuredContent() error = %v, isExpectedErr %v", err, tt.isExpectedErr)
+			}
+		})
+	}
+}
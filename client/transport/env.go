@@ -0,0 +1,174 @@
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// 環境変数の値の中で、シークレットを参照するためのプレフィックス
+// "${secret:NAME}"と書くと、SecretProviderからNAMEの値を取得して埋め込む
+const SECRET_REFERENCE_PREFIX = "secret:"
+
+// ログ上でシークレットの値を置き換える文字列
+const REDACTED = "[REDACTED]"
+
+// サーバープロセスに渡すシークレットを解決する
+type SecretProvider interface {
+	Secret(name string) (string, error)
+}
+
+// 指定したシークレットが見つからない場合に返すエラー
+var ErrSecretNotFound = errors.New("secret not found")
+
+// メモリ上のシークレットを返すプロバイダー
+// OSのキーチェーンなどから読み出した値を渡す場合や、テストで使用する
+type MapSecretProvider map[string]string
+
+func (m MapSecretProvider) Secret(name string) (string, error) {
+	value, ok := m[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+	}
+	return value, nil
+}
+
+// "KEY=VALUE"形式のファイルからシークレットを読み出すプロバイダー
+// 空行と"#"から始まる行は無視し、値を囲む引用符は取り除く
+type EnvFileSecretProvider struct {
+	Path string
+}
+
+func (e EnvFileSecretProvider) Secret(name string) (string, error) {
+	data, err := os.ReadFile(e.Path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read env file: %w", err)
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(key) != name {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		return value, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read env file: %w", err)
+	}
+	return "", fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+}
+
+// コマンドの標準出力からシークレットを読み出すプロバイダー
+// Argsの末尾にシークレット名を付けて実行し、出力の前後の空白を取り除いた値を返す
+// 例: CommandSecretProvider{Command: "pass", Args: []string{"show"}}
+type CommandSecretProvider struct {
+	Command string
+	Args    []string
+}
+
+func (c CommandSecretProvider) Secret(name string) (string, error) {
+	args := append(append([]string{}, c.Args...), name)
+	output, err := exec.Command(c.Command, args...).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to run secret command for %s: %w", name, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// サーバープロセスに渡す環境変数を組み立てる
+// 値に含まれる"${VAR}"、"$VAR"は、組み立て中の環境変数、親プロセスの環境変数の順に探して展開し、
+// "${secret:NAME}"はsecretsから取得した値で置き換える
+// 戻り値のsecretValuesは、ログから取り除くべき値の一覧
+func buildEnvironment(params StdioServerParameters) (env []string, secretValues []string, err error) {
+	var base []string
+	if !params.IsolateEnv {
+		base = getDefaultEnvironment()
+	}
+	values := make(map[string]string, len(base)+len(params.Env))
+	order := make([]string, 0, len(base)+len(params.Env))
+	set := func(key, value string) {
+		if _, ok := values[key]; !ok {
+			order = append(order, key)
+		}
+		values[key] = value
+	}
+	for _, kv := range base {
+		key, value, _ := strings.Cut(kv, "=")
+		set(key, value)
+	}
+
+	for _, kv := range params.Env {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok {
+			return nil, nil, fmt.Errorf("invalid environment variable %q: must be in key=value format", kv)
+		}
+		var expandErr error
+		expanded := os.Expand(value, func(name string) string {
+			if secretName, ok := strings.CutPrefix(name, SECRET_REFERENCE_PREFIX); ok {
+				if params.Secrets == nil {
+					expandErr = fmt.Errorf("environment variable %s refers to secret %s, but no secret provider is set", key, secretName)
+					return ""
+				}
+				secret, err := params.Secrets.Secret(secretName)
+				if err != nil {
+					expandErr = fmt.Errorf("failed to resolve secret %s for environment variable %s: %w", secretName, key, err)
+					return ""
+				}
+				if secret != "" {
+					secretValues = append(secretValues, secret)
+				}
+				return secret
+			}
+			if v, ok := values[name]; ok {
+				return v
+			}
+			return os.Getenv(name)
+		})
+		if expandErr != nil {
+			return nil, nil, expandErr
+		}
+		set(key, expanded)
+	}
+
+	env = make([]string, 0, len(order))
+	for _, key := range order {
+		env = append(env, key+"="+values[key])
+	}
+	return env, secretValues, nil
+}
+
+// ログに出力する文字列からシークレットの値を取り除く
+type redactor struct {
+	replacer *strings.Replacer
+}
+
+func newRedactor(secretValues []string) *redactor {
+	if len(secretValues) == 0 {
+		return &redactor{}
+	}
+	oldnew := make([]string, 0, len(secretValues)*2)
+	for _, secret := range secretValues {
+		oldnew = append(oldnew, secret, REDACTED)
+	}
+	return &redactor{replacer: strings.NewReplacer(oldnew...)}
+}
+
+func (r *redactor) redact(s string) string {
+	if r == nil || r.replacer == nil {
+		return s
+	}
+	return r.replacer.Replace(s)
+}
@@ -0,0 +1,198 @@
+package transport
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kakkky/mcp-sdk-go/client"
+	"github.com/kakkky/mcp-sdk-go/shared/schema"
+)
+
+func TestBuildEnvironment(t *testing.T) {
+	t.Setenv("PATH", "/usr/bin")
+	t.Setenv("MCP_TEST_PARENT", "parent")
+	envFile := filepath.Join(t.TempDir(), "secrets.env")
+	if err := os.WriteFile(envFile, []byte("# comment\nexport DB_PASSWORD=\"p@ss\"\nOTHER=x\n"), 0o600); err != nil {
+		t.Fatalf("failed to write env file: %v", err)
+	}
+	tests := []struct {
+		name        string
+		params      StdioServerParameters
+		wantEnv     []string // 含まれているべき環境変数
+		wantMissing []string // 含まれていてはならない環境変数名
+		wantSecrets []string
+		wantErr     error
+	}{
+		{
+			name:    "normal : default environment is inherited when Env is empty",
+			params:  StdioServerParameters{},
+			wantEnv: []string{"PATH=/usr/bin"},
+		},
+		{
+			name:    "normal : Env is merged into the default environment",
+			params:  StdioServerParameters{Env: []string{"FOO=bar"}},
+			wantEnv: []string{"FOO=bar", "PATH=/usr/bin"},
+		},
+		{
+			name:    "normal : Env overrides the default environment",
+			params:  StdioServerParameters{Env: []string{"FOO=bar", "PATH=/opt/bin"}},
+			wantEnv: []string{"FOO=bar", "PATH=/opt/bin"},
+		},
+		{
+			name:        "normal : only Env is passed with IsolateEnv",
+			params:      StdioServerParameters{Env: []string{"FOO=bar"}, IsolateEnv: true},
+			wantEnv:     []string{"FOO=bar"},
+			wantMissing: []string{"PATH"},
+		},
+		{
+			name: "normal : variables are expanded with the environment being built and the parent environment",
+			params: StdioServerParameters{
+				Env: []string{"PATH=/opt/bin:${PATH}", "FROM_PARENT=$MCP_TEST_PARENT", "DERIVED=${FROM_PARENT}-child"},
+			},
+			wantEnv: []string{"PATH=/opt/bin:/usr/bin", "FROM_PARENT=parent", "DERIVED=parent-child"},
+		},
+		{
+			name: "normal : secrets are resolved from the map provider",
+			params: StdioServerParameters{
+				Env:     []string{"API_KEY=${secret:api-key}", "AUTH=Bearer ${secret:api-key}"},
+				Secrets: MapSecretProvider{"api-key": "s3cr3t"},
+			},
+			wantEnv:     []string{"API_KEY=s3cr3t", "AUTH=Bearer s3cr3t"},
+			wantSecrets: []string{"s3cr3t", "s3cr3t"},
+		},
+		{
+			name: "normal : secrets are resolved from the env file provider",
+			params: StdioServerParameters{
+				Env:     []string{"DB_PASSWORD=${secret:DB_PASSWORD}"},
+				Secrets: EnvFileSecretProvider{Path: envFile},
+			},
+			wantEnv:     []string{"DB_PASSWORD=p@ss"},
+			wantSecrets: []string{"p@ss"},
+		},
+		{
+			name: "normal : secrets are resolved from the command output",
+			params: StdioServerParameters{
+				Env:     []string{"TOKEN=${secret:token}"},
+				Secrets: CommandSecretProvider{Command: "printf", Args: []string{"%s-value\n"}},
+			},
+			wantEnv:     []string{"TOKEN=token-value"},
+			wantSecrets: []string{"token-value"},
+		},
+		{
+			name: "semi normal : unknown secret",
+			params: StdioServerParameters{
+				Env:     []string{"API_KEY=${secret:missing}"},
+				Secrets: MapSecretProvider{},
+			},
+			wantErr: ErrSecretNotFound,
+		},
+		{
+			name: "semi normal : secret reference without provider",
+			params: StdioServerParameters{
+				Env: []string{"API_KEY=${secret:api-key}"},
+			},
+			wantErr: errors.New("no secret provider is set"),
+		},
+		{
+			name: "semi normal : invalid environment variable format",
+			params: StdioServerParameters{
+				Env: []string{"INVALID"},
+			},
+			wantErr: errors.New("must be in key=value format"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env, secrets, err := buildEnvironment(tt.params)
+			if tt.wantErr != nil {
+				if err == nil {
+					t.Fatalf("buildEnvironment() error = nil, want %v", tt.wantErr)
+				}
+				if !errors.Is(err, tt.wantErr) && !strings.Contains(err.Error(), tt.wantErr.Error()) {
+					t.Errorf("buildEnvironment() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("buildEnvironment() error = %v", err)
+			}
+			for _, want := range tt.wantEnv {
+				if !slices.Contains(env, want) {
+					t.Errorf("environment %v does not contain %s", env, want)
+				}
+			}
+			for _, key := range tt.wantMissing {
+				if slices.ContainsFunc(env, func(kv string) bool { return strings.HasPrefix(kv, key+"=") }) {
+					t.Errorf("environment %v should not contain %s", env, key)
+				}
+			}
+			if diff := cmp.Diff(tt.wantSecrets, secrets); diff != "" {
+				t.Errorf("secret values mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestStdioClientTransport_RedactSecrets(t *testing.T) {
+	shPath, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("cannot find 'sh' command, skipping test")
+	}
+	var logBuf bytes.Buffer
+	messageLogger := slog.New(slog.NewTextHandler(&logBuf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	// シークレットを標準エラー出力に書き出した上で、受け取ったメッセージをそのまま返すサーバー
+	sut := NewStdioClientTransport(StdioServerParameters{
+		Command:       shPath,
+		Args:          []string{"-c", `echo "using key $API_KEY" >&2; exec cat`},
+		Env:           []string{"API_KEY=${secret:api-key}"},
+		Secrets:       MapSecretProvider{"api-key": "s3cr3t"},
+		Stderr:        PIPE,
+		MessageLogger: messageLogger,
+	})
+	received := make(chan struct{})
+	sut.SetOnReceiveMessage(func(msg schema.JsonRpcMessage) { close(received) })
+	go func() {
+		if err := sut.Start(); err != nil {
+			t.Errorf("failed to start transport: %v", err)
+		}
+	}()
+	<-client.TransportStartedNotify
+	if err := sut.SendMessage(schema.JsonRpcRequest{
+		BaseMessage: schema.BaseMessage{Jsonrpc: schema.JSON_RPC_VERSION, Id: 1},
+		Request: &schema.InitializeRequestSchema{
+			MethodName: "initialize",
+			ParamsData: schema.InitializeRequestParams{
+				ProtocolVersion: schema.LATEST_PROTOCOL_VERSION,
+				ClientInfo:      schema.Implementation{Name: "s3cr3t", Version: "1.0.0"},
+			},
+		},
+	}); err != nil {
+		t.Fatalf("SendMessage() error = %v", err)
+	}
+	select {
+	case <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("message was not echoed back")
+	}
+	if err := sut.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+
+	logs := logBuf.String()
+	if strings.Contains(logs, "s3cr3t") || strings.Count(logs, REDACTED) != 2 {
+		t.Errorf("secret is not redacted from the sent and received messages: %s", logs)
+	}
+	if diff := cmp.Diff([]string{"using key " + REDACTED}, sut.RecentStderr()); diff != "" {
+		t.Errorf("RecentStderr() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,80 @@
+package transport
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kakkky/mcp-sdk-go/client"
+	mcpserver "github.com/kakkky/mcp-sdk-go/mcp-server"
+	"github.com/kakkky/mcp-sdk-go/mcp-server/server"
+	"github.com/kakkky/mcp-sdk-go/shared/schema"
+)
+
+func TestExchangeElicit_WithMcpServer(t *testing.T) {
+	mcpServer := mcpserver.NewMcpServer(schema.Implementation{Name: "booking-server", Version: "1.0.0"}, &server.ServerOptions{
+		Capabilities: schema.ServerCapabilities{
+			Tools: &schema.Tools{ListChanged: true},
+		},
+	})
+	if _, err := mcpServer.ToolWithExchange("book", "book a table", schema.PropertySchema{}, nil, func(ctx context.Context, exchange *mcpserver.Exchange, args map[string]any) (schema.CallToolResultSchema, error) {
+		// ツールの処理中にクライアントへ入力を要求する
+		result, err := exchange.Elicit(schema.ElicitRequestParams{
+			Message:         "How many people?",
+			RequestedSchema: schema.Object(schema.PropertySchema{"people": schema.Integer("number of people")}, "people"),
+		})
+		if err != nil {
+			return schema.CallToolResultSchema{}, err
+		}
+		text := "cancelled"
+		if result.Action == schema.ELICIT_ACTION_ACCEPT {
+			text = "booked"
+		}
+		return schema.CallToolResultSchema{
+			Content: []schema.ToolContentSchema{&schema.TextContentSchema{Type: "text", Text: text}},
+		}, nil
+	}); err != nil {
+		t.Fatalf("failed to register tool: %v", err)
+	}
+
+	clientTransport, serverTransport := NewInMemoryTransportPair(&InMemoryTransportOptions{Serialize: true})
+	defer clientTransport.Close()
+	if err := mcpServer.Connect(serverTransport); err != nil {
+		t.Fatalf("failed to connect server: %v", err)
+	}
+	c := client.NewClient(schema.Implementation{Name: "booking-client", Version: "1.0.0"}, &client.ClientOptions{
+		Capabilities: schema.ClientCapabilities{Elicitation: &schema.Elicitation{}},
+	})
+	var gotMessage string
+	c.SetRequestHandler(&schema.ElicitRequestSchema{MethodName: "elicitation/create"}, func(request schema.JsonRpcRequest) (schema.Result, error) {
+		gotMessage = request.Request.(*schema.ElicitRequestSchema).ParamsData.Message
+		return &schema.ElicitResultSchema{Action: schema.ELICIT_ACTION_ACCEPT, Content: map[string]any{"people": 2}}, nil
+	})
+	errCh := make(chan error, 1)
+	go func() {
+		if err := c.Connect(clientTransport); err != nil {
+			errCh <- err
+		}
+	}()
+	select {
+	case err := <-errCh:
+		t.Fatalf("failed to connect: %v", err)
+	case <-client.OperationPhaseStartedNotify:
+	}
+	// サーバー側のinitialized通知を読み捨てる
+	<-server.OperationPhaseStartedNotify
+
+	result, err := c.CallTool(schema.CallToolRequestParams{Name: "book"})
+	if err != nil {
+		t.Fatalf("CallTool() error = %v", err)
+	}
+	want := &schema.CallToolResultSchema{
+		Content: []schema.ToolContentSchema{&schema.TextContentSchema{Type: "text", Text: "booked"}},
+	}
+	if diff := cmp.Diff(want, result); diff != "" {
+		t.Errorf("CallTool() mismatch (-want +got):\n%s", diff)
+	}
+	if gotMessage != "How many people?" {
+		t.Errorf("elicitation message = %q, want %q", gotMessage, "How many people?")
+	}
+}
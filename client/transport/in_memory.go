@@ -0,0 +1,187 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/kakkky/mcp-sdk-go/client"
+	"github.com/kakkky/mcp-sdk-go/shared/schema"
+	"github.com/kakkky/mcp-sdk-go/shared/schema/jsonrpc"
+)
+
+type InMemoryTransportOptions struct {
+	// trueの場合、送信するメッセージをjsonrpc.Marshal/Unmarshalで往復させてから相手に渡す
+	// エンコード・デコードの不具合をテストで検出するために使用する
+	Serialize bool
+}
+
+// 同一プロセス内で、2つのトランスポート間でschema.JsonRpcMessageを直接受け渡すトランスポート
+// テストや、自プロセス内にMCPサーバーを組み込む用途で使用する
+type InMemoryTransport struct {
+	peer     *InMemoryTransport
+	options  InMemoryTransportOptions
+	isClient bool // Client.Connectに接続確立を通知するかどうか
+
+	mu        sync.Mutex
+	queue     []schema.JsonRpcMessage // 受信したが未処理のメッセージ
+	notify    chan struct{}           // queueにメッセージが追加されたことを通知する
+	isStarted bool
+	isClosed  bool
+	done      chan struct{}
+
+	onReceiveMessage func(schema.JsonRpcMessage)
+	onClose          func()
+	onError          func(error)
+}
+
+// 互いに接続された2つのトランスポートを生成する
+// 1つ目はClient.Connectに、2つ目はMcpServer(Server).Connectに渡す
+func NewInMemoryTransportPair(options *InMemoryTransportOptions) (*InMemoryTransport, *InMemoryTransport) {
+	opts := InMemoryTransportOptions{}
+	if options != nil {
+		opts = *options
+	}
+	clientTransport := newInMemoryTransport(opts, true)
+	serverTransport := newInMemoryTransport(opts, false)
+	clientTransport.peer = serverTransport
+	serverTransport.peer = clientTransport
+	return clientTransport, serverTransport
+}
+
+func newInMemoryTransport(options InMemoryTransportOptions, isClient bool) *InMemoryTransport {
+	return &InMemoryTransport{
+		options:  options,
+		isClient: isClient,
+		notify:   make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+}
+
+// 受信キューの処理を開始する
+// Start前に相手から送られたメッセージは、Start後に順番に処理される
+func (t *InMemoryTransport) Start() error {
+	t.mu.Lock()
+	if t.isStarted {
+		t.mu.Unlock()
+		return errors.New("in-memory transport is already started")
+	}
+	if t.isClosed {
+		t.mu.Unlock()
+		return errors.New("in-memory transport is closed")
+	}
+	t.isStarted = true
+	t.mu.Unlock()
+
+	go t.deliverLoop()
+	if t.isClient {
+		go func() {
+			client.TransportStartedNotify <- struct{}{}
+		}()
+	}
+	return nil
+}
+
+func (t *InMemoryTransport) SendMessage(message schema.JsonRpcMessage) error {
+	t.mu.Lock()
+	isClosed := t.isClosed
+	t.mu.Unlock()
+	if isClosed {
+		return errors.New("in-memory transport is closed")
+	}
+	if t.options.Serialize {
+		data, err := jsonrpc.Marshal(message)
+		if err != nil {
+			return fmt.Errorf("failed to marshal message: %w", err)
+		}
+		message, err = jsonrpc.Unmarshal(data)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal message: %w", err)
+		}
+	}
+	return t.peer.enqueue(message)
+}
+
+// 自身と相手の両方を閉じ、それぞれのOnCloseを呼び出す
+func (t *InMemoryTransport) Close() error {
+	if !t.close() {
+		return errors.New("in-memory transport is already closed")
+	}
+	t.peer.close()
+	return nil
+}
+
+func (t *InMemoryTransport) close() bool {
+	t.mu.Lock()
+	if t.isClosed {
+		t.mu.Unlock()
+		return false
+	}
+	t.isClosed = true
+	t.queue = nil
+	close(t.done)
+	t.mu.Unlock()
+	t.OnClose()
+	return true
+}
+
+func (t *InMemoryTransport) enqueue(message schema.JsonRpcMessage) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.isClosed {
+		return errors.New("peer in-memory transport is closed")
+	}
+	t.queue = append(t.queue, message)
+	select {
+	case t.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// キューに積まれたメッセージを送信順にonReceiveMessageへ渡す
+func (t *InMemoryTransport) deliverLoop() {
+	for {
+		t.mu.Lock()
+		queue := t.queue
+		t.queue = nil
+		t.mu.Unlock()
+		for _, message := range queue {
+			select {
+			case <-t.done:
+				return
+			default:
+			}
+			t.onReceiveMessage(message)
+		}
+		select {
+		case <-t.done:
+			return
+		case <-t.notify:
+		}
+	}
+}
+
+func (t *InMemoryTransport) OnClose() {
+	if t.onClose != nil {
+		t.onClose()
+	}
+}
+
+func (t *InMemoryTransport) OnError(err error) {
+	if t.onError != nil {
+		t.onError(err)
+	}
+}
+
+func (t *InMemoryTransport) SetOnReceiveMessage(onReceiveMessage func(schema.JsonRpcMessage)) {
+	t.onReceiveMessage = onReceiveMessage
+}
+
+func (t *InMemoryTransport) SetOnClose(onClose func()) {
+	t.onClose = onClose
+}
+
+func (t *InMemoryTransport) SetOnError(onError func(error)) {
+	t.onError = onError
+}
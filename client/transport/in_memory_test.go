@@ -0,0 +1,113 @@
+package transport
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kakkky/mcp-sdk-go/client"
+	mcpserver "github.com/kakkky/mcp-sdk-go/mcp-server"
+	"github.com/kakkky/mcp-sdk-go/mcp-server/server"
+	"github.com/kakkky/mcp-sdk-go/shared/schema"
+)
+
+func TestInMemoryTransportPair_WithMcpServer(t *testing.T) {
+	tests := []struct {
+		name         string
+		options      *InMemoryTransportOptions
+		expectedType string
+	}{
+		{
+			name:         "normal : messages are passed as they are",
+			options:      nil,
+			expectedType: "int",
+		},
+		{
+			name:         "normal : messages are passed through jsonrpc.Marshal/Unmarshal",
+			options:      &InMemoryTransportOptions{Serialize: true},
+			expectedType: "float64",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mcpServer := mcpserver.NewMcpServer(schema.Implementation{Name: "in-memory-server", Version: "1.0.0"}, &server.ServerOptions{
+				Capabilities: schema.ServerCapabilities{
+					Tools: &schema.Tools{ListChanged: true},
+				},
+			})
+			if _, err := mcpServer.Tool(
+				"type-of",
+				"returns the go type of the argument",
+				schema.PropertySchema{
+					"value": schema.PropertyInfoSchema{Type: "number", Description: "any number"},
+				},
+				nil,
+				func(args map[string]any) (schema.CallToolResultSchema, error) {
+					var typeName string
+					switch args["value"].(type) {
+					case int:
+						typeName = "int"
+					case float64:
+						typeName = "float64"
+					}
+					return schema.CallToolResultSchema{
+						Content: []schema.ToolContentSchema{
+							&schema.TextContentSchema{Type: "text", Text: typeName},
+						},
+					}, nil
+				},
+			); err != nil {
+				t.Fatalf("failed to register tool: %v", err)
+			}
+
+			clientTransport, serverTransport := NewInMemoryTransportPair(tt.options)
+			if err := mcpServer.Connect(serverTransport); err != nil {
+				t.Fatalf("failed to connect server: %v", err)
+			}
+
+			c := client.NewClient(schema.Implementation{Name: "in-memory-client", Version: "1.0.0"}, nil)
+			errCh := make(chan error, 1)
+			go func() {
+				if err := c.Connect(clientTransport); err != nil {
+					errCh <- err
+				}
+			}()
+			select {
+			case err := <-errCh:
+				t.Fatalf("failed to connect: %v", err)
+			case <-client.OperationPhaseStartedNotify:
+			}
+			// サーバー側のinitialized通知を読み捨てる
+			<-server.OperationPhaseStartedNotify
+
+			result, err := c.CallTool(schema.CallToolRequestParams{
+				Name:      "type-of",
+				Arguments: map[string]any{"value": 1},
+			})
+			if err != nil {
+				t.Fatalf("CallTool() error = %v", err)
+			}
+			want := &schema.CallToolResultSchema{
+				Content: []schema.ToolContentSchema{
+					&schema.TextContentSchema{Type: "text", Text: tt.expectedType},
+				},
+			}
+			if diff := cmp.Diff(want, result); diff != "" {
+				t.Errorf("CallTool() mismatch (-want +got):\n%s", diff)
+			}
+
+			// 片方を閉じると相手側も閉じられる
+			serverClosed := make(chan struct{})
+			serverTransport.SetOnClose(func() { close(serverClosed) })
+			if err := clientTransport.Close(); err != nil {
+				t.Errorf("Close() error = %v", err)
+			}
+			<-serverClosed
+			if err := serverTransport.SendMessage(schema.JsonRpcNotification{
+				Jsonrpc:      schema.JSON_RPC_VERSION,
+				Notification: &schema.ToolListChangedNotificationSchema{MethodName: "notifications/tools/list_changed"},
+			}); err == nil {
+				t.Error("SendMessage() after Close should return an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package transport
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kakkky/mcp-sdk-go/client"
+	mcpserver "github.com/kakkky/mcp-sdk-go/mcp-server"
+	"github.com/kakkky/mcp-sdk-go/mcp-server/server"
+	"github.com/kakkky/mcp-sdk-go/shared/protocol"
+	"github.com/kakkky/mcp-sdk-go/shared/schema"
+)
+
+// 接続のたびに新しいMcpServerを起動し、サーバー側のトランスポートを記録するテスト用のファクトリ
+type serverFactory struct {
+	t  *testing.T
+	mu sync.Mutex
+	// 接続ごとのサーバー側のトランスポート
+	serverTransports []*InMemoryTransport
+	// サーバーが受け取ったロギングレベル
+	loggingLevels []schema.LoggingLevelSchema
+}
+
+func (f *serverFactory) newTransport() (protocol.Transport, error) {
+	mcpServer := mcpserver.NewMcpServer(schema.Implementation{Name: "reconnect-server", Version: "1.0.0"}, &server.ServerOptions{
+		Capabilities: schema.ServerCapabilities{
+			Tools:   &schema.Tools{ListChanged: true},
+			Logging: &schema.Logging{},
+		},
+	})
+	if _, err := mcpServer.Tool("echo", "echo tool", schema.PropertySchema{}, nil,
+		func(args map[string]any) (schema.CallToolResultSchema, error) {
+			return schema.CallToolResultSchema{}, nil
+		},
+	); err != nil {
+		return nil, err
+	}
+	mcpServer.Server.SetRequestHandler(&schema.SetLevelRequestSchema{MethodName: "logging/setLevel"}, func(request schema.JsonRpcRequest) (schema.Result, error) {
+		params := request.Params().(schema.SetLoggingLevelRequestParams)
+		f.mu.Lock()
+		f.loggingLevels = append(f.loggingLevels, params.Level)
+		f.mu.Unlock()
+		return &schema.EmptyResultSchema{}, nil
+	})
+	clientTransport, serverTransport := NewInMemoryTransportPair(nil)
+	if err := mcpServer.Connect(serverTransport); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	f.serverTransports = append(f.serverTransports, serverTransport)
+	f.mu.Unlock()
+	return clientTransport, nil
+}
+
+// 最後に接続したサーバーを落とす
+func (f *serverFactory) crash() {
+	f.mu.Lock()
+	serverTransport := f.serverTransports[len(f.serverTransports)-1]
+	f.mu.Unlock()
+	if err := serverTransport.Close(); err != nil {
+		f.t.Errorf("failed to close server transport: %v", err)
+	}
+}
+
+// 他のテストに影響しないよう、送信された通知を読み捨てる
+func drainOperationPhaseNotify() {
+	for {
+		select {
+		case <-client.OperationPhaseStartedNotify:
+		case <-server.OperationPhaseStartedNotify:
+		default:
+			return
+		}
+	}
+}
+
+func TestReconnectingClient_RestoreSession(t *testing.T) {
+	defer drainOperationPhaseNotify()
+	factory := &serverFactory{t: t}
+	reconnected := make(chan struct{}, 1)
+	sut := client.NewReconnectingClient(
+		client.NewClient(schema.Implementation{Name: "reconnect-client", Version: "1.0.0"}, nil),
+		factory.newTransport,
+		&client.ReconnectOptions{
+			InitialBackoff: 10 * time.Millisecond,
+			OnReconnect:    func() { reconnected <- struct{}{} },
+			OnError:        func(err error) { t.Errorf("unexpected error: %v", err) },
+		},
+	)
+	if err := sut.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	if _, err := sut.SetLoggingLevel(schema.LoggingLevelSchema("debug")); err != nil {
+		t.Fatalf("SetLoggingLevel() error = %v", err)
+	}
+	if _, err := sut.ListTools(); err != nil {
+		t.Fatalf("ListTools() error = %v", err)
+	}
+
+	factory.crash()
+	select {
+	case <-reconnected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("client did not reconnect")
+	}
+
+	if _, err := sut.CallTool(schema.CallToolRequestParams{Name: "echo", Arguments: map[string]any{}}); err != nil {
+		t.Errorf("CallTool() after reconnect error = %v", err)
+	}
+	// 再接続後のサーバーにもロギングレベルが再設定されている
+	factory.mu.Lock()
+	if diff := cmp.Diff([]schema.LoggingLevelSchema{"debug", "debug"}, factory.loggingLevels); diff != "" {
+		t.Errorf("logging levels mismatch (-want +got):\n%s", diff)
+	}
+	if len(factory.serverTransports) != 2 {
+		t.Errorf("number of connections = %d, want 2", len(factory.serverTransports))
+	}
+	factory.mu.Unlock()
+	// 一覧は再接続後に取得し直されている
+	if _, ok := sut.CachedList("tools/list"); !ok {
+		t.Error("tools/list is not cached")
+	}
+
+	if err := sut.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	if _, err := sut.Ping(); !errors.Is(err, client.ErrClientClosed) {
+		t.Errorf("Ping() after Close error = %v, want %v", err, client.ErrClientClosed)
+	}
+}
+
+func TestReconnectingClient_Policy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  client.ReconnectPolicy
+		wantErr error
+	}{
+		{
+			name:    "semi normal : requests fail while reconnecting",
+			policy:  client.FAIL_WHILE_RECONNECTING,
+			wantErr: client.ErrReconnecting,
+		},
+		{
+			name:    "normal : requests are held until reconnected",
+			policy:  client.HOLD_WHILE_RECONNECTING,
+			wantErr: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer drainOperationPhaseNotify()
+			factory := &serverFactory{t: t}
+			sut := client.NewReconnectingClient(
+				client.NewClient(schema.Implementation{Name: "reconnect-client", Version: "1.0.0"}, nil),
+				factory.newTransport,
+				&client.ReconnectOptions{
+					// 再接続中にリクエストを発行できるよう、待機時間を長めにとる
+					InitialBackoff: 200 * time.Millisecond,
+					Policy:         tt.policy,
+				},
+			)
+			if err := sut.Connect(); err != nil {
+				t.Fatalf("Connect() error = %v", err)
+			}
+			defer func() {
+				_ = sut.Close()
+			}()
+
+			factory.crash()
+			_, err := sut.Ping()
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Ping() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReconnectingClient_GiveUp(t *testing.T) {
+	defer drainOperationPhaseNotify()
+	factory := &serverFactory{t: t}
+	connectCount := 0
+	sut := client.NewReconnectingClient(
+		client.NewClient(schema.Implementation{Name: "reconnect-client", Version: "1.0.0"}, nil),
+		func() (protocol.Transport, error) {
+			connectCount++
+			// 2回目以降の接続は全て失敗させる
+			if connectCount > 1 {
+				return nil, errors.New("server is down")
+			}
+			return factory.newTransport()
+		},
+		&client.ReconnectOptions{
+			InitialBackoff: 10 * time.Millisecond,
+			MaxRetries:     3,
+			Policy:         client.HOLD_WHILE_RECONNECTING,
+		},
+	)
+	if err := sut.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+
+	factory.crash()
+	select {
+	case <-sut.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("client did not give up reconnecting")
+	}
+	if sut.Err() == nil {
+		t.Error("Err() should return the reason of giving up")
+	}
+	if _, err := sut.Ping(); !errors.Is(err, client.ErrClientClosed) {
+		t.Errorf("Ping() error = %v, want %v", err, client.ErrClientClosed)
+	}
+	if connectCount != 4 {
+		t.Errorf("connect count = %d, want 4", connectCount)
+	}
+}
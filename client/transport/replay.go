@@ -0,0 +1,25 @@
+package transport
+
+import (
+	"github.com/kakkky/mcp-sdk-go/client"
+	"github.com/kakkky/mcp-sdk-go/shared/transport"
+)
+
+// 記録したクライアントのセッションを、client.Clientに対して再生するトランスポートを生成する
+// client.ClientのConnectが接続の確立を待てるよう、Start時にTransportStartedNotifyへ通知する
+func NewReplayClientTransport(records []transport.Record, options *transport.ReplayOptions) *transport.ReplayTransport {
+	opts := transport.ReplayOptions{}
+	if options != nil {
+		opts = *options
+	}
+	onStarted := opts.OnStarted
+	opts.OnStarted = func() {
+		if onStarted != nil {
+			onStarted()
+		}
+		go func() {
+			client.TransportStartedNotify <- struct{}{}
+		}()
+	}
+	return transport.NewReplayTransport(records, &opts)
+}
@@ -0,0 +1,119 @@
+package transport
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kakkky/mcp-sdk-go/client"
+	mcpserver "github.com/kakkky/mcp-sdk-go/mcp-server"
+	"github.com/kakkky/mcp-sdk-go/mcp-server/server"
+	"github.com/kakkky/mcp-sdk-go/shared/schema"
+	"github.com/kakkky/mcp-sdk-go/shared/transport"
+)
+
+// McpServerとのセッションを記録し、JSONLを返す
+func recordSession(t *testing.T) []byte {
+	t.Helper()
+	mcpServer := mcpserver.NewMcpServer(schema.Implementation{Name: "recorded-server", Version: "1.0.0"}, &server.ServerOptions{
+		Capabilities: schema.ServerCapabilities{
+			Tools: &schema.Tools{ListChanged: true},
+		},
+	})
+	if _, err := mcpServer.Tool("echo", "echo tool", schema.PropertySchema{}, nil,
+		func(args map[string]any) (schema.CallToolResultSchema, error) {
+			return schema.CallToolResultSchema{}, nil
+		},
+	); err != nil {
+		t.Fatalf("failed to register tool: %v", err)
+	}
+	clientTransport, serverTransport := NewInMemoryTransportPair(&InMemoryTransportOptions{Serialize: true})
+	if err := mcpServer.Connect(serverTransport); err != nil {
+		t.Fatalf("failed to connect server: %v", err)
+	}
+
+	var recorded bytes.Buffer
+	c := client.NewClient(schema.Implementation{Name: "recorded-client", Version: "1.0.0"}, nil)
+	if err := c.Connect(transport.NewRecordingTransport(clientTransport, &recorded)); err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	if _, err := c.ListTools(); err != nil {
+		t.Fatalf("ListTools() error = %v", err)
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	return recorded.Bytes()
+}
+
+func TestReplayClientTransport(t *testing.T) {
+	tests := []struct {
+		name string
+		// 再生中にクライアントが行う操作
+		operate        func(c *client.Client)
+		wantMismatches []int // 食い違いが検出される記録の位置
+	}{
+		{
+			name: "normal : the client behaves as recorded",
+			operate: func(c *client.Client) {
+				if _, err := c.ListTools(); err != nil {
+					t.Errorf("ListTools() error = %v", err)
+				}
+			},
+			wantMismatches: nil,
+		},
+		{
+			name: "semi normal : the client sends a different request from the recording",
+			operate: func(c *client.Client) {
+				// 記録されたtools/listの結果が届くため、型の不一致でエラーとなる
+				_, _ = c.ListPrompts()
+			},
+			wantMismatches: []int{3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer drainOperationPhaseNotify()
+			records, err := transport.ReadRecords(bytes.NewReader(recordSession(t)))
+			if err != nil {
+				t.Fatalf("ReadRecords() error = %v", err)
+			}
+			// initialize、initialized通知、tools/listとその応答
+			wantDirections := []transport.Direction{transport.DIRECTION_OUT, transport.DIRECTION_IN, transport.DIRECTION_OUT, transport.DIRECTION_OUT, transport.DIRECTION_IN}
+			if len(records) != len(wantDirections) {
+				t.Fatalf("len(records) = %d, want %d", len(records), len(wantDirections))
+			}
+			for i, record := range records {
+				if record.Direction != wantDirections[i] {
+					t.Errorf("records[%d].Direction = %s, want %s", i, record.Direction, wantDirections[i])
+				}
+			}
+
+			sut := NewReplayClientTransport(records, &transport.ReplayOptions{Timeout: time.Second})
+			c := client.NewClient(schema.Implementation{Name: "recorded-client", Version: "1.0.0"}, nil)
+			if err := c.Connect(sut); err != nil {
+				t.Fatalf("failed to connect: %v", err)
+			}
+			tt.operate(c)
+			select {
+			case <-sut.Done():
+			case <-time.After(5 * time.Second):
+				t.Fatal("replay did not finish")
+			}
+
+			mismatches := sut.Mismatches()
+			if len(mismatches) != len(tt.wantMismatches) {
+				t.Fatalf("Mismatches() = %v, want indexes %v", mismatches, tt.wantMismatches)
+			}
+			for i, mismatch := range mismatches {
+				if mismatch.Index != tt.wantMismatches[i] {
+					t.Errorf("Mismatches()[%d].Index = %d, want %d", i, mismatch.Index, tt.wantMismatches[i])
+				}
+			}
+			if err := sut.Err(); (err != nil) != (len(tt.wantMismatches) > 0) || (err != nil && !errors.Is(err, transport.ErrReplayMismatch)) {
+				t.Errorf("Err() = %v", err)
+			}
+		})
+	}
+}
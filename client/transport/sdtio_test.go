@@ -1,11 +1,19 @@
 package transport
 
 import (
+	"bytes"
+	"io"
+	"log/slog"
 	"os/exec"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/kakkky/mcp-sdk-go/client"
+	mcperr "github.com/kakkky/mcp-sdk-go/shared/mcp-err"
+	"github.com/kakkky/mcp-sdk-go/shared/protocol"
 	"github.com/kakkky/mcp-sdk-go/shared/schema"
 )
 
@@ -46,6 +54,26 @@ func TestStdioClientTransport(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "normal : message larger than 64KiB",
+			message: schema.JsonRpcRequest{
+				BaseMessage: schema.BaseMessage{
+					Jsonrpc: "2.0",
+					Id:      1,
+				},
+				Request: &schema.InitializeRequestSchema{
+					MethodName: "initialize",
+					ParamsData: schema.InitializeRequestParams{
+						ProtocolVersion: "1.0",
+						Capabilities:    schema.ClientCapabilities{},
+						ClientInfo: schema.Implementation{
+							Name:    strings.Repeat("a", 5*1024*1024),
+							Version: "1.0.0",
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -96,3 +124,230 @@ func TestStdioClientTransport(t *testing.T) {
 		})
 	}
 }
+
+func TestStdioClientTransport_Close(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		args    []string
+	}{
+		{
+			name:    "normal : process exits when stdin is closed",
+			command: "cat",
+		},
+		{
+			name:    "normal : process ignoring stdin EOF is terminated by SIGTERM",
+			command: "sh",
+			args:    []string{"-c", "exec sleep 30"},
+		},
+		{
+			name:    "normal : process ignoring SIGTERM is killed by SIGKILL",
+			command: "sh",
+			args:    []string{"-c", `trap "" TERM; exec sleep 30`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := exec.LookPath(tt.command)
+			if err != nil {
+				t.Skipf("cannot find '%s' command, skipping test", tt.command)
+			}
+			sut := NewStdioClientTransport(StdioServerParameters{
+				Command:         path,
+				Args:            tt.args,
+				ShutdownTimeout: 200 * time.Millisecond,
+			})
+			closed := make(chan struct{})
+			sut.SetOnReceiveMessage(func(schema.JsonRpcMessage) {})
+			sut.SetOnClose(func() { close(closed) })
+			// Closeによる終了はエラーとして通知されない
+			sut.SetOnError(func(err error) {
+				t.Errorf("transport error: %v", err)
+			})
+			if err := sut.Start(); err != nil {
+				t.Fatalf("failed to start transport: %v", err)
+			}
+			<-client.TransportStartedNotify
+
+			if err := sut.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+			<-closed
+			if sut.ProcessState() == nil {
+				t.Error("ProcessState() should not be nil after Close")
+			}
+		})
+	}
+}
+
+func TestStdioClientTransport_CloseDrainsWriteQueue(t *testing.T) {
+	shPath, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("cannot find 'sh' command, skipping test")
+	}
+	// 読み取りを始めるまでに時間がかかるエコーサーバー
+	// パイプのバッファを超えるメッセージを送るため、Closeの時点でキューにメッセージが残る
+	sut := NewStdioClientTransport(StdioServerParameters{
+		Command:         shPath,
+		Args:            []string{"-c", "sleep 0.3; exec cat"},
+		WriteQueueSize:  32,
+		ShutdownTimeout: 5 * time.Second,
+	})
+	var mu sync.Mutex
+	var gotIds []any
+	closed := make(chan struct{})
+	sut.SetOnReceiveMessage(func(msg schema.JsonRpcMessage) {
+		mu.Lock()
+		defer mu.Unlock()
+		gotIds = append(gotIds, msg.(schema.JsonRpcRequest).Id)
+	})
+	sut.SetOnClose(func() { close(closed) })
+	sut.SetOnError(func(err error) {
+		t.Errorf("transport error: %v", err)
+	})
+	if err := sut.Start(); err != nil {
+		t.Fatalf("failed to start transport: %v", err)
+	}
+	<-client.TransportStartedNotify
+
+	const count = 32
+	var wantIds []any
+	for i := range count {
+		wantIds = append(wantIds, i+1)
+		message := schema.JsonRpcRequest{
+			BaseMessage: schema.BaseMessage{
+				Jsonrpc: "2.0",
+				Id:      i + 1,
+			},
+			Request: &schema.InitializeRequestSchema{
+				MethodName: "initialize",
+				ParamsData: schema.InitializeRequestParams{
+					ProtocolVersion: "1.0",
+					ClientInfo: schema.Implementation{
+						Name:    strings.Repeat("a", 64*1024),
+						Version: "1.0.0",
+					},
+				},
+			},
+		}
+		if err := sut.SendMessage(message); err != nil {
+			t.Fatalf("SendMessage() #%d error = %v", i, err)
+		}
+	}
+	if err := sut.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	<-closed
+
+	mu.Lock()
+	defer mu.Unlock()
+	if diff := cmp.Diff(wantIds, gotIds); diff != "" {
+		t.Errorf("received ids mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestStdioClientTransport_ProcessExit(t *testing.T) {
+	shPath, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("cannot find 'sh' command, skipping test")
+	}
+	// リクエストを1行読み取った後、応答せずに異常終了するサーバー
+	sut := NewStdioClientTransport(StdioServerParameters{
+		Command: shPath,
+		Args:    []string{"-c", "read line; exit 3"},
+	})
+	errCh := make(chan error, 1)
+	p := protocol.NewProtocol(nil)
+	p.SetOnError(func(err error) { errCh <- err })
+	if err := p.Connect(sut); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	<-client.TransportStartedNotify
+
+	// 応答待ちのリクエストは接続断のエラーで終了する
+	_, err = p.Request(&schema.PingRequestSchema{MethodName: "ping"}, &schema.EmptyResultSchema{})
+	mcpErr, ok := err.(*mcperr.McpErr)
+	if !ok || mcpErr.Code != mcperr.CONNECTION_CLOSED {
+		t.Errorf("Request() error = %v, want connection closed error", err)
+	}
+
+	select {
+	case err := <-errCh:
+		exitErr, ok := err.(*ProcessExitError)
+		if !ok {
+			t.Fatalf("OnError() got %v, want *ProcessExitError", err)
+		}
+		if exitErr.ExitCode != 3 {
+			t.Errorf("ExitCode = %d, want 3", exitErr.ExitCode)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnError was not called")
+	}
+}
+
+func TestStdioClientTransport_Stderr(t *testing.T) {
+	shPath, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("cannot find 'sh' command, skipping test")
+	}
+	// 1行を複数回に分けて書き込んだ上で150行を出力し、異常終了するサーバー
+	// Stderrチャネルは誰も受け取らないため、バッファが溢れても読み取りがブロックしないことも確認する
+	script := `printf 'first ' >&2; sleep 0.1; echo 'line' >&2; i=1; while [ $i -le 150 ]; do echo "line $i" >&2; i=$((i+1)); done; read line; exit 4`
+	var logBuf bytes.Buffer
+	var logMu sync.Mutex
+	logger := slog.New(slog.NewTextHandler(&lockedWriter{mu: &logMu, w: &logBuf}, nil))
+	sut := NewStdioClientTransport(StdioServerParameters{
+		Command:      shPath,
+		Args:         []string{"-c", script},
+		Stderr:       PIPE,
+		StderrLogger: logger,
+		Name:         "crashy",
+	})
+	errCh := make(chan error, 1)
+	p := protocol.NewProtocol(nil)
+	p.SetOnError(func(err error) { errCh <- err })
+	if err := p.Connect(sut); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	<-client.TransportStartedNotify
+	_, _ = p.Request(&schema.PingRequestSchema{MethodName: "ping"}, &schema.EmptyResultSchema{})
+
+	select {
+	case err := <-errCh:
+		exitErr, ok := err.(*ProcessExitError)
+		if !ok {
+			t.Fatalf("OnError() got %v, want *ProcessExitError", err)
+		}
+		// 直近100行のみが保持される
+		if len(exitErr.Stderr) != 100 {
+			t.Fatalf("len(Stderr) = %d, want 100", len(exitErr.Stderr))
+		}
+		if diff := cmp.Diff([]string{"line 51", "line 150"}, []string{exitErr.Stderr[0], exitErr.Stderr[99]}); diff != "" {
+			t.Errorf("Stderr mismatch (-want +got):\n%s", diff)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnError was not called")
+	}
+
+	logMu.Lock()
+	logs := logBuf.String()
+	logMu.Unlock()
+	// 分割して書き込まれた行も1行として扱われる
+	if !strings.Contains(logs, `msg="first line" server=crashy stream=stderr`) {
+		t.Errorf("stderr line was not forwarded to the logger: %s", logs)
+	}
+	if got := strings.Count(logs, "\n"); got != 151 {
+		t.Errorf("number of forwarded lines = %d, want 151", got)
+	}
+}
+
+type lockedWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
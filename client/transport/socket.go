@@ -0,0 +1,169 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/kakkky/mcp-sdk-go/client"
+	"github.com/kakkky/mcp-sdk-go/shared/schema"
+	"github.com/kakkky/mcp-sdk-go/shared/schema/jsonrpc"
+	"github.com/kakkky/mcp-sdk-go/shared/transport"
+)
+
+// Unixドメインソケット、またはTCPでサーバーに接続するトランスポート
+// メッセージはデフォルトではstdioと同様に改行区切りで送受信する
+type SocketClientTransport struct {
+	network    string
+	address    string
+	conn       net.Conn
+	readBuffer *transport.ReadBuffer
+	framer     transport.Framer
+	writer     *transport.MessageWriter
+	closeOnce  sync.Once
+	// 送信キューに溜めておけるメッセージ数
+	writeQueueSize int
+
+	onReceiveMessage func(schema.JsonRpcMessage)
+	onClose          func()
+	onError          func(error)
+}
+
+// networkには"unix"または"tcp"を指定する
+func NewSocketClientTransport(network string, address string) *SocketClientTransport {
+	return &SocketClientTransport{
+		network:    network,
+		address:    address,
+		readBuffer: transport.NewReadBuffer(),
+		framer:     transport.NewlineFramer{},
+	}
+}
+
+func (s *SocketClientTransport) Start() error {
+	if s.conn != nil {
+		return errors.New("socket client transport is already started")
+	}
+	conn, err := net.Dial(s.network, s.address)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s %s: %w", s.network, s.address, err)
+	}
+	s.conn = conn
+	s.writer = transport.NewMessageWriter(conn, &transport.MessageWriterOptions{QueueSize: s.writeQueueSize}, s.OnError)
+	go func() {
+		client.TransportStartedNotify <- struct{}{}
+		s.readLoop()
+	}()
+	return nil
+}
+
+// メッセージの区切り方を設定する
+// 指定しなかった場合はtransport.NewlineFramerとなる。Startより前に呼び出す必要がある
+func (s *SocketClientTransport) SetFramer(framer transport.Framer) {
+	s.framer = framer
+	s.readBuffer.SetFramer(framer)
+}
+
+// 送信キューのサイズを設定する
+// 0の場合はキューを使わず、SendMessageは書き込みが完了するまでブロックする
+// 1以上の場合はキューに積んだ時点で戻り、キューが一杯の間はブロックする。Startより前に呼び出す必要がある
+func (s *SocketClientTransport) SetWriteQueueSize(size int) {
+	s.writeQueueSize = size
+}
+
+func (s *SocketClientTransport) SendMessage(message schema.JsonRpcMessage) error {
+	return s.SendMessageWithContext(context.Background(), message)
+}
+
+// 書き込みを待っている間にctxがキャンセルされた場合は、送信せずにエラーを返す
+// 並行して呼び出しても、メッセージは1つずつ直列に書き込まれる
+func (s *SocketClientTransport) SendMessageWithContext(ctx context.Context, message schema.JsonRpcMessage) error {
+	if s.conn == nil {
+		return errors.New("socket client transport is not started")
+	}
+	data, err := jsonrpc.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	// フレームに変換して書き込む
+	if err := s.writer.Write(ctx, s.framer.Encode(data)); err != nil {
+		return fmt.Errorf("client failed to write message to socket: %w", err)
+	}
+	return nil
+}
+
+func (s *SocketClientTransport) Close() error {
+	if s.conn == nil {
+		return errors.New("socket client transport is not started")
+	}
+	s.writer.Close()
+	return s.conn.Close()
+}
+
+func (s *SocketClientTransport) OnClose() {
+	if s.onClose != nil {
+		s.onClose()
+	}
+}
+
+func (s *SocketClientTransport) OnError(err error) {
+	if s.onError != nil {
+		s.onError(err)
+	}
+}
+
+func (s *SocketClientTransport) SetOnReceiveMessage(onReceiveMessage func(schema.JsonRpcMessage)) {
+	s.onReceiveMessage = onReceiveMessage
+}
+
+func (s *SocketClientTransport) SetOnClose(onClose func()) {
+	s.onClose = onClose
+}
+
+func (s *SocketClientTransport) SetOnError(onError func(error)) {
+	s.onError = onError
+}
+
+// 接続からデータを読み取り、メッセージ単位でonReceiveMessageコールバックを呼び出す
+// 接続が閉じられるとOnCloseを呼び出す
+func (s *SocketClientTransport) readLoop() {
+	defer s.closeOnce.Do(func() {
+		s.readBuffer.Clear()
+		s.writer.Close()
+		s.OnClose()
+	})
+	chunk := make([]byte, readChunkSize)
+	for {
+		n, err := s.conn.Read(chunk)
+		if n > 0 {
+			if err := s.readBuffer.Append(chunk[:n]); err != nil {
+				s.OnError(err)
+				return
+			}
+			s.processReadBuffer()
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				s.OnError(fmt.Errorf("failed to read data from socket: %w", err))
+			}
+			return
+		}
+	}
+}
+
+// バッファ内の全てのメッセージを読み取り、onReceiveMessageコールバックを呼び出す
+func (s *SocketClientTransport) processReadBuffer() {
+	for {
+		msg, err := s.readBuffer.ReadMessage()
+		if err != nil {
+			s.OnError(err)
+			continue
+		}
+		if msg == nil {
+			return
+		}
+		s.onReceiveMessage(msg)
+	}
+}
@@ -0,0 +1,114 @@
+package transport
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kakkky/mcp-sdk-go/client"
+	mcpserver "github.com/kakkky/mcp-sdk-go/mcp-server"
+	"github.com/kakkky/mcp-sdk-go/mcp-server/server"
+	servertransport "github.com/kakkky/mcp-sdk-go/mcp-server/transport"
+	"github.com/kakkky/mcp-sdk-go/shared/protocol"
+	"github.com/kakkky/mcp-sdk-go/shared/schema"
+	"github.com/kakkky/mcp-sdk-go/shared/transport"
+)
+
+func TestSocketClientTransport_WithMcpServer(t *testing.T) {
+	tests := []struct {
+		name    string
+		network string
+		address func(t *testing.T) string
+		framer  transport.Framer
+	}{
+		{
+			name:    "normal : unix socket",
+			network: "unix",
+			address: func(t *testing.T) string {
+				return filepath.Join(t.TempDir(), "mcp.sock")
+			},
+		},
+		{
+			name:    "normal : tcp on localhost",
+			network: "tcp",
+			address: func(t *testing.T) string {
+				return "127.0.0.1:0"
+			},
+		},
+		{
+			name:    "normal : Content-Length framing",
+			network: "unix",
+			address: func(t *testing.T) string {
+				return filepath.Join(t.TempDir(), "mcp.sock")
+			},
+			framer: transport.ContentLengthFramer{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			socketServer, err := servertransport.NewSocketServer(tt.network, tt.address(t), &servertransport.SocketServerOptions{Framer: tt.framer})
+			if err != nil {
+				t.Fatalf("NewSocketServer() error = %v", err)
+			}
+			defer func() {
+				if err := socketServer.Close(); err != nil {
+					t.Errorf("failed to close socket server: %v", err)
+				}
+			}()
+			go func() {
+				_ = socketServer.Serve(func(tr protocol.Transport) {
+					// セッションごとにMcpServerを生成する
+					mcpServer := mcpserver.NewMcpServer(schema.Implementation{Name: "socket-server", Version: "1.0.0"}, &server.ServerOptions{
+						Capabilities: schema.ServerCapabilities{
+							Prompts: &schema.Prompts{ListChanged: true},
+						},
+					})
+					if _, err := mcpServer.Prompt("greeting", "greeting prompt", []schema.PromptAugmentSchema{{Name: "name"}},
+						func(args []schema.PromptAugmentSchema) (schema.GetPromptResultSchema, error) {
+							return schema.GetPromptResultSchema{}, nil
+						},
+					); err != nil {
+						t.Errorf("failed to register prompt: %v", err)
+						return
+					}
+					if err := mcpServer.Connect(tr); err != nil {
+						t.Errorf("failed to connect server: %v", err)
+					}
+				})
+			}()
+
+			c := client.NewClient(schema.Implementation{Name: "socket-client", Version: "1.0.0"}, nil)
+			sut := NewSocketClientTransport(socketServer.Addr().Network(), socketServer.Addr().String())
+			if tt.framer != nil {
+				sut.SetFramer(tt.framer)
+			}
+			errCh := make(chan error, 1)
+			go func() {
+				if err := c.Connect(sut); err != nil {
+					errCh <- err
+				}
+			}()
+			select {
+			case err := <-errCh:
+				t.Fatalf("failed to connect: %v", err)
+			case <-client.OperationPhaseStartedNotify:
+			}
+
+			result, err := c.ListPrompts()
+			if err != nil {
+				t.Fatalf("ListPrompts() error = %v", err)
+			}
+			want := &schema.ListPromptsResultSchema{
+				Prompts: []schema.PromptSchema{
+					{Name: "greeting", Description: "greeting prompt", Auguments: []schema.PromptAugmentSchema{{Name: "name"}}},
+				},
+			}
+			if diff := cmp.Diff(want, result); diff != "" {
+				t.Errorf("ListPrompts() mismatch (-want +got):\n%s", diff)
+			}
+			if err := c.Close(); err != nil {
+				t.Errorf("Close() error = %v", err)
+			}
+		})
+	}
+}
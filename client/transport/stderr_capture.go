@@ -0,0 +1,115 @@
+package transport
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+const (
+	// 保持しておく直近の標準エラー出力の行数のデフォルト値
+	defaultStderrBufferLines = 100
+	// 1行として扱う最大バイト数。これを超える部分は切り捨てる
+	maxStderrLineSize = 64 * 1024
+	// Stderrチャネルのバッファサイズ。受け取る側がいない間に溢れた行は破棄する
+	stderrChanSize = 64
+)
+
+// サーバープロセスの標準エラー出力を行単位で読み取り、直近の行をリングバッファに保持する
+type stderrCapture struct {
+	mu    sync.Mutex
+	lines []string
+	start int // リングバッファの先頭の位置
+	size  int // 保持している行数
+
+	ch     chan error
+	logger *slog.Logger
+	name   string
+	done   chan struct{}
+}
+
+func newStderrCapture(bufferLines int, logger *slog.Logger, name string) *stderrCapture {
+	if bufferLines <= 0 {
+		bufferLines = defaultStderrBufferLines
+	}
+	return &stderrCapture{
+		lines:  make([]string, bufferLines),
+		ch:     make(chan error, stderrChanSize),
+		logger: logger,
+		name:   name,
+		done:   make(chan struct{}),
+	}
+}
+
+// rが閉じられるまで行単位で読み取る
+// 各行はredactを通してから保持する。読み取りが終了するとdoneをcloseする
+func (c *stderrCapture) readFrom(r io.Reader, redact func(string) string, onError func(error)) {
+	defer close(c.done)
+	reader := bufio.NewReaderSize(r, 4096)
+	var line []byte
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		if len(line) < maxStderrLineSize {
+			line = append(line, chunk[:min(len(chunk), maxStderrLineSize-len(line))]...)
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if len(line) > 0 {
+			c.addLine(redact(string(trimLineEnding(line))))
+			line = line[:0]
+		}
+		if err != nil {
+			// プロセス終了時にパイプが閉じられた場合はエラーとしない
+			if !errors.Is(err, io.EOF) && !errors.Is(err, os.ErrClosed) {
+				onError(err)
+			}
+			return
+		}
+	}
+}
+
+func trimLineEnding(line []byte) []byte {
+	if len(line) > 0 && line[len(line)-1] == '\n' {
+		line = line[:len(line)-1]
+	}
+	if len(line) > 0 && line[len(line)-1] == '\r' {
+		line = line[:len(line)-1]
+	}
+	return line
+}
+
+func (c *stderrCapture) addLine(line string) {
+	c.mu.Lock()
+	index := (c.start + c.size) % len(c.lines)
+	c.lines[index] = line
+	if c.size < len(c.lines) {
+		c.size++
+	} else {
+		c.start = (c.start + 1) % len(c.lines)
+	}
+	c.mu.Unlock()
+
+	if c.logger != nil {
+		c.logger.Info(line, slog.String("server", c.name), slog.String("stream", "stderr"))
+	}
+	// 受け取る側がいなくても読み取りがブロックしないように、溢れた行は破棄する
+	select {
+	case c.ch <- errors.New(line):
+	default:
+	}
+}
+
+// 直近の行を古い順に返す
+func (c *stderrCapture) recentLines() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	lines := make([]string, 0, c.size)
+	for i := range c.size {
+		lines = append(lines, c.lines[(c.start+i)%len(c.lines)])
+	}
+	return lines
+}
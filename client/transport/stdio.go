@@ -1,15 +1,19 @@
 package transport
 
 import (
-	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/kakkky/mcp-sdk-go/client"
 	"github.com/kakkky/mcp-sdk-go/shared/schema"
@@ -29,13 +33,75 @@ const (
 type StdioServerParameters struct {
 	Command string
 	Args    []string
-	Env     []string // "key=value"形式の環境変数のリスト
-	Stderr  IOType   // サーバープロセスの標準エラー出力の取り扱い
+	// "key=value"形式の環境変数のリスト
+	// 値に含まれる"${VAR}"は展開され、"${secret:NAME}"はSecretsから取得した値に置き換えられる
+	Env []string
+	// Envはデフォルトで継承される環境変数（PATH、HOMEなど）に上書きでマージされる
+	// trueの場合はマージせず、Envだけをサーバープロセスに渡す
+	IsolateEnv bool
+	// Envの中で参照されるシークレットを解決する
+	// 解決したシークレットの値は、MessageLoggerや標準エラー出力のログ上で伏せ字に置き換えられる
+	Secrets SecretProvider
+	Stderr  IOType // サーバープロセスの標準エラー出力の取り扱い
 	// サーバーのプロセスが実行されるディレクトリ
 	// 指定しなかった場合は、curent working directoryが使用される
 	Cwd string
+	// Close時に、標準入力を閉じてからSIGTERMを送るまで、SIGTERMを送ってからSIGKILLを送るまでの猶予時間
+	// 指定しなかった場合は2秒となる
+	ShutdownTimeout time.Duration
+	// サーバーから受信する1メッセージあたりの最大バイト数
+	// これを超えるメッセージはOnErrorで通知した上で破棄し、後続のメッセージの受信は継続する
+	// 指定しなかった場合はtransport.DEFAULT_MAX_MESSAGE_SIZE、負の値を指定した場合は上限なしとなる
+	MaxMessageSize int
+	// 送信キューに溜めておけるメッセージ数
+	// 指定しなかった場合はキューを使わず、SendMessageは書き込みが完了するまでブロックする
+	// 指定した場合はキューに積んだ時点で戻り、キューが一杯の間はブロックする
+	// Close時にキューに残っているメッセージは、ShutdownTimeoutの間に書き込まれる
+	WriteQueueSize int
+	// メッセージの区切り方。サーバー側と同じものを指定する必要がある
+	// 指定しなかった場合はtransport.NewlineFramerとなる
+	Framer transport.Framer
+	// 以下はStderrがPIPE、OVERLAPPEDの場合のみ有効
+	// 保持しておく直近の標準エラー出力の行数。指定しなかった場合は100行となる
+	// 保持した行はRecentStderrで取得でき、プロセスがクラッシュした場合はProcessExitErrorにも添付される
+	StderrBufferLines int
+	// 指定した場合、標準エラー出力を1行ずつ転送する。各行にはNameがserver属性として付与される
+	StderrLogger *slog.Logger
+	// ログに付与するサーバー名。指定しなかった場合はCommandのファイル名となる
+	Name string
+	// 指定した場合、送受信したメッセージをDebugレベルで出力する
+	// メッセージは1件ごとに、シークレットの値を伏せ字に置き換えてから出力する
+	MessageLogger *slog.Logger
 }
 
+// 標準出力を読み取る際のチャンクサイズ
+const readChunkSize = 32 * 1024
+
+const defaultShutdownTimeout = 2 * time.Second
+
+// サーバープロセスがClose以外の理由で終了した場合にOnErrorに渡されるエラー
+type ProcessExitError struct {
+	ExitCode int      // シグナルにより終了した場合は-1
+	Signal   string   // シグナルにより終了した場合のシグナル名
+	Stderr   []string // 終了直前の標準エラー出力（StderrがPIPE、OVERLAPPEDの場合のみ）
+}
+
+func (e *ProcessExitError) Error() string {
+	var msg string
+	if e.Signal != "" {
+		msg = fmt.Sprintf("server process was terminated by signal: %s", e.Signal)
+	} else {
+		msg = fmt.Sprintf("server process exited with code %d", e.ExitCode)
+	}
+	if len(e.Stderr) > 0 {
+		msg += "\nrecent stderr:\n" + strings.Join(e.Stderr, "\n")
+	}
+	return msg
+}
+
+// プロセス終了後、標準エラー出力の残りを読み取るのを待つ時間
+const stderrDrainTimeout = 100 * time.Millisecond
+
 // サーバープロセスに継承されるデフォルトの環境変数のリスト
 func defaultInheritedEnvVars() []string {
 	switch runtime.GOOS {
@@ -77,9 +143,14 @@ type StdioClientTransport struct {
 	process      *exec.Cmd
 	readBuffer   *transport.ReadBuffer
 	serverParams StdioServerParameters
-	stderrChan   chan error     // PIPEの場合にサーバープロセスの標準エラー出力を受け取るチャネル
-	stdinPipe    io.WriteCloser // 標準入力のパイプ（サーバープロセスにメッセージを送信するため）
-	stdoutPipe   io.ReadCloser  // 標準出力のパイプ（サーバープロセスからのメッセージを受信するため）
+	stderr       *stderrCapture           // PIPEの場合にサーバープロセスの標準エラー出力を行単位で保持する
+	redactor     *redactor                // ログからシークレットの値を取り除く
+	stdinPipe    io.WriteCloser           // 標準入力のパイプ（サーバープロセスにメッセージを送信するため）
+	stdoutPipe   io.ReadCloser            // 標準出力のパイプ（サーバープロセスからのメッセージを受信するため）
+	writer       *transport.MessageWriter // 標準入力への書き込みを直列化する
+	exited       chan struct{}            // サーバープロセスの終了を回収した時にcloseされる
+	closingMu    sync.Mutex
+	isClosing    bool // Closeによる終了処理中かどうか
 
 	onReceiveMessage func(schema.JsonRpcMessage)
 	onClose          func()
@@ -91,24 +162,36 @@ func NewStdioClientTransport(server StdioServerParameters) *StdioClientTransport
 		serverParams: server,
 		readBuffer:   transport.NewReadBuffer(),
 	}
+	if server.MaxMessageSize != 0 {
+		s.readBuffer.SetMaxMessageSize(server.MaxMessageSize)
+	}
+	if server.Framer == nil {
+		s.serverParams.Framer = transport.NewlineFramer{}
+	}
+	s.readBuffer.SetFramer(s.serverParams.Framer)
+	if s.serverParams.Name == "" {
+		s.serverParams.Name = filepath.Base(server.Command)
+	}
 	if (s.serverParams.Stderr == PIPE) || (s.serverParams.Stderr == OVERLAPPED) {
-		s.stderrChan = make(chan error, 1) // 標準エラー出力を受け取るチャネルを初期化
+		s.stderr = newStderrCapture(server.StderrBufferLines, server.StderrLogger, s.serverParams.Name)
 	}
 	return s
 }
 
 func (s *StdioClientTransport) Start() error {
-	s.process = exec.Command(s.serverParams.Command, s.serverParams.Args...)
-	if len(s.serverParams.Env) > 0 {
-		s.process.Env = s.serverParams.Env
-	} else {
-		s.process.Env = getDefaultEnvironment()
+	env, secretValues, err := buildEnvironment(s.serverParams)
+	if err != nil {
+		return err
 	}
+	s.redactor = newRedactor(secretValues)
+	s.process = exec.Command(s.serverParams.Command, s.serverParams.Args...)
+	s.process.Env = env
 	stdinPipe, err := s.process.StdinPipe()
 	if err != nil {
 		return err
 	}
 	s.stdinPipe = stdinPipe // 標準入力のパイプ
+	s.writer = transport.NewMessageWriter(stdinPipe, &transport.MessageWriterOptions{QueueSize: s.serverParams.WriteQueueSize}, s.OnError)
 	stdoutPipe, err := s.process.StdoutPipe()
 	if err != nil {
 		return err
@@ -118,31 +201,22 @@ func (s *StdioClientTransport) Start() error {
 	case INHERIT:
 		s.process.Stderr = os.Stderr // 標準エラー出力を親プロセスに継承
 	case PIPE, OVERLAPPED:
-		// チャネルで標準エラー出力を受け取る
-		stderrPipe, err := s.process.StderrPipe()
+		// exec.CmdのStderrPipeはWaitで閉じられ、終了直前の出力を取りこぼすことがあるため、
+		// 自前のパイプを使い、書き込み側が全て閉じられるまで読み取る
+		stderrR, stderrW, err := os.Pipe()
 		if err != nil {
 			return err
 		}
+		s.process.Stderr = stderrW
+		defer func() {
+			// 子プロセスに引き継いだ後は、親プロセス側の書き込み口は不要
+			_ = stderrW.Close()
+		}()
 		go func() {
 			defer func() {
-				err := stderrPipe.Close()
-				if err != nil {
-					fmt.Println("Failed to close stderr pipe:", err)
-				}
+				_ = stderrR.Close()
 			}()
-			for {
-				data := make([]byte, 1024)
-				n, err := stderrPipe.Read(data)
-				if err != nil {
-					if err.Error() != io.EOF.Error() {
-						s.OnError(err) // エラーを通知
-					}
-					break
-				}
-				if n > 0 {
-					s.stderrChan <- errors.New(string(data[:n])) // チャネルにデータを送信
-				}
-			}
+			s.stderr.readFrom(stderrR, s.redactor.redact, s.OnError)
 		}()
 	case IGNORE:
 		s.process.Stderr = nil // 標準エラー出力を無視
@@ -154,44 +228,160 @@ func (s *StdioClientTransport) Start() error {
 	if err := s.process.Start(); err != nil {
 		return err
 	}
+	s.exited = make(chan struct{})
 	go func() {
 		client.TransportStartedNotify <- struct{}{}
 		s.stdinOnData() // 標準入力からのデータを読み取る
+		// 標準出力がEOFになったら、プロセスの終了を回収する
+		s.waitProcess()
 	}()
 	return nil
 }
 
+// サーバープロセスの終了を回収し、終了状態をOnError/OnCloseで通知する
+// Closeを呼ばずにプロセスが終了した場合（クラッシュなど）は、異常終了であればOnErrorを呼び出す
+func (s *StdioClientTransport) waitProcess() {
+	_ = s.process.Wait()
+	// クラッシュ時のエラーに添付できるよう、標準エラー出力を読み切るのを待つ
+	if s.stderr != nil {
+		select {
+		case <-s.stderr.done:
+		case <-time.After(stderrDrainTimeout):
+		}
+	}
+	close(s.exited)
+	// 書き込み待ちの送信を終了させる
+	s.writer.Close()
+	s.closingMu.Lock()
+	isClosing := s.isClosing
+	s.closingMu.Unlock()
+	if !isClosing {
+		if err := processExitError(s.process.ProcessState); err != nil {
+			if s.stderr != nil {
+				err.Stderr = s.stderr.recentLines()
+			}
+			s.OnError(err)
+		}
+	}
+	s.readBuffer.Clear()
+	s.OnClose()
+}
+
+// 正常終了の場合はnilを返す
+func processExitError(state *os.ProcessState) *ProcessExitError {
+	if state == nil || state.Success() {
+		return nil
+	}
+	exitErr := &ProcessExitError{ExitCode: state.ExitCode()}
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		exitErr.Signal = status.Signal().String()
+	}
+	return exitErr
+}
+
 func (s *StdioClientTransport) SendMessage(message schema.JsonRpcMessage) error {
+	return s.SendMessageWithContext(context.Background(), message)
+}
+
+// 書き込みを待っている間にctxがキャンセルされた場合は、送信せずにエラーを返す
+// 並行して呼び出しても、メッセージは1つずつ直列に書き込まれる
+func (s *StdioClientTransport) SendMessageWithContext(ctx context.Context, message schema.JsonRpcMessage) error {
+	if s.writer == nil {
+		return fmt.Errorf("process is not running")
+	}
 	data, err := jsonrpc.Marshal(message)
 	if err != nil {
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
-	// 改行を追加して標準入力に書き込む
-	log.Println(" Client : ", string(data))
-	_, err = s.stdinPipe.Write(append(data, '\n'))
-	if err != nil {
+	s.logMessage("send", data)
+	// フレームに変換して標準入力に書き込む
+	if err := s.writer.Write(ctx, s.serverParams.Framer.Encode(data)); err != nil {
 		return fmt.Errorf("client failed to write message to stdin: %w", err)
 	}
 	return nil
 }
 
+// サーバープロセスを段階的に終了させる
+// 1. 送信キューに残っているメッセージを書き込んでから標準入力を閉じ、プロセスが自ら終了するのを待つ
+// 2. 終了しなければSIGTERMを送信する
+// 3. それでも終了しなければSIGKILLを送信する
+// キューの書き込みはShutdownTimeoutまで待ち、それを過ぎた場合は残りのメッセージを破棄する
+// プロセスの終了を回収した後、OnCloseが呼ばれる
 func (s *StdioClientTransport) Close() error {
-	if s.process.Process == nil {
+	if s.process == nil || s.process.Process == nil {
 		return fmt.Errorf("process is not running")
 	}
-	if err := s.process.Process.Kill(); err != nil {
+	s.closingMu.Lock()
+	s.isClosing = true
+	s.closingMu.Unlock()
+
+	timeout := s.serverParams.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+	drainCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	_ = s.writer.Shutdown(drainCtx)
+	cancel()
+	if err := s.stdinPipe.Close(); err != nil && !errors.Is(err, os.ErrClosed) {
+		return fmt.Errorf("failed to close stdin pipe: %w", err)
+	}
+	if s.waitExit(timeout) {
+		return nil
+	}
+	// Windowsなど、SIGTERMを送信できない環境ではそのままSIGKILLを送信する
+	if err := s.process.Process.Signal(syscall.SIGTERM); err == nil {
+		if s.waitExit(timeout) {
+			return nil
+		}
+	}
+	if err := s.process.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
 		return fmt.Errorf("failed to kill process: %w", err)
 	}
-	s.readBuffer.Clear()
+	if !s.waitExit(timeout) {
+		return fmt.Errorf("server process did not exit after kill")
+	}
 	return nil
 }
 
+// タイムアウトまでにプロセスの終了を回収できたかどうかを返す
+func (s *StdioClientTransport) waitExit(timeout time.Duration) bool {
+	select {
+	case <-s.exited:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// サーバープロセスの終了状態を返す
+// プロセスが終了していない場合はnilを返す
+func (s *StdioClientTransport) ProcessState() *os.ProcessState {
+	if s.exited == nil {
+		return nil
+	}
+	select {
+	case <-s.exited:
+		return s.process.ProcessState
+	default:
+		return nil
+	}
+}
+
+// 標準エラー出力を1行ずつ受け取るチャネル
+// 受け取る側がいない間に溢れた行は破棄される（RecentStderrでは取得できる）
 func (s *StdioClientTransport) Stderr() <-chan error {
-	if s.stderrChan == nil {
+	if s.stderr == nil {
 		return nil // 標準エラー出力を受け取るチャネルがない場合はnilを返す
 	}
-	return s.stderrChan
+	return s.stderr.ch
+}
 
+// 直近の標準エラー出力を古い順に返す
+func (s *StdioClientTransport) RecentStderr() []string {
+	if s.stderr == nil {
+		return nil
+	}
+	return s.stderr.recentLines()
 }
 
 func (s *StdioClientTransport) OnClose() {
@@ -218,15 +408,22 @@ func (s *StdioClientTransport) SetOnError(onError func(error)) {
 	s.onError = onError
 }
 
-// 標準出力でデータを\nごとに受け取り、onDataコールバックを呼び出す
+// 標準出力からデータをチャンク単位で受け取り、onDataコールバックを呼び出す
+// 1行の長さに上限はなく、メッセージの区切りはReadBufferが改行をもとに判断する
 func (s *StdioClientTransport) stdinOnData() {
-	scanner := bufio.NewScanner(s.stdoutPipe)
-	// 標準出力のスキャナーを使用して、データを読み取る
-	for scanner.Scan() {
-		data := scanner.Text()
-		// Scannerは改行を含まないので、改行を追加して
-		if err := s.onData([]byte(data + "\n")); err != nil {
-			s.OnError(fmt.Errorf("failed to read data from stdout: %w", err))
+	chunk := make([]byte, readChunkSize)
+	for {
+		n, err := s.stdoutPipe.Read(chunk)
+		if n > 0 {
+			if err := s.onData(chunk[:n]); err != nil {
+				s.OnError(fmt.Errorf("failed to read data from stdout: %w", err))
+				return
+			}
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, os.ErrClosed) {
+				s.OnError(fmt.Errorf("failed to read data from stdout: %w", err))
+			}
 			return
 		}
 	}
@@ -237,19 +434,35 @@ func (s *StdioClientTransport) onData(chunk []byte) error {
 	if err := s.readBuffer.Append(chunk); err != nil {
 		return err
 	}
-	log.Println(" Server :  ", string(chunk))
 	s.processReadBuffer()
 	return nil
 }
 
-// バッファからメッセージを読み取り、onReceiveMessageコールバックを呼び出す
+// バッファ内の全てのメッセージを読み取り、onReceiveMessageコールバックを呼び出す
+// 最大サイズを超えたメッセージや不正なメッセージはOnErrorで通知し、読み取りを継続する
 func (s *StdioClientTransport) processReadBuffer() {
-	msg, err := s.readBuffer.ReadMessage()
-	if err != nil {
-		s.OnError(err)
+	for {
+		msg, err := s.readBuffer.ReadMessage()
+		if err != nil {
+			s.OnError(err)
+			continue
+		}
+		if msg == nil {
+			return
+		}
+		if s.serverParams.MessageLogger != nil {
+			if data, err := jsonrpc.Marshal(msg); err == nil {
+				s.logMessage("receive", data)
+			}
+		}
+		s.onReceiveMessage(msg)
 	}
-	if msg == nil {
+}
+
+// フレーム単位ではなくメッセージ単位で伏せ字にするため、チャンクの境目で分かれたシークレットも出力されない
+func (s *StdioClientTransport) logMessage(direction string, data []byte) {
+	if s.serverParams.MessageLogger == nil {
 		return
 	}
-	s.onReceiveMessage(msg)
+	s.serverParams.MessageLogger.Debug("mcp message", "server", s.serverParams.Name, "direction", direction, "message", s.redactor.redact(string(data)))
 }
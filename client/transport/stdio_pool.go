@@ -0,0 +1,388 @@
+package transport
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kakkky/mcp-sdk-go/client"
+	"github.com/kakkky/mcp-sdk-go/shared/protocol"
+)
+
+// プールからクライアントを払い出す方式
+type PoolStrategy string
+
+const (
+	ROUND_ROBIN PoolStrategy = "round-robin" // 払い出せるプロセスに順番に払い出す
+	LEAST_BUSY  PoolStrategy = "least-busy"  // 払い出し中の数が最も少ないプロセスに払い出す
+)
+
+// 再起動を諦めたプロセスを起動し直す間隔の初期値と上限。ReconnectOptionsで指定しなかった場合に使用する
+const (
+	defaultRespawnBackoff    = 500 * time.Millisecond
+	defaultMaxRespawnBackoff = 30 * time.Second
+)
+
+// 接続中のプロセスが1つもない場合にAcquireが返すエラー
+var ErrNoHealthyProcess = errors.New("no healthy server process in the pool")
+
+type StdioPoolOptions struct {
+	// 起動しておくサーバープロセスの数。指定しなかった場合は1となる
+	Size int
+	// クライアントの払い出し方式。指定しなかった場合はROUND_ROBINとなる
+	Strategy PoolStrategy
+	// 1つのプロセスを同時に払い出す呼び出し元の数の上限。0の場合は無制限
+	// 1を指定すると、呼び出し元ごとのセッションの状態（ログレベルや購読など）が混ざらないよう、プロセスを専有させる
+	MaxLeasesPerProcess int
+	// クラッシュしたプロセスを再起動する際の待機時間など
+	// OnReconnectとOnErrorはプールが使用するため、指定しても無視される
+	client.ReconnectOptions
+}
+
+// 同じStdioServerParametersから複数のサーバープロセスを起動し、常にSize個のプロセスが動いている状態を保つ
+// クラッシュしたプロセスはバックオフを挟んで再起動され、MaxRetriesの再起動に失敗したプロセスは新しいクライアントで起動し直す
+// クライアントは並行したリクエストに対応しているため、MaxLeasesPerProcessを指定しない限り、1つのプロセスを複数の呼び出し元に同時に払い出す
+type StdioPool struct {
+	serverParams StdioServerParameters
+	newClient    func() *client.Client
+	options      StdioPoolOptions
+
+	mu      sync.Mutex
+	members []*poolMember
+	next    int
+	closed  bool
+	// Closeした時にcloseされる
+	done chan struct{}
+	// 返却や再起動によって、払い出せるプロセスや接続中のプロセスが増えた可能性があることを、
+	// AcquireやWaitHealthyで待っている呼び出し元に伝える
+	// 通知するたびにcloseして新しいチャネルに差し替える
+	changed chan struct{}
+}
+
+type poolMember struct {
+	index    int
+	client   *client.ReconnectingClient
+	restarts int
+	respawns int
+	inFlight int
+	leases   int
+	lastErr  error
+}
+
+// プロセスごとの状態
+type StdioPoolMemberStatus struct {
+	Index int
+	// プロセスが起動しており、リクエストを送信できる状態かどうか
+	Healthy bool
+	// クラッシュ後に再起動した回数
+	Restarts int
+	// 再起動を諦めた後に、新しいクライアントで起動し直した回数
+	Respawns int
+	// 払い出し中の数
+	InFlight int
+	// これまでに払い出した回数
+	Leases int
+	// 直近の再起動の失敗理由
+	LastError error
+}
+
+// newClientはプロセスごとに呼ばれ、新しいクライアントを返す必要がある
+func NewStdioPool(server StdioServerParameters, newClient func() *client.Client, options *StdioPoolOptions) *StdioPool {
+	opts := StdioPoolOptions{}
+	if options != nil {
+		opts = *options
+	}
+	if opts.Size <= 0 {
+		opts.Size = 1
+	}
+	if opts.Strategy == "" {
+		opts.Strategy = ROUND_ROBIN
+	}
+	return &StdioPool{
+		serverParams: server,
+		newClient:    newClient,
+		options:      opts,
+		changed:      make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+}
+
+// p.muを保持した状態で呼び出す
+func (p *StdioPool) notifyChanged() {
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+// 全てのサーバープロセスを起動し、initializeまでを行う
+// 1つでも起動に失敗した場合は、起動済みのプロセスを終了させてエラーを返す
+func (p *StdioPool) Start() error {
+	p.mu.Lock()
+	if p.members != nil {
+		p.mu.Unlock()
+		return errors.New("stdio pool is already started")
+	}
+	p.mu.Unlock()
+
+	members := make([]*poolMember, 0, p.options.Size)
+	for i := range p.options.Size {
+		member := &poolMember{index: i}
+		member.client = p.newMemberClient(member)
+		if err := member.client.Connect(); err != nil {
+			for _, started := range members {
+				_ = started.client.Close()
+			}
+			return fmt.Errorf("failed to start server process %d: %w", i, err)
+		}
+		members = append(members, member)
+	}
+	p.mu.Lock()
+	p.members = members
+	p.mu.Unlock()
+	for _, member := range members {
+		go p.superviseMember(member, member.client)
+	}
+	return nil
+}
+
+// memberのプロセスを起動・再起動するクライアントを生成する
+func (p *StdioPool) newMemberClient(member *poolMember) *client.ReconnectingClient {
+	reconnectOptions := p.options.ReconnectOptions
+	reconnectOptions.OnReconnect = func() {
+		p.mu.Lock()
+		member.restarts++
+		member.lastErr = nil
+		p.notifyChanged()
+		p.mu.Unlock()
+	}
+	reconnectOptions.OnError = func(err error) {
+		p.mu.Lock()
+		member.lastErr = err
+		p.mu.Unlock()
+	}
+	return client.NewReconnectingClient(p.newClient(), func() (protocol.Transport, error) {
+		return NewStdioClientTransport(p.serverParams), nil
+	}, &reconnectOptions)
+}
+
+// クライアントが再起動を諦めた場合は、プールを閉じるまでバックオフを挟みながら新しいクライアントで起動し直す
+// これにより、MaxRetriesを指定しても、プロセスの数がSizeを下回ったままにならない
+func (p *StdioPool) superviseMember(member *poolMember, current *client.ReconnectingClient) {
+	backoff := p.options.InitialBackoff
+	if backoff <= 0 {
+		backoff = defaultRespawnBackoff
+	}
+	maxBackoff := p.options.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaultMaxRespawnBackoff
+	}
+	<-current.Done()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-time.After(backoff):
+		}
+		respawned := p.newMemberClient(member)
+		if err := respawned.Connect(); err != nil {
+			p.mu.Lock()
+			member.lastErr = fmt.Errorf("failed to respawn server process %d: %w", member.index, err)
+			p.mu.Unlock()
+			backoff = min(backoff*2, maxBackoff)
+			continue
+		}
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			_ = respawned.Close()
+			return
+		}
+		member.client = respawned
+		member.respawns++
+		member.lastErr = nil
+		p.notifyChanged()
+		p.mu.Unlock()
+		current = respawned
+		backoff = p.options.InitialBackoff
+		if backoff <= 0 {
+			backoff = defaultRespawnBackoff
+		}
+		<-current.Done()
+	}
+}
+
+// 払い出されたクライアント
+// 使い終わったらReleaseを呼び出す必要がある。払い出し中の数はReleaseするまで減らない
+type PooledClient struct {
+	*client.ReconnectingClient
+	pool        *StdioPool
+	member      *poolMember
+	releaseOnce sync.Once
+}
+
+// クライアントをプールに返却する
+func (c *PooledClient) Release() {
+	c.releaseOnce.Do(func() {
+		c.pool.mu.Lock()
+		c.member.inFlight--
+		c.pool.notifyChanged()
+		c.pool.mu.Unlock()
+	})
+}
+
+// Strategyに従って、接続中で払い出せるプロセスのクライアントを払い出す
+// 全てのプロセスがMaxLeasesPerProcessに達している場合は、いずれかが返却されるまで待つ
+func (p *StdioPool) Acquire() (*PooledClient, error) {
+	return p.AcquireWithContext(context.Background())
+}
+
+// 払い出せるプロセスを待っている間にctxがキャンセルされた場合は、ctxのエラーを返す
+// 接続中のプロセスが1つもない場合は、待たずにErrNoHealthyProcessを返す
+func (p *StdioPool) AcquireWithContext(ctx context.Context) (*PooledClient, error) {
+	for {
+		p.mu.Lock()
+		if p.members == nil {
+			p.mu.Unlock()
+			return nil, errors.New("stdio pool is not started")
+		}
+		selected, healthy := p.selectMember()
+		if selected != nil {
+			selected.inFlight++
+			selected.leases++
+			p.mu.Unlock()
+			return &PooledClient{
+				ReconnectingClient: selected.client,
+				pool:               p,
+				member:             selected,
+			}, nil
+		}
+		changed := p.changed
+		p.mu.Unlock()
+		if !healthy {
+			return nil, ErrNoHealthyProcess
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// p.muを保持した状態で呼び出す
+// 払い出すプロセスと、接続中のプロセスが1つでもあるかを返す
+func (p *StdioPool) selectMember() (*poolMember, bool) {
+	healthy := false
+	var selected *poolMember
+	switch p.options.Strategy {
+	case LEAST_BUSY:
+		for _, member := range p.members {
+			if !member.client.IsConnected() {
+				continue
+			}
+			healthy = true
+			if p.isFull(member) {
+				continue
+			}
+			if selected == nil || member.inFlight < selected.inFlight {
+				selected = member
+			}
+		}
+	default:
+		for i := range p.members {
+			member := p.members[(p.next+i)%len(p.members)]
+			if !member.client.IsConnected() {
+				continue
+			}
+			healthy = true
+			if !p.isFull(member) {
+				selected = member
+				p.next = (member.index + 1) % len(p.members)
+				break
+			}
+		}
+	}
+	return selected, healthy
+}
+
+// p.muを保持した状態で呼び出す
+func (p *StdioPool) isFull(member *poolMember) bool {
+	return p.options.MaxLeasesPerProcess > 0 && member.inFlight >= p.options.MaxLeasesPerProcess
+}
+
+// 各プロセスの状態を返す
+func (p *StdioPool) Status() []StdioPoolMemberStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	statuses := make([]StdioPoolMemberStatus, 0, len(p.members))
+	for _, member := range p.members {
+		statuses = append(statuses, StdioPoolMemberStatus{
+			Index:     member.index,
+			Healthy:   member.client.IsConnected(),
+			Restarts:  member.restarts,
+			Respawns:  member.respawns,
+			InFlight:  member.inFlight,
+			Leases:    member.leases,
+			LastError: member.lastErr,
+		})
+	}
+	return statuses
+}
+
+// 全てのサーバープロセスを終了させる
+// 再起動中のプロセスは再起動を取りやめる
+func (p *StdioPool) Close() error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.done)
+	}
+	clients := make([]*client.ReconnectingClient, 0, len(p.members))
+	for _, member := range p.members {
+		clients = append(clients, member.client)
+	}
+	p.mu.Unlock()
+	var errs []error
+	for i, c := range clients {
+		if err := c.Close(); err != nil && !errors.Is(err, client.ErrClientClosed) {
+			errs = append(errs, fmt.Errorf("failed to close server process %d: %w", i, err))
+		}
+	}
+	// Acquireで待っている呼び出し元に、接続中のプロセスが無くなったことを伝える
+	p.mu.Lock()
+	p.notifyChanged()
+	p.mu.Unlock()
+	return errors.Join(errs...)
+}
+
+// 全てのプロセスが接続中になるまで待つ
+// timeout以内に揃わなかった場合は、接続していないプロセスの数と直近の失敗理由を含むエラーを返す
+func (p *StdioPool) WaitHealthy(timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		p.mu.Lock()
+		var unhealthy []error
+		for _, member := range p.members {
+			if !member.client.IsConnected() {
+				unhealthy = append(unhealthy, fmt.Errorf("server process %d: %w", member.index, cmp.Or(member.lastErr, errors.New("not connected"))))
+			}
+		}
+		closed := p.closed
+		changed := p.changed
+		p.mu.Unlock()
+		if len(unhealthy) == 0 {
+			return nil
+		}
+		if closed {
+			return errors.New("stdio pool is closed")
+		}
+		select {
+		case <-changed:
+		case <-timer.C:
+			return fmt.Errorf("timed out waiting for server processes to become healthy: %d of %d are not connected: %w", len(unhealthy), p.options.Size, errors.Join(unhealthy...))
+		}
+	}
+}
@@ -0,0 +1,334 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kakkky/mcp-sdk-go/client"
+	mcpserver "github.com/kakkky/mcp-sdk-go/mcp-server"
+	"github.com/kakkky/mcp-sdk-go/mcp-server/server"
+	servertransport "github.com/kakkky/mcp-sdk-go/mcp-server/transport"
+	"github.com/kakkky/mcp-sdk-go/shared/schema"
+)
+
+// テストバイナリ自身をstdioのMCPサーバーとして起動するための環境変数
+const helperServerEnv = "MCP_SDK_GO_HELPER_SERVER"
+
+// 指定したファイルが存在する間、ヘルパーのサーバーは起動に失敗する
+const helperFailFileEnv = "MCP_SDK_GO_HELPER_FAIL_FILE"
+
+// helperServerEnvが設定されている場合のみ、stdioのMCPサーバーとして動作する
+// pidツールはプロセスIDを、crashツールはプロセスを異常終了させる
+func TestHelperStdioServer(t *testing.T) {
+	if os.Getenv(helperServerEnv) != "1" {
+		t.Skip("helper process for stdio server tests")
+	}
+	if failFile := os.Getenv(helperFailFileEnv); failFile != "" {
+		if _, err := os.Stat(failFile); err == nil {
+			os.Exit(3)
+		}
+	}
+	mcpServer := mcpserver.NewMcpServer(schema.Implementation{Name: "helper-server", Version: "1.0.0"}, &server.ServerOptions{
+		Capabilities: schema.ServerCapabilities{
+			Tools: &schema.Tools{ListChanged: true},
+		},
+	})
+	_, _ = mcpServer.Tool("pid", "returns the process id", schema.PropertySchema{}, nil,
+		func(args map[string]any) (schema.CallToolResultSchema, error) {
+			return schema.CallToolResultSchema{
+				Content: []schema.ToolContentSchema{
+					&schema.TextContentSchema{Type: "text", Text: fmt.Sprint(os.Getpid())},
+				},
+			}, nil
+		},
+	)
+	_, _ = mcpServer.Tool("crash", "exits the process abnormally", schema.PropertySchema{}, nil,
+		func(args map[string]any) (schema.CallToolResultSchema, error) {
+			os.Exit(1)
+			return schema.CallToolResultSchema{}, nil
+		},
+	)
+	transport := servertransport.NewStdioServerTransport()
+	if err := mcpServer.Connect(transport); err != nil {
+		os.Exit(2)
+	}
+	<-transport.Done()
+	os.Exit(0)
+}
+
+func helperServerParameters() StdioServerParameters {
+	return StdioServerParameters{
+		Command: os.Args[0],
+		Args:    []string{"-test.run=^TestHelperStdioServer$"},
+		Env:     []string{helperServerEnv + "=1"},
+		Stderr:  IGNORE,
+	}
+}
+
+func callPid(t *testing.T, c *PooledClient) string {
+	t.Helper()
+	result, err := c.CallTool(schema.CallToolRequestParams{Name: "pid", Arguments: map[string]any{}})
+	if err != nil {
+		t.Fatalf("CallTool() error = %v", err)
+	}
+	return result.(*schema.CallToolResultSchema).Content[0].(*schema.TextContentSchema).Text
+}
+
+func newPoolClient() *client.Client {
+	return client.NewClient(schema.Implementation{Name: "pool-client", Version: "1.0.0"}, nil)
+}
+
+func TestStdioPool_Strategy(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy PoolStrategy
+	}{
+		{
+			name:     "normal : round robin hands out processes in turn",
+			strategy: ROUND_ROBIN,
+		},
+		{
+			name:     "normal : least busy hands out the process with the fewest clients in use",
+			strategy: LEAST_BUSY,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer drainOperationPhaseNotify()
+			sut := NewStdioPool(helperServerParameters(), newPoolClient, &StdioPoolOptions{
+				Size:     2,
+				Strategy: tt.strategy,
+			})
+			if err := sut.Start(); err != nil {
+				t.Fatalf("Start() error = %v", err)
+			}
+			defer func() {
+				if err := sut.Close(); err != nil {
+					t.Errorf("Close() error = %v", err)
+				}
+			}()
+
+			first, err := sut.Acquire()
+			if err != nil {
+				t.Fatalf("Acquire() error = %v", err)
+			}
+			firstPid := callPid(t, first)
+			second, err := sut.Acquire()
+			if err != nil {
+				t.Fatalf("Acquire() error = %v", err)
+			}
+			secondPid := callPid(t, second)
+			if firstPid == secondPid {
+				t.Errorf("both clients were handed out from the same process %s", firstPid)
+			}
+			// どちらの方式でも、3つ目は返却されたプロセスから払い出される
+			first.Release()
+			third, err := sut.Acquire()
+			if err != nil {
+				t.Fatalf("Acquire() error = %v", err)
+			}
+			if thirdPid := callPid(t, third); thirdPid != firstPid {
+				t.Errorf("third client was handed out from process %s, want %s", thirdPid, firstPid)
+			}
+			second.Release()
+			third.Release()
+		})
+	}
+}
+
+func TestStdioPool_ShareProcess(t *testing.T) {
+	defer drainOperationPhaseNotify()
+	sut := NewStdioPool(helperServerParameters(), newPoolClient, &StdioPoolOptions{Size: 1})
+	if err := sut.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer func() {
+		if err := sut.Close(); err != nil {
+			t.Errorf("Close() error = %v", err)
+		}
+	}()
+
+	// MaxLeasesPerProcessを指定しない場合は、払い出し中のプロセスも払い出す
+	first, err := sut.Acquire()
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	second, err := sut.Acquire()
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	if firstPid, secondPid := callPid(t, first), callPid(t, second); firstPid != secondPid {
+		t.Errorf("clients were handed out from processes %s and %s, want the same process", firstPid, secondPid)
+	}
+	if inFlight := sut.Status()[0].InFlight; inFlight != 2 {
+		t.Errorf("InFlight = %d, want 2", inFlight)
+	}
+	first.Release()
+	second.Release()
+	if diff := cmp.Diff([]int{0, 2}, []int{sut.Status()[0].InFlight, sut.Status()[0].Leases}); diff != "" {
+		t.Errorf("InFlight and Leases mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestStdioPool_AcquireWaitsForRelease(t *testing.T) {
+	defer drainOperationPhaseNotify()
+	sut := NewStdioPool(helperServerParameters(), newPoolClient, &StdioPoolOptions{Size: 1, MaxLeasesPerProcess: 1})
+	if err := sut.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer func() {
+		if err := sut.Close(); err != nil {
+			t.Errorf("Close() error = %v", err)
+		}
+	}()
+
+	first, err := sut.Acquire()
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	// 払い出し中のプロセスは、返却されるまで他の呼び出し元に払い出されない
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := sut.AcquireWithContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("AcquireWithContext() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	acquired := make(chan *PooledClient, 1)
+	go func() {
+		second, err := sut.Acquire()
+		if err != nil {
+			t.Errorf("Acquire() error = %v", err)
+			return
+		}
+		acquired <- second
+	}()
+	callPid(t, first)
+	first.Release()
+	select {
+	case second := <-acquired:
+		callPid(t, second)
+		second.Release()
+	case <-time.After(5 * time.Second):
+		t.Fatal("Acquire() did not return after Release")
+	}
+}
+
+func TestStdioPool_RestartCrashedProcess(t *testing.T) {
+	defer drainOperationPhaseNotify()
+	sut := NewStdioPool(helperServerParameters(), newPoolClient, &StdioPoolOptions{
+		Size: 2,
+		ReconnectOptions: client.ReconnectOptions{
+			InitialBackoff: 10 * time.Millisecond,
+		},
+	})
+	if err := sut.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer func() {
+		if err := sut.Close(); err != nil {
+			t.Errorf("Close() error = %v", err)
+		}
+	}()
+
+	c, err := sut.Acquire()
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	crashedPid := callPid(t, c)
+	// サーバープロセスが応答せずに終了するため、リクエストは接続断のエラーとなる
+	if _, err := c.CallTool(schema.CallToolRequestParams{Name: "crash", Arguments: map[string]any{}}); err == nil {
+		t.Error("CallTool(crash) should return an error")
+	}
+	c.Release()
+
+	if err := sut.WaitHealthy(10 * time.Second); err != nil {
+		t.Fatalf("WaitHealthy() error = %v", err)
+	}
+	restarts := 0
+	for _, status := range sut.Status() {
+		restarts += status.Restarts
+		if status.InFlight != 0 {
+			t.Errorf("process %d is still in use", status.Index)
+		}
+	}
+	if restarts != 1 {
+		t.Errorf("total restarts = %d, want 1", restarts)
+	}
+	// 再起動後は、クラッシュしたプロセスに代わって新しいプロセスが払い出される
+	for range 2 {
+		c, err := sut.Acquire()
+		if err != nil {
+			t.Fatalf("Acquire() error = %v", err)
+		}
+		if pid := callPid(t, c); pid == crashedPid {
+			t.Errorf("crashed process %s is still in use", pid)
+		}
+		c.Release()
+	}
+}
+
+func TestStdioPool_RespawnAfterGivingUp(t *testing.T) {
+	defer drainOperationPhaseNotify()
+	failFile := filepath.Join(t.TempDir(), "fail")
+	params := helperServerParameters()
+	params.Env = append(params.Env, helperFailFileEnv+"="+failFile)
+	sut := NewStdioPool(params, newPoolClient, &StdioPoolOptions{
+		Size: 1,
+		ReconnectOptions: client.ReconnectOptions{
+			InitialBackoff: 10 * time.Millisecond,
+			MaxBackoff:     50 * time.Millisecond,
+			MaxRetries:     1,
+		},
+	})
+	if err := sut.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer func() {
+		if err := sut.Close(); err != nil {
+			t.Errorf("Close() error = %v", err)
+		}
+	}()
+
+	c, err := sut.Acquire()
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	// 再起動が失敗するようにしてからクラッシュさせ、クライアントに再起動を諦めさせる
+	if err := os.WriteFile(failFile, nil, 0o600); err != nil {
+		t.Fatalf("failed to create fail file: %v", err)
+	}
+	_, _ = c.CallTool(schema.CallToolRequestParams{Name: "crash", Arguments: map[string]any{}})
+	c.Release()
+	select {
+	case <-c.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("client did not give up reconnecting")
+	}
+	// 起動し直せない間は、接続していないプロセスがあることをエラーで伝える
+	if err := sut.WaitHealthy(100 * time.Millisecond); err == nil || !strings.Contains(err.Error(), "1 of 1 are not connected") {
+		t.Fatalf("WaitHealthy() error = %v, want the number of processes that are not connected", err)
+	}
+
+	if err := os.Remove(failFile); err != nil {
+		t.Fatalf("failed to remove fail file: %v", err)
+	}
+	if err := sut.WaitHealthy(10 * time.Second); err != nil {
+		t.Fatalf("WaitHealthy() error = %v", err)
+	}
+	if respawns := sut.Status()[0].Respawns; respawns != 1 {
+		t.Errorf("Respawns = %d, want 1", respawns)
+	}
+	respawned, err := sut.Acquire()
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	callPid(t, respawned)
+	respawned.Release()
+}
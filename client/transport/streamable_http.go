@@ -0,0 +1,367 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kakkky/mcp-sdk-go/client"
+	"github.com/kakkky/mcp-sdk-go/client/auth"
+	"github.com/kakkky/mcp-sdk-go/shared/schema"
+	"github.com/kakkky/mcp-sdk-go/shared/schema/jsonrpc"
+	"github.com/kakkky/mcp-sdk-go/shared/transport"
+)
+
+// セッションを終了する際のDELETEリクエストのタイムアウト
+const sessionTerminationTimeout = 5 * time.Second
+
+// サーバーがセッションを認識しない（終了した）場合にSendMessageが返すエラー
+// 新しいトランスポートで接続し直す必要がある
+var ErrSessionNotFound = errors.New("session not found")
+
+type StreamableHTTPClientOptions struct {
+	// リクエストの送信に使用するHTTPクライアント。指定しなかった場合はhttp.DefaultClientとなる
+	HTTPClient *http.Client
+	// 全てのリクエストに付与するHTTPヘッダー
+	Header http.Header
+	// 指定した場合、リクエストに認可のヘッダーを設定する
+	// サーバーが401を返した場合は、認可をやり直してから1度だけ再送する
+	Authorizer auth.Authorizer
+}
+
+// Streamable HTTPでサーバーに接続するトランスポート
+// メッセージごとにPOSTし、応答はapplication/json、またはSSEで受け取る
+// 初期化後は、サーバーからのリクエストや通知を受け取るためにGETでSSEのストリームを開く
+// 参照：(https://modelcontextprotocol.io/specification/2025-03-26/basic/transports#streamable-http)
+type StreamableHTTPClientTransport struct {
+	url     string
+	options StreamableHTTPClientOptions
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu              sync.Mutex
+	sessionId       string
+	protocolVersion string
+	isStarted       bool
+	closeOnce       sync.Once
+	// 複数のストリームから受信したメッセージを、1つずつonReceiveMessageに渡す
+	receiveMu sync.Mutex
+
+	onReceiveMessage func(schema.JsonRpcMessage)
+	onClose          func()
+	onError          func(error)
+}
+
+// urlは http:// または https:// から始まるMCPエンドポイント
+func NewStreamableHTTPClientTransport(url string, options *StreamableHTTPClientOptions) *StreamableHTTPClientTransport {
+	opts := StreamableHTTPClientOptions{}
+	if options != nil {
+		opts = *options
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+	return &StreamableHTTPClientTransport{
+		url:     url,
+		options: opts,
+	}
+}
+
+func (s *StreamableHTTPClientTransport) Start() error {
+	s.mu.Lock()
+	if s.isStarted {
+		s.mu.Unlock()
+		return errors.New("streamable http client transport is already started")
+	}
+	s.isStarted = true
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.mu.Unlock()
+	go func() {
+		client.TransportStartedNotify <- struct{}{}
+	}()
+	return nil
+}
+
+// サーバーが割り当てたセッションID。initializeの応答を受け取るまでは空文字となる
+func (s *StreamableHTTPClientTransport) SessionId() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sessionId
+}
+
+func (s *StreamableHTTPClientTransport) SendMessage(message schema.JsonRpcMessage) error {
+	return s.SendMessageWithContext(context.Background(), message)
+}
+
+// ctxはPOSTの送信とレスポンスヘッダーの受信まで有効で、SSEで届く応答の受信には影響しない
+func (s *StreamableHTTPClientTransport) SendMessageWithContext(ctx context.Context, message schema.JsonRpcMessage) error {
+	if s.ctx == nil {
+		return errors.New("streamable http client transport is not started")
+	}
+	data, err := jsonrpc.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	// レスポンスボディはSendMessageが返った後も読み続けるため、ctxはレスポンスヘッダーを受け取るまでのみ反映する
+	reqCtx, cancelReq := context.WithCancel(s.ctx)
+	stop := context.AfterFunc(ctx, cancelReq)
+	resp, err := s.do(reqCtx, http.MethodPost, data, http.Header{
+		"Content-Type": {transport.CONTENT_TYPE_JSON},
+		"Accept":       {transport.CONTENT_TYPE_JSON + ", " + transport.CONTENT_TYPE_SSE},
+	})
+	if !stop() {
+		// ctxがキャンセルされた場合
+		cancelReq()
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+		return ctx.Err()
+	}
+	if err != nil {
+		cancelReq()
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	if err := s.checkResponse(resp); err != nil {
+		cancelReq()
+		return err
+	}
+	if sessionId := resp.Header.Get(transport.MCP_SESSION_ID_HEADER); sessionId != "" {
+		s.mu.Lock()
+		s.sessionId = sessionId
+		s.mu.Unlock()
+	}
+
+	if resp.StatusCode == http.StatusAccepted {
+		_ = resp.Body.Close()
+		cancelReq()
+		// 初期化が完了したら、サーバーからのメッセージを受け取るストリームを開く
+		if notification, ok := message.(schema.JsonRpcNotification); ok && notification.Method() == "notifications/initialized" {
+			go s.openStandaloneStream()
+		}
+		return nil
+	}
+	contentType := resp.Header.Get("Content-Type")
+	switch {
+	case strings.HasPrefix(contentType, transport.CONTENT_TYPE_SSE):
+		go func() {
+			defer cancelReq()
+			s.readStream(resp.Body)
+		}()
+		return nil
+	case strings.HasPrefix(contentType, transport.CONTENT_TYPE_JSON):
+		defer cancelReq()
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response: %w", err)
+		}
+		s.deliver(body)
+		return nil
+	default:
+		_ = resp.Body.Close()
+		cancelReq()
+		return fmt.Errorf("unexpected content type: %q", contentType)
+	}
+}
+
+// サーバーが401（またはスコープ不足の403）を返した場合は、Authorizerで認可をやり直して1度だけ再送する
+func (s *StreamableHTTPClientTransport) do(ctx context.Context, method string, body []byte, header http.Header) (*http.Response, error) {
+	for retried := false; ; retried = true {
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+		req, err := s.newRequest(ctx, method, reader)
+		if err != nil {
+			return nil, err
+		}
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		resp, err := s.options.HTTPClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if retried || s.options.Authorizer == nil || !needsAuthorization(resp) {
+			return resp, nil
+		}
+		err = s.options.Authorizer.HandleUnauthorized(ctx, resp)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to authorize: %w", err)
+		}
+	}
+}
+
+func needsAuthorization(resp *http.Response) bool {
+	if resp.StatusCode == http.StatusUnauthorized {
+		return true
+	}
+	return resp.StatusCode == http.StatusForbidden && strings.Contains(resp.Header.Get("WWW-Authenticate"), "insufficient_scope")
+}
+
+func (s *StreamableHTTPClientTransport) newRequest(ctx context.Context, method string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.url, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if s.options.Authorizer != nil {
+		if err := s.options.Authorizer.SetAuthHeader(ctx, req); err != nil {
+			return nil, fmt.Errorf("failed to set authorization: %w", err)
+		}
+	}
+	for key, values := range s.options.Header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	s.mu.Lock()
+	if s.sessionId != "" {
+		req.Header.Set(transport.MCP_SESSION_ID_HEADER, s.sessionId)
+	}
+	if s.protocolVersion != "" {
+		req.Header.Set(transport.MCP_PROTOCOL_VERSION_HEADER, s.protocolVersion)
+	}
+	s.mu.Unlock()
+	return req, nil
+}
+
+// 2xx以外のステータスコードの場合はボディを閉じてエラーを返す
+func (s *StreamableHTTPClientTransport) checkResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	s.mu.Lock()
+	hasSession := s.sessionId != ""
+	s.mu.Unlock()
+	if resp.StatusCode == http.StatusNotFound && hasSession {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, strings.TrimSpace(string(body)))
+	}
+	return fmt.Errorf("server returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+}
+
+// GETでサーバーからのメッセージを受け取るストリームを開く
+// サーバーがストリームに対応していない（405を返す）場合は何もしない
+func (s *StreamableHTTPClientTransport) openStandaloneStream() {
+	resp, err := s.do(s.ctx, http.MethodGet, nil, http.Header{"Accept": {transport.CONTENT_TYPE_SSE}})
+	if err != nil {
+		if s.ctx.Err() == nil {
+			s.OnError(fmt.Errorf("failed to open event stream: %w", err))
+		}
+		return
+	}
+	if resp.StatusCode == http.StatusMethodNotAllowed {
+		_ = resp.Body.Close()
+		return
+	}
+	if err := s.checkResponse(resp); err != nil {
+		s.OnError(fmt.Errorf("failed to open event stream: %w", err))
+		return
+	}
+	s.readStream(resp.Body)
+}
+
+// SSEのストリームが終わるまでメッセージを受信する
+func (s *StreamableHTTPClientTransport) readStream(body io.ReadCloser) {
+	defer body.Close()
+	reader := transport.NewSSEReader(body)
+	for {
+		data, err := reader.Next()
+		if err != nil {
+			if !errors.Is(err, io.EOF) && s.ctx.Err() == nil {
+				s.OnError(fmt.Errorf("failed to read event stream: %w", err))
+			}
+			return
+		}
+		s.deliver(data)
+	}
+}
+
+func (s *StreamableHTTPClientTransport) deliver(data []byte) {
+	message, err := jsonrpc.Unmarshal(data)
+	if err != nil {
+		s.OnError(fmt.Errorf("failed to unmarshal message: %w", err))
+		return
+	}
+	// 以降のリクエストには、ネゴシエーションしたプロトコルバージョンを付与する
+	if response, ok := message.(schema.JsonRpcResponse); ok {
+		if result, ok := response.Result.(*schema.InitializeResultSchema); ok {
+			s.mu.Lock()
+			s.protocolVersion = result.ProtocolVersion
+			s.mu.Unlock()
+		}
+	}
+	s.receiveMu.Lock()
+	defer s.receiveMu.Unlock()
+	if s.ctx.Err() != nil {
+		return
+	}
+	s.onReceiveMessage(message)
+}
+
+// DELETEでサーバーにセッションの終了を伝えた上で、全てのストリームを閉じる
+func (s *StreamableHTTPClientTransport) Close() error {
+	if s.ctx == nil {
+		return errors.New("streamable http client transport is not started")
+	}
+	s.closeOnce.Do(func() {
+		s.terminateSession()
+		s.cancel()
+		s.OnClose()
+	})
+	return nil
+}
+
+func (s *StreamableHTTPClientTransport) terminateSession() {
+	s.mu.Lock()
+	sessionId := s.sessionId
+	s.mu.Unlock()
+	if sessionId == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sessionTerminationTimeout)
+	defer cancel()
+	req, err := s.newRequest(ctx, http.MethodDelete, nil)
+	if err != nil {
+		return
+	}
+	resp, err := s.options.HTTPClient.Do(req)
+	if err != nil {
+		return
+	}
+	// セッションの終了に対応していないサーバー（405）もあるため、ステータスコードは確認しない
+	_ = resp.Body.Close()
+}
+
+func (s *StreamableHTTPClientTransport) OnClose() {
+	if s.onClose != nil {
+		s.onClose()
+	}
+}
+
+func (s *StreamableHTTPClientTransport) OnError(err error) {
+	if s.onError != nil {
+		s.onError(err)
+	}
+}
+
+func (s *StreamableHTTPClientTransport) SetOnReceiveMessage(onReceiveMessage func(schema.JsonRpcMessage)) {
+	s.onReceiveMessage = onReceiveMessage
+}
+
+func (s *StreamableHTTPClientTransport) SetOnClose(onClose func()) {
+	s.onClose = onClose
+}
+
+func (s *StreamableHTTPClientTransport) SetOnError(onError func(error)) {
+	s.onError = onError
+}
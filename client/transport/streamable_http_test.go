@@ -0,0 +1,207 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kakkky/mcp-sdk-go/client"
+	"github.com/kakkky/mcp-sdk-go/client/auth"
+	"github.com/kakkky/mcp-sdk-go/client/auth/authtest"
+	mcpserver "github.com/kakkky/mcp-sdk-go/mcp-server"
+	mcpauth "github.com/kakkky/mcp-sdk-go/mcp-server/auth"
+	"github.com/kakkky/mcp-sdk-go/mcp-server/server"
+	servertransport "github.com/kakkky/mcp-sdk-go/mcp-server/transport"
+	sharedauth "github.com/kakkky/mcp-sdk-go/shared/auth"
+	"github.com/kakkky/mcp-sdk-go/shared/protocol"
+	"github.com/kakkky/mcp-sdk-go/shared/schema"
+)
+
+func TestStreamableHTTPClientTransport_WithMcpServer(t *testing.T) {
+	tests := []struct {
+		name    string
+		options *servertransport.StreamableHTTPServerOptions
+	}{
+		{
+			name:    "normal : responses are streamed with SSE",
+			options: nil,
+		},
+		{
+			name:    "normal : responses are returned as application/json",
+			options: &servertransport.StreamableHTTPServerOptions{JSONResponse: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer drainOperationPhaseNotify()
+			sessionClosed := make(chan struct{}, 1)
+			handler := servertransport.NewStreamableHTTPHandler(func(tr protocol.Transport) {
+				// セッションごとにMcpServerを生成する
+				mcpServer := mcpserver.NewMcpServer(schema.Implementation{Name: "http-server", Version: "1.0.0"}, &server.ServerOptions{
+					Capabilities: schema.ServerCapabilities{
+						Tools: &schema.Tools{ListChanged: true},
+					},
+				})
+				if _, err := mcpServer.Tool("echo", "echo tool", schema.PropertySchema{"text": schema.PropertyInfoSchema{Type: "string"}}, nil,
+					func(args map[string]any) (schema.CallToolResultSchema, error) {
+						return schema.CallToolResultSchema{
+							Content: []schema.ToolContentSchema{&schema.TextContentSchema{Type: "text", Text: args["text"].(string)}},
+						}, nil
+					},
+				); err != nil {
+					t.Errorf("failed to register tool: %v", err)
+					return
+				}
+				go func() {
+					<-tr.(interface{ Done() <-chan struct{} }).Done()
+					sessionClosed <- struct{}{}
+				}()
+				if err := mcpServer.Connect(tr); err != nil {
+					t.Errorf("failed to connect server: %v", err)
+				}
+			}, tt.options)
+			httpServer := httptest.NewServer(handler)
+			defer httpServer.Close()
+			defer handler.Close()
+
+			c := client.NewClient(schema.Implementation{Name: "http-client", Version: "1.0.0"}, nil)
+			sut := NewStreamableHTTPClientTransport(httpServer.URL, nil)
+			if err := c.Connect(sut); err != nil {
+				t.Fatalf("failed to connect: %v", err)
+			}
+			if sut.SessionId() == "" {
+				t.Error("SessionId() is empty after initialization")
+			}
+
+			result, err := c.CallTool(schema.CallToolRequestParams{Name: "echo", Arguments: map[string]any{"text": "hello"}})
+			if err != nil {
+				t.Fatalf("CallTool() error = %v", err)
+			}
+			want := &schema.CallToolResultSchema{
+				Content: []schema.ToolContentSchema{&schema.TextContentSchema{Type: "text", Text: "hello"}},
+			}
+			if diff := cmp.Diff(want, result); diff != "" {
+				t.Errorf("CallTool() mismatch (-want +got):\n%s", diff)
+			}
+
+			// Closeでセッションが終了し、同じセッションIDは使えなくなる
+			if err := c.Close(); err != nil {
+				t.Errorf("Close() error = %v", err)
+			}
+			select {
+			case <-sessionClosed:
+			case <-time.After(5 * time.Second):
+				t.Fatal("server session was not closed")
+			}
+			stale := NewStreamableHTTPClientTransport(httpServer.URL, nil)
+			if err := stale.Start(); err != nil {
+				t.Fatalf("Start() error = %v", err)
+			}
+			<-client.TransportStartedNotify
+			stale.sessionId = sut.SessionId()
+			err = stale.SendMessage(schema.JsonRpcRequest{
+				BaseMessage: schema.BaseMessage{Jsonrpc: schema.JSON_RPC_VERSION, Id: 1},
+				Request:     &schema.PingRequestSchema{MethodName: "ping"},
+			})
+			if !errors.Is(err, ErrSessionNotFound) {
+				t.Errorf("SendMessage() with a terminated session error = %v, want %v", err, ErrSessionNotFound)
+			}
+		})
+	}
+}
+
+func TestStreamableHTTPClientTransport_WithOAuth(t *testing.T) {
+	defer drainOperationPhaseNotify()
+	authorizationServer := authtest.NewAuthorizationServer()
+	defer authorizationServer.Close()
+	authorizationServer.SetSubject("alice")
+
+	mux := http.NewServeMux()
+	httpServer := httptest.NewServer(mux)
+	defer httpServer.Close()
+	resource := httpServer.URL + "/mcp"
+	metadata := &sharedauth.ProtectedResourceMetadata{
+		Resource:             resource,
+		AuthorizationServers: []string{authorizationServer.URL},
+	}
+	handler := servertransport.NewStreamableHTTPHandler(func(tr protocol.Transport) {
+		mcpServer := mcpserver.NewMcpServer(schema.Implementation{Name: "protected-server", Version: "1.0.0"}, &server.ServerOptions{
+			Capabilities: schema.ServerCapabilities{Tools: &schema.Tools{ListChanged: true}},
+		})
+		if _, err := mcpServer.ToolWithExchange("whoami", "returns the caller", schema.PropertySchema{}, nil,
+			func(ctx context.Context, exchange *mcpserver.Exchange, args map[string]any) (schema.CallToolResultSchema, error) {
+				return schema.CallToolResultSchema{
+					Content: []schema.ToolContentSchema{&schema.TextContentSchema{Type: "text", Text: exchange.AuthInfo.Subject}},
+				}, nil
+			},
+		); err != nil {
+			t.Errorf("failed to register tool: %v", err)
+		}
+		if err := mcpServer.Connect(tr); err != nil {
+			t.Errorf("failed to connect server: %v", err)
+		}
+	}, &servertransport.StreamableHTTPServerOptions{
+		Auth: &mcpauth.ResourceServerOptions{
+			Verifier: mcpauth.NewIntrospectionVerifier(authorizationServer.Metadata().IntrospectionEndpoint, &mcpauth.IntrospectionVerifierOptions{
+				Audience: resource,
+			}),
+			Metadata: metadata,
+		},
+	})
+	defer handler.Close()
+	mux.Handle("/mcp", handler)
+	metadataURL, _ := sharedauth.ProtectedResourceMetadataURL(resource)
+	u, _ := url.Parse(metadataURL)
+	mux.Handle(u.Path, mcpauth.NewProtectedResourceMetadataHandler(*metadata))
+
+	opened := 0
+	authorizer := auth.NewOAuthClient(&auth.OAuthClientOptions{
+		OpenURL: func(authorizationURL string) error {
+			// ブラウザの代わりに認可URLを開き、リダイレクト先まで辿る
+			opened++
+			resp, err := http.Get(authorizationURL)
+			if err != nil {
+				return err
+			}
+			return resp.Body.Close()
+		},
+	})
+	c := client.NewClient(schema.Implementation{Name: "oauth-client", Version: "1.0.0"}, nil)
+	sut := NewStreamableHTTPClientTransport(resource, &StreamableHTTPClientOptions{Authorizer: authorizer})
+	if err := c.Connect(sut); err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer c.Close()
+
+	want := &schema.CallToolResultSchema{
+		Content: []schema.ToolContentSchema{&schema.TextContentSchema{Type: "text", Text: "alice"}},
+	}
+	result, err := c.CallTool(schema.CallToolRequestParams{Name: "whoami"})
+	if err != nil {
+		t.Fatalf("CallTool() error = %v", err)
+	}
+	if diff := cmp.Diff(want, result); diff != "" {
+		t.Errorf("CallTool() mismatch (-want +got):\n%s", diff)
+	}
+
+	// アクセストークンが無効になっても、リフレッシュトークンで更新して続けられる
+	authorizationServer.RevokeAccessTokens()
+	result, err = c.CallTool(schema.CallToolRequestParams{Name: "whoami"})
+	if err != nil {
+		t.Fatalf("CallTool() after revocation error = %v", err)
+	}
+	if diff := cmp.Diff(want, result); diff != "" {
+		t.Errorf("CallTool() after revocation mismatch (-want +got):\n%s", diff)
+	}
+	if opened != 1 {
+		t.Errorf("authorization url was opened %d times, want 1", opened)
+	}
+	if got := authorizationServer.TokenRequests("refresh_token"); got != 1 {
+		t.Errorf("refresh token requests = %d, want 1", got)
+	}
+}
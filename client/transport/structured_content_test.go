@@ -0,0 +1,81 @@
+package transport
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kakkky/mcp-sdk-go/client"
+	mcpserver "github.com/kakkky/mcp-sdk-go/mcp-server"
+	"github.com/kakkky/mcp-sdk-go/mcp-server/server"
+	"github.com/kakkky/mcp-sdk-go/shared/schema"
+)
+
+type weatherInput struct {
+	City string `json:"city"`
+}
+
+type weatherOutput struct {
+	City        string  `json:"city"`
+	Temperature float64 `json:"temperature"`
+}
+
+func TestCallToolStructured_WithMcpServer(t *testing.T) {
+	mcpServer := mcpserver.NewMcpServer(schema.Implementation{Name: "weather-server", Version: "1.0.0"}, &server.ServerOptions{
+		Capabilities: schema.ServerCapabilities{
+			Tools: &schema.Tools{ListChanged: true},
+		},
+	})
+	if _, err := mcpserver.AddTool(mcpServer, "weather", "current weather", func(ctx context.Context, in weatherInput) (weatherOutput, error) {
+		return weatherOutput{City: in.City, Temperature: 22.5}, nil
+	}); err != nil {
+		t.Fatalf("failed to register tool: %v", err)
+	}
+
+	clientTransport, serverTransport := NewInMemoryTransportPair(&InMemoryTransportOptions{Serialize: true})
+	defer clientTransport.Close()
+	if err := mcpServer.Connect(serverTransport); err != nil {
+		t.Fatalf("failed to connect server: %v", err)
+	}
+	c := client.NewClient(schema.Implementation{Name: "weather-client", Version: "1.0.0"}, nil)
+	errCh := make(chan error, 1)
+	go func() {
+		if err := c.Connect(clientTransport); err != nil {
+			errCh <- err
+		}
+	}()
+	select {
+	case err := <-errCh:
+		t.Fatalf("failed to connect: %v", err)
+	case <-client.OperationPhaseStartedNotify:
+	}
+	// サーバー側のinitialized通知を読み捨てる
+	<-server.OperationPhaseStartedNotify
+
+	result, err := c.ListTools()
+	if err != nil {
+		t.Fatalf("ListTools() error = %v", err)
+	}
+	wantOutputSchema := &schema.JsonSchema{
+		Type: "object",
+		Properties: schema.PropertySchema{
+			"city":        {Type: "string"},
+			"temperature": {Type: "number"},
+		},
+		Required: []string{"city", "temperature"},
+	}
+	if diff := cmp.Diff(wantOutputSchema, result.(*schema.ListToolsResultSchema).Tools[0].OutputSchema); diff != "" {
+		t.Errorf("output schema mismatch (-want +got):\n%s", diff)
+	}
+
+	got, err := client.CallToolStructured[weatherOutput](c, schema.CallToolRequestParams{
+		Name:      "weather",
+		Arguments: map[string]any{"city": "Tokyo"},
+	})
+	if err != nil {
+		t.Fatalf("CallToolStructured() error = %v", err)
+	}
+	if diff := cmp.Diff(weatherOutput{City: "Tokyo", Temperature: 22.5}, got); diff != "" {
+		t.Errorf("CallToolStructured() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,148 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/websocket"
+	"github.com/kakkky/mcp-sdk-go/client"
+	"github.com/kakkky/mcp-sdk-go/client/auth"
+	"github.com/kakkky/mcp-sdk-go/shared/schema"
+	"github.com/kakkky/mcp-sdk-go/shared/transport"
+)
+
+type WebSocketClientOptions struct {
+	transport.WebSocketOptions
+	// ハンドシェイク時に付与するHTTPヘッダー
+	Header http.Header
+	// 指定した場合、ハンドシェイクのリクエストに認可のためのヘッダーを設定する
+	// サーバーが401を返した場合は、認可をやり直して1度だけ接続を試みる
+	Authorizer auth.Authorizer
+}
+
+type WebSocketClientTransport struct {
+	url     string
+	options *WebSocketClientOptions
+	conn    *transport.WebSocketConn
+
+	onReceiveMessage func(schema.JsonRpcMessage)
+	onClose          func()
+	onError          func(error)
+}
+
+// urlは ws:// または wss:// から始まるWebSocketサーバーのエンドポイント
+func NewWebSocketClientTransport(url string, options *WebSocketClientOptions) *WebSocketClientTransport {
+	return &WebSocketClientTransport{
+		url:     url,
+		options: options,
+	}
+}
+
+func (w *WebSocketClientTransport) Start() error {
+	if w.conn != nil {
+		return errors.New("websocket client transport is already started")
+	}
+	dialer := websocket.Dialer{
+		Proxy:        http.ProxyFromEnvironment,
+		Subprotocols: []string{transport.WEBSOCKET_SUBPROTOCOL},
+	}
+	var wsOptions *transport.WebSocketOptions
+	if w.options != nil {
+		wsOptions = &w.options.WebSocketOptions
+	}
+	conn, err := w.dial(context.Background(), dialer)
+	if err != nil {
+		return err
+	}
+	w.conn = transport.NewWebSocketConn(conn, wsOptions)
+	w.conn.Start(w.onReceiveMessage, w.OnError, w.OnClose)
+	go func() {
+		client.TransportStartedNotify <- struct{}{}
+	}()
+	return nil
+}
+
+func (w *WebSocketClientTransport) dial(ctx context.Context, dialer websocket.Dialer) (*websocket.Conn, error) {
+	var authorizer auth.Authorizer
+	if w.options != nil {
+		authorizer = w.options.Authorizer
+	}
+	for retried := false; ; retried = true {
+		header := http.Header{}
+		if w.options != nil {
+			header = w.options.Header.Clone()
+			if header == nil {
+				header = http.Header{}
+			}
+		}
+		if authorizer != nil {
+			// Authorizerはhttp.Requestにヘッダーを設定するため、ハンドシェイクのリクエストに見立てて渡す
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, w.url, nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create request: %w", err)
+			}
+			req.Header = header
+			if err := authorizer.SetAuthHeader(ctx, req); err != nil {
+				return nil, fmt.Errorf("failed to set authorization: %w", err)
+			}
+		}
+		conn, resp, err := dialer.DialContext(ctx, w.url, header)
+		if err == nil {
+			return conn, nil
+		}
+		if resp == nil {
+			return nil, fmt.Errorf("failed to connect to websocket server: %w", err)
+		}
+		if retried || authorizer == nil || !needsAuthorization(resp) {
+			return nil, fmt.Errorf("failed to connect to websocket server (status %d): %w", resp.StatusCode, err)
+		}
+		if err := authorizer.HandleUnauthorized(ctx, resp); err != nil {
+			return nil, fmt.Errorf("failed to authorize: %w", err)
+		}
+	}
+}
+
+func (w *WebSocketClientTransport) SendMessage(message schema.JsonRpcMessage) error {
+	return w.SendMessageWithContext(context.Background(), message)
+}
+
+// 書き込みを待っている間にctxがキャンセルされた場合は、送信せずにエラーを返す
+func (w *WebSocketClientTransport) SendMessageWithContext(ctx context.Context, message schema.JsonRpcMessage) error {
+	if w.conn == nil {
+		return errors.New("websocket client transport is not started")
+	}
+	return w.conn.WriteMessage(ctx, message)
+}
+
+func (w *WebSocketClientTransport) Close() error {
+	if w.conn == nil {
+		return errors.New("websocket client transport is not started")
+	}
+	return w.conn.Close()
+}
+
+func (w *WebSocketClientTransport) OnClose() {
+	if w.onClose != nil {
+		w.onClose()
+	}
+}
+
+func (w *WebSocketClientTransport) OnError(err error) {
+	if w.onError != nil {
+		w.onError(err)
+	}
+}
+
+func (w *WebSocketClientTransport) SetOnReceiveMessage(onReceiveMessage func(schema.JsonRpcMessage)) {
+	w.onReceiveMessage = onReceiveMessage
+}
+
+func (w *WebSocketClientTransport) SetOnClose(onClose func()) {
+	w.onClose = onClose
+}
+
+func (w *WebSocketClientTransport) SetOnError(onError func(error)) {
+	w.onError = onError
+}
@@ -0,0 +1,276 @@
+package transport
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kakkky/mcp-sdk-go/client"
+	"github.com/kakkky/mcp-sdk-go/client/auth"
+	mcpserver "github.com/kakkky/mcp-sdk-go/mcp-server"
+	mcpauth "github.com/kakkky/mcp-sdk-go/mcp-server/auth"
+	"github.com/kakkky/mcp-sdk-go/mcp-server/server"
+	servertransport "github.com/kakkky/mcp-sdk-go/mcp-server/transport"
+	"github.com/kakkky/mcp-sdk-go/shared/schema"
+	"github.com/kakkky/mcp-sdk-go/shared/transport"
+)
+
+func newWebSocketTestServer(t *testing.T, options *servertransport.WebSocketServerOptions) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 接続ごとにMcpServerを生成する
+		mcpServer := mcpserver.NewMcpServer(schema.Implementation{Name: "websocket-server", Version: "1.0.0"}, &server.ServerOptions{
+			Capabilities: schema.ServerCapabilities{
+				Tools: &schema.Tools{ListChanged: true},
+			},
+		})
+		if _, err := mcpServer.Tool(
+			"echo",
+			"echo back the message",
+			schema.PropertySchema{
+				"message": schema.PropertyInfoSchema{Type: "string", Description: "message to echo"},
+			},
+			nil,
+			func(args map[string]any) (schema.CallToolResultSchema, error) {
+				return schema.CallToolResultSchema{
+					Content: []schema.ToolContentSchema{
+						&schema.TextContentSchema{Type: "text", Text: args["message"].(string)},
+					},
+				}, nil
+			},
+		); err != nil {
+			t.Errorf("failed to register tool: %v", err)
+			return
+		}
+		serverTransport, err := servertransport.NewWebSocketServerTransport(w, r, options)
+		if err != nil {
+			t.Errorf("failed to create server transport: %v", err)
+			return
+		}
+		if err := mcpServer.Connect(serverTransport); err != nil {
+			t.Errorf("failed to connect server: %v", err)
+		}
+	}))
+}
+
+func TestWebSocketClientTransport_WithMcpServer(t *testing.T) {
+	httpServer := newWebSocketTestServer(t, nil)
+	defer httpServer.Close()
+
+	c := client.NewClient(schema.Implementation{Name: "websocket-client", Version: "1.0.0"}, nil)
+	sut := NewWebSocketClientTransport("ws"+strings.TrimPrefix(httpServer.URL, "http"), nil)
+	sut.SetOnError(func(err error) {
+		t.Errorf("transport error: %v", err)
+	})
+
+	errCh := make(chan error, 1)
+	go func() {
+		if err := c.Connect(sut); err != nil {
+			errCh <- err
+		}
+	}()
+	select {
+	case err := <-errCh:
+		t.Fatalf("failed to connect: %v", err)
+	case <-client.OperationPhaseStartedNotify:
+	}
+	// サーバー側のinitialized通知を読み捨てる
+	<-server.OperationPhaseStartedNotify
+
+	result, err := c.CallTool(schema.CallToolRequestParams{
+		Name:      "echo",
+		Arguments: map[string]any{"message": "hello over websocket"},
+	})
+	if err != nil {
+		t.Fatalf("CallTool() error = %v", err)
+	}
+	want := &schema.CallToolResultSchema{
+		Content: []schema.ToolContentSchema{
+			&schema.TextContentSchema{Type: "text", Text: "hello over websocket"},
+		},
+	}
+	if diff := cmp.Diff(want, result); diff != "" {
+		t.Errorf("CallTool() mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+}
+
+func TestWebSocketClientTransport_CloseMapping(t *testing.T) {
+	tests := []struct {
+		name          string
+		serverOptions *servertransport.WebSocketServerOptions
+		message       schema.JsonRpcMessage
+		isExpectedErr bool
+	}{
+		{
+			name: "normal : server closes normally and only OnClose is called",
+			message: schema.JsonRpcRequest{
+				BaseMessage: schema.BaseMessage{Jsonrpc: schema.JSON_RPC_VERSION, Id: 1},
+				Request:     &schema.PingRequestSchema{MethodName: "ping"},
+			},
+			isExpectedErr: false,
+		},
+		{
+			name: "semi normal : message exceeding the maximum size closes the connection with an error",
+			serverOptions: &servertransport.WebSocketServerOptions{
+				WebSocketOptions: transport.WebSocketOptions{MaxMessageSize: 64},
+			},
+			message: schema.JsonRpcRequest{
+				BaseMessage: schema.BaseMessage{Jsonrpc: schema.JSON_RPC_VERSION, Id: 1},
+				Request: &schema.CallToolRequestSchema{
+					MethodName: "tools/call",
+					ParamsData: schema.CallToolRequestParams{
+						Name:      "echo",
+						Arguments: map[string]any{"message": strings.Repeat("a", 128)},
+					},
+				},
+			},
+			isExpectedErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverClosed := make(chan struct{})
+			httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				serverTransport, err := servertransport.NewWebSocketServerTransport(w, r, tt.serverOptions)
+				if err != nil {
+					t.Errorf("failed to create server transport: %v", err)
+					return
+				}
+				serverTransport.SetOnReceiveMessage(func(schema.JsonRpcMessage) {
+					// メッセージを受け取ったら正常にクローズする
+					if err := serverTransport.Close(); err != nil {
+						t.Errorf("failed to close server transport: %v", err)
+					}
+				})
+				serverTransport.SetOnClose(func() { close(serverClosed) })
+				if err := serverTransport.Start(); err != nil {
+					t.Errorf("failed to start server transport: %v", err)
+				}
+			}))
+			defer httpServer.Close()
+
+			sut := NewWebSocketClientTransport("ws"+strings.TrimPrefix(httpServer.URL, "http"), nil)
+			errCh := make(chan error, 1)
+			closeCh := make(chan struct{})
+			sut.SetOnReceiveMessage(func(schema.JsonRpcMessage) {})
+			sut.SetOnError(func(err error) { errCh <- err })
+			sut.SetOnClose(func() { close(closeCh) })
+			if err := sut.Start(); err != nil {
+				t.Fatalf("Start() error = %v", err)
+			}
+			<-client.TransportStartedNotify
+
+			if err := sut.SendMessage(tt.message); err != nil {
+				t.Fatalf("SendMessage() error = %v", err)
+			}
+			select {
+			case <-closeCh:
+			case <-time.After(5 * time.Second):
+				t.Fatal("OnClose was not called")
+			}
+			<-serverClosed
+			select {
+			case err := <-errCh:
+				if !tt.isExpectedErr {
+					t.Errorf("unexpected OnError: %v", err)
+				}
+			default:
+				if tt.isExpectedErr {
+					t.Error("OnError was not called")
+				}
+			}
+		})
+	}
+}
+
+func TestWebSocketClientTransport_WithAPIKey(t *testing.T) {
+	verifier := mcpauth.NewAPIKeyVerifier(mcpauth.StaticAPIKeyStore{
+		{Key: "secret", Name: "service-a", Scopes: []string{"mcp"}},
+	}, nil)
+	tests := []struct {
+		name            string
+		authorizer      auth.Authorizer
+		expectedSubject string
+		isExpectedErr   bool
+	}{
+		{
+			name:            "normal : api key header",
+			authorizer:      auth.NewAPIKeyAuthorizer("X-API-Key", "secret"),
+			expectedSubject: "service-a",
+		},
+		{
+			name:            "normal : bearer token",
+			authorizer:      auth.NewBearerTokenAuthorizer("secret"),
+			expectedSubject: "service-a",
+		},
+		{
+			name:          "semi normal : unknown key",
+			authorizer:    auth.NewAPIKeyAuthorizer("X-API-Key", "forged"),
+			isExpectedErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subjectCh := make(chan string, 1)
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				serverTransport, err := servertransport.NewWebSocketServerTransport(w, r, nil)
+				if err != nil {
+					t.Errorf("failed to create server transport: %v", err)
+					return
+				}
+				serverTransport.SetOnReceiveMessage(func(message schema.JsonRpcMessage) {
+					request, ok := message.(schema.JsonRpcRequest)
+					if !ok || request.AuthInfo == nil {
+						subjectCh <- ""
+						return
+					}
+					subjectCh <- request.AuthInfo.Subject
+				})
+				if err := serverTransport.Start(); err != nil {
+					t.Errorf("failed to start server transport: %v", err)
+				}
+			})
+			httpServer := httptest.NewServer(mcpauth.RequireBearerToken(mcpauth.ResourceServerOptions{
+				Verifier:     verifier,
+				APIKeyHeader: "X-API-Key",
+			})(handler))
+			defer httpServer.Close()
+
+			sut := NewWebSocketClientTransport("ws"+strings.TrimPrefix(httpServer.URL, "http"), &WebSocketClientOptions{
+				Authorizer: tt.authorizer,
+			})
+			sut.SetOnReceiveMessage(func(schema.JsonRpcMessage) {})
+			err := sut.Start()
+			if (err != nil) != tt.isExpectedErr {
+				t.Fatalf("Start() error = %v, isExpectedErr %v", err, tt.isExpectedErr)
+			}
+			if err != nil {
+				return
+			}
+			<-client.TransportStartedNotify
+			defer sut.Close()
+
+			if err := sut.SendMessage(schema.JsonRpcRequest{
+				BaseMessage: schema.BaseMessage{Jsonrpc: schema.JSON_RPC_VERSION, Id: 1},
+				Request:     &schema.PingRequestSchema{MethodName: "ping"},
+			}); err != nil {
+				t.Fatalf("SendMessage() error = %v", err)
+			}
+			select {
+			case got := <-subjectCh:
+				if got != tt.expectedSubject {
+					t.Errorf("subject = %q, want %q", got, tt.expectedSubject)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("server did not receive the request")
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	mcperr "github.com/kakkky/mcp-sdk-go/shared/mcp-err"
+	"github.com/kakkky/mcp-sdk-go/shared/protocol"
+	"github.com/kakkky/mcp-sdk-go/shared/schema"
+	"github.com/kakkky/mcp-sdk-go/shared/schema/jsonrpc"
+)
+
+// 1つのセッションについて、クライアント側（downstream）とサーバー側（upstream）のトランスポートの間でメッセージを中継する
+// メッセージの中身は解釈しないため、キャンセル、進捗の通知、サーバーからのリクエストもそのまま双方向に転送される
+// どちらかのトランスポートが閉じられた場合は、もう一方も閉じる
+type bridge struct {
+	downstream protocol.Transport
+	upstream   protocol.Transport
+	logger     *slog.Logger
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+func newBridge(downstream, upstream protocol.Transport, logger *slog.Logger) *bridge {
+	b := &bridge{
+		downstream: downstream,
+		upstream:   upstream,
+		logger:     logger,
+		done:       make(chan struct{}),
+	}
+	downstream.SetOnReceiveMessage(b.forwarder(upstream, downstream, "upstream"))
+	upstream.SetOnReceiveMessage(b.forwarder(downstream, upstream, "downstream"))
+	downstream.SetOnError(func(err error) { logger.Error("downstream transport error", "error", err) })
+	upstream.SetOnError(func(err error) { logger.Error("upstream transport error", "error", err) })
+	downstream.SetOnClose(func() { b.close(upstream) })
+	upstream.SetOnClose(func() { b.close(downstream) })
+	return b
+}
+
+// upstream、downstreamの順にトランスポートを開始する
+// upstreamが開始できない場合は、downstreamにメッセージを受け付けさせない
+func (b *bridge) start() error {
+	if err := b.upstream.Start(); err != nil {
+		return fmt.Errorf("failed to start upstream: %w", err)
+	}
+	if err := b.downstream.Start(); err != nil {
+		_ = b.upstream.Close()
+		return fmt.Errorf("failed to start downstream: %w", err)
+	}
+	return nil
+}
+
+// 中継が終了した時にcloseされるチャネル
+func (b *bridge) Done() <-chan struct{} {
+	return b.done
+}
+
+// 相手のCloseから再びcloseが呼ばれるため、Closeはonceの外で呼び出す
+func (b *bridge) close(other protocol.Transport) {
+	first := false
+	b.closeOnce.Do(func() {
+		first = true
+		close(b.done)
+	})
+	if first {
+		_ = other.Close()
+	}
+}
+
+// fromから受信したメッセージをtoに転送する関数を返す
+// HTTPの場合はリクエストの送信が応答まで戻らないことがあるため、リクエストは並行して転送し、
+// 後続のキャンセルの通知などを待たせないようにする
+// initializeはセッションIDが割り当てられるまで後続のメッセージを送れないため、順に転送する
+func (b *bridge) forwarder(to, from protocol.Transport, direction string) func(schema.JsonRpcMessage) {
+	return func(message schema.JsonRpcMessage) {
+		if b.logger.Enabled(context.Background(), slog.LevelDebug) {
+			if data, err := jsonrpc.Marshal(message); err == nil {
+				b.logger.Debug("forwarding message", "direction", direction, "message", string(data))
+			}
+		}
+		request, isRequest := message.(schema.JsonRpcRequest)
+		if !isRequest || request.Method() == "initialize" {
+			if err := to.SendMessage(message); err != nil {
+				b.logger.Error("failed to forward message", "direction", direction, "error", err)
+			}
+			return
+		}
+		go func() {
+			if err := to.SendMessage(message); err != nil {
+				b.logger.Error("failed to forward request", "direction", direction, "method", request.Method(), "error", err)
+				// 転送できなかったリクエストには、送信元にエラーを返す
+				if err := from.SendMessage(schema.JsonRpcError{
+					BaseMessage: schema.BaseMessage{Jsonrpc: schema.JSON_RPC_VERSION, Id: request.Id},
+					Error: schema.Error{
+						Code:    mcperr.INTERNAL_ERROR,
+						Message: fmt.Sprintf("failed to forward request: %v", err),
+					},
+				}); err != nil {
+					b.logger.Error("failed to reply forwarding error", "direction", direction, "error", err)
+				}
+			}
+		}()
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kakkky/mcp-sdk-go/client"
+	clienttransport "github.com/kakkky/mcp-sdk-go/client/transport"
+	mcpserver "github.com/kakkky/mcp-sdk-go/mcp-server"
+	"github.com/kakkky/mcp-sdk-go/mcp-server/server"
+	"github.com/kakkky/mcp-sdk-go/shared/protocol"
+	"github.com/kakkky/mcp-sdk-go/shared/schema"
+)
+
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func receive(t *testing.T, ch <-chan schema.JsonRpcMessage) schema.JsonRpcMessage {
+	t.Helper()
+	select {
+	case message := <-ch:
+		return message
+	case <-time.After(5 * time.Second):
+		t.Fatal("message was not forwarded")
+		return nil
+	}
+}
+
+func TestServeHandler_WithMcpServer(t *testing.T) {
+	// stdioのサーバーの代わりに、McpServerにつながったトランスポートを中継先とする
+	handler := newServeHandler(func() protocol.Transport {
+		mcpServer := mcpserver.NewMcpServer(schema.Implementation{Name: "bridged-server", Version: "1.0.0"}, &server.ServerOptions{
+			Capabilities: schema.ServerCapabilities{
+				Tools: &schema.Tools{ListChanged: true},
+			},
+		})
+		if _, err := mcpServer.Tool("echo", "echo tool", schema.PropertySchema{}, nil,
+			func(args map[string]any) (schema.CallToolResultSchema, error) {
+				return schema.CallToolResultSchema{
+					Content: []schema.ToolContentSchema{&schema.TextContentSchema{Type: "text", Text: "echo"}},
+				}, nil
+			},
+		); err != nil {
+			t.Errorf("failed to register tool: %v", err)
+		}
+		upstream, serverTransport := clienttransport.NewInMemoryTransportPair(nil)
+		if err := mcpServer.Connect(serverTransport); err != nil {
+			t.Errorf("failed to connect server: %v", err)
+		}
+		return upstream
+	}, nil, discardLogger)
+	httpServer := httptest.NewServer(handler)
+	defer httpServer.Close()
+	defer handler.Close()
+
+	c := client.NewClient(schema.Implementation{Name: "http-client", Version: "1.0.0"}, nil)
+	if err := c.Connect(clienttransport.NewStreamableHTTPClientTransport(httpServer.URL, nil)); err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	<-client.OperationPhaseStartedNotify
+	result, err := c.CallTool(schema.CallToolRequestParams{Name: "echo"})
+	if err != nil {
+		t.Fatalf("CallTool() error = %v", err)
+	}
+	want := &schema.CallToolResultSchema{
+		Content: []schema.ToolContentSchema{&schema.TextContentSchema{Type: "text", Text: "echo"}},
+	}
+	if diff := cmp.Diff(want, result); diff != "" {
+		t.Errorf("CallTool() mismatch (-want +got):\n%s", diff)
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+}
+
+func TestServeHandler_ForwardsMessagesInBothDirections(t *testing.T) {
+	// 中継先のサーバーとして振る舞うトランスポート
+	fromClient := make(chan schema.JsonRpcMessage, 16)
+	var serverPeer protocol.Transport
+	handler := newServeHandler(func() protocol.Transport {
+		upstream, peer := clienttransport.NewInMemoryTransportPair(nil)
+		peer.SetOnReceiveMessage(func(message schema.JsonRpcMessage) { fromClient <- message })
+		if err := peer.Start(); err != nil {
+			t.Errorf("failed to start server peer: %v", err)
+		}
+		serverPeer = peer
+		return upstream
+	}, nil, discardLogger)
+	httpServer := httptest.NewServer(handler)
+	defer httpServer.Close()
+	defer handler.Close()
+
+	fromServer := make(chan schema.JsonRpcMessage, 16)
+	sut := clienttransport.NewStreamableHTTPClientTransport(httpServer.URL, nil)
+	sut.SetOnReceiveMessage(func(message schema.JsonRpcMessage) { fromServer <- message })
+	if err := sut.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	<-client.TransportStartedNotify
+
+	clientSend := func(message schema.JsonRpcMessage) {
+		t.Helper()
+		if err := sut.SendMessage(message); err != nil {
+			t.Fatalf("client SendMessage() error = %v", err)
+		}
+	}
+	serverSend := func(message schema.JsonRpcMessage) {
+		t.Helper()
+		if err := serverPeer.SendMessage(message); err != nil {
+			t.Fatalf("server SendMessage() error = %v", err)
+		}
+	}
+	assertReceived := func(ch <-chan schema.JsonRpcMessage, want schema.JsonRpcMessage) {
+		t.Helper()
+		if diff := cmp.Diff(want, receive(t, ch)); diff != "" {
+			t.Errorf("forwarded message mismatch (-want +got):\n%s", diff)
+		}
+	}
+
+	// 初期化
+	initialize := schema.JsonRpcRequest{
+		BaseMessage: schema.BaseMessage{Jsonrpc: schema.JSON_RPC_VERSION, Id: 1},
+		Request: &schema.InitializeRequestSchema{
+			MethodName: "initialize",
+			ParamsData: schema.InitializeRequestParams{
+				ProtocolVersion: schema.LATEST_PROTOCOL_VERSION,
+				ClientInfo:      schema.Implementation{Name: "raw-client", Version: "1.0.0"},
+			},
+		},
+	}
+	clientSend(initialize)
+	assertReceived(fromClient, initialize)
+	initializeResult := schema.JsonRpcResponse{
+		BaseMessage: schema.BaseMessage{Jsonrpc: schema.JSON_RPC_VERSION, Id: 1},
+		Result: &schema.InitializeResultSchema{
+			ProtocolVersion: schema.LATEST_PROTOCOL_VERSION,
+			ServerInfo:      schema.Implementation{Name: "raw-server", Version: "1.0.0"},
+		},
+	}
+	serverSend(initializeResult)
+	assertReceived(fromServer, initializeResult)
+	initialized := schema.JsonRpcNotification{
+		Jsonrpc:      schema.JSON_RPC_VERSION,
+		Notification: &schema.InitializeNotificationSchema{MethodName: "notifications/initialized"},
+	}
+	clientSend(initialized)
+	assertReceived(fromClient, initialized)
+
+	// 進捗の通知とサーバーからのリクエストが、処理中のツール呼び出しと並行してクライアントに届く
+	callTool := schema.JsonRpcRequest{
+		BaseMessage: schema.BaseMessage{Jsonrpc: schema.JSON_RPC_VERSION, Id: 2},
+		Request: &schema.CallToolRequestSchema{
+			MethodName: "tools/call",
+			ParamsData: schema.CallToolRequestParams{Name: "slow", Meta: &schema.RequestMetaSchema{ProgressToken: "token-1"}},
+		},
+	}
+	clientSend(callTool)
+	assertReceived(fromClient, callTool)
+	progress := schema.JsonRpcNotification{
+		Jsonrpc: schema.JSON_RPC_VERSION,
+		Notification: &schema.ProgressNotificationSchema{
+			MethodName: "notifications/progress",
+			ParamsData: schema.ProgressNotificationParams{ProgressToken: "token-1", Progress: 1, Total: 2},
+		},
+	}
+	serverSend(progress)
+	assertReceived(fromServer, progress)
+	sampling := schema.JsonRpcRequest{
+		BaseMessage: schema.BaseMessage{Jsonrpc: schema.JSON_RPC_VERSION, Id: 100},
+		Request: &schema.CreateMessageRequestSchema[schema.TextContentSchema]{
+			MethodName: "sampling/createMessage",
+			ParamsData: schema.CreateMessageRequestParams[schema.TextContentSchema]{
+				Messages: []schema.SamplingMessageSchema[schema.TextContentSchema]{
+					{Role: "user", Content: schema.TextContentSchema{Type: "text", Text: "hello"}},
+				},
+				MaxTokens: 10,
+			},
+		},
+	}
+	serverSend(sampling)
+	assertReceived(fromServer, sampling)
+	samplingResult := schema.JsonRpcResponse{
+		BaseMessage: schema.BaseMessage{Jsonrpc: schema.JSON_RPC_VERSION, Id: 100},
+		Result: &schema.CreateMessageResultSchema[schema.TextContentSchema]{
+			Model:   "test-model",
+			Role:    "assistant",
+			Content: schema.TextContentSchema{Type: "text", Text: "hi"},
+		},
+	}
+	clientSend(samplingResult)
+	assertReceived(fromClient, samplingResult)
+
+	// キャンセルの通知がサーバーに届き、サーバーのエラー応答がクライアントに届く
+	cancelled := schema.JsonRpcNotification{
+		Jsonrpc: schema.JSON_RPC_VERSION,
+		Notification: &schema.CancelledNotificationSchema{
+			MethodName: "notifications/cancelled",
+			ParamsData: schema.CancelledNotificationParams{RequestId: 2, Reason: "user requested"},
+		},
+	}
+	clientSend(cancelled)
+	assertReceived(fromClient, cancelled)
+	cancelledResponse := schema.JsonRpcError{
+		BaseMessage: schema.BaseMessage{Jsonrpc: schema.JSON_RPC_VERSION, Id: 2},
+		Error:       schema.Error{Code: -32800, Message: "request cancelled"},
+	}
+	serverSend(cancelledResponse)
+	assertReceived(fromServer, cancelledResponse)
+
+	// クライアントがセッションを終了すると、中継先も閉じられる
+	serverClosed := make(chan struct{})
+	serverPeer.SetOnClose(func() { close(serverClosed) })
+	if err := sut.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	select {
+	case <-serverClosed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("upstream was not closed")
+	}
+}
+
+func TestListenServe(t *testing.T) {
+	tests := []struct {
+		name         string
+		address      string
+		allowedHosts []string
+		wantErr      bool
+	}{
+		{
+			name:    "normal : omitted host listens on loopback",
+			address: ":0",
+		},
+		{
+			name:    "normal : loopback address",
+			address: "127.0.0.1:0",
+		},
+		{
+			name:         "normal : non-loopback address with allowed host",
+			address:      "0.0.0.0:0",
+			allowedHosts: []string{"example.com"},
+		},
+		{
+			name:    "semi normal : non-loopback address without allowed host",
+			address: "0.0.0.0:0",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listener, err := listenServe(tt.address, tt.allowedHosts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("listenServe() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			defer func() {
+				_ = listener.Close()
+			}()
+			if tt.address == ":0" && !strings.HasPrefix(listener.Addr().String(), "127.0.0.1:") {
+				t.Errorf("listener address = %s, want loopback", listener.Addr())
+			}
+		})
+	}
+}
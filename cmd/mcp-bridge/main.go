@@ -0,0 +1,226 @@
+// mcp-bridgeは、stdioとStreamable HTTPの間でMCPのセッションを中継する
+//
+// stdioのMCPサーバーをHTTPで公開する場合:
+//
+//	mcp-bridge serve -addr 127.0.0.1:8080 -path /mcp -- command [args...]
+//
+// stdioのみに対応したクライアントから、リモートのHTTPサーバーに接続する場合:
+//
+//	mcp-bridge connect -url https://example.com/mcp -header "Authorization: Bearer xxx"
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/kakkky/mcp-sdk-go/client"
+	clienttransport "github.com/kakkky/mcp-sdk-go/client/transport"
+	servertransport "github.com/kakkky/mcp-sdk-go/mcp-server/transport"
+	"github.com/kakkky/mcp-sdk-go/shared/protocol"
+)
+
+const usage = `usage:
+  mcp-bridge serve [flags] -- command [args...]
+  mcp-bridge connect [flags]
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	// 標準出力はMCPのメッセージに使用するため、ログは全て標準エラー出力に書き出す
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var err error
+	switch os.Args[1] {
+	case "serve":
+		err = runServe(ctx, os.Args[2:], logger)
+	case "connect":
+		err = runConnect(ctx, os.Args[2:], logger)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		logger.Error("mcp-bridge failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+// stdioのMCPサーバーをStreamable HTTPで公開する
+// HTTPのセッションごとにサーバーのプロセスを1つ起動し、1対1で中継する
+func runServe(ctx context.Context, args []string, logger *slog.Logger) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := flags.String("addr", "127.0.0.1:8080", "address to listen on")
+	path := flags.String("path", "/mcp", "path of the MCP endpoint")
+	jsonResponse := flags.Bool("json", false, "respond with application/json instead of SSE")
+	verbose := flags.Bool("v", false, "log every forwarded message")
+	var allowedHosts, allowedOrigins stringsFlag
+	flags.Var(&allowedHosts, "allowed-host", "Host header to accept (repeatable, loopback only by default, required for a non-loopback -addr)")
+	flags.Var(&allowedOrigins, "allowed-origin", `Origin header to accept ("https://example.com" or "*", repeatable)`)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	command := flags.Args()
+	if len(command) == 0 {
+		return errors.New("command of the stdio server is required after --")
+	}
+	logger = verboseLogger(logger, *verbose)
+
+	newUpstream := func() protocol.Transport {
+		return clienttransport.NewStdioClientTransport(clienttransport.StdioServerParameters{
+			Command: command[0],
+			Args:    command[1:],
+			Stderr:  clienttransport.INHERIT,
+		})
+	}
+	options := &servertransport.StreamableHTTPServerOptions{
+		JSONResponse: *jsonResponse,
+		HttpSecurityOptions: servertransport.HttpSecurityOptions{
+			AllowedHosts:   allowedHosts,
+			AllowedOrigins: allowedOrigins,
+		},
+	}
+	listener, err := listenServe(*addr, allowedHosts)
+	if err != nil {
+		return err
+	}
+	handler := newServeHandler(newUpstream, options, logger)
+	mux := http.NewServeMux()
+	mux.Handle(*path, handler)
+	httpServer := &http.Server{Handler: mux}
+
+	go func() {
+		<-ctx.Done()
+		_ = handler.Close()
+		_ = httpServer.Close()
+	}()
+	logger.Info("serving stdio server over streamable http", "addr", listener.Addr().String(), "path", *path, "command", strings.Join(command, " "))
+	if err := httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// servertransport.NewStreamableHTTPServerと同様に、ホストを省略した場合はループバックアドレスで待ち受け、
+// ループバック以外のアドレスで待ち受ける場合は-allowed-hostの指定を必須とする
+func listenServe(address string, allowedHosts []string) (net.Listener, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %q: %w", address, err)
+	}
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	if ip := net.ParseIP(host); !strings.EqualFold(host, "localhost") && (ip == nil || !ip.IsLoopback()) && len(allowedHosts) == 0 {
+		return nil, fmt.Errorf("listening on non-loopback address %q requires -allowed-host", host)
+	}
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", address, err)
+	}
+	return listener, nil
+}
+
+// HTTPのセッションごとにnewUpstreamで生成したトランスポートを開始し、中継するhttp.Handlerを返す
+func newServeHandler(newUpstream func() protocol.Transport, options *servertransport.StreamableHTTPServerOptions, logger *slog.Logger) *servertransport.StreamableHTTPHandler {
+	return servertransport.NewStreamableHTTPHandler(newSessionHandler(newUpstream, logger), options)
+}
+
+// HTTPのセッションが開始されるたびに、newUpstreamで生成したトランスポートとの中継を開始する関数を返す
+func newSessionHandler(newUpstream func() protocol.Transport, logger *slog.Logger) func(protocol.Transport) {
+	return func(session protocol.Transport) {
+		upstream := newUpstream()
+		b := newBridge(session, upstream, logger)
+		if err := b.start(); err != nil {
+			logger.Error("failed to start session", "error", err)
+			return
+		}
+		// クライアント側のトランスポートは、開始したことをClient.Connectに通知するため、それを受け取る
+		<-client.TransportStartedNotify
+		logger.Info("session started")
+		go func() {
+			<-b.Done()
+			logger.Info("session closed")
+		}()
+	}
+}
+
+// 標準入出力で受け付けたセッションを、リモートのStreamable HTTPサーバーに中継する
+func runConnect(ctx context.Context, args []string, logger *slog.Logger) error {
+	flags := flag.NewFlagSet("connect", flag.ContinueOnError)
+	url := flags.String("url", "", "URL of the remote MCP endpoint")
+	var headers headerFlags
+	flags.Var(&headers, "header", `HTTP header to send with every request ("Name: value", repeatable)`)
+	verbose := flags.Bool("v", false, "log every forwarded message")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *url == "" {
+		return errors.New("-url is required")
+	}
+	logger = verboseLogger(logger, *verbose)
+
+	upstream := clienttransport.NewStreamableHTTPClientTransport(*url, &clienttransport.StreamableHTTPClientOptions{
+		Header: http.Header(headers),
+	})
+	downstream := servertransport.NewStdioServerTransportWithIO(ctx, os.Stdin, os.Stdout)
+	b := newBridge(downstream, upstream, logger)
+	if err := b.start(); err != nil {
+		return err
+	}
+	<-client.TransportStartedNotify
+	<-b.Done()
+	return nil
+}
+
+// 転送したメッセージのログ（Debugレベル）は、-vを指定した場合のみ出力する
+func verboseLogger(logger *slog.Logger, verbose bool) *slog.Logger {
+	if !verbose {
+		return logger
+	}
+	return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+}
+
+// 複数回指定できる文字列のフラグ
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringsFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// "Name: value"形式で複数回指定できるフラグ
+type headerFlags http.Header
+
+func (h *headerFlags) String() string {
+	return fmt.Sprint(http.Header(*h))
+}
+
+func (h *headerFlags) Set(value string) error {
+	name, v, ok := strings.Cut(value, ":")
+	if !ok {
+		return fmt.Errorf("header must be in \"Name: value\" format: %q", value)
+	}
+	if *h == nil {
+		*h = headerFlags{}
+	}
+	http.Header(*h).Add(strings.TrimSpace(name), strings.TrimSpace(v))
+	return nil
+}
@@ -1,8 +1,6 @@
 package main
 
 import (
-	"sync"
-
 	mcpserver "github.com/kakkky/mcp-sdk-go/mcp-server"
 	"github.com/kakkky/mcp-sdk-go/mcp-server/server"
 	"github.com/kakkky/mcp-sdk-go/mcp-server/transport"
@@ -21,15 +19,9 @@ func main() {
 			},
 		})
 	transport := transport.NewStdioServerTransport()
-	wg := sync.WaitGroup{}
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		err := mcpServer.Connect(transport)
-		if err != nil {
-			panic(err)
-		}
-	}()
+	if err := mcpServer.Connect(transport); err != nil {
+		panic(err)
+	}
 	<-server.OperationPhaseStartedNotify
 	if err := mcpServer.Server.SendLoggingMessage(
 		schema.LoggingMessageNotificationParams{
@@ -41,5 +33,5 @@ func main() {
 	}
 	_, _ = mcpServer.Server.Ping()
 	_, _ = mcpServer.Server.ListRoots()
-	wg.Wait()
+	<-transport.Done()
 }
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/url"
+	"os"
+	"os/signal"
 
 	mcpserver "github.com/kakkky/mcp-sdk-go/mcp-server"
 	"github.com/kakkky/mcp-sdk-go/mcp-server/server"
@@ -190,8 +193,13 @@ func main() {
 		panic(err)
 	}
 
-	transport := transport.NewStdioServerTransport()
+	// Ctrl+Cでトランスポートを閉じる
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	transport := transport.NewStdioServerTransportWithIO(ctx, os.Stdin, os.Stdout)
 	if err := mcpServer.Connect(transport); err != nil {
 		panic(err)
 	}
+	// クライアントが切断する（標準入力がEOFになる）まで待機する
+	<-transport.Done()
 }
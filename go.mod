@@ -5,6 +5,7 @@ go 1.24.4
 require (
 	dario.cat/mergo v1.0.2
 	github.com/google/go-cmp v0.7.0
+	github.com/gorilla/websocket v1.5.3
 	github.com/jtacoma/uritemplates v1.0.0
 	github.com/stretchr/testify v1.10.0
 	go.uber.org/mock v0.5.2
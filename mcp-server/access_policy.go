@@ -0,0 +1,37 @@
+package mcpserver
+
+import (
+	"fmt"
+
+	"github.com/kakkky/mcp-sdk-go/shared/auth"
+	mcperr "github.com/kakkky/mcp-sdk-go/shared/mcp-err"
+)
+
+// 登録したツール、リソース、プロンプトを利用できる呼び出し元を制限する
+// 許可されない呼び出し元には一覧で返さず、呼び出した場合はPERMISSION_DENIEDのエラーとなる
+type AccessPolicy struct {
+	// 呼び出し元の認証情報に必要なスコープ
+	RequiredScopes []string
+	// 指定した場合、RequiredScopesを満たした上で、trueを返した呼び出し元のみ許可する
+	// トランスポートで認証を行っていない場合、authInfoはnilとなる
+	Allow func(authInfo *auth.AuthInfo) bool
+}
+
+// ポリシーが指定されていない場合は、全ての呼び出し元を許可する
+func (p *AccessPolicy) allows(authInfo *auth.AuthInfo) bool {
+	if p == nil {
+		return true
+	}
+	if !authInfo.HasScopes(p.RequiredScopes...) {
+		return false
+	}
+	return p.Allow == nil || p.Allow(authInfo)
+}
+
+func newPermissionDeniedErr(kind string, name string, policy *AccessPolicy) error {
+	var data any
+	if len(policy.RequiredScopes) > 0 {
+		data = map[string]any{"requiredScopes": policy.RequiredScopes}
+	}
+	return mcperr.NewMcpErr(mcperr.PERMISSION_DENIED, fmt.Sprintf("permission denied for %s %s", kind, name), data)
+}
@@ -0,0 +1,258 @@
+package mcpserver
+
+import (
+	"net/url"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kakkky/mcp-sdk-go/mcp-server/server"
+	"github.com/kakkky/mcp-sdk-go/shared/auth"
+	mcperr "github.com/kakkky/mcp-sdk-go/shared/mcp-err"
+	"github.com/kakkky/mcp-sdk-go/shared/protocol"
+	"github.com/kakkky/mcp-sdk-go/shared/protocol/mock"
+	"github.com/kakkky/mcp-sdk-go/shared/schema"
+	"go.uber.org/mock/gomock"
+)
+
+// モックのトランスポートでMcpServerに接続し、送受信したメッセージを記録する
+type testConnection struct {
+	t                *testing.T
+	onReceiveMessage func(schema.JsonRpcMessage)
+	mu               sync.Mutex
+	sent             []schema.JsonRpcMessage
+	// リクエストはハンドラーのゴルーチンで処理されるため、応答が送られたことをrequestに伝える
+	responded chan schema.JsonRpcMessage
+}
+
+func newTestConnection(t *testing.T, connect func(transport protocol.Transport) error) *testConnection {
+	t.Helper()
+	c := &testConnection{t: t, responded: make(chan schema.JsonRpcMessage, 1)}
+	ctrl := gomock.NewController(t)
+	mockTransport := mock.NewMockTransport(ctrl)
+	mockTransport.EXPECT().SetOnClose(gomock.Any()).AnyTimes()
+	mockTransport.EXPECT().SetOnError(gomock.Any()).AnyTimes()
+	mockTransport.EXPECT().SetOnReceiveMessage(gomock.Any()).Do(func(f func(schema.JsonRpcMessage)) { c.onReceiveMessage = f })
+	mockTransport.EXPECT().Start().Return(nil)
+	mockTransport.EXPECT().SendMessage(gomock.Any()).DoAndReturn(func(message schema.JsonRpcMessage) error {
+		c.mu.Lock()
+		c.sent = append(c.sent, message)
+		c.mu.Unlock()
+		switch message.(type) {
+		case schema.JsonRpcResponse, schema.JsonRpcError:
+			c.responded <- message
+		}
+		return nil
+	}).AnyTimes()
+	if err := connect(mockTransport); err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	return c
+}
+
+// 認証情報を付けたリクエストを送り、応答を返す
+func (c *testConnection) request(request schema.Request, authInfo *auth.AuthInfo) schema.JsonRpcMessage {
+	c.mu.Lock()
+	c.sent = nil
+	c.mu.Unlock()
+	c.onReceiveMessage(schema.JsonRpcRequest{
+		BaseMessage: schema.BaseMessage{Jsonrpc: schema.JSON_RPC_VERSION, Id: 1},
+		Request:     request,
+		AuthInfo:    authInfo,
+	})
+	select {
+	case response := <-c.responded:
+		return response
+	case <-time.After(time.Second):
+		c.t.Errorf("no response to %s", request.Method())
+		return nil
+	}
+}
+
+// 前回の呼び出し以降に送られた通知のメソッド
+func (c *testConnection) notifications() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var methods []string
+	for _, message := range c.sent {
+		if notification, ok := message.(schema.JsonRpcNotification); ok {
+			methods = append(methods, notification.Method())
+		}
+	}
+	c.sent = nil
+	return methods
+}
+
+func TestMcpServer_AccessPolicy(t *testing.T) {
+	sut := NewMcpServer(schema.Implementation{}, &server.ServerOptions{})
+	send := newTestConnection(t, sut.Connect).request
+	toolResult := schema.CallToolResultSchema{
+		Content: []schema.ToolContentSchema{&schema.TextContentSchema{Type: "text", Text: "ok"}},
+	}
+	for _, name := range []string{"public", "admin-only", "tenant-a"} {
+		if _, err := sut.Tool(name, name, schema.PropertySchema{}, nil, func(args map[string]any) (schema.CallToolResultSchema, error) {
+			return toolResult, nil
+		}); err != nil {
+			t.Fatalf("failed to register tool: %v", err)
+		}
+	}
+	sut.registerdTools["admin-only"].Update(ToolUpdates{Access: &AccessPolicy{RequiredScopes: []string{"admin"}}})
+	sut.registerdTools["tenant-a"].Update(ToolUpdates{Access: &AccessPolicy{
+		Allow: func(authInfo *auth.AuthInfo) bool { return authInfo != nil && authInfo.Claims["tenant"] == "a" },
+	}})
+	resource, err := sut.Resource("secret", "file:///secret.txt", nil, func(url url.URL) (schema.ReadResourceResultSchema, error) {
+		return schema.ReadResourceResultSchema{}, nil
+	})
+	if err != nil {
+		t.Fatalf("failed to register resource: %v", err)
+	}
+	resource.Update(ResourceUpdates{Access: &AccessPolicy{RequiredScopes: []string{"admin"}}})
+	prompt, err := sut.Prompt("secret", "secret prompt", []schema.PromptAugmentSchema{}, func(args []schema.PromptAugmentSchema) (schema.GetPromptResultSchema, error) {
+		return schema.GetPromptResultSchema{}, nil
+	})
+	if err != nil {
+		t.Fatalf("failed to register prompt: %v", err)
+	}
+	prompt.Update(PromptUpdates{Access: &AccessPolicy{RequiredScopes: []string{"admin"}}})
+
+	admin := &auth.AuthInfo{Subject: "alice", Scopes: []string{"admin"}, Claims: map[string]any{"tenant": "a"}}
+	user := &auth.AuthInfo{Subject: "bob", Claims: map[string]any{"tenant": "b"}}
+
+	tests := []struct {
+		name              string
+		authInfo          *auth.AuthInfo
+		expectedTools     []string
+		expectedResources int
+		expectedPrompts   int
+	}{
+		{
+			name:              "normal : caller with admin scope sees everything",
+			authInfo:          admin,
+			expectedTools:     []string{"admin-only", "public", "tenant-a"},
+			expectedResources: 1,
+			expectedPrompts:   1,
+		},
+		{
+			name:          "normal : caller without scope sees only allowed registrations",
+			authInfo:      user,
+			expectedTools: []string{"public"},
+		},
+		{
+			name:          "normal : unauthenticated caller sees only public registrations",
+			expectedTools: []string{"public"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := send(&schema.ListToolsRequestSchema{MethodName: "tools/list"}, tt.authInfo)
+			var gotTools []string
+			for _, tool := range response.(schema.JsonRpcResponse).Result.(*schema.ListToolsResultSchema).Tools {
+				gotTools = append(gotTools, tool.Name)
+			}
+			sort.Strings(gotTools)
+			if diff := cmp.Diff(tt.expectedTools, gotTools); diff != "" {
+				t.Errorf("tools/list mismatch (-want +got):\n%s", diff)
+			}
+			response = send(&schema.ListResourceRequestSchema{MethodName: "resources/list"}, tt.authInfo)
+			if got := len(response.(schema.JsonRpcResponse).Result.(*schema.ListResourcesResultSchema).Resources); got != tt.expectedResources {
+				t.Errorf("resources/list returned %d resources, want %d", got, tt.expectedResources)
+			}
+			response = send(&schema.ListPromptsRequestSchema{MethodName: "prompts/list"}, tt.authInfo)
+			if got := len(response.(schema.JsonRpcResponse).Result.(*schema.ListPromptsResultSchema).Prompts); got != tt.expectedPrompts {
+				t.Errorf("prompts/list returned %d prompts, want %d", got, tt.expectedPrompts)
+			}
+		})
+	}
+
+	t.Run("semi normal : calling a registration without permission is rejected", func(t *testing.T) {
+		requests := []schema.Request{
+			&schema.CallToolRequestSchema{MethodName: "tools/call", ParamsData: schema.CallToolRequestParams{Name: "admin-only"}},
+			&schema.CallToolRequestSchema{MethodName: "tools/call", ParamsData: schema.CallToolRequestParams{Name: "tenant-a"}},
+			&schema.ReadResourceRequestSchema{MethodName: "resources/read", ParamsData: schema.ReadResourceRequestParams{Uri: "file:///secret.txt"}},
+			&schema.GetPromptRequestSchema{MethodName: "prompts/get", ParamsData: schema.GetPromptRequestParams{Name: "secret"}},
+		}
+		for _, request := range requests {
+			response, ok := send(request, user).(schema.JsonRpcError)
+			if !ok {
+				t.Errorf("%s: expected an error response", request.Method())
+				continue
+			}
+			if response.Error.Code != mcperr.PERMISSION_DENIED {
+				t.Errorf("%s: error code = %d, want %d", request.Method(), response.Error.Code, mcperr.PERMISSION_DENIED)
+			}
+			// 許可された呼び出し元は呼び出せる
+			if _, ok := send(request, admin).(schema.JsonRpcResponse); !ok {
+				t.Errorf("%s: expected a successful response for an allowed caller", request.Method())
+			}
+		}
+	})
+}
+
+func TestMcpServer_AccessPolicyListChanged(t *testing.T) {
+	sut := NewMcpServer(schema.Implementation{}, &server.ServerOptions{
+		Capabilities: schema.ServerCapabilities{Tools: &schema.Tools{ListChanged: true}},
+	})
+	tool, err := sut.Tool("admin-only", "admin tool", schema.PropertySchema{}, nil, func(args map[string]any) (schema.CallToolResultSchema, error) {
+		return schema.CallToolResultSchema{}, nil
+	})
+	if err != nil {
+		t.Fatalf("failed to register tool: %v", err)
+	}
+	adminPolicy := &AccessPolicy{RequiredScopes: []string{"admin"}}
+	tool.Update(ToolUpdates{Access: adminPolicy})
+
+	connect := func(authInfo *auth.AuthInfo) *testConnection {
+		conn := newTestConnection(t, func(transport protocol.Transport) error {
+			_, err := sut.ConnectSession(transport)
+			return err
+		})
+		conn.request(&schema.InitializeRequestSchema{MethodName: "initialize"}, authInfo)
+		// セッションの認証情報は、一覧の取得などのリクエストで記録される
+		conn.request(&schema.ListToolsRequestSchema{MethodName: "tools/list"}, authInfo)
+		conn.notifications()
+		return conn
+	}
+	connections := map[string]*testConnection{
+		"admin": connect(&auth.AuthInfo{Subject: "alice", Scopes: []string{"admin"}}),
+		"user":  connect(&auth.AuthInfo{Subject: "bob"}),
+	}
+
+	tests := []struct {
+		name     string
+		updates  ToolUpdates
+		notified []string
+	}{
+		{
+			name:     "normal : change to a restricted tool is notified only to allowed sessions",
+			updates:  ToolUpdates{Description: "updated admin tool"},
+			notified: []string{"admin"},
+		},
+		{
+			name:     "normal : opening the tool is notified to sessions allowed after the change",
+			updates:  ToolUpdates{Access: &AccessPolicy{}},
+			notified: []string{"admin", "user"},
+		},
+		{
+			name:     "normal : restricting the tool is notified to sessions allowed before the change",
+			updates:  ToolUpdates{Access: adminPolicy},
+			notified: []string{"admin", "user"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tool.Update(tt.updates)
+			var notified []string
+			for name, conn := range connections {
+				if len(conn.notifications()) > 0 {
+					notified = append(notified, name)
+				}
+			}
+			sort.Strings(notified)
+			if diff := cmp.Diff(tt.notified, notified); diff != "" {
+				t.Errorf("notified sessions mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
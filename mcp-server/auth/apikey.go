@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/kakkky/mcp-sdk-go/shared/auth"
+)
+
+// レート制限を超えたリクエストに対して返すエラー
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// レート制限を超えた場合に返すエラー。次のリクエストを受け付けるまでの時間を持つ
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%v: retry after %v", ErrRateLimited, e.RetryAfter)
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// Perの間にRequests回までリクエストを受け付ける
+type RateLimit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// 1つのAPIキーと、その持ち主（プリンシパル）
+type APIKey struct {
+	// キーの値
+	Key string
+	// キーの持ち主の名前。AuthInfo.Subjectに設定される
+	Name string
+	// キーに付与するスコープ
+	Scopes []string
+	// キーの有効期間。ゼロ値の場合は制限なし
+	// キーをローテーションする場合は、新旧のキーの有効期間を重ねて登録する
+	NotBefore time.Time
+	ExpiresAt time.Time
+	// キーごとのレート制限。nilの場合は制限なし
+	RateLimit *RateLimit
+}
+
+// 有効なAPIキーを返す
+// 毎回呼び出されるため、キーのローテーションや失効は実装側で反映できる
+type APIKeyStore interface {
+	APIKeys(ctx context.Context) ([]APIKey, error)
+}
+
+// 固定のAPIキーを持つAPIKeyStore
+type StaticAPIKeyStore []APIKey
+
+func (s StaticAPIKeyStore) APIKeys(ctx context.Context) ([]APIKey, error) {
+	return s, nil
+}
+
+// 実行中にAPIキーを入れ替えられるAPIKeyStore
+type MemoryAPIKeyStore struct {
+	mu   sync.RWMutex
+	keys []APIKey
+}
+
+func NewMemoryAPIKeyStore(keys ...APIKey) *MemoryAPIKeyStore {
+	return &MemoryAPIKeyStore{keys: keys}
+}
+
+func (m *MemoryAPIKeyStore) APIKeys(ctx context.Context) ([]APIKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.keys, nil
+}
+
+// 全てのAPIキーを入れ替える
+func (m *MemoryAPIKeyStore) SetAPIKeys(keys ...APIKey) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keys = keys
+}
+
+type APIKeyVerifierOptions struct {
+	// 現在時刻を返す関数。指定しなかった場合はtime.Nowとなる
+	Now func() time.Time
+}
+
+// APIKeyStoreのAPIキーと照合するTokenVerifier
+// ResourceServerOptions.Verifierに指定して、OAuthの代わりに使用する
+type APIKeyVerifier struct {
+	store   APIKeyStore
+	options APIKeyVerifierOptions
+
+	mu      sync.Mutex
+	buckets map[[sha256.Size]byte]*tokenBucket
+}
+
+func NewAPIKeyVerifier(store APIKeyStore, options *APIKeyVerifierOptions) *APIKeyVerifier {
+	opts := APIKeyVerifierOptions{}
+	if options != nil {
+		opts = *options
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &APIKeyVerifier{
+		store:   store,
+		options: opts,
+		buckets: make(map[[sha256.Size]byte]*tokenBucket),
+	}
+}
+
+// キーの長さや一致した位置から推測されないよう、ハッシュ値を全てのキーと定数時間で比較する
+func (v *APIKeyVerifier) VerifyAccessToken(ctx context.Context, token string) (*auth.AuthInfo, error) {
+	keys, err := v.store.APIKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load api keys: %w", err)
+	}
+	hash := sha256.Sum256([]byte(token))
+	var matched *APIKey
+	for i := range keys {
+		keyHash := sha256.Sum256([]byte(keys[i].Key))
+		if subtle.ConstantTimeCompare(hash[:], keyHash[:]) == 1 && matched == nil {
+			matched = &keys[i]
+		}
+	}
+	if matched == nil {
+		return nil, fmt.Errorf("%w: unknown api key", ErrInvalidToken)
+	}
+	now := v.options.Now()
+	if !matched.NotBefore.IsZero() && now.Before(matched.NotBefore) {
+		return nil, fmt.Errorf("%w: api key is not valid yet", ErrInvalidToken)
+	}
+	if !matched.ExpiresAt.IsZero() && !now.Before(matched.ExpiresAt) {
+		return nil, fmt.Errorf("%w: api key is expired", ErrInvalidToken)
+	}
+	if matched.RateLimit != nil {
+		if retryAfter, ok := v.take(hash, *matched.RateLimit, now); !ok {
+			return nil, &RateLimitError{RetryAfter: retryAfter}
+		}
+	}
+	return &auth.AuthInfo{
+		Token:     token,
+		Subject:   matched.Name,
+		Scopes:    matched.Scopes,
+		ExpiresAt: matched.ExpiresAt,
+		Claims:    map[string]any{"sub": matched.Name},
+	}, nil
+}
+
+// 1つの接続で複数のメッセージを受け付けるトランスポート（WebSocket）で、メッセージごとにレート制限を適用する
+// 認証はハンドシェイク時の1回のみのため、トランスポートは受信したリクエストごとにAllowMessageを呼び出す
+type MessageRateLimiter interface {
+	AllowMessage(ctx context.Context, info *auth.AuthInfo) error
+}
+
+// 受信したリクエストごとにキーのレート制限を適用する
+// 接続中にキーが失効、または入れ替えられた場合もエラーを返す
+func (v *APIKeyVerifier) AllowMessage(ctx context.Context, info *auth.AuthInfo) error {
+	_, err := v.VerifyAccessToken(ctx, info.Token)
+	return err
+}
+
+// トークンバケット方式で、キーごとのリクエスト数を制限する
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// リクエストを受け付けられる場合はtrueを返す
+// 受け付けられない場合は、次に受け付けられるまでの時間を返す
+func (v *APIKeyVerifier) take(key [sha256.Size]byte, limit RateLimit, now time.Time) (time.Duration, bool) {
+	if limit.Requests <= 0 || limit.Per <= 0 {
+		return limit.Per, false
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	capacity := float64(limit.Requests)
+	rate := capacity / limit.Per.Seconds()
+	bucket, ok := v.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: capacity, last: now}
+		v.buckets[key] = bucket
+	}
+	bucket.tokens = math.Min(capacity, bucket.tokens+now.Sub(bucket.last).Seconds()*rate)
+	bucket.last = now
+	if bucket.tokens < 1 {
+		return time.Duration((1 - bucket.tokens) / rate * float64(time.Second)), false
+	}
+	bucket.tokens--
+	return 0, true
+}
@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kakkky/mcp-sdk-go/shared/auth"
+)
+
+func TestAPIKeyVerifier_VerifyAccessToken(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := StaticAPIKeyStore{
+		{Key: "current-key", Name: "service-a", Scopes: []string{"mcp"}},
+		// ローテーション中の旧キーと新キー
+		{Key: "old-key", Name: "service-b", ExpiresAt: now},
+		{Key: "new-key", Name: "service-b", NotBefore: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour)},
+		{Key: "future-key", Name: "service-c", NotBefore: now.Add(time.Hour)},
+	}
+	tests := []struct {
+		name          string
+		token         string
+		expected      *auth.AuthInfo
+		isExpectedErr bool
+	}{
+		{
+			name:  "normal : known key",
+			token: "current-key",
+			expected: &auth.AuthInfo{
+				Token:   "current-key",
+				Subject: "service-a",
+				Scopes:  []string{"mcp"},
+				Claims:  map[string]any{"sub": "service-a"},
+			},
+		},
+		{
+			name:  "normal : rotated key within its validity window",
+			token: "new-key",
+			expected: &auth.AuthInfo{
+				Token:     "new-key",
+				Subject:   "service-b",
+				ExpiresAt: now.Add(time.Hour),
+				Claims:    map[string]any{"sub": "service-b"},
+			},
+		},
+		{
+			name:          "semi normal : expired key",
+			token:         "old-key",
+			isExpectedErr: true,
+		},
+		{
+			name:          "semi normal : key not valid yet",
+			token:         "future-key",
+			isExpectedErr: true,
+		},
+		{
+			name:          "semi normal : unknown key",
+			token:         "current-ke",
+			isExpectedErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sut := NewAPIKeyVerifier(store, &APIKeyVerifierOptions{Now: func() time.Time { return now }})
+			got, err := sut.VerifyAccessToken(context.Background(), tt.token)
+			if (err != nil) != tt.isExpectedErr {
+				t.Fatalf("VerifyAccessToken() error = %v, isExpectedErr %v", err, tt.isExpectedErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("VerifyAccessToken() error = %v, want ErrInvalidToken", err)
+			}
+			if diff := cmp.Diff(tt.expected, got); diff != "" {
+				t.Errorf("VerifyAccessToken() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAPIKeyVerifier_RateLimit(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryAPIKeyStore(
+		APIKey{Key: "limited", Name: "service-a", RateLimit: &RateLimit{Requests: 2, Per: time.Minute}},
+		APIKey{Key: "unlimited", Name: "service-b"},
+	)
+	verifier := NewAPIKeyVerifier(store, &APIKeyVerifierOptions{Now: func() time.Time { return now }})
+	sut := RequireBearerToken(ResourceServerOptions{Verifier: verifier, APIKeyHeader: "X-API-Key"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	send := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+		req.Header.Set("X-API-Key", key)
+		rec := httptest.NewRecorder()
+		sut.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := 0; i < 2; i++ {
+		if rec := send("limited"); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want %d", i, rec.Code, http.StatusOK)
+		}
+	}
+	rec := send("limited")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("Retry-After = %q, want %q", got, "30")
+	}
+	// 他のキーのレート制限には影響しない
+	if rec := send("unlimited"); rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	// 時間が経過すると再び受け付ける
+	now = now.Add(30 * time.Second)
+	if rec := send("limited"); rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	// キーを入れ替えると、旧キーは拒否される
+	store.SetAPIKeys(APIKey{Key: "rotated", Name: "service-a"})
+	if rec := send("limited"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if rec := send("rotated"); rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kakkky/mcp-sdk-go/shared/auth"
+)
+
+// イントロスペクションの応答として読み込む最大バイト数
+const maxIntrospectionResponseSize = 1 << 20
+
+type IntrospectionVerifierOptions struct {
+	// イントロスペクションエンドポイントに対するリソースサーバーのクライアント認証情報（Basic認証）
+	ClientId     string
+	ClientSecret string
+	// audクレームに含まれることを検証する。必須
+	// 指定しなかった場合は、他のリソースサーバー向けのトークンを受け付けないように全てのトークンを拒否する
+	Audience string
+	// 指定しなかった場合はhttp.DefaultClientとなる
+	HTTPClient *http.Client
+	// 現在時刻を返す関数。指定しなかった場合はtime.Nowとなる
+	Now func() time.Time
+}
+
+// 認可サーバーのイントロスペクションエンドポイントに問い合わせてアクセストークンを検証するTokenVerifier
+// 参照：(https://datatracker.ietf.org/doc/html/rfc7662)
+type IntrospectionVerifier struct {
+	endpoint string
+	options  IntrospectionVerifierOptions
+}
+
+func NewIntrospectionVerifier(endpoint string, options *IntrospectionVerifierOptions) *IntrospectionVerifier {
+	opts := IntrospectionVerifierOptions{}
+	if options != nil {
+		opts = *options
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &IntrospectionVerifier{endpoint: endpoint, options: opts}
+}
+
+func (v *IntrospectionVerifier) VerifyAccessToken(ctx context.Context, token string) (*auth.AuthInfo, error) {
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create introspection request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if v.options.ClientId != "" {
+		req.SetBasicAuth(url.QueryEscape(v.options.ClientId), url.QueryEscape(v.options.ClientSecret))
+	}
+	resp, err := v.options.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call introspection endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("introspection endpoint returned %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxIntrospectionResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read introspection response: %w", err)
+	}
+	var claims map[string]any
+	if err := json.Unmarshal(body, &claims); err != nil {
+		return nil, fmt.Errorf("failed to parse introspection response: %w", err)
+	}
+	if active, _ := claims["active"].(bool); !active {
+		return nil, fmt.Errorf("%w: token is not active", ErrInvalidToken)
+	}
+	if exp, ok := numericDate(claims["exp"]); ok && !v.options.Now().Before(exp) {
+		return nil, fmt.Errorf("%w: token is expired", ErrInvalidToken)
+	}
+	if v.options.Audience == "" || !slices.Contains(stringsClaim(claims["aud"]), v.options.Audience) {
+		return nil, fmt.Errorf("%w: token is not intended for this resource", ErrInvalidToken)
+	}
+	return authInfoFromClaims(token, claims), nil
+}
@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kakkky/mcp-sdk-go/shared/auth"
+)
+
+// 認可サーバーのイントロスペクションエンドポイントの代わりとなるサーバー
+func newIntrospectionServer(t *testing.T, responses map[string]map[string]any) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientId, clientSecret, ok := r.BasicAuth()
+		if !ok || clientId != "resource-server" || clientSecret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		response, ok := responses[r.PostForm.Get("token")]
+		if !ok {
+			response = map[string]any{"active": false}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+}
+
+func TestIntrospectionVerifier_VerifyAccessToken(t *testing.T) {
+	introspectionServer := newIntrospectionServer(t, map[string]map[string]any{
+		"active-token": {
+			"active":    true,
+			"client_id": "client-1",
+			"sub":       "user-1",
+			"scope":     "tools:call",
+			"aud":       "https://mcp.example.com/mcp",
+			"exp":       testNow.Add(time.Hour).Unix(),
+		},
+		"expired-token": {
+			"active": true,
+			"aud":    "https://mcp.example.com/mcp",
+			"exp":    testNow.Add(-time.Hour).Unix(),
+		},
+		"other-resource-token": {
+			"active": true,
+			"aud":    "https://other.example.com/mcp",
+		},
+	})
+	defer introspectionServer.Close()
+
+	tests := []struct {
+		name             string
+		clientSecret     string
+		audience         string
+		token            string
+		expectedAuthInfo *auth.AuthInfo
+		wantInvalidToken bool
+		wantErr          bool
+	}{
+		{
+			name:         "normal : active token",
+			clientSecret: "secret",
+			audience:     "https://mcp.example.com/mcp",
+			token:        "active-token",
+			expectedAuthInfo: &auth.AuthInfo{
+				ClientId:  "client-1",
+				Subject:   "user-1",
+				Scopes:    []string{"tools:call"},
+				ExpiresAt: testNow.Add(time.Hour),
+			},
+		},
+		{
+			name:             "semi normal : inactive token",
+			clientSecret:     "secret",
+			audience:         "https://mcp.example.com/mcp",
+			token:            "unknown-token",
+			wantInvalidToken: true,
+		},
+		{
+			name:             "semi normal : expired token",
+			clientSecret:     "secret",
+			audience:         "https://mcp.example.com/mcp",
+			token:            "expired-token",
+			wantInvalidToken: true,
+		},
+		{
+			name:             "semi normal : token for another resource",
+			clientSecret:     "secret",
+			audience:         "https://mcp.example.com/mcp",
+			token:            "other-resource-token",
+			wantInvalidToken: true,
+		},
+		{
+			name:             "semi normal : audience is not configured",
+			clientSecret:     "secret",
+			token:            "active-token",
+			wantInvalidToken: true,
+		},
+		{
+			name:         "semi normal : introspection endpoint rejects the resource server",
+			clientSecret: "wrong",
+			audience:     "https://mcp.example.com/mcp",
+			token:        "active-token",
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sut := NewIntrospectionVerifier(introspectionServer.URL, &IntrospectionVerifierOptions{
+				ClientId:     "resource-server",
+				ClientSecret: tt.clientSecret,
+				Audience:     tt.audience,
+				Now:          func() time.Time { return testNow },
+			})
+			got, err := sut.VerifyAccessToken(context.Background(), tt.token)
+			if tt.wantInvalidToken || tt.wantErr {
+				if err == nil {
+					t.Fatal("VerifyAccessToken() error = nil, want error")
+				}
+				// 認可サーバーの障害はトークンが無効であることを意味しない
+				if errors.Is(err, ErrInvalidToken) != tt.wantInvalidToken {
+					t.Errorf("VerifyAccessToken() error = %v, wantInvalidToken %v", err, tt.wantInvalidToken)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyAccessToken() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.expectedAuthInfo, got, cmpAuthInfoOptions); diff != "" {
+				t.Errorf("VerifyAccessToken() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kakkky/mcp-sdk-go/shared/auth"
+)
+
+type JWTVerifierOptions struct {
+	// issクレームが一致することを検証する。必須
+	Issuer string
+	// audクレームに含まれることを検証する。必須
+	// 他のリソースサーバー向けに発行されたトークンを受け付けないように、リソース（MCPエンドポイント）のURLを指定する
+	// 参照：(https://modelcontextprotocol.io/specification/2025-06-18/basic/authorization#token-audience-binding-and-validation)
+	Audience string
+	// exp、nbfの検証で許容する時刻のずれ
+	Leeway time.Duration
+	// 現在時刻を返す関数。指定しなかった場合はtime.Nowとなる
+	Now func() time.Time
+}
+
+// JWT形式のアクセストークンを、ローカルに持つJWKSの公開鍵で検証するTokenVerifier
+// 対応する署名アルゴリズムはRS256/384/512、PS256/384/512、ES256/384/512、EdDSA
+// 参照：(https://datatracker.ietf.org/doc/html/rfc9068)
+type JWTVerifier struct {
+	keys    []publicKey
+	options JWTVerifierOptions
+}
+
+func NewJWTVerifier(jwks []byte, options *JWTVerifierOptions) (*JWTVerifier, error) {
+	keys, err := parseJWKS(jwks)
+	if err != nil {
+		return nil, err
+	}
+	opts := JWTVerifierOptions{}
+	if options != nil {
+		opts = *options
+	}
+	if opts.Issuer == "" {
+		return nil, errors.New("issuer is required to verify jwt")
+	}
+	if opts.Audience == "" {
+		return nil, errors.New("audience is required to verify jwt")
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &JWTVerifier{keys: keys, options: opts}, nil
+}
+
+func (v *JWTVerifier) VerifyAccessToken(ctx context.Context, token string) (*auth.AuthInfo, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed jwt", ErrInvalidToken)
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: invalid header: %v", ErrInvalidToken, err)
+	}
+	signature, err := decodeBase64URL(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid signature encoding", ErrInvalidToken)
+	}
+	if err := v.verifySignature(header.Alg, header.Kid, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: invalid claims: %v", ErrInvalidToken, err)
+	}
+	if err := v.validateClaims(claims); err != nil {
+		return nil, err
+	}
+	return authInfoFromClaims(token, claims), nil
+}
+
+// kidが一致する鍵（kidが無い場合はアルゴリズムに合う全ての鍵）で署名を検証する
+func (v *JWTVerifier) verifySignature(alg string, kid string, signingInput string, signature []byte) error {
+	for _, key := range v.keys {
+		if kid != "" && key.kid != kid {
+			continue
+		}
+		if key.alg != "" && key.alg != alg {
+			continue
+		}
+		ok, err := verifyWithKey(alg, key.key, []byte(signingInput), signature)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: signature verification failed", ErrInvalidToken)
+}
+
+// アルゴリズムと鍵の種類が合わない場合は、検証せずにfalseを返す
+// "none"やHS256など、JWKSの公開鍵で検証できないアルゴリズムはエラーとする
+func verifyWithKey(alg string, key crypto.PublicKey, signingInput []byte, signature []byte) (bool, error) {
+	switch alg {
+	case "RS256", "RS384", "RS512", "PS256", "PS384", "PS512":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return false, nil
+		}
+		hashFunc, digest := digestFor(alg[2:], signingInput)
+		if alg[0] == 'P' {
+			return rsa.VerifyPSS(rsaKey, hashFunc, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}) == nil, nil
+		}
+		return rsa.VerifyPKCS1v15(rsaKey, hashFunc, digest, signature) == nil, nil
+	case "ES256", "ES384", "ES512":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return false, nil
+		}
+		// 署名はr、sを鍵の長さで連結したもの
+		size := (ecKey.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return false, nil
+		}
+		_, digest := digestFor(alg[2:], signingInput)
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		return ecdsa.Verify(ecKey, digest, r, s), nil
+	case "EdDSA":
+		edKey, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return false, nil
+		}
+		return ed25519.Verify(edKey, signingInput, signature), nil
+	default:
+		return false, fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, alg)
+	}
+}
+
+func digestFor(bits string, data []byte) (crypto.Hash, []byte) {
+	var h hash.Hash
+	var hashFunc crypto.Hash
+	switch bits {
+	case "384":
+		h, hashFunc = sha512.New384(), crypto.SHA384
+	case "512":
+		h, hashFunc = sha512.New(), crypto.SHA512
+	default:
+		h, hashFunc = sha256.New(), crypto.SHA256
+	}
+	h.Write(data)
+	return hashFunc, h.Sum(nil)
+}
+
+func (v *JWTVerifier) validateClaims(claims map[string]any) error {
+	now := v.options.Now()
+	exp, hasExp := numericDate(claims["exp"])
+	if !hasExp {
+		return fmt.Errorf("%w: exp claim is required", ErrInvalidToken)
+	}
+	if !now.Before(exp.Add(v.options.Leeway)) {
+		return fmt.Errorf("%w: token is expired", ErrInvalidToken)
+	}
+	if nbf, ok := numericDate(claims["nbf"]); ok && now.Add(v.options.Leeway).Before(nbf) {
+		return fmt.Errorf("%w: token is not valid yet", ErrInvalidToken)
+	}
+	if claims["iss"] != v.options.Issuer {
+		return fmt.Errorf("%w: unexpected issuer", ErrInvalidToken)
+	}
+	if !slices.Contains(stringsClaim(claims["aud"]), v.options.Audience) {
+		return fmt.Errorf("%w: token is not intended for this resource", ErrInvalidToken)
+	}
+	return nil
+}
+
+// JWT、イントロスペクションの応答に共通するクレームから認証情報を生成する
+func authInfoFromClaims(token string, claims map[string]any) *auth.AuthInfo {
+	info := &auth.AuthInfo{
+		Token:  token,
+		Claims: claims,
+	}
+	info.Subject, _ = claims["sub"].(string)
+	info.ClientId, _ = claims["client_id"].(string)
+	if info.ClientId == "" {
+		info.ClientId, _ = claims["azp"].(string)
+	}
+	// scopeはスペース区切りの文字列、scpは配列で表される
+	if scope, ok := claims["scope"].(string); ok {
+		info.Scopes = strings.Fields(scope)
+	} else {
+		info.Scopes = stringsClaim(claims["scp"])
+	}
+	if exp, ok := numericDate(claims["exp"]); ok {
+		info.ExpiresAt = exp
+	}
+	return info
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := decodeBase64URL(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// NumericDate（1970年からの秒数）をtime.Timeに変換する
+func numericDate(v any) (time.Time, bool) {
+	switch n := v.(type) {
+	case float64:
+		return time.Unix(int64(n), 0), true
+	case json.Number:
+		seconds, err := n.Int64()
+		return time.Unix(seconds, 0), err == nil
+	default:
+		return time.Time{}, false
+	}
+}
+
+// 文字列、または文字列の配列で表されるクレームをスライスにする
+func stringsClaim(v any) []string {
+	switch s := v.(type) {
+	case string:
+		return []string{s}
+	case []any:
+		values := make([]string, 0, len(s))
+		for _, item := range s {
+			if str, ok := item.(string); ok {
+				values = append(values, str)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
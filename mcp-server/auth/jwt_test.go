@@ -0,0 +1,311 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kakkky/mcp-sdk-go/shared/auth"
+)
+
+var testNow = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// テスト用にJWTへ署名する鍵
+type testSigner struct {
+	kid string
+	alg string
+	key crypto.Signer
+}
+
+func (s testSigner) jwk() map[string]any {
+	b64 := base64.RawURLEncoding.EncodeToString
+	switch key := s.key.Public().(type) {
+	case *rsa.PublicKey:
+		return map[string]any{"kty": "RSA", "kid": s.kid, "alg": s.alg, "n": b64(key.N.Bytes()), "e": b64(big.NewInt(int64(key.E)).Bytes())}
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		return map[string]any{"kty": "EC", "kid": s.kid, "crv": key.Curve.Params().Name, "x": b64(key.X.FillBytes(make([]byte, size))), "y": b64(key.Y.FillBytes(make([]byte, size)))}
+	case ed25519.PublicKey:
+		return map[string]any{"kty": "OKP", "kid": s.kid, "crv": "Ed25519", "x": b64(key)}
+	}
+	return nil
+}
+
+func (s testSigner) sign(t *testing.T, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]any{"alg": s.alg, "kid": s.kid, "typ": "at+jwt"})
+	payload, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	var signature []byte
+	var err error
+	switch key := s.key.(type) {
+	case ed25519.PrivateKey:
+		signature = ed25519.Sign(key, []byte(signingInput))
+	case *ecdsa.PrivateKey:
+		digest := sha256.Sum256([]byte(signingInput))
+		var r, sig *big.Int
+		r, sig, err = ecdsa.Sign(rand.Reader, key, digest[:])
+		signature = append(r.FillBytes(make([]byte, 32)), sig.FillBytes(make([]byte, 32))...)
+	default:
+		digest := sha256.Sum256([]byte(signingInput))
+		signature, err = s.key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		t.Fatalf("failed to sign jwt: %v", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func newTestSigners(t *testing.T) (rsaSigner, ecSigner, edSigner testSigner) {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate rsa key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate ec key: %v", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate ed25519 key: %v", err)
+	}
+	return testSigner{kid: "rsa-key", alg: "RS256", key: rsaKey},
+		testSigner{kid: "ec-key", alg: "ES256", key: ecKey},
+		testSigner{kid: "ed-key", alg: "EdDSA", key: edKey}
+}
+
+func jwksOf(signers ...testSigner) []byte {
+	keys := make([]map[string]any, 0, len(signers))
+	for _, signer := range signers {
+		keys = append(keys, signer.jwk())
+	}
+	data, _ := json.Marshal(map[string]any{"keys": keys})
+	return data
+}
+
+func TestJWTVerifier_VerifyAccessToken(t *testing.T) {
+	rsaSigner, ecSigner, edSigner := newTestSigners(t)
+	otherSigner, _, _ := newTestSigners(t)
+	sut, err := NewJWTVerifier(jwksOf(rsaSigner, ecSigner, edSigner), &JWTVerifierOptions{
+		Issuer:   "https://auth.example.com",
+		Audience: "https://mcp.example.com/mcp",
+		Now:      func() time.Time { return testNow },
+	})
+	if err != nil {
+		t.Fatalf("NewJWTVerifier() error = %v", err)
+	}
+	claims := func(overrides map[string]any) map[string]any {
+		c := map[string]any{
+			"iss":       "https://auth.example.com",
+			"aud":       []any{"https://mcp.example.com/mcp"},
+			"sub":       "user-1",
+			"client_id": "client-1",
+			"scope":     "tools:read tools:call",
+			"exp":       float64(testNow.Add(time.Hour).Unix()),
+		}
+		for k, v := range overrides {
+			if v == nil {
+				delete(c, k)
+				continue
+			}
+			c[k] = v
+		}
+		return c
+	}
+	tests := []struct {
+		name             string
+		token            func() string
+		expectedAuthInfo *auth.AuthInfo
+		wantInvalidToken bool
+	}{
+		{
+			name:  "normal : RS256",
+			token: func() string { return rsaSigner.sign(t, claims(nil)) },
+			expectedAuthInfo: &auth.AuthInfo{
+				ClientId:  "client-1",
+				Subject:   "user-1",
+				Scopes:    []string{"tools:read", "tools:call"},
+				ExpiresAt: testNow.Add(time.Hour),
+			},
+		},
+		{
+			name:  "normal : ES256 with scp claim",
+			token: func() string { return ecSigner.sign(t, claims(map[string]any{"scope": nil, "scp": []any{"admin"}})) },
+			expectedAuthInfo: &auth.AuthInfo{
+				ClientId:  "client-1",
+				Subject:   "user-1",
+				Scopes:    []string{"admin"},
+				ExpiresAt: testNow.Add(time.Hour),
+			},
+		},
+		{
+			name:  "normal : EdDSA with audience as string",
+			token: func() string { return edSigner.sign(t, claims(map[string]any{"aud": "https://mcp.example.com/mcp"})) },
+			expectedAuthInfo: &auth.AuthInfo{
+				ClientId:  "client-1",
+				Subject:   "user-1",
+				Scopes:    []string{"tools:read", "tools:call"},
+				ExpiresAt: testNow.Add(time.Hour),
+			},
+		},
+		{
+			name: "semi normal : expired token",
+			token: func() string {
+				return rsaSigner.sign(t, claims(map[string]any{"exp": float64(testNow.Add(-time.Minute).Unix())}))
+			},
+			wantInvalidToken: true,
+		},
+		{
+			name:             "semi normal : token without exp",
+			token:            func() string { return rsaSigner.sign(t, claims(map[string]any{"exp": nil})) },
+			wantInvalidToken: true,
+		},
+		{
+			name: "semi normal : token not valid yet",
+			token: func() string {
+				return rsaSigner.sign(t, claims(map[string]any{"nbf": float64(testNow.Add(time.Minute).Unix())}))
+			},
+			wantInvalidToken: true,
+		},
+		{
+			name:             "semi normal : unexpected issuer",
+			token:            func() string { return rsaSigner.sign(t, claims(map[string]any{"iss": "https://evil.example.com"})) },
+			wantInvalidToken: true,
+		},
+		{
+			name: "semi normal : token for another resource",
+			token: func() string {
+				return rsaSigner.sign(t, claims(map[string]any{"aud": "https://other.example.com/mcp"}))
+			},
+			wantInvalidToken: true,
+		},
+		{
+			name: "semi normal : token without audience",
+			token: func() string {
+				return rsaSigner.sign(t, claims(map[string]any{"aud": nil}))
+			},
+			wantInvalidToken: true,
+		},
+		{
+			name: "semi normal : signed by an unknown key with the same kid",
+			token: func() string {
+				return testSigner{kid: rsaSigner.kid, alg: "RS256", key: otherSigner.key}.sign(t, claims(nil))
+			},
+			wantInvalidToken: true,
+		},
+		{
+			name: "semi normal : alg none",
+			token: func() string {
+				header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+				payload, _ := json.Marshal(claims(nil))
+				return header + "." + base64.RawURLEncoding.EncodeToString(payload) + "."
+			},
+			wantInvalidToken: true,
+		},
+		{
+			name:             "semi normal : malformed token",
+			token:            func() string { return "not-a-jwt" },
+			wantInvalidToken: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := tt.token()
+			got, err := sut.VerifyAccessToken(context.Background(), token)
+			if tt.wantInvalidToken {
+				if !errors.Is(err, ErrInvalidToken) {
+					t.Errorf("VerifyAccessToken() error = %v, want %v", err, ErrInvalidToken)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyAccessToken() error = %v", err)
+			}
+			if got.Token != token {
+				t.Errorf("Token = %q, want %q", got.Token, token)
+			}
+			if diff := cmp.Diff(tt.expectedAuthInfo, got, cmpAuthInfoOptions); diff != "" {
+				t.Errorf("VerifyAccessToken() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// トークンとクレームは各テストで個別に確認する
+var cmpAuthInfoOptions = cmp.FilterPath(func(p cmp.Path) bool {
+	name := p.Last().String()
+	return name == ".Token" || name == ".Claims"
+}, cmp.Ignore())
+
+func TestNewJWTVerifier(t *testing.T) {
+	rsaSigner, _, _ := newTestSigners(t)
+	validOptions := &JWTVerifierOptions{Issuer: "https://auth.example.com", Audience: "https://mcp.example.com/mcp"}
+	tests := []struct {
+		name    string
+		jwks    string
+		options *JWTVerifierOptions
+		wantErr bool
+	}{
+		{
+			name:    "normal : issuer and audience are configured",
+			jwks:    string(jwksOf(rsaSigner)),
+			options: validOptions,
+		},
+		{
+			name:    "semi normal : audience is not configured",
+			jwks:    string(jwksOf(rsaSigner)),
+			options: &JWTVerifierOptions{Issuer: "https://auth.example.com"},
+			wantErr: true,
+		},
+		{
+			name:    "semi normal : issuer is not configured",
+			jwks:    string(jwksOf(rsaSigner)),
+			options: &JWTVerifierOptions{Audience: "https://mcp.example.com/mcp"},
+			wantErr: true,
+		},
+		{
+			name:    "semi normal : no options",
+			jwks:    string(jwksOf(rsaSigner)),
+			wantErr: true,
+		},
+		{
+			name:    "semi normal : invalid json",
+			jwks:    `{"keys":`,
+			options: validOptions,
+			wantErr: true,
+		},
+		{
+			name:    "semi normal : no signing key",
+			jwks:    `{"keys":[{"kty":"oct","k":"c2VjcmV0"}]}`,
+			options: validOptions,
+			wantErr: true,
+		},
+		{
+			name:    "semi normal : ec point not on the curve",
+			jwks:    `{"keys":[{"kty":"EC","crv":"P-256","x":"AQ","y":"AQ"}]}`,
+			options: validOptions,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewJWTVerifier([]byte(tt.jwks), tt.options)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewJWTVerifier() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
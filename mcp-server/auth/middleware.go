@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/kakkky/mcp-sdk-go/shared/auth"
+)
+
+type ResourceServerOptions struct {
+	// アクセストークンの検証方法
+	Verifier TokenVerifier
+	// 全てのリクエストに必要なスコープ
+	RequiredScopes []string
+	// 公開する保護されたリソースのメタデータ
+	// 指定した場合、401の応答のWWW-Authenticateヘッダーでメタデータの場所をクライアントに伝える
+	Metadata *auth.ProtectedResourceMetadata
+	// Authorizationヘッダーの代わりにトークンを受け付けるヘッダー名（例：X-API-Key）
+	// 指定した場合でも、Authorizationヘッダーのトークンは受け付ける
+	APIKeyHeader string
+}
+
+// Bearerトークンを検証し、検証した認証情報をリクエストのcontextに設定するミドルウェアを返す
+// 認証情報はauth.AuthInfoFromContextで取り出せる
+// VerifierがMessageRateLimiterを実装している場合は、MessageRateLimiterFromContextで取り出せるように設定する
+// トークンが無い、または無効な場合は401、スコープが足りない場合は403、レート制限を超えた場合は429を返す
+// 参照：(https://modelcontextprotocol.io/specification/2025-06-18/basic/authorization)
+func RequireBearerToken(options ResourceServerOptions) func(http.Handler) http.Handler {
+	resourceMetadataURL := ""
+	if options.Metadata != nil {
+		resourceMetadataURL, _ = auth.ProtectedResourceMetadataURL(options.Metadata.Resource)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := bearerToken(r)
+			if !ok && options.APIKeyHeader != "" {
+				token = strings.TrimSpace(r.Header.Get(options.APIKeyHeader))
+				ok = token != ""
+			}
+			if !ok {
+				writeAuthError(w, http.StatusUnauthorized, "", "", resourceMetadataURL, "")
+				return
+			}
+			info, err := options.Verifier.VerifyAccessToken(r.Context(), token)
+			if err != nil {
+				var rateLimitErr *RateLimitError
+				if errors.As(err, &rateLimitErr) {
+					w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(rateLimitErr.RetryAfter.Seconds()))))
+					writeAuthError(w, http.StatusTooManyRequests, "rate_limited", err.Error(), "", "")
+					return
+				}
+				if errors.Is(err, ErrInvalidToken) {
+					writeAuthError(w, http.StatusUnauthorized, "invalid_token", err.Error(), resourceMetadataURL, "")
+					return
+				}
+				writeAuthError(w, http.StatusInternalServerError, "server_error", "failed to verify access token", "", "")
+				return
+			}
+			if !info.HasScopes(options.RequiredScopes...) {
+				scope := strings.Join(options.RequiredScopes, " ")
+				writeAuthError(w, http.StatusForbidden, "insufficient_scope", "token does not have the required scopes", resourceMetadataURL, scope)
+				return
+			}
+			ctx := auth.WithAuthInfo(r.Context(), info)
+			if limiter, ok := options.Verifier.(MessageRateLimiter); ok {
+				ctx = context.WithValue(ctx, messageRateLimiterKey{}, limiter)
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+type messageRateLimiterKey struct{}
+
+// RequireBearerTokenが設定した、メッセージごとのレート制限を取り出す
+func MessageRateLimiterFromContext(ctx context.Context) (MessageRateLimiter, bool) {
+	limiter, ok := ctx.Value(messageRateLimiterKey{}).(MessageRateLimiter)
+	return limiter, ok
+}
+
+// Authorizationヘッダーからトークンを取り出す。クエリパラメータでのトークンの受け渡しは受け付けない
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// トークンが無い場合は、エラーコードを含めずに認証方法のみを伝える
+// 参照：(https://datatracker.ietf.org/doc/html/rfc6750#section-3)
+func writeAuthError(w http.ResponseWriter, status int, code string, description string, resourceMetadataURL string, scope string) {
+	if status == http.StatusUnauthorized || status == http.StatusForbidden {
+		var params []string
+		if code != "" {
+			params = append(params, fmt.Sprintf("error=%q", code))
+		}
+		if description != "" {
+			params = append(params, fmt.Sprintf("error_description=%q", description))
+		}
+		if scope != "" {
+			params = append(params, fmt.Sprintf("scope=%q", scope))
+		}
+		if resourceMetadataURL != "" {
+			params = append(params, fmt.Sprintf("resource_metadata=%q", resourceMetadataURL))
+		}
+		challenge := "Bearer"
+		if len(params) > 0 {
+			challenge += " " + strings.Join(params, ", ")
+		}
+		w.Header().Set("WWW-Authenticate", challenge)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if code == "" {
+		code = "invalid_request"
+		description = "authorization is required"
+	}
+	_ = json.NewEncoder(w).Encode(struct {
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description,omitempty"`
+	}{Error: code, ErrorDescription: description})
+}
+
+// 保護されたリソースのメタデータをJSONで返すhttp.Handler
+// auth.ProtectedResourceMetadataURLで得られるパスで公開する
+func NewProtectedResourceMetadataHandler(metadata auth.ProtectedResourceMetadata) http.Handler {
+	if len(metadata.BearerMethodsSupported) == 0 {
+		metadata.BearerMethodsSupported = []string{"header"}
+	}
+	body, _ := json.Marshal(metadata)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// ブラウザ上のクライアントからも取得できるようにする
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+		case http.MethodOptions:
+			w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		default:
+			w.Header().Set("Allow", "GET, OPTIONS")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	})
+}
@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kakkky/mcp-sdk-go/shared/auth"
+)
+
+// トークンと認証情報の対応を持つTokenVerifier
+type stubVerifier map[string]*auth.AuthInfo
+
+func (v stubVerifier) VerifyAccessToken(ctx context.Context, token string) (*auth.AuthInfo, error) {
+	if token == "unavailable" {
+		return nil, errors.New("authorization server is unavailable")
+	}
+	info, ok := v[token]
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	return info, nil
+}
+
+func TestRequireBearerToken(t *testing.T) {
+	verifier := stubVerifier{
+		"valid":   {Subject: "user-1", Scopes: []string{"mcp"}},
+		"noscope": {Subject: "user-2"},
+	}
+	options := ResourceServerOptions{
+		Verifier:       verifier,
+		RequiredScopes: []string{"mcp"},
+		Metadata:       &auth.ProtectedResourceMetadata{Resource: "https://mcp.example.com/mcp"},
+	}
+	tests := []struct {
+		name                    string
+		authorization           string
+		expectedStatus          int
+		expectedWWWAuthenticate string
+		expectedSubject         string
+	}{
+		{
+			name:            "normal : valid token",
+			authorization:   "Bearer valid",
+			expectedStatus:  http.StatusOK,
+			expectedSubject: "user-1",
+		},
+		{
+			name:            "normal : scheme is case insensitive",
+			authorization:   "bearer valid",
+			expectedStatus:  http.StatusOK,
+			expectedSubject: "user-1",
+		},
+		{
+			name:                    "semi normal : no token",
+			expectedStatus:          http.StatusUnauthorized,
+			expectedWWWAuthenticate: `Bearer resource_metadata="https://mcp.example.com/.well-known/oauth-protected-resource/mcp"`,
+		},
+		{
+			name:                    "semi normal : basic authorization",
+			authorization:           "Basic dXNlcjpwYXNz",
+			expectedStatus:          http.StatusUnauthorized,
+			expectedWWWAuthenticate: `Bearer resource_metadata="https://mcp.example.com/.well-known/oauth-protected-resource/mcp"`,
+		},
+		{
+			name:                    "semi normal : invalid token",
+			authorization:           "Bearer forged",
+			expectedStatus:          http.StatusUnauthorized,
+			expectedWWWAuthenticate: `Bearer error="invalid_token", error_description="invalid token", resource_metadata="https://mcp.example.com/.well-known/oauth-protected-resource/mcp"`,
+		},
+		{
+			name:                    "semi normal : insufficient scope",
+			authorization:           "Bearer noscope",
+			expectedStatus:          http.StatusForbidden,
+			expectedWWWAuthenticate: `Bearer error="insufficient_scope", error_description="token does not have the required scopes", scope="mcp", resource_metadata="https://mcp.example.com/.well-known/oauth-protected-resource/mcp"`,
+		},
+		{
+			name:           "semi normal : verifier failure",
+			authorization:  "Bearer unavailable",
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var subject string
+			sut := RequireBearerToken(options)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				info, ok := auth.AuthInfoFromContext(r.Context())
+				if !ok {
+					t.Error("auth info is not set to the request context")
+					return
+				}
+				subject = info.Subject
+			}))
+			req := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			sut.ServeHTTP(rec, req)
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.expectedStatus)
+			}
+			if got := rec.Header().Get("WWW-Authenticate"); got != tt.expectedWWWAuthenticate {
+				t.Errorf("WWW-Authenticate = %q, want %q", got, tt.expectedWWWAuthenticate)
+			}
+			if subject != tt.expectedSubject {
+				t.Errorf("subject = %q, want %q", subject, tt.expectedSubject)
+			}
+		})
+	}
+}
+
+func TestNewProtectedResourceMetadataHandler(t *testing.T) {
+	sut := NewProtectedResourceMetadataHandler(auth.ProtectedResourceMetadata{
+		Resource:             "https://mcp.example.com/mcp",
+		AuthorizationServers: []string{"https://auth.example.com"},
+		ScopesSupported:      []string{"mcp"},
+	})
+	rec := httptest.NewRecorder()
+	sut.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/.well-known/oauth-protected-resource/mcp", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var got auth.ProtectedResourceMetadata
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to parse metadata: %v", err)
+	}
+	want := auth.ProtectedResourceMetadata{
+		Resource:               "https://mcp.example.com/mcp",
+		AuthorizationServers:   []string{"https://auth.example.com"},
+		ScopesSupported:        []string{"mcp"},
+		BearerMethodsSupported: []string{"header"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("metadata mismatch (-want +got):\n%s", diff)
+	}
+}
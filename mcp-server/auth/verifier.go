@@ -0,0 +1,18 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	"github.com/kakkky/mcp-sdk-go/shared/auth"
+)
+
+// アクセストークンが無効な場合（署名不正、期限切れ、対象外など）に返すエラー
+var ErrInvalidToken = errors.New("invalid token")
+
+// アクセストークンを検証し、認証情報を返す
+// トークン自体が無効な場合は、ErrInvalidTokenをラップしたエラーを返す
+// それ以外のエラー（認可サーバーに接続できないなど）はサーバーエラーとして扱われる
+type TokenVerifier interface {
+	VerifyAccessToken(ctx context.Context, token string) (*auth.AuthInfo, error)
+}
@@ -0,0 +1,133 @@
+package mcpserver
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kakkky/mcp-sdk-go/shared/auth"
+	"github.com/kakkky/mcp-sdk-go/shared/schema"
+)
+
+// ツール、リソース、プロンプトのコールバックに渡す、処理中のリクエストの情報
+// リクエストを送ったクライアントへのログや進捗の通知、サンプリングや入力の要求に使用する
+type Exchange struct {
+	RequestId int
+	Method    string
+	// リクエストを送ったクライアントとのセッション
+	Session  *Session
+	AuthInfo *auth.AuthInfo
+	// リクエストのparamsの"_meta"。指定されていない場合はnil
+	Meta *schema.RequestMetaSchema
+
+	ctx context.Context
+}
+
+type exchangeKey struct{}
+
+// コールバックに渡すctxから、処理中のリクエストのExchangeを取り出す
+func ExchangeFromContext(ctx context.Context) (*Exchange, bool) {
+	exchange, ok := ctx.Value(exchangeKey{}).(*Exchange)
+	return exchange, ok
+}
+
+func newExchange(session *Session, request schema.JsonRpcRequest) *Exchange {
+	exchange := &Exchange{
+		RequestId: request.Id,
+		Method:    request.Method(),
+		Session:   session,
+		AuthInfo:  request.AuthInfo,
+		Meta:      requestMetaOf(request.Request),
+	}
+	ctx := request.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if request.AuthInfo != nil {
+		ctx = auth.WithAuthInfo(ctx, request.AuthInfo)
+	}
+	exchange.ctx = context.WithValue(ctx, exchangeKey{}, exchange)
+	return exchange
+}
+
+func requestMetaOf(request schema.Request) *schema.RequestMetaSchema {
+	switch r := request.(type) {
+	case *schema.CallToolRequestSchema:
+		return r.ParamsData.Meta
+	case *schema.GetPromptRequestSchema:
+		return r.ParamsData.Meta
+	case *schema.ReadResourceRequestSchema:
+		return r.ParamsData.Meta
+	}
+	return nil
+}
+
+func (e *Exchange) ClientInfo() schema.Implementation {
+	return e.Session.ClientInfo()
+}
+
+func (e *Exchange) ClientCapabilities() schema.ClientCapabilities {
+	return e.Session.ClientCapabilities()
+}
+
+// リクエストを送ったクライアントに、notifications/messageでログを送る
+// サーバーのケーパビリティにloggingが無い場合はエラーを返す
+// リクエストが取り消された場合は送らずにctx.Err()を返す
+func (e *Exchange) Log(level schema.LoggingLevelSchema, logger string, data any) error {
+	if err := e.ctx.Err(); err != nil {
+		return err
+	}
+	return e.Session.Server.SendLoggingMessage(schema.LoggingMessageNotificationParams{
+		Level:  level,
+		Logger: logger,
+		Data:   data,
+	})
+}
+
+// リクエストの_meta.progressTokenを指定して、notifications/progressで進捗を送る
+// クライアントがprogressTokenを指定していない場合は何もしない
+// totalが分からない場合は0を指定する
+// リクエストが取り消された場合は送らずにctx.Err()を返す
+func (e *Exchange) ReportProgress(progress float64, total float64, message string) error {
+	if err := e.ctx.Err(); err != nil {
+		return err
+	}
+	if e.Meta == nil || e.Meta.ProgressToken == nil {
+		return nil
+	}
+	return e.Session.Server.Notificate(&schema.ProgressNotificationSchema{
+		MethodName: "notifications/progress",
+		ParamsData: schema.ProgressNotificationParams{
+			ProgressToken: e.Meta.ProgressToken,
+			Progress:      progress,
+			Total:         total,
+			Message:       message,
+		},
+	})
+}
+
+// リクエストを送ったクライアントに、sampling/createMessageでLLMの呼び出しを依頼する
+// paramsとcontentTypeはserver.Server.CreateMessageと同じ
+// リクエストが取り消された場合は、応答を待たずにctx.Err()を返す
+func (e *Exchange) CreateMessage(params any, contentType string) (schema.Result, error) {
+	if e.ClientCapabilities().Sampling == nil {
+		return nil, fmt.Errorf("client %s does not support sampling", e.ClientInfo().Name)
+	}
+	return e.Session.Server.CreateMessageWithContext(e.ctx, params, contentType)
+}
+
+// リクエストを送ったクライアントに、elicitation/createでユーザーへの入力の要求を依頼する
+// リクエストが取り消された場合は、応答を待たずにctx.Err()を返す
+func (e *Exchange) Elicit(params schema.ElicitRequestParams) (*schema.ElicitResultSchema, error) {
+	if e.ClientCapabilities().Elicitation == nil {
+		return nil, fmt.Errorf("client %s does not support elicitation", e.ClientInfo().Name)
+	}
+	result, err := e.Session.Server.ElicitWithContext(e.ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	elicitResult, ok := result.(*schema.ElicitResultSchema)
+	if !ok {
+		return nil, fmt.Errorf("unexpected result type: %T", result)
+	}
+	return elicitResult, nil
+}
@@ -0,0 +1,132 @@
+package mcpserver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kakkky/mcp-sdk-go/mcp-server/server"
+	"github.com/kakkky/mcp-sdk-go/shared/auth"
+	"github.com/kakkky/mcp-sdk-go/shared/protocol"
+	"github.com/kakkky/mcp-sdk-go/shared/schema"
+)
+
+func TestMcpServer_ToolWithExchange(t *testing.T) {
+	tests := []struct {
+		name                  string
+		meta                  *schema.RequestMetaSchema
+		authInfo              *auth.AuthInfo
+		expectedNotifications []string
+	}{
+		{
+			name:                  "normal : progress is reported with the progress token of the request",
+			meta:                  &schema.RequestMetaSchema{ProgressToken: "token-1"},
+			authInfo:              &auth.AuthInfo{Subject: "alice"},
+			expectedNotifications: []string{"notifications/progress", "notifications/progress"},
+		},
+		{
+			name: "normal : progress is not reported without a progress token",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sut := NewMcpServer(schema.Implementation{}, &server.ServerOptions{})
+			var session *Session
+			conn := newTestConnection(t, func(transport protocol.Transport) error {
+				var err error
+				session, err = sut.ConnectSession(transport)
+				return err
+			})
+			var gotExchange *Exchange
+			var fromContext *Exchange
+			var gotAuthInfo *auth.AuthInfo
+			if _, err := sut.ToolWithExchange("slow", "slow tool", schema.PropertySchema{}, nil, func(ctx context.Context, exchange *Exchange, args map[string]any) (schema.CallToolResultSchema, error) {
+				gotExchange = exchange
+				fromContext, _ = ExchangeFromContext(ctx)
+				gotAuthInfo, _ = auth.AuthInfoFromContext(ctx)
+				for i := 1; i <= 2; i++ {
+					if err := exchange.ReportProgress(float64(i), 2, ""); err != nil {
+						return schema.CallToolResultSchema{}, err
+					}
+				}
+				return schema.CallToolResultSchema{Content: []schema.ToolContentSchema{}}, nil
+			}); err != nil {
+				t.Fatalf("failed to register tool: %v", err)
+			}
+
+			response := conn.request(&schema.CallToolRequestSchema{
+				MethodName: "tools/call",
+				ParamsData: schema.CallToolRequestParams{Name: "slow", Meta: tt.meta},
+			}, tt.authInfo)
+			if _, ok := response.(schema.JsonRpcResponse); !ok {
+				t.Fatalf("response = %+v, want result", response)
+			}
+			if gotExchange == nil {
+				t.Fatal("exchange was not passed to the callback")
+			}
+			if gotExchange != fromContext {
+				t.Errorf("ExchangeFromContext() = %p, want %p", fromContext, gotExchange)
+			}
+			if gotExchange.RequestId != 1 || gotExchange.Method != "tools/call" || gotExchange.Session != session {
+				t.Errorf("exchange = %+v, want request 1 of tools/call on the connected session", gotExchange)
+			}
+			if diff := cmp.Diff(tt.meta, gotExchange.Meta); diff != "" {
+				t.Errorf("meta mismatch (-want +got):\n%s", diff)
+			}
+			if gotExchange.AuthInfo != tt.authInfo || gotAuthInfo != tt.authInfo {
+				t.Errorf("auth info = %v (context: %v), want %v", gotExchange.AuthInfo, gotAuthInfo, tt.authInfo)
+			}
+			if diff := cmp.Diff(tt.expectedNotifications, conn.notifications()); diff != "" {
+				t.Errorf("notifications mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// クライアントがリクエストを取り消した場合、Elicitは応答を待たずに終了し、送ったリクエストも取り消す
+func TestExchange_ElicitIsCancelled(t *testing.T) {
+	sut := NewMcpServer(schema.Implementation{}, &server.ServerOptions{})
+	conn := newTestConnection(t, func(transport protocol.Transport) error {
+		_, err := sut.ConnectSession(transport)
+		return err
+	})
+	conn.request(&schema.InitializeRequestSchema{
+		MethodName: "initialize",
+		ParamsData: schema.InitializeRequestParams{Capabilities: schema.ClientCapabilities{Elicitation: &schema.Elicitation{}}},
+	}, nil)
+	elicitErr := make(chan error, 1)
+	if _, err := sut.ToolWithExchange("ask", "ask the user", schema.PropertySchema{}, nil, func(ctx context.Context, exchange *Exchange, args map[string]any) (schema.CallToolResultSchema, error) {
+		_, err := exchange.Elicit(schema.ElicitRequestParams{Message: "name?"})
+		elicitErr <- err
+		return schema.CallToolResultSchema{}, err
+	}); err != nil {
+		t.Fatalf("failed to register tool: %v", err)
+	}
+	conn.notifications()
+
+	// ツールがelicitation/createの応答を待ち始めると、受信ループは次のメッセージを受信する
+	conn.onReceiveMessage(schema.JsonRpcRequest{
+		BaseMessage: schema.BaseMessage{Jsonrpc: schema.JSON_RPC_VERSION, Id: 1},
+		Request:     &schema.CallToolRequestSchema{MethodName: "tools/call", ParamsData: schema.CallToolRequestParams{Name: "ask"}},
+	})
+	conn.onReceiveMessage(schema.JsonRpcNotification{
+		Jsonrpc: schema.JSON_RPC_VERSION,
+		Notification: &schema.CancelledNotificationSchema{
+			MethodName: "notifications/cancelled",
+			ParamsData: schema.CancelledNotificationParams{RequestId: 1},
+		},
+	})
+	select {
+	case err := <-elicitErr:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Elicit() error = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Elicit() did not return after the request was cancelled")
+	}
+	if diff := cmp.Diff([]string{"notifications/cancelled"}, conn.notifications()); diff != "" {
+		t.Errorf("notifications mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -8,19 +8,23 @@ import (
 	"github.com/kakkky/mcp-sdk-go/shared/schema"
 )
 
-func (m *McpServer) handleResourceCompletion(request schema.CompleteRequestSchema, ref schema.ResourceReferenceSchema) (*schema.CompleteResultSchema, error) {
+func (m *McpServer) handleResourceCompletion(exchange *Exchange, request schema.CompleteRequestSchema, ref schema.ResourceReferenceSchema) (*schema.CompleteResultSchema, error) {
 	params := request.Params().(schema.CompleteRequestParams)
 	// refで渡されたリソーステンプレートのURIと一致するテンプレートを探す
+	m.registryMu.RLock()
 	var template *RegisteredResourceTemplate
-	for _, registeredTemplate := range m.registeredResourceTemplates {
-		if registeredTemplate.resourceTemplate.uriTemplate().ToString() == ref.UriOrName() {
-			template = registeredTemplate
+	for name, registeredTemplate := range m.registeredResourceTemplates {
+		if registeredTemplate.resourceTemplate.uriTemplate().ToString() == ref.UriOrName() && m.isVisible(exchange.Session, Registration{Kind: REGISTRATION_KIND_RESOURCE_TEMPLATE, Name: name}) {
+			templateCopy := *registeredTemplate
+			template = &templateCopy
 			break
 		}
 	}
+	isResource := m.registeredResources[ref.UriOrName()] != nil
+	m.registryMu.RUnlock()
 	// テンプレートが見つからなかったが、固定リソースが見つかった場合は空の補完を返す（しかし、リクエストエラーとすべきだろう）
 	if template == nil {
-		if m.registeredResources[ref.UriOrName()] != nil {
+		if isResource {
 			return EmptyCompletionResult(), nil
 		}
 		return nil, mcperr.NewMcpErr(
@@ -29,6 +33,9 @@ func (m *McpServer) handleResourceCompletion(request schema.CompleteRequestSchem
 			nil,
 		)
 	}
+	if !template.access.allows(exchange.AuthInfo) {
+		return nil, newPermissionDeniedErr("resource template", ref.UriOrName(), template.access)
+	}
 	// テンプレートの変数名から補完コールバックを取得
 	completer := template.resourceTemplate.CompleteCallBack(params.Argument.Name)
 	if completer == nil {
@@ -38,9 +45,9 @@ func (m *McpServer) handleResourceCompletion(request schema.CompleteRequestSchem
 	return createCompletionResult(suggestions), nil
 }
 
-func (m *McpServer) handlePromptCompletion(request schema.CompleteRequestSchema, ref schema.PromptReferenceSchema) (*schema.CompleteResultSchema, error) {
+func (m *McpServer) handlePromptCompletion(exchange *Exchange, request schema.CompleteRequestSchema, ref schema.PromptReferenceSchema) (*schema.CompleteResultSchema, error) {
 	params := request.Params().(schema.CompleteRequestParams)
-	prompt, ok := m.registeredPrompts[ref.UriOrName()]
+	prompt, ok := m.lookupPrompt(exchange.Session, ref.UriOrName())
 	if !ok {
 		return nil, mcperr.NewMcpErr(
 			mcperr.INVALID_PARAMS,
@@ -55,6 +62,9 @@ func (m *McpServer) handlePromptCompletion(request schema.CompleteRequestSchema,
 			nil,
 		)
 	}
+	if !prompt.access.allows(exchange.AuthInfo) {
+		return nil, newPermissionDeniedErr("prompt", ref.UriOrName(), prompt.access)
+	}
 	if prompt.argsSchema == nil {
 		return EmptyCompletionResult(), nil
 	}
@@ -3,6 +3,7 @@ package mcpserver
 import (
 	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/kakkky/mcp-sdk-go/mcp-server/server"
 	"github.com/kakkky/mcp-sdk-go/shared/protocol"
@@ -22,16 +23,44 @@ type McpServer struct {
 	isToolHandlersInitialized       bool
 	isPromptHandlersInitialized     bool
 	isCompletionHandlersInitialized bool
+
+	// 登録済みのツール、リソース、プロンプトと、公開する登録を決める関数を保護する
+	// ロックを保持したままコールバックの呼び出しや通知の送信を行わない
+	registryMu sync.RWMutex
+
+	// ConnectSessionで生成するServerに引き継ぐ設定
+	serverInfo      schema.Implementation
+	options         *server.ServerOptions
+	capabilities    schema.ServerCapabilities
+	requestHandlers []requestHandlerEntry
+
+	sessionsMu sync.Mutex
+	sessions   []*Session
+	// Connectで接続する、Serverプロパティのセッション
+	defaultSession *Session
+	visibility     VisibilityFunc
+
+	toolArgumentValidation ToolArgumentValidationOptions
+}
+
+type requestHandlerEntry struct {
+	requestSchema schema.Request
+	handler       func(exchange *Exchange, request schema.JsonRpcRequest) (schema.Result, error)
 }
 
 func NewMcpServer(serverInfo schema.Implementation, options *server.ServerOptions) *McpServer {
-	return &McpServer{
+	m := &McpServer{
 		Server:                      server.NewServer(serverInfo, options),
 		registeredResources:         make(map[string]*RegisteredResource),
 		registeredResourceTemplates: make(map[string]*RegisteredResourceTemplate),
 		registerdTools:              make(map[string]*RegisteredTool),
 		registeredPrompts:           make(map[string]*RegisteredPrompt),
+		serverInfo:                  serverInfo,
+		options:                     options,
 	}
+	m.defaultSession = &Session{Server: m.Server, mcpServer: m}
+	m.sessions = []*Session{m.defaultSession}
+	return m
 }
 
 func (m *McpServer) Connect(transport protocol.Transport) error {
@@ -42,8 +71,34 @@ func (m *McpServer) Close() error {
 	return m.Server.Close()
 }
 
-func (m *McpServer) isConnected() bool {
-	return m.Server.Transport() != nil
+// 全てのセッションにリクエストハンドラーを設定する。以降に接続したセッションにも設定する
+func (m *McpServer) setRequestHandler(requestSchema schema.Request, handler func(exchange *Exchange, request schema.JsonRpcRequest) (schema.Result, error)) {
+	m.sessionsMu.Lock()
+	defer m.sessionsMu.Unlock()
+	entry := requestHandlerEntry{requestSchema: requestSchema, handler: handler}
+	m.requestHandlers = append(m.requestHandlers, entry)
+	for _, session := range m.sessions {
+		m.installRequestHandler(session, entry)
+	}
+}
+
+// リクエストごとにExchangeを生成し、リクエストの認証情報とセッションをハンドラーに渡す
+// 複数のセッションからのリクエストは並行して処理される
+func (m *McpServer) installRequestHandler(session *Session, entry requestHandlerEntry) {
+	session.Server.SetRequestHandler(entry.requestSchema, func(request schema.JsonRpcRequest) (schema.Result, error) {
+		session.setAuthInfo(request.AuthInfo)
+		return entry.handler(newExchange(session, request), request)
+	})
+}
+
+// 全てのセッションと、以降に接続するセッションのケーパビリティに追加する
+func (m *McpServer) registerCapabilities(capabilities schema.ServerCapabilities) {
+	m.sessionsMu.Lock()
+	defer m.sessionsMu.Unlock()
+	m.capabilities = protocol.MergeCapabilities(m.capabilities, capabilities)
+	for _, session := range m.sessions {
+		_ = session.Server.RegisterCapabilities(capabilities)
+	}
 }
 
 // Resource はURIベースのリソースを登録します
@@ -52,6 +107,16 @@ func (m *McpServer) Resource(
 	uri string,
 	metadata *schema.ResourceMetadata,
 	readResourceCallBack ReadResourceCallback[schema.ResourceContentSchema],
+) (*RegisteredResource, error) {
+	return m.ResourceWithExchange(name, uri, metadata, readResourceCallBack.handler())
+}
+
+// コールバックがctxとExchangeを受け取る点以外は、Resourceと同じ
+func (m *McpServer) ResourceWithExchange(
+	name string,
+	uri string,
+	metadata *schema.ResourceMetadata,
+	readResourceCallBack ReadResourceHandler,
 ) (*RegisteredResource, error) {
 	if uri == "" {
 		return nil, errors.New("uri is required")
@@ -59,66 +124,77 @@ func (m *McpServer) Resource(
 	if readResourceCallBack == nil {
 		return nil, errors.New("readResourceCallBack is required")
 	}
+	m.registryMu.Lock()
 	if m.registeredResources[uri] != nil {
+		m.registryMu.Unlock()
 		return nil, fmt.Errorf("resource %s is already registered", uri)
 	}
 
 	uriPtr := &uri
+	update := func(updates ResourceUpdates) {
+		m.registryMu.Lock()
+		if _, ok := m.registeredResources[*uriPtr]; !ok {
+			m.registryMu.Unlock()
+			fmt.Println("resource not found")
+			return
+		}
+		before := listChange{registration: Registration{Kind: REGISTRATION_KIND_RESOURCE, Name: *uriPtr}, access: m.registeredResources[*uriPtr].access}
+		if updates.Uri != "" && updates.Uri != *uriPtr {
+			resourceCopy := m.registeredResources[*uriPtr]
+			delete(m.registeredResources, *uriPtr)
+			m.registeredResources[updates.Uri] = resourceCopy
+
+			// 参照する値を更新することで、uriの値が変わった場合でも、以降の処理 & Disable/Enable/Removeが正しく動作するようにする
+			*uriPtr = updates.Uri
+		}
+		if updates.Name != "" {
+			m.registeredResources[*uriPtr].name = updates.Name
+		}
+		if updates.Metadata != nil {
+			m.registeredResources[*uriPtr].metadata = updates.Metadata
+		}
+		if updates.Callback != nil {
+			m.registeredResources[*uriPtr].readCallback = updates.Callback.handler()
+		}
+		if updates.Handler != nil {
+			m.registeredResources[*uriPtr].readCallback = updates.Handler
+		}
+		if updates.Enabled != nil {
+			m.registeredResources[*uriPtr].enabled = *updates.Enabled
+		}
+		if updates.Access != nil {
+			m.registeredResources[*uriPtr].access = updates.Access
+		}
+		after := listChange{registration: Registration{Kind: REGISTRATION_KIND_RESOURCE, Name: *uriPtr}, access: m.registeredResources[*uriPtr].access}
+		m.registryMu.Unlock()
+		m.sendResourceListChanged(before, after)
+	}
 	registeredResource := RegisteredResource{
 		name:         name,
 		metadata:     metadata,
 		readCallback: readResourceCallBack,
 		enabled:      true,
 		Disable: func() {
-			if _, ok := m.registeredResources[*uriPtr]; !ok {
-				fmt.Println("resource not found")
-				return
-			}
 			disabled := false
-			m.registeredResources[*uriPtr].Update(ResourceUpdates{Enabled: &disabled})
+			update(ResourceUpdates{Enabled: &disabled})
 		},
 		Enable: func() {
-			if _, ok := m.registeredResources[*uriPtr]; !ok {
-				fmt.Println("resource not found")
-				return
-			}
 			enabled := true
-			m.registeredResources[*uriPtr].Update(ResourceUpdates{Enabled: &enabled})
+			update(ResourceUpdates{Enabled: &enabled})
 		},
-		Remove: func() { delete(m.registeredResources, *uriPtr) },
-		Update: func(updates ResourceUpdates) {
-			if _, ok := m.registeredResources[*uriPtr]; !ok {
-				fmt.Println("resource not found")
-				return
-			}
-			if updates.Uri != "" && updates.Uri != *uriPtr {
-				resourceCopy := m.registeredResources[*uriPtr]
-				delete(m.registeredResources, *uriPtr)
-				m.registeredResources[updates.Uri] = resourceCopy
-
-				// 参照する値を更新することで、uriの値が変わった場合でも、以降の処理 & Disable/Enable/Removeが正しく動作するようにする
-				*uriPtr = updates.Uri
-			}
-			if updates.Name != "" {
-				m.registeredResources[*uriPtr].name = updates.Name
-			}
-			if updates.Metadata != nil {
-				m.registeredResources[*uriPtr].metadata = updates.Metadata
-			}
-			if updates.Callback != nil {
-				m.registeredResources[*uriPtr].readCallback = *updates.Callback
-			}
-			if updates.Enabled != nil {
-				m.registeredResources[*uriPtr].enabled = *updates.Enabled
-			}
-			_ = m.Server.SendResourceListChanged()
+		Remove: func() {
+			m.registryMu.Lock()
+			defer m.registryMu.Unlock()
+			delete(m.registeredResources, *uriPtr)
 		},
+		Update: update,
 	}
 	m.registeredResources[*uriPtr] = &registeredResource
 
 	_ = m.setResourceRequestHandlers()
+	m.registryMu.Unlock()
 
-	_ = m.Server.SendResourceListChanged
+	m.sendResourceListChanged(listChange{registration: Registration{Kind: REGISTRATION_KIND_RESOURCE, Name: uri}})
 	return &registeredResource, nil
 }
 
@@ -128,6 +204,16 @@ func (m *McpServer) ResourceTemplate(
 	template *ResourceTemplate,
 	metadata *schema.ResourceMetadata,
 	readResourceTemplateCallBack ReadResourceTemplateCallback[schema.ResourceContentSchema],
+) (*RegisteredResourceTemplate, error) {
+	return m.ResourceTemplateWithExchange(name, template, metadata, readResourceTemplateCallBack.handler())
+}
+
+// コールバックがctxとExchangeを受け取る点以外は、ResourceTemplateと同じ
+func (m *McpServer) ResourceTemplateWithExchange(
+	name string,
+	template *ResourceTemplate,
+	metadata *schema.ResourceMetadata,
+	readResourceTemplateCallBack ReadResourceTemplateHandler,
 ) (*RegisteredResourceTemplate, error) {
 	if template == nil {
 		return nil, errors.New("template is required")
@@ -135,64 +221,75 @@ func (m *McpServer) ResourceTemplate(
 	if readResourceTemplateCallBack == nil {
 		return nil, errors.New("readResourceTemplateCallBack is required")
 	}
+	m.registryMu.Lock()
 	if m.registeredResourceTemplates[name] != nil {
+		m.registryMu.Unlock()
 		return nil, fmt.Errorf("resource template %s is already registered", name)
 	}
 
 	namePtr := &name
+	update := func(updates ResourceTemplateUpdates) {
+		m.registryMu.Lock()
+		if _, ok := m.registeredResourceTemplates[*namePtr]; !ok {
+			m.registryMu.Unlock()
+			fmt.Println("resource template not found")
+			return
+		}
+		before := listChange{registration: Registration{Kind: REGISTRATION_KIND_RESOURCE_TEMPLATE, Name: *namePtr}, access: m.registeredResourceTemplates[*namePtr].access}
+		if updates.Name != "" && updates.Name != *namePtr {
+			resourceTemplateCopy := m.registeredResourceTemplates[*namePtr]
+			delete(m.registeredResourceTemplates, *namePtr)
+			m.registeredResourceTemplates[updates.Name] = resourceTemplateCopy
+
+			// 参照する値を更新することで、nameの値が変わった場合でも、以降の処理 & Disable/Enable/Removeが正しく動作するようにする
+			*namePtr = updates.Name
+		}
+		if updates.Template != nil {
+			m.registeredResourceTemplates[*namePtr].resourceTemplate = updates.Template
+		}
+		if updates.Metadata != nil {
+			m.registeredResourceTemplates[*namePtr].metadata = updates.Metadata
+		}
+		if updates.Callback != nil {
+			m.registeredResourceTemplates[*namePtr].readCallback = updates.Callback.handler()
+		}
+		if updates.Handler != nil {
+			m.registeredResourceTemplates[*namePtr].readCallback = updates.Handler
+		}
+		if updates.Enabled != nil {
+			m.registeredResourceTemplates[*namePtr].enabled = *updates.Enabled
+		}
+		if updates.Access != nil {
+			m.registeredResourceTemplates[*namePtr].access = updates.Access
+		}
+		after := listChange{registration: Registration{Kind: REGISTRATION_KIND_RESOURCE_TEMPLATE, Name: *namePtr}, access: m.registeredResourceTemplates[*namePtr].access}
+		m.registryMu.Unlock()
+		m.sendResourceListChanged(before, after)
+	}
 	registeredResourceTemplate := RegisteredResourceTemplate{
 		resourceTemplate: template,
 		metadata:         metadata,
 		readCallback:     readResourceTemplateCallBack,
 		enabled:          true,
 		Disable: func() {
-			if _, ok := m.registeredResourceTemplates[*namePtr]; !ok {
-				fmt.Println("resource template not found")
-				return
-			}
 			disabled := false
-			m.registeredResourceTemplates[*namePtr].Update(ResourceTemplateUpdates{Enabled: &disabled})
+			update(ResourceTemplateUpdates{Enabled: &disabled})
 		},
 		Enable: func() {
-			if _, ok := m.registeredResourceTemplates[*namePtr]; !ok {
-				fmt.Println("resource template not found")
-				return
-			}
 			enabled := true
-			m.registeredResourceTemplates[*namePtr].Update(ResourceTemplateUpdates{Enabled: &enabled})
+			update(ResourceTemplateUpdates{Enabled: &enabled})
 		},
-		Remove: func() { delete(m.registeredResourceTemplates, *namePtr) },
-		Update: func(updates ResourceTemplateUpdates) {
-			if _, ok := m.registeredResourceTemplates[*namePtr]; !ok {
-				fmt.Println("resource template not found")
-				return
-			}
-			if updates.Name != "" && updates.Name != *namePtr {
-				resourceTemplateCopy := m.registeredResourceTemplates[*namePtr]
-				delete(m.registeredResourceTemplates, *namePtr)
-				m.registeredResourceTemplates[updates.Name] = resourceTemplateCopy
-
-				// 参照する値を更新することで、nameの値が変わった場合でも、以降の処理 & Disable/Enable/Removeが正しく動作するようにする
-				*namePtr = updates.Name
-			}
-			if updates.Template != nil {
-				m.registeredResourceTemplates[*namePtr].resourceTemplate = updates.Template
-			}
-			if updates.Metadata != nil {
-				m.registeredResourceTemplates[*namePtr].metadata = updates.Metadata
-			}
-			if updates.Callback != nil {
-				m.registeredResourceTemplates[*namePtr].readCallback = *updates.Callback
-			}
-			if updates.Enabled != nil {
-				m.registeredResourceTemplates[*namePtr].enabled = *updates.Enabled
-			}
-			_ = m.Server.SendResourceListChanged()
+		Remove: func() {
+			m.registryMu.Lock()
+			defer m.registryMu.Unlock()
+			delete(m.registeredResourceTemplates, *namePtr)
 		},
+		Update: update,
 	}
 	m.registeredResourceTemplates[*namePtr] = &registeredResourceTemplate
 	_ = m.setResourceRequestHandlers()
-	m.sendResourceListChanged()
+	m.registryMu.Unlock()
+	m.sendResourceListChanged(listChange{registration: Registration{Kind: REGISTRATION_KIND_RESOURCE_TEMPLATE, Name: name}})
 	return &registeredResourceTemplate, nil
 }
 
@@ -203,58 +300,102 @@ func (m *McpServer) Tool(
 	annotations *schema.ToolAnotationsSchema,
 	callback ToolCallback,
 ) (*RegisteredTool, error) {
-	if m.registerdTools[name] != nil {
-		return nil, fmt.Errorf("tool %s is already registered", name)
-	}
-	namePtr := &name
-	registeredTool := RegisteredTool{
+	return m.ToolWithExchange(name, description, propertySchema, annotations, callback.handler())
+}
+
+// コールバックがctxとExchangeを受け取る点以外は、Toolと同じ
+func (m *McpServer) ToolWithExchange(
+	name string,
+	description string,
+	propertySchema schema.PropertySchema,
+	annotations *schema.ToolAnotationsSchema,
+	callback ToolHandler,
+) (*RegisteredTool, error) {
+	return m.registerTool(name, RegisteredTool{
 		description:    description,
 		propertySchema: propertySchema,
 		annotations:    annotations,
 		callback:       callback,
-		enabled:        true,
-		Disable: func() {
-			m.registerdTools[*namePtr].Update(ToolUpdates{Enabled: &[]bool{false}[0]})
-		},
-		Enable: func() {
-			m.registerdTools[*namePtr].Update(ToolUpdates{Enabled: &[]bool{true}[0]})
-		},
-		Remove: func() { delete(m.registerdTools, *namePtr) },
-		Update: func(updates ToolUpdates) {
-			if _, ok := m.registerdTools[*namePtr]; !ok {
-				fmt.Println("tool not found")
-				return
-			}
-			if updates.Name != "" && updates.Name != *namePtr {
-				toolCopy := m.registerdTools[*namePtr]
-				delete(m.registerdTools, *namePtr)
-				m.registerdTools[updates.Name] = toolCopy
+	})
+}
 
-				// 参照する値を更新することで、nameの値が変わった場合でも、以降の処理 & Disable/Enable/Removeが正しく動作するようにする
-				*namePtr = updates.Name
-			}
-			if updates.Description != "" {
-				m.registerdTools[*namePtr].description = updates.Description
-			}
-			if updates.ParamsSchema != nil {
-				m.registerdTools[*namePtr].propertySchema = updates.ParamsSchema
-			}
-			if updates.callback != nil {
-				m.registerdTools[*namePtr].callback = updates.callback
-			}
-			if updates.Annotations != nil {
-				m.registerdTools[*namePtr].annotations = updates.Annotations
-			}
-			if updates.Enabled != nil {
-				m.registerdTools[*namePtr].enabled = *updates.Enabled
+// ツールを登録し、tools/list_changedを送信する
+// 入力や出力のスキーマなどを含め、公開する前に全てのフィールドを設定した状態で登録する
+func (m *McpServer) registerTool(name string, registeredTool RegisteredTool) (*RegisteredTool, error) {
+	m.registryMu.Lock()
+	if m.registerdTools[name] != nil {
+		m.registryMu.Unlock()
+		return nil, fmt.Errorf("tool %s is already registered", name)
+	}
+	namePtr := &name
+	update := func(updates ToolUpdates) {
+		m.registryMu.Lock()
+		if _, ok := m.registerdTools[*namePtr]; !ok {
+			m.registryMu.Unlock()
+			fmt.Println("tool not found")
+			return
+		}
+		before := listChange{registration: Registration{Kind: REGISTRATION_KIND_TOOL, Name: *namePtr}, access: m.registerdTools[*namePtr].access}
+		if updates.Name != "" && updates.Name != *namePtr {
+			toolCopy := m.registerdTools[*namePtr]
+			delete(m.registerdTools, *namePtr)
+			m.registerdTools[updates.Name] = toolCopy
+
+			// 参照する値を更新することで、nameの値が変わった場合でも、以降の処理 & Disable/Enable/Removeが正しく動作するようにする
+			*namePtr = updates.Name
+		}
+		if updates.Description != "" {
+			m.registerdTools[*namePtr].description = updates.Description
+		}
+		if updates.ParamsSchema != nil {
+			m.registerdTools[*namePtr].propertySchema = updates.ParamsSchema
+			m.registerdTools[*namePtr].inputSchema = nil
+		}
+		if updates.InputSchema != nil {
+			properties := updates.InputSchema.Properties
+			if properties == nil {
+				properties = schema.PropertySchema{}
 			}
-			m.sendToolListChanged()
-		},
+			m.registerdTools[*namePtr].propertySchema = properties
+			m.registerdTools[*namePtr].inputSchema = updates.InputSchema
+		}
+		if updates.OutputSchema != nil {
+			m.registerdTools[*namePtr].outputSchema = updates.OutputSchema
+		}
+		if updates.Handler != nil {
+			m.registerdTools[*namePtr].callback = updates.Handler
+		}
+		if updates.Annotations != nil {
+			m.registerdTools[*namePtr].annotations = updates.Annotations
+		}
+		if updates.Enabled != nil {
+			m.registerdTools[*namePtr].enabled = *updates.Enabled
+		}
+		if updates.Access != nil {
+			m.registerdTools[*namePtr].access = updates.Access
+		}
+		after := listChange{registration: Registration{Kind: REGISTRATION_KIND_TOOL, Name: *namePtr}, access: m.registerdTools[*namePtr].access}
+		m.registryMu.Unlock()
+		m.sendToolListChanged(before, after)
+	}
+	registeredTool.enabled = true
+	registeredTool.Disable = func() {
+		update(ToolUpdates{Enabled: &[]bool{false}[0]})
 	}
+	registeredTool.Enable = func() {
+		update(ToolUpdates{Enabled: &[]bool{true}[0]})
+	}
+	registeredTool.Remove = func() {
+		m.registryMu.Lock()
+		defer m.registryMu.Unlock()
+		delete(m.registerdTools, *namePtr)
+	}
+	registeredTool.Update = update
 	m.registerdTools[*namePtr] = &registeredTool
 
 	_ = m.setToolRequestHandlers()
-	m.sendToolListChanged()
+	m.registryMu.Unlock()
+	m.sendToolListChanged(listChange{registration: Registration{Kind: REGISTRATION_KIND_TOOL, Name: name}})
 	return &registeredTool, nil
 }
 
@@ -264,61 +405,82 @@ func (m *McpServer) Prompt(
 	argsSchema []schema.PromptAugmentSchema,
 	callback PromptCallback,
 ) (*RegisteredPrompt, error) {
+	return m.PromptWithExchange(name, description, argsSchema, callback.handler())
+}
+
+// コールバックがctxとExchangeを受け取る点以外は、Promptと同じ
+func (m *McpServer) PromptWithExchange(
+	name string,
+	description string,
+	argsSchema []schema.PromptAugmentSchema,
+	callback PromptHandler,
+) (*RegisteredPrompt, error) {
+	m.registryMu.Lock()
 	if m.registeredPrompts[name] != nil {
+		m.registryMu.Unlock()
 		return nil, fmt.Errorf("prompt %s is already registered", name)
 	}
 	namePtr := &name
+	update := func(updates PromptUpdates) {
+		m.registryMu.Lock()
+		if _, ok := m.registeredPrompts[*namePtr]; !ok {
+			m.registryMu.Unlock()
+			fmt.Println("prompt not found")
+			return
+		}
+		before := listChange{registration: Registration{Kind: REGISTRATION_KIND_PROMPT, Name: *namePtr}, access: m.registeredPrompts[*namePtr].access}
+		if updates.Name != "" && updates.Name != *namePtr {
+			promptCopy := m.registeredPrompts[*namePtr]
+			delete(m.registeredPrompts, *namePtr)
+			m.registeredPrompts[updates.Name] = promptCopy
+			// 参照する値を更新することで、nameの値が変わった場合でも、以降の処理 & Disable/Enable/Removeが正しく動作するようにする
+			*namePtr = updates.Name
+		}
+		if updates.Description != "" {
+			m.registeredPrompts[*namePtr].description = updates.Description
+		}
+		if updates.ArgsSchema != nil {
+			m.registeredPrompts[*namePtr].argsSchema = updates.ArgsSchema
+		}
+		if updates.Callback != nil {
+			m.registeredPrompts[*namePtr].callback = updates.Callback.handler()
+		}
+		if updates.Handler != nil {
+			m.registeredPrompts[*namePtr].callback = updates.Handler
+		}
+		if updates.Enabled != nil {
+			m.registeredPrompts[*namePtr].enabled = *updates.Enabled
+		}
+		if updates.Access != nil {
+			m.registeredPrompts[*namePtr].access = updates.Access
+		}
+		after := listChange{registration: Registration{Kind: REGISTRATION_KIND_PROMPT, Name: *namePtr}, access: m.registeredPrompts[*namePtr].access}
+		m.registryMu.Unlock()
+		m.sendPromptListChanged(before, after)
+	}
 	registeredPrompt := RegisteredPrompt{
 		description: description,
 		argsSchema:  argsSchema,
 		callback:    callback,
 		enabled:     true,
 		Disable: func() {
-			if _, ok := m.registeredPrompts[*namePtr]; !ok {
-				fmt.Println("prompt not found")
-				return
-			}
 			disabled := false
-			m.registeredPrompts[*namePtr].Update(PromptUpdates{Enabled: &disabled})
+			update(PromptUpdates{Enabled: &disabled})
 		},
 		Enable: func() {
-			if _, ok := m.registeredPrompts[*namePtr]; !ok {
-				fmt.Println("prompt not found")
-				return
-			}
 			enabled := true
-			m.registeredPrompts[*namePtr].Update(PromptUpdates{Enabled: &enabled})
+			update(PromptUpdates{Enabled: &enabled})
 		},
-		Remove: func() { delete(m.registeredPrompts, *namePtr) },
-		Update: func(updates PromptUpdates) {
-			if _, ok := m.registeredPrompts[*namePtr]; !ok {
-				fmt.Println("prompt not found")
-				return
-			}
-			if updates.Name != "" && updates.Name != *namePtr {
-				promptCopy := m.registeredPrompts[*namePtr]
-				delete(m.registeredPrompts, *namePtr)
-				m.registeredPrompts[updates.Name] = promptCopy
-				// 参照する値を更新することで、nameの値が変わった場合でも、以降の処理 & Disable/Enable/Removeが正しく動作するようにする
-				*namePtr = updates.Name
-			}
-			if updates.Description != "" {
-				m.registeredPrompts[*namePtr].description = updates.Description
-			}
-			if updates.ArgsSchema != nil {
-				m.registeredPrompts[*namePtr].argsSchema = updates.ArgsSchema
-			}
-			if updates.Callback != nil {
-				m.registeredPrompts[*namePtr].callback = updates.Callback
-			}
-			if updates.Enabled != nil {
-				m.registeredPrompts[*namePtr].enabled = *updates.Enabled
-			}
-			m.sendPromptListChanged()
+		Remove: func() {
+			m.registryMu.Lock()
+			defer m.registryMu.Unlock()
+			delete(m.registeredPrompts, *namePtr)
 		},
+		Update: update,
 	}
 	m.registeredPrompts[*namePtr] = &registeredPrompt
 	_ = m.setPromptRequestHandlers()
-	m.sendPromptListChanged()
+	m.registryMu.Unlock()
+	m.sendPromptListChanged(listChange{registration: Registration{Kind: REGISTRATION_KIND_PROMPT, Name: name}})
 	return &registeredPrompt, nil
 }
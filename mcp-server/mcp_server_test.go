@@ -435,7 +435,7 @@ func TestMcpServer_Tool(t *testing.T) {
 				annotations: &schema.ToolAnotationsSchema{
 					Title: "Test Tool",
 				},
-				callback: func(args map[string]any) (schema.CallToolResultSchema, error) {
+				callback: ToolCallback(func(args map[string]any) (schema.CallToolResultSchema, error) {
 					params1 := args["param1"].(int)
 					params2 := args["param2"].(int)
 
@@ -448,7 +448,7 @@ func TestMcpServer_Tool(t *testing.T) {
 							},
 						},
 					}, nil
-				},
+				}).handler(),
 				enabled: true,
 			},
 			isExpectedErr: false,
@@ -1,6 +1,7 @@
 package mcpserver
 
 import (
+	"context"
 	"net/url"
 
 	"github.com/kakkky/mcp-sdk-go/shared/schema"
@@ -9,8 +10,9 @@ import (
 type RegisteredResource struct {
 	name         string
 	metadata     *schema.ResourceMetadata
-	readCallback ReadResourceCallback[schema.ResourceContentSchema]
+	readCallback ReadResourceHandler
 	enabled      bool
+	access       *AccessPolicy
 	Enable       func()
 	Disable      func()
 	Update       func(ResourceUpdates)
@@ -21,16 +23,33 @@ type ResourceUpdates struct {
 	Uri      string
 	Metadata *schema.ResourceMetadata
 	Callback *ReadResourceCallback[schema.ResourceContentSchema]
+	Handler  ReadResourceHandler
 	Enabled  *bool
+	Access   *AccessPolicy
 }
 
 type ReadResourceCallback[T schema.ResourceContentSchema] func(url url.URL) (schema.ReadResourceResultSchema, error)
 
+// ctxはクライアントがリクエストを取り消すか、接続が閉じられると取り消される
+// ctxからは、auth.AuthInfoFromContextで認証情報を、ExchangeFromContextでExchangeを取り出せる
+type ReadResourceHandler func(ctx context.Context, exchange *Exchange, url url.URL) (schema.ReadResourceResultSchema, error)
+
+// Exchangeを受け取らないコールバックを、ReadResourceHandlerとして呼び出せるようにする
+func (c ReadResourceCallback[T]) handler() ReadResourceHandler {
+	if c == nil {
+		return nil
+	}
+	return func(ctx context.Context, exchange *Exchange, url url.URL) (schema.ReadResourceResultSchema, error) {
+		return c(url)
+	}
+}
+
 type RegisteredResourceTemplate struct {
 	resourceTemplate *ResourceTemplate
 	metadata         *schema.ResourceMetadata
-	readCallback     ReadResourceTemplateCallback[schema.ResourceContentSchema]
+	readCallback     ReadResourceTemplateHandler
 	enabled          bool
+	access           *AccessPolicy
 	Enable           func()
 	Disable          func()
 	Update           func(ResourceTemplateUpdates)
@@ -42,39 +61,78 @@ type ResourceTemplateUpdates struct {
 	Template *ResourceTemplate
 	Metadata *schema.ResourceMetadata
 	Callback *ReadResourceTemplateCallback[schema.ResourceContentSchema]
+	Handler  ReadResourceTemplateHandler
 	Enabled  *bool
+	Access   *AccessPolicy
 }
 
 type ReadResourceTemplateCallback[T schema.ResourceContentSchema] func(url url.URL, variables map[string]any) (schema.ReadResourceResultSchema, error)
 
+type ReadResourceTemplateHandler func(ctx context.Context, exchange *Exchange, url url.URL, variables map[string]any) (schema.ReadResourceResultSchema, error)
+
+func (c ReadResourceTemplateCallback[T]) handler() ReadResourceTemplateHandler {
+	if c == nil {
+		return nil
+	}
+	return func(ctx context.Context, exchange *Exchange, url url.URL, variables map[string]any) (schema.ReadResourceResultSchema, error) {
+		return c(url, variables)
+	}
+}
+
 type RegisteredTool struct {
 	description    string
 	propertySchema schema.PropertySchema
-	annotations    *schema.ToolAnotationsSchema
-	callback       ToolCallback
-	enabled        bool
-	Enable         func()
-	Remove         func()
-	Disable        func()
-	Update         func(ToolUpdates)
+	// 指定した場合、propertySchemaの代わりにtools/listで返す入力スキーマ
+	inputSchema *schema.InputSchema
+	// 指定した場合、結果のstructuredContentをこのスキーマで検証する
+	outputSchema *schema.JsonSchema
+	annotations  *schema.ToolAnotationsSchema
+	callback     ToolHandler
+	enabled      bool
+	access       *AccessPolicy
+	Enable       func()
+	Remove       func()
+	Disable      func()
+	Update       func(ToolUpdates)
+
+	// trueの場合、outputSchemaがあってもstructuredContentの無い結果を許可する
+	// AddToolのOutがポインタで、nilを返した場合に使用する
+	structuredContentOptional bool
 }
 
 type ToolUpdates struct {
 	Name         string
 	Description  string
 	ParamsSchema schema.PropertySchema
-	callback     ToolCallback
+	// properties以外のキーワード（required、additionalPropertiesなど）も含む入力スキーマ
+	InputSchema *schema.InputSchema
+	// 結果のstructuredContentのスキーマ
+	OutputSchema *schema.JsonSchema
+	Handler      ToolHandler
 	Annotations  *schema.ToolAnotationsSchema
 	Enabled      *bool
+	Access       *AccessPolicy
 }
 
 type ToolCallback func(args map[string]any) (schema.CallToolResultSchema, error)
 
+type ToolHandler func(ctx context.Context, exchange *Exchange, args map[string]any) (schema.CallToolResultSchema, error)
+
+func (c ToolCallback) handler() ToolHandler {
+	if c == nil {
+		return nil
+	}
+	return func(ctx context.Context, exchange *Exchange, args map[string]any) (schema.CallToolResultSchema, error) {
+		return c(args)
+	}
+}
+
 type RegisteredPrompt struct {
 	description string
 	argsSchema  []schema.PromptAugmentSchema
-	callback    PromptCallback
+	callback    PromptHandler
 	enabled     bool
+	access      *AccessPolicy
 	Enable      func()
 	Disable     func()
 	Remove      func()
@@ -86,7 +144,20 @@ type PromptUpdates struct {
 	Description string
 	ArgsSchema  []schema.PromptAugmentSchema
 	Callback    PromptCallback
+	Handler     PromptHandler
 	Enabled     *bool
+	Access      *AccessPolicy
 }
 
 type PromptCallback func(args []schema.PromptAugmentSchema) (schema.GetPromptResultSchema, error)
+
+type PromptHandler func(ctx context.Context, exchange *Exchange, args []schema.PromptAugmentSchema) (schema.GetPromptResultSchema, error)
+
+func (c PromptCallback) handler() PromptHandler {
+	if c == nil {
+		return nil
+	}
+	return func(ctx context.Context, exchange *Exchange, args []schema.PromptAugmentSchema) (schema.GetPromptResultSchema, error) {
+		return c(args)
+	}
+}
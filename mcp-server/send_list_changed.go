@@ -1,19 +1,46 @@
 package mcpserver
 
-func (m *McpServer) sendResourceListChanged() {
-	if m.isConnected() {
-		_ = m.Server.SendResourceListChanged()
+// list_changedの通知先を決めるための、変更された登録とそのアクセスポリシー
+type listChange struct {
+	registration Registration
+	access       *AccessPolicy
+}
+
+// changesのいずれかを公開し、アクセスポリシーで許可されているセッションにのみ通知する
+// 変更前後で登録の名前やアクセスポリシーが変わる場合は、両方を渡す
+func (m *McpServer) sendResourceListChanged(changes ...listChange) {
+	for _, session := range m.affectedSessions(changes) {
+		_ = session.Server.SendResourceListChanged()
+	}
+}
+
+func (m *McpServer) sendToolListChanged(changes ...listChange) {
+	for _, session := range m.affectedSessions(changes) {
+		_ = session.Server.SendToolListChanged()
 	}
 }
 
-func (m *McpServer) sendToolListChanged() {
-	if m.isConnected() {
-		_ = m.Server.SendToolListChanged()
+func (m *McpServer) sendPromptListChanged(changes ...listChange) {
+	for _, session := range m.affectedSessions(changes) {
+		_ = session.Server.SendPromptListChanged()
 	}
 }
 
-func (m *McpServer) sendPromptListChanged() {
-	if m.isConnected() {
-		_ = m.Server.SendPromptListChanged()
+// 認証情報はリクエストごとに渡されるため、アクセスポリシーはセッションが最後に送ったリクエストの認証情報で判定する
+// 一覧の取得などのリクエストをまだ送っていないセッションは、認証されていない呼び出し元として扱う
+func (m *McpServer) affectedSessions(changes []listChange) []*Session {
+	sessions := m.connectedSessions()
+	m.registryMu.RLock()
+	defer m.registryMu.RUnlock()
+	var affected []*Session
+	for _, session := range sessions {
+		authInfo := session.lastAuthInfo()
+		for _, change := range changes {
+			if m.isVisible(session, change.registration) && change.access.allows(authInfo) {
+				affected = append(affected, session)
+				break
+			}
+		}
 	}
+	return affected
 }
@@ -13,6 +13,10 @@ func (s *Server) validateCapabilityForMethod(method string) error {
 		if s.clientCapabilities.Roots == nil {
 			return fmt.Errorf("client does not support roots (required for %s)", method)
 		}
+	case "elicitation/create":
+		if s.clientCapabilities.Elicitation == nil {
+			return fmt.Errorf("client does not support elicitation (required for %s)", method)
+		}
 	case "ping":
 		break
 	}
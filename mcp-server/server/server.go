@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
@@ -82,13 +83,27 @@ func (s *Server) onInitialize(request schema.JsonRpcRequest) (*schema.Initialize
 	}, nil
 }
 
+// initializeリクエストでクライアントから通知された情報。初期化前はゼロ値となる
+func (s *Server) ClientCapabilities() schema.ClientCapabilities {
+	return s.clientCapabilities
+}
+
+func (s *Server) ClientVersion() schema.Implementation {
+	return s.clientVersion
+}
+
 // クライアントから initialized Notification が送られたときにチャネルに通知が送られる
 // この通知を受信後、OperationPhaseが開始できる
 // Connect後にServerからリクエストを送る場合は、このチャネル受信後に行う必要がある
 var OperationPhaseStartedNotify = make(chan struct{}, 1)
 
 func (s *Server) onInitialized() error {
-	OperationPhaseStartedNotify <- struct{}{}
+	// 複数セッションを扱うトランスポートでは、誰も受信していない通知が溜まっていると
+	// 後続セッションの受信処理がブロックされるため、既に通知済みの場合は破棄する
+	select {
+	case OperationPhaseStartedNotify <- struct{}{}:
+	default:
+	}
 	return nil
 }
 
@@ -100,36 +115,53 @@ func (s *Server) Ping() (schema.Result, error) {
 }
 
 func (s *Server) CreateMessage(params any, contentType string) (schema.Result, error) {
+	request, resultSchema, err := createMessageRequestOf(params, contentType)
+	if err != nil {
+		return nil, err
+	}
+	return s.Request(request, resultSchema)
+}
+
+// ctxが取り消された場合は応答を待たずにctx.Err()を返す点以外は、CreateMessageと同じ
+func (s *Server) CreateMessageWithContext(ctx context.Context, params any, contentType string) (schema.Result, error) {
+	request, resultSchema, err := createMessageRequestOf(params, contentType)
+	if err != nil {
+		return nil, err
+	}
+	return s.RequestWithContext(ctx, request, resultSchema)
+}
+
+func createMessageRequestOf(params any, contentType string) (schema.Request, any, error) {
 	switch contentType {
 	case "text":
 		typedParams, ok := params.(schema.CreateMessageRequestParams[schema.TextContentSchema])
 		if !ok {
-			return nil, fmt.Errorf("invalid params type: %T", params)
+			return nil, nil, fmt.Errorf("invalid params type: %T", params)
 		}
-		return s.Request(&schema.CreateMessageRequestSchema[schema.TextContentSchema]{
+		return &schema.CreateMessageRequestSchema[schema.TextContentSchema]{
 			MethodName: "sampling/createMessage",
 			ParamsData: typedParams,
-		}, &schema.CreateMessageResultSchema[schema.TextContentSchema]{})
+		}, &schema.CreateMessageResultSchema[schema.TextContentSchema]{}, nil
 	case "image":
 		typedParams, ok := params.(schema.CreateMessageRequestParams[schema.ImageContentSchema])
 		if !ok {
-			return nil, fmt.Errorf("invalid params type: %T", params)
+			return nil, nil, fmt.Errorf("invalid params type: %T", params)
 		}
-		return s.Request(&schema.CreateMessageRequestSchema[schema.ImageContentSchema]{
+		return &schema.CreateMessageRequestSchema[schema.ImageContentSchema]{
 			MethodName: "sampling/createMessage",
 			ParamsData: typedParams,
-		}, &schema.CreateMessageResultSchema[schema.ImageContentSchema]{})
+		}, &schema.CreateMessageResultSchema[schema.ImageContentSchema]{}, nil
 	case "audio":
 		typedParams, ok := params.(schema.CreateMessageRequestParams[schema.AudioContentSchema])
 		if !ok {
-			return nil, fmt.Errorf("invalid params type: %T", params)
+			return nil, nil, fmt.Errorf("invalid params type: %T", params)
 		}
-		return s.Request(&schema.CreateMessageRequestSchema[schema.AudioContentSchema]{
+		return &schema.CreateMessageRequestSchema[schema.AudioContentSchema]{
 			MethodName: "sampling/createMessage",
 			ParamsData: typedParams,
-		}, &schema.CreateMessageResultSchema[schema.AudioContentSchema]{})
+		}, &schema.CreateMessageResultSchema[schema.AudioContentSchema]{}, nil
 	}
-	return nil, fmt.Errorf("invalid content type: %s", contentType)
+	return nil, nil, fmt.Errorf("invalid content type: %s", contentType)
 }
 
 func (s *Server) ListRoots() (schema.Result, error) {
@@ -138,6 +170,28 @@ func (s *Server) ListRoots() (schema.Result, error) {
 	}, &schema.ListRootsResultSchema{})
 }
 
+// ctxが取り消された場合は応答を待たずにctx.Err()を返す点以外は、ListRootsと同じ
+func (s *Server) ListRootsWithContext(ctx context.Context) (schema.Result, error) {
+	return s.RequestWithContext(ctx, &schema.ListRootsRequestSchema{
+		MethodName: "roots/list",
+	}, &schema.ListRootsResultSchema{})
+}
+
+func (s *Server) Elicit(params schema.ElicitRequestParams) (schema.Result, error) {
+	return s.Request(&schema.ElicitRequestSchema{
+		MethodName: "elicitation/create",
+		ParamsData: params,
+	}, &schema.ElicitResultSchema{})
+}
+
+// ctxが取り消された場合は応答を待たずにctx.Err()を返す点以外は、Elicitと同じ
+func (s *Server) ElicitWithContext(ctx context.Context, params schema.ElicitRequestParams) (schema.Result, error) {
+	return s.RequestWithContext(ctx, &schema.ElicitRequestSchema{
+		MethodName: "elicitation/create",
+		ParamsData: params,
+	}, &schema.ElicitResultSchema{})
+}
+
 func (s *Server) SendLoggingMessage(params schema.LoggingMessageNotificationParams) error {
 	return s.Notificate(&schema.LoggingMessageNotificationSchema{
 		MethodName: "notifications/message",
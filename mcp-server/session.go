@@ -0,0 +1,232 @@
+package mcpserver
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/kakkky/mcp-sdk-go/mcp-server/server"
+	"github.com/kakkky/mcp-sdk-go/shared/auth"
+	"github.com/kakkky/mcp-sdk-go/shared/protocol"
+	"github.com/kakkky/mcp-sdk-go/shared/schema"
+)
+
+type RegistrationKind string
+
+const (
+	REGISTRATION_KIND_TOOL              RegistrationKind = "tool"
+	REGISTRATION_KIND_RESOURCE          RegistrationKind = "resource"
+	REGISTRATION_KIND_RESOURCE_TEMPLATE RegistrationKind = "resourceTemplate"
+	REGISTRATION_KIND_PROMPT            RegistrationKind = "prompt"
+)
+
+// セッションに公開するかどうかを判定する、登録済みのツール、リソース、プロンプト
+type Registration struct {
+	Kind RegistrationKind
+	// ツール名、リソースのURI、リソーステンプレート名、プロンプト名
+	Name string
+}
+
+// セッションごとに、登録を公開するかどうかを返す関数
+// 公開しない登録は一覧に含まれず、呼び出した場合は登録されていない場合と同じエラーとなる
+// 登録の一覧を参照している間に呼び出されるため、関数内でツールなどの登録や変更を行ってはならない
+type VisibilityFunc func(session *Session, registration Registration) bool
+
+// McpServerに接続した1つのクライアントとのセッション
+type Session struct {
+	// セッションの下位のServer。このセッションのクライアントへの通知やリクエストに使用する
+	Server *server.Server
+
+	mcpServer *McpServer
+	// Connectが完了したかどうか。接続が終了したセッションを取り除くために使用する
+	started    bool
+	mu         sync.RWMutex
+	attributes map[string]any
+	// 最後に受け取ったリクエストの認証情報。list_changedの通知先をAccessPolicyで絞り込むために使用する
+	authInfo *auth.AuthInfo
+}
+
+// initializeリクエストでクライアントが通知したクライアントの情報
+func (s *Session) ClientInfo() schema.Implementation {
+	return s.Server.ClientVersion()
+}
+
+// initializeリクエストでクライアントが通知したケーパビリティ
+func (s *Session) ClientCapabilities() schema.ClientCapabilities {
+	return s.Server.ClientCapabilities()
+}
+
+// SetAttributeで設定した値を返す
+func (s *Session) Attribute(key string) (any, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	value, ok := s.attributes[key]
+	return value, ok
+}
+
+// セッションに任意の属性を設定する。VisibilityFuncから参照できる
+// 属性の変更によってセッションに公開する登録が変わった場合は、このセッションにのみlist_changedを通知する
+func (s *Session) SetAttribute(key string, value any) {
+	before := s.mcpServer.visibleRegistrations(s)
+	s.mu.Lock()
+	if s.attributes == nil {
+		s.attributes = make(map[string]any)
+	}
+	s.attributes[key] = value
+	s.mu.Unlock()
+	s.mcpServer.notifyVisibilityChanged(s, before)
+}
+
+// 認証情報はリクエストごとに渡されるため、セッションでは最後に受け取ったものを保持する
+func (s *Session) setAuthInfo(authInfo *auth.AuthInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.authInfo = authInfo
+}
+
+func (s *Session) lastAuthInfo() *auth.AuthInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.authInfo
+}
+
+func (s *Session) Close() error {
+	return s.Server.Close()
+}
+
+func (s *Session) isConnected() bool {
+	return s.Server.Transport() != nil
+}
+
+// 1つのMcpServerで複数のクライアントと接続する場合に使用する
+// セッションごとに下位のServerを生成し、登録済みのツール、リソース、プロンプトを共有する
+// Streamable HTTPなど、セッションごとにトランスポートを生成するトランスポートで、接続ごとに呼び出す
+func (m *McpServer) ConnectSession(transport protocol.Transport) (*Session, error) {
+	options := server.ServerOptions{}
+	if m.options != nil {
+		options = *m.options
+	}
+	m.sessionsMu.Lock()
+	options.Capabilities = protocol.MergeCapabilities(options.Capabilities, m.capabilities)
+	session := &Session{
+		Server:    server.NewServer(m.serverInfo, &options),
+		mcpServer: m,
+	}
+	for _, handler := range m.requestHandlers {
+		m.installRequestHandler(session, handler)
+	}
+	m.sessions = append(m.sessions, session)
+	m.sessionsMu.Unlock()
+
+	if err := session.Server.Connect(transport); err != nil {
+		m.removeSession(session)
+		return nil, err
+	}
+	m.sessionsMu.Lock()
+	session.started = true
+	m.sessionsMu.Unlock()
+	return session, nil
+}
+
+// 接続中の全てのセッション
+func (m *McpServer) Sessions() []*Session {
+	return m.connectedSessions()
+}
+
+// セッションごとに公開する登録を決める関数を設定する
+// 公開する登録が変わったセッションには、list_changedを通知する
+func (m *McpServer) SetVisibility(visibility VisibilityFunc) {
+	sessions := m.connectedSessions()
+	befores := make([]map[Registration]bool, len(sessions))
+	for i, session := range sessions {
+		befores[i] = m.visibleRegistrations(session)
+	}
+	m.registryMu.Lock()
+	m.visibility = visibility
+	m.registryMu.Unlock()
+	for i, session := range sessions {
+		m.notifyVisibilityChanged(session, befores[i])
+	}
+}
+
+// registryMuを保持した状態で呼び出す
+func (m *McpServer) isVisible(session *Session, registration Registration) bool {
+	if m.visibility == nil || session == nil {
+		return true
+	}
+	return m.visibility(session, registration)
+}
+
+// セッションに公開している、有効な登録の一覧
+func (m *McpServer) visibleRegistrations(session *Session) map[Registration]bool {
+	m.registryMu.RLock()
+	defer m.registryMu.RUnlock()
+	visible := make(map[Registration]bool)
+	add := func(registration Registration, enabled bool) {
+		if enabled && m.isVisible(session, registration) {
+			visible[registration] = true
+		}
+	}
+	for name, tool := range m.registerdTools {
+		add(Registration{Kind: REGISTRATION_KIND_TOOL, Name: name}, tool.enabled)
+	}
+	for uri, resource := range m.registeredResources {
+		add(Registration{Kind: REGISTRATION_KIND_RESOURCE, Name: uri}, resource.enabled)
+	}
+	for name, template := range m.registeredResourceTemplates {
+		add(Registration{Kind: REGISTRATION_KIND_RESOURCE_TEMPLATE, Name: name}, template.enabled)
+	}
+	for name, prompt := range m.registeredPrompts {
+		add(Registration{Kind: REGISTRATION_KIND_PROMPT, Name: name}, prompt.enabled)
+	}
+	return visible
+}
+
+// 公開する登録が変わった種類のlist_changedのみを通知する
+func (m *McpServer) notifyVisibilityChanged(session *Session, before map[Registration]bool) {
+	if !session.isConnected() {
+		return
+	}
+	after := m.visibleRegistrations(session)
+	changed := make(map[RegistrationKind]bool)
+	for registration := range before {
+		if !after[registration] {
+			changed[registration.Kind] = true
+		}
+	}
+	for registration := range after {
+		if !before[registration] {
+			changed[registration.Kind] = true
+		}
+	}
+	if changed[REGISTRATION_KIND_TOOL] {
+		_ = session.Server.SendToolListChanged()
+	}
+	if changed[REGISTRATION_KIND_RESOURCE] || changed[REGISTRATION_KIND_RESOURCE_TEMPLATE] {
+		_ = session.Server.SendResourceListChanged()
+	}
+	if changed[REGISTRATION_KIND_PROMPT] {
+		_ = session.Server.SendPromptListChanged()
+	}
+}
+
+// 接続中のセッションを返す。接続が終了したセッションは取り除く
+func (m *McpServer) connectedSessions() []*Session {
+	m.sessionsMu.Lock()
+	defer m.sessionsMu.Unlock()
+	connected := []*Session{}
+	m.sessions = slices.DeleteFunc(m.sessions, func(session *Session) bool {
+		if session.isConnected() {
+			connected = append(connected, session)
+			return false
+		}
+		// McpServer.Connectで接続するセッションは、再接続できるため取り除かない
+		return session.started && session != m.defaultSession
+	})
+	return connected
+}
+
+func (m *McpServer) removeSession(session *Session) {
+	m.sessionsMu.Lock()
+	defer m.sessionsMu.Unlock()
+	m.sessions = slices.DeleteFunc(m.sessions, func(s *Session) bool { return s == session })
+}
@@ -0,0 +1,200 @@
+package mcpserver
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kakkky/mcp-sdk-go/mcp-server/server"
+	"github.com/kakkky/mcp-sdk-go/mcp-server/transport"
+	"github.com/kakkky/mcp-sdk-go/shared/auth"
+	mcperr "github.com/kakkky/mcp-sdk-go/shared/mcp-err"
+	"github.com/kakkky/mcp-sdk-go/shared/protocol"
+	"github.com/kakkky/mcp-sdk-go/shared/schema"
+)
+
+func TestMcpServer_SessionVisibility(t *testing.T) {
+	sut := NewMcpServer(schema.Implementation{Name: "server"}, &server.ServerOptions{
+		Capabilities: schema.ServerCapabilities{Tools: &schema.Tools{ListChanged: true}},
+	})
+	callback := func(args map[string]any) (schema.CallToolResultSchema, error) {
+		return schema.CallToolResultSchema{}, nil
+	}
+	if _, err := sut.Tool("basic", "basic tool", schema.PropertySchema{}, nil, callback); err != nil {
+		t.Fatalf("failed to register tool: %v", err)
+	}
+	beta, err := sut.Tool("beta", "beta tool", schema.PropertySchema{}, nil, callback)
+	if err != nil {
+		t.Fatalf("failed to register tool: %v", err)
+	}
+	// betaツールは、beta-clientか、beta属性を持つセッションにのみ公開する
+	sut.SetVisibility(func(session *Session, registration Registration) bool {
+		if registration.Kind != REGISTRATION_KIND_TOOL || registration.Name != "beta" {
+			return true
+		}
+		enabled, _ := session.Attribute("beta")
+		return session.ClientInfo().Name == "beta-client" || enabled == true
+	})
+
+	connect := func(clientName string) (*Session, *testConnection) {
+		var session *Session
+		conn := newTestConnection(t, func(transport protocol.Transport) error {
+			var err error
+			session, err = sut.ConnectSession(transport)
+			return err
+		})
+		conn.request(&schema.InitializeRequestSchema{
+			MethodName: "initialize",
+			ParamsData: schema.InitializeRequestParams{ClientInfo: schema.Implementation{Name: clientName}},
+		}, nil)
+		return session, conn
+	}
+	listTools := func(conn *testConnection) []string {
+		response, ok := conn.request(&schema.ListToolsRequestSchema{MethodName: "tools/list"}, nil).(schema.JsonRpcResponse)
+		if !ok {
+			t.Fatal("tools/list failed")
+		}
+		var names []string
+		for _, tool := range response.Result.(*schema.ListToolsResultSchema).Tools {
+			names = append(names, tool.Name)
+		}
+		sort.Strings(names)
+		return names
+	}
+	sessionA, connA := connect("client")
+	_, connB := connect("beta-client")
+
+	if diff := cmp.Diff([]string{"basic"}, listTools(connA)); diff != "" {
+		t.Errorf("tools/list of session A mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"basic", "beta"}, listTools(connB)); diff != "" {
+		t.Errorf("tools/list of session B mismatch (-want +got):\n%s", diff)
+	}
+	// 公開していないツールは、登録されていないツールと同じエラーとなる
+	response, ok := connA.request(&schema.CallToolRequestSchema{MethodName: "tools/call", ParamsData: schema.CallToolRequestParams{Name: "beta"}}, nil).(schema.JsonRpcError)
+	if !ok || response.Error.Code != mcperr.INVALID_PARAMS {
+		t.Errorf("tools/call of a hidden tool = %+v, want INVALID_PARAMS error", response)
+	}
+
+	// 変更したツールを公開しているセッションにのみ通知する
+	connA.notifications()
+	connB.notifications()
+	beta.Update(ToolUpdates{Description: "updated beta tool"})
+	if got := connA.notifications(); len(got) != 0 {
+		t.Errorf("session A was notified %v, want nothing", got)
+	}
+	if diff := cmp.Diff([]string{"notifications/tools/list_changed"}, connB.notifications()); diff != "" {
+		t.Errorf("notifications of session B mismatch (-want +got):\n%s", diff)
+	}
+
+	// 属性の変更で公開するツールが変わったセッションにのみ通知する
+	sessionA.SetAttribute("beta", true)
+	if diff := cmp.Diff([]string{"notifications/tools/list_changed"}, connA.notifications()); diff != "" {
+		t.Errorf("notifications of session A mismatch (-want +got):\n%s", diff)
+	}
+	if got := connB.notifications(); len(got) != 0 {
+		t.Errorf("session B was notified %v, want nothing", got)
+	}
+	if diff := cmp.Diff([]string{"basic", "beta"}, listTools(connA)); diff != "" {
+		t.Errorf("tools/list of session A mismatch (-want +got):\n%s", diff)
+	}
+
+	// 全てのセッションに公開するツールの追加は、全てのセッションに通知する
+	if _, err := sut.Tool("basic2", "another basic tool", schema.PropertySchema{}, nil, callback); err != nil {
+		t.Fatalf("failed to register tool: %v", err)
+	}
+	for name, conn := range map[string]*testConnection{"A": connA, "B": connB} {
+		if diff := cmp.Diff([]string{"notifications/tools/list_changed"}, conn.notifications()); diff != "" {
+			t.Errorf("notifications of session %s mismatch (-want +got):\n%s", name, diff)
+		}
+	}
+	if got := len(sut.Sessions()); got != 2 {
+		t.Errorf("Sessions() returned %d sessions, want 2", got)
+	}
+}
+
+func TestMcpServer_ConcurrentSessions(t *testing.T) {
+	sut := NewMcpServer(schema.Implementation{Name: "server"}, &server.ServerOptions{})
+	waiting, released := make(chan struct{}), make(chan struct{})
+	gotSubjects := make(chan string, 2)
+	if _, err := sut.ToolWithExchange("wait", "waits for release", schema.PropertySchema{}, nil, func(ctx context.Context, exchange *Exchange, args map[string]any) (schema.CallToolResultSchema, error) {
+		close(waiting)
+		<-released
+		gotSubjects <- exchange.AuthInfo.Subject
+		return schema.CallToolResultSchema{Content: []schema.ToolContentSchema{}}, nil
+	}); err != nil {
+		t.Fatalf("failed to register tool: %v", err)
+	}
+	if _, err := sut.ToolWithExchange("release", "releases wait", schema.PropertySchema{}, nil, func(ctx context.Context, exchange *Exchange, args map[string]any) (schema.CallToolResultSchema, error) {
+		close(released)
+		gotSubjects <- exchange.AuthInfo.Subject
+		return schema.CallToolResultSchema{Content: []schema.ToolContentSchema{}}, nil
+	}); err != nil {
+		t.Fatalf("failed to register tool: %v", err)
+	}
+	connect := func() *testConnection {
+		return newTestConnection(t, func(transport protocol.Transport) error {
+			_, err := sut.ConnectSession(transport)
+			return err
+		})
+	}
+	alice, bob := connect(), connect()
+
+	// 一方のセッションのコールバックの処理中も、他方のセッションのリクエストは処理される
+	callTool := func(conn *testConnection, name string, subject string) <-chan schema.JsonRpcMessage {
+		done := make(chan schema.JsonRpcMessage, 1)
+		go func() {
+			done <- conn.request(&schema.CallToolRequestSchema{MethodName: "tools/call", ParamsData: schema.CallToolRequestParams{Name: name}}, &auth.AuthInfo{Subject: subject})
+		}()
+		return done
+	}
+	waitDone := callTool(alice, "wait", "alice")
+	<-waiting
+	releaseDone := callTool(bob, "release", "bob")
+	for _, done := range []<-chan schema.JsonRpcMessage{releaseDone, waitDone} {
+		select {
+		case response := <-done:
+			if _, ok := response.(schema.JsonRpcResponse); !ok {
+				t.Fatalf("response = %+v, want result", response)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("request of the other session was blocked")
+		}
+	}
+	// それぞれのコールバックには、自身のリクエストの認証情報が渡される
+	if diff := cmp.Diff([]string{"bob", "alice"}, []string{<-gotSubjects, <-gotSubjects}); diff != "" {
+		t.Errorf("subjects mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// 入力がEOFに達しても、それまでに受信したリクエストには受信した順に応答してからトランスポートを閉じる
+func TestMcpServer_StdioFiniteInput(t *testing.T) {
+	sut := NewMcpServer(schema.Implementation{Name: "server"}, &server.ServerOptions{})
+	stdin := strings.NewReader(
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{},"clientInfo":{"name":"client","version":"1.0.0"}}}` + "\n" +
+			`{"jsonrpc":"2.0","id":2,"method":"ping"}` + "\n",
+	)
+	var stdout bytes.Buffer
+	stdio := transport.NewStdioServerTransportWithIO(context.Background(), stdin, &stdout)
+	if err := sut.Connect(stdio); err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	select {
+	case <-stdio.Done():
+	case <-time.After(time.Second):
+		t.Fatal("transport was not closed after EOF")
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 responses, got %d: %q", len(lines), stdout.String())
+	}
+	for i, wantId := range []string{`"id":1`, `"id":2`} {
+		if !strings.Contains(lines[i], wantId) || !strings.Contains(lines[i], `"result"`) {
+			t.Errorf("response %d = %s, want result with %s", i, lines[i], wantId)
+		}
+	}
+}
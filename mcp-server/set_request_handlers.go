@@ -8,6 +8,7 @@ import (
 	"github.com/kakkky/mcp-sdk-go/shared/schema"
 )
 
+// registryMuを保持した状態で呼び出す
 func (m *McpServer) setResourceRequestHandlers() error {
 	if m.isResourceHandlersInitialized {
 		return nil
@@ -19,15 +20,16 @@ func (m *McpServer) setResourceRequestHandlers() error {
 			return err
 		}
 	}
-	_ = m.Server.RegisterCapabilities(schema.ServerCapabilities{
+	m.registerCapabilities(schema.ServerCapabilities{
 		Resources: &schema.Resources{
 			ListChanged: true,
 		},
 	})
-	m.Server.SetRequestHandler(&schema.ListResourceRequestSchema{MethodName: "resources/list"}, func(req schema.JsonRpcRequest) (schema.Result, error) {
+	m.setRequestHandler(&schema.ListResourceRequestSchema{MethodName: "resources/list"}, func(exchange *Exchange, req schema.JsonRpcRequest) (schema.Result, error) {
+		m.registryMu.RLock()
 		var resources []schema.ResourceSchema
 		for uri, registerdResource := range m.registeredResources {
-			if registerdResource.enabled {
+			if registerdResource.enabled && m.isVisible(exchange.Session, Registration{Kind: REGISTRATION_KIND_RESOURCE, Name: uri}) && registerdResource.access.allows(exchange.AuthInfo) {
 				resources = append(resources, schema.ResourceSchema{
 					Uri:              uri,
 					Name:             registerdResource.name,
@@ -35,12 +37,18 @@ func (m *McpServer) setResourceRequestHandlers() error {
 				})
 			}
 		}
-
-		var templateResources []schema.ResourceSchema
-		for _, registerdResourceTemplate := range m.registeredResourceTemplates {
-			if registerdResourceTemplate.resourceTemplate.ListCallback() == nil {
+		var listedTemplates []RegisteredResourceTemplate
+		for name, registerdResourceTemplate := range m.registeredResourceTemplates {
+			if registerdResourceTemplate.resourceTemplate.ListCallback() == nil || !m.isVisible(exchange.Session, Registration{Kind: REGISTRATION_KIND_RESOURCE_TEMPLATE, Name: name}) || !registerdResourceTemplate.access.allows(exchange.AuthInfo) {
 				continue
 			}
+			listedTemplates = append(listedTemplates, *registerdResourceTemplate)
+		}
+		m.registryMu.RUnlock()
+
+		// リソーステンプレートのListコールバックは、ロックを解放してから呼び出す
+		var templateResources []schema.ResourceSchema
+		for _, registerdResourceTemplate := range listedTemplates {
 			result := registerdResourceTemplate.resourceTemplate.ListCallback()()
 			for _, resource := range result.Resources {
 				resource = schema.ResourceSchema{
@@ -58,9 +66,14 @@ func (m *McpServer) setResourceRequestHandlers() error {
 		}, nil
 	})
 
-	m.Server.SetRequestHandler(&schema.ListResourceTemplatesRequestSchema{MethodName: "resources/templates/list"}, func(req schema.JsonRpcRequest) (schema.Result, error) {
+	m.setRequestHandler(&schema.ListResourceTemplatesRequestSchema{MethodName: "resources/templates/list"}, func(exchange *Exchange, req schema.JsonRpcRequest) (schema.Result, error) {
+		m.registryMu.RLock()
+		defer m.registryMu.RUnlock()
 		var resourceTemplates []schema.ResourceTemplateSchema
 		for name, registerdResourceTemplate := range m.registeredResourceTemplates {
+			if !m.isVisible(exchange.Session, Registration{Kind: REGISTRATION_KIND_RESOURCE_TEMPLATE, Name: name}) || !registerdResourceTemplate.access.allows(exchange.AuthInfo) {
+				continue
+			}
 			resourceTemplate := schema.ResourceTemplateSchema{
 				Name:             name,
 				UriTemplate:      registerdResourceTemplate.resourceTemplate.uriTemp.ToString(),
@@ -73,7 +86,7 @@ func (m *McpServer) setResourceRequestHandlers() error {
 		}, nil
 	})
 
-	m.Server.SetRequestHandler(&schema.ReadResourceRequestSchema{MethodName: "resources/read"}, func(req schema.JsonRpcRequest) (schema.Result, error) {
+	m.setRequestHandler(&schema.ReadResourceRequestSchema{MethodName: "resources/read"}, func(exchange *Exchange, req schema.JsonRpcRequest) (schema.Result, error) {
 		request, ok := req.Request.(*schema.ReadResourceRequestSchema)
 		if !ok {
 			return nil, mcperr.NewMcpErr(mcperr.INVALID_REQUEST, "invalid request", nil)
@@ -82,30 +95,44 @@ func (m *McpServer) setResourceRequestHandlers() error {
 		if err != nil {
 			return nil, mcperr.NewMcpErr(mcperr.INVALID_PARAMS, fmt.Sprintf("invalid uri %s", uri.String()), nil)
 		}
-		resource, ok := m.registeredResources[uri.String()]
+		m.registryMu.RLock()
+		var resource RegisteredResource
+		registerdResource, ok := m.registeredResources[uri.String()]
+		if ok {
+			resource = *registerdResource
+		}
+		// セッションに公開していないリソースは、登録されていないものとして扱う
+		if ok && !m.isVisible(exchange.Session, Registration{Kind: REGISTRATION_KIND_RESOURCE, Name: uri.String()}) {
+			ok = false
+		}
+		m.registryMu.RUnlock()
 
 		// paramsのuriからリソースを取得できなかった場合、リソーステンプレートを確認する
 		if !ok {
-			for _, registerdResourceTemplate := range m.registeredResourceTemplates {
-				variables, err := registerdResourceTemplate.resourceTemplate.uriTemp.Match(uri.String())
-				if err != nil {
-					return nil, mcperr.NewMcpErr(mcperr.INVALID_PARAMS, fmt.Sprintf("invalid uri template %s", request.ParamsData.Uri), nil)
-				}
-				if variables != nil {
-					result, err := registerdResourceTemplate.readCallback(*uri, variables)
-					if err != nil {
-						return nil, mcperr.NewMcpErr(mcperr.INTERNAL_ERROR, fmt.Sprintf("failed to read resource %s", uri.String()), err)
-					}
-					return &result, nil
-				}
+			resourceTemplate, variables, err := m.matchResourceTemplate(exchange.Session, uri.String())
+			if err != nil {
+				return nil, mcperr.NewMcpErr(mcperr.INVALID_PARAMS, fmt.Sprintf("invalid uri template %s", request.ParamsData.Uri), nil)
+			}
+			if resourceTemplate == nil {
+				return nil, mcperr.NewMcpErr(mcperr.INVALID_PARAMS, fmt.Sprintf("resource %s not found", uri.String()), nil)
+			}
+			if !resourceTemplate.access.allows(exchange.AuthInfo) {
+				return nil, newPermissionDeniedErr("resource", uri.String(), resourceTemplate.access)
 			}
-			return nil, mcperr.NewMcpErr(mcperr.INVALID_PARAMS, fmt.Sprintf("resource %s not found", uri.String()), nil)
+			result, err := resourceTemplate.readCallback(exchange.ctx, exchange, *uri, variables)
+			if err != nil {
+				return nil, mcperr.NewMcpErr(mcperr.INTERNAL_ERROR, fmt.Sprintf("failed to read resource %s", uri.String()), err)
+			}
+			return &result, nil
 		}
 
 		if !resource.enabled {
 			return nil, mcperr.NewMcpErr(mcperr.INVALID_PARAMS, fmt.Sprintf("resource %s disabled", uri.String()), nil)
 		}
-		result, err := resource.readCallback(*uri)
+		if !resource.access.allows(exchange.AuthInfo) {
+			return nil, newPermissionDeniedErr("resource", uri.String(), resource.access)
+		}
+		result, err := resource.readCallback(exchange.ctx, exchange, *uri)
 		if err != nil {
 			return nil, mcperr.NewMcpErr(mcperr.INTERNAL_ERROR, fmt.Sprintf("failed to read resource %s", uri.String()), err)
 		}
@@ -118,6 +145,28 @@ func (m *McpServer) setResourceRequestHandlers() error {
 	return nil
 }
 
+// セッションに公開しているリソーステンプレートから、uriに一致するものを探す
+// 一致するテンプレートが無い場合はnilを返す
+func (m *McpServer) matchResourceTemplate(session *Session, uri string) (*RegisteredResourceTemplate, map[string]any, error) {
+	m.registryMu.RLock()
+	defer m.registryMu.RUnlock()
+	for name, registerdResourceTemplate := range m.registeredResourceTemplates {
+		if !m.isVisible(session, Registration{Kind: REGISTRATION_KIND_RESOURCE_TEMPLATE, Name: name}) {
+			continue
+		}
+		variables, err := registerdResourceTemplate.resourceTemplate.uriTemp.Match(uri)
+		if err != nil {
+			return nil, nil, err
+		}
+		if variables != nil {
+			resourceTemplate := *registerdResourceTemplate
+			return &resourceTemplate, variables, nil
+		}
+	}
+	return nil, nil, nil
+}
+
+// registryMuを保持した状態で呼び出す
 func (m *McpServer) setCompletionRequestHandlers() error {
 	if m.isCompletionHandlersInitialized {
 		return nil
@@ -125,7 +174,7 @@ func (m *McpServer) setCompletionRequestHandlers() error {
 	if err := m.Server.ValidateCanSetRequestHandler("completion/complete"); err != nil {
 		return err
 	}
-	m.Server.SetRequestHandler(&schema.CompleteRequestSchema{MethodName: "completion/complete"}, func(req schema.JsonRpcRequest) (schema.Result, error) {
+	m.setRequestHandler(&schema.CompleteRequestSchema{MethodName: "completion/complete"}, func(exchange *Exchange, req schema.JsonRpcRequest) (schema.Result, error) {
 		request, ok := req.Request.(*schema.CompleteRequestSchema)
 		if !ok {
 			return nil, mcperr.NewMcpErr(mcperr.INVALID_REQUEST, "invalid request", nil)
@@ -137,13 +186,13 @@ func (m *McpServer) setCompletionRequestHandlers() error {
 			if !ok {
 				return nil, mcperr.NewMcpErr(mcperr.INVALID_PARAMS, "invalid ref type", nil)
 			}
-			return m.handlePromptCompletion(*request, *ref)
+			return m.handlePromptCompletion(exchange, *request, *ref)
 		case "ref/resource":
 			ref, ok := params.Ref.(*schema.ResourceReferenceSchema)
 			if !ok {
 				return nil, mcperr.NewMcpErr(mcperr.INVALID_PARAMS, "invalid ref type", nil)
 			}
-			return m.handleResourceCompletion(*request, *ref)
+			return m.handleResourceCompletion(exchange, *request, *ref)
 		default:
 			return nil, mcperr.NewMcpErr(mcperr.INVALID_PARAMS, fmt.Sprintf("invalid completion reference : %s", params.Ref), nil)
 		}
@@ -152,6 +201,7 @@ func (m *McpServer) setCompletionRequestHandlers() error {
 	return nil
 }
 
+// registryMuを保持した状態で呼び出す
 func (m *McpServer) setToolRequestHandlers() error {
 	if m.isToolHandlersInitialized {
 		return nil
@@ -162,24 +212,24 @@ func (m *McpServer) setToolRequestHandlers() error {
 			return err
 		}
 	}
-	_ = m.Server.RegisterCapabilities(schema.ServerCapabilities{
+	m.registerCapabilities(schema.ServerCapabilities{
 		Tools: &schema.Tools{
 			ListChanged: true,
 		},
 	})
 
-	m.Server.SetRequestHandler(&schema.ListToolsRequestSchema{MethodName: "tools/list"}, func(jrr schema.JsonRpcRequest) (schema.Result, error) {
+	m.setRequestHandler(&schema.ListToolsRequestSchema{MethodName: "tools/list"}, func(exchange *Exchange, jrr schema.JsonRpcRequest) (schema.Result, error) {
+		m.registryMu.RLock()
+		defer m.registryMu.RUnlock()
 		var tools []schema.ToolSchema
 		for name, registerdTool := range m.registerdTools {
-			if registerdTool.enabled {
+			if registerdTool.enabled && m.isVisible(exchange.Session, Registration{Kind: REGISTRATION_KIND_TOOL, Name: name}) && registerdTool.access.allows(exchange.AuthInfo) {
 				tools = append(tools, schema.ToolSchema{
-					Name:        name,
-					Description: registerdTool.description,
-					InputSchema: schema.InputSchema{
-						Type:       "object",
-						Properties: registerdTool.propertySchema,
-					},
-					Annotations: registerdTool.annotations,
+					Name:         name,
+					Description:  registerdTool.description,
+					InputSchema:  registerdTool.resolvedInputSchema(),
+					OutputSchema: registerdTool.outputSchema,
+					Annotations:  registerdTool.annotations,
 				})
 			}
 		}
@@ -188,28 +238,57 @@ func (m *McpServer) setToolRequestHandlers() error {
 		}, nil
 	})
 
-	m.Server.SetRequestHandler(&schema.CallToolRequestSchema{MethodName: "tools/call"}, func(jrr schema.JsonRpcRequest) (schema.Result, error) {
+	m.setRequestHandler(&schema.CallToolRequestSchema{MethodName: "tools/call"}, func(exchange *Exchange, jrr schema.JsonRpcRequest) (schema.Result, error) {
 		request, ok := jrr.Request.(*schema.CallToolRequestSchema)
 		if !ok {
 			return nil, mcperr.NewMcpErr(mcperr.INVALID_REQUEST, "invalid request", nil)
 		}
-		tool := m.registerdTools[request.ParamsData.Name]
-		if tool == nil {
+		// コールバックの処理中に登録が変更されても影響しないよう、登録の内容を複製してからロックを解放する
+		m.registryMu.RLock()
+		var tool RegisteredTool
+		registerdTool, ok := m.registerdTools[request.ParamsData.Name]
+		if ok {
+			tool = *registerdTool
+		}
+		visible := ok && m.isVisible(exchange.Session, Registration{Kind: REGISTRATION_KIND_TOOL, Name: request.ParamsData.Name})
+		m.registryMu.RUnlock()
+		if !visible {
 			return nil, mcperr.NewMcpErr(mcperr.INVALID_PARAMS, fmt.Sprintf("tool %s not found", request.ParamsData.Name), nil)
 		}
 		if !tool.enabled {
 			return nil, mcperr.NewMcpErr(mcperr.INVALID_PARAMS, fmt.Sprintf("tool %s disabled", request.ParamsData.Name), nil)
 		}
+		if !tool.access.allows(exchange.AuthInfo) {
+			return nil, newPermissionDeniedErr("tool", request.ParamsData.Name, tool.access)
+		}
 		if tool.propertySchema == nil {
 			return nil, mcperr.NewMcpErr(mcperr.INVALID_PARAMS, fmt.Sprintf("tool %s has no input schema", request.ParamsData.Name), nil)
 		}
 		args := request.ParamsData.Arguments
+		if !m.toolArgumentValidation.Disabled {
+			validatedArgs, err := validateToolArguments(request.ParamsData.Name, tool.resolvedInputSchema(), args, &schema.ValidateOptions{
+				CoerceTypes:   m.toolArgumentValidation.CoerceTypes,
+				ApplyDefaults: m.toolArgumentValidation.ApplyDefaults,
+			})
+			if err != nil {
+				return nil, err
+			}
+			args = validatedArgs
+		}
 		callback := tool.callback
 		// コールバック内のクライアントエラーならエラーは返さない
-		result, err := callback(args)
+		result, err := callback(exchange.ctx, exchange, args)
+		// 引数の検証エラーなど、コールバックがMCPエラーを返した場合はそのまま返す
+		if mcpErr, ok := err.(*mcperr.McpErr); ok {
+			return nil, mcpErr
+		}
 		if err != nil {
 			return nil, mcperr.NewMcpErr(mcperr.INTERNAL_ERROR, fmt.Sprintf("failed to call tool %s", request.ParamsData.Name), err.Error())
 		}
+		result, err = completeStructuredContent(request.ParamsData.Name, tool.outputSchema, tool.structuredContentOptional, result)
+		if err != nil {
+			return nil, err
+		}
 		return &result, nil
 	})
 
@@ -218,6 +297,7 @@ func (m *McpServer) setToolRequestHandlers() error {
 	return nil
 }
 
+// registryMuを保持した状態で呼び出す
 func (m *McpServer) setPromptRequestHandlers() error {
 	if m.isPromptHandlersInitialized {
 		return nil
@@ -228,16 +308,18 @@ func (m *McpServer) setPromptRequestHandlers() error {
 			return err
 		}
 	}
-	_ = m.Server.RegisterCapabilities(schema.ServerCapabilities{
+	m.registerCapabilities(schema.ServerCapabilities{
 		Prompts: &schema.Prompts{
 			ListChanged: true,
 		},
 	})
 
-	m.Server.SetRequestHandler(&schema.ListPromptsRequestSchema{MethodName: "prompts/list"}, func(jrr schema.JsonRpcRequest) (schema.Result, error) {
+	m.setRequestHandler(&schema.ListPromptsRequestSchema{MethodName: "prompts/list"}, func(exchange *Exchange, jrr schema.JsonRpcRequest) (schema.Result, error) {
+		m.registryMu.RLock()
+		defer m.registryMu.RUnlock()
 		var prompts []schema.PromptSchema
 		for name, registerdPrompt := range m.registeredPrompts {
-			if registerdPrompt.enabled {
+			if registerdPrompt.enabled && m.isVisible(exchange.Session, Registration{Kind: REGISTRATION_KIND_PROMPT, Name: name}) && registerdPrompt.access.allows(exchange.AuthInfo) {
 				prompts = append(prompts, schema.PromptSchema{
 					Name:        name,
 					Description: registerdPrompt.description,
@@ -250,22 +332,25 @@ func (m *McpServer) setPromptRequestHandlers() error {
 		}, nil
 	})
 
-	m.Server.SetRequestHandler(&schema.GetPromptRequestSchema{MethodName: "prompts/get"}, func(jrr schema.JsonRpcRequest) (schema.Result, error) {
+	m.setRequestHandler(&schema.GetPromptRequestSchema{MethodName: "prompts/get"}, func(exchange *Exchange, jrr schema.JsonRpcRequest) (schema.Result, error) {
 		request, ok := jrr.Request.(*schema.GetPromptRequestSchema)
 		if !ok {
 			return nil, mcperr.NewMcpErr(mcperr.INVALID_REQUEST, "invalid request", nil)
 		}
-		prompt := m.registeredPrompts[request.ParamsData.Name]
-		if prompt == nil {
+		prompt, ok := m.lookupPrompt(exchange.Session, request.ParamsData.Name)
+		if !ok {
 			return nil, mcperr.NewMcpErr(mcperr.INVALID_PARAMS, fmt.Sprintf("prompt %s not found", request.ParamsData.Name), nil)
 		}
 		if !prompt.enabled {
 			return nil, mcperr.NewMcpErr(mcperr.INVALID_PARAMS, fmt.Sprintf("prompt %s disabled", request.ParamsData.Name), nil)
 		}
+		if !prompt.access.allows(exchange.AuthInfo) {
+			return nil, newPermissionDeniedErr("prompt", request.ParamsData.Name, prompt.access)
+		}
 		if prompt.argsSchema == nil {
 			return nil, mcperr.NewMcpErr(mcperr.INVALID_PARAMS, fmt.Sprintf("prompt %s has no input schema", request.ParamsData.Name), nil)
 		}
-		result, err := prompt.callback(prompt.argsSchema)
+		result, err := prompt.callback(exchange.ctx, exchange, prompt.argsSchema)
 		if err != nil {
 			return nil, mcperr.NewMcpErr(mcperr.INTERNAL_ERROR, fmt.Sprintf("failed to get prompt %s", request.ParamsData.Name), err.Error())
 		}
@@ -276,3 +361,14 @@ func (m *McpServer) setPromptRequestHandlers() error {
 	m.isPromptHandlersInitialized = true
 	return nil
 }
+
+// セッションに公開しているプロンプトを、登録の内容を複製して返す
+func (m *McpServer) lookupPrompt(session *Session, name string) (RegisteredPrompt, bool) {
+	m.registryMu.RLock()
+	defer m.registryMu.RUnlock()
+	registerdPrompt, ok := m.registeredPrompts[name]
+	if !ok || !m.isVisible(session, Registration{Kind: REGISTRATION_KIND_PROMPT, Name: name}) {
+		return RegisteredPrompt{}, false
+	}
+	return *registerdPrompt, true
+}
@@ -0,0 +1,64 @@
+package mcpserver
+
+import (
+	"errors"
+	"fmt"
+
+	mcperr "github.com/kakkky/mcp-sdk-go/shared/mcp-err"
+	"github.com/kakkky/mcp-sdk-go/shared/schema"
+)
+
+// tools/callの引数の検証の設定
+type ToolArgumentValidationOptions struct {
+	// trueの場合は、引数を検証せずにそのままコールバックに渡す
+	Disabled bool
+	// "10"と10のような単純な型の不一致を、入力スキーマの型に変換してからコールバックに渡す
+	CoerceTypes bool
+	// 省略された引数に、入力スキーマのdefaultの値を設定してからコールバックに渡す
+	ApplyDefaults bool
+}
+
+// tools/callの引数を入力スキーマで検証する方法を設定する
+// nilの場合は、型の変換や既定値の設定を行わずに検証する
+func (m *McpServer) SetToolArgumentValidation(options *ToolArgumentValidationOptions) {
+	m.toolArgumentValidation = ToolArgumentValidationOptions{}
+	if options != nil {
+		m.toolArgumentValidation = *options
+	}
+}
+
+// tools/listで返し、tools/callの引数の検証に使う入力スキーマ
+func (t *RegisteredTool) resolvedInputSchema() schema.InputSchema {
+	if t.inputSchema != nil {
+		return *t.inputSchema
+	}
+	return schema.InputSchema{
+		Type:       "object",
+		Properties: t.propertySchema,
+	}
+}
+
+// 一致しない場合は、不一致の箇所をdataに持つINVALID_PARAMSのエラーを返す
+func validateToolArguments(name string, inputSchema schema.InputSchema, args map[string]any, options *schema.ValidateOptions) (map[string]any, error) {
+	value := args
+	if value == nil {
+		value = map[string]any{}
+	}
+	validated, err := inputSchema.Validate(value, options)
+	if err != nil {
+		var validationErrs schema.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			return nil, mcperr.NewMcpErr(mcperr.INVALID_PARAMS, fmt.Sprintf("invalid arguments for tool %s: %v", name, validationErrs), validationErrs)
+		}
+		return nil, mcperr.NewMcpErr(mcperr.INVALID_PARAMS, fmt.Sprintf("invalid arguments for tool %s: %v", name, err), nil)
+	}
+	// 変換も既定値の設定も行わない場合は、トランスポートから受け取った引数をそのまま渡す
+	if options == nil || (!options.CoerceTypes && !options.ApplyDefaults) {
+		return args, nil
+	}
+	validatedArgs, ok := validated.(map[string]any)
+	if !ok {
+		return nil, mcperr.NewMcpErr(mcperr.INVALID_PARAMS, fmt.Sprintf("invalid arguments for tool %s: arguments must be an object", name), nil)
+	}
+	return validatedArgs, nil
+}
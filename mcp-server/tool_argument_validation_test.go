@@ -0,0 +1,100 @@
+package mcpserver
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kakkky/mcp-sdk-go/mcp-server/server"
+	mcperr "github.com/kakkky/mcp-sdk-go/shared/mcp-err"
+	"github.com/kakkky/mcp-sdk-go/shared/schema"
+)
+
+func TestMcpServer_ToolArgumentValidation(t *testing.T) {
+	tests := []struct {
+		name           string
+		options        *ToolArgumentValidationOptions
+		arguments      map[string]any
+		expectedArgs   map[string]any
+		expectedErrors schema.ValidationErrors
+	}{
+		{
+			name:         "normal : valid arguments are passed as they are",
+			arguments:    map[string]any{"city": "Tokyo", "days": 3},
+			expectedArgs: map[string]any{"city": "Tokyo", "days": 3},
+		},
+		{
+			name:         "normal : types are coerced and defaults are applied",
+			options:      &ToolArgumentValidationOptions{CoerceTypes: true, ApplyDefaults: true},
+			arguments:    map[string]any{"city": "Tokyo", "days": "3"},
+			expectedArgs: map[string]any{"city": "Tokyo", "days": float64(3), "unit": "celsius"},
+		},
+		{
+			name:         "normal : validation is disabled",
+			options:      &ToolArgumentValidationOptions{Disabled: true},
+			arguments:    map[string]any{"days": "many"},
+			expectedArgs: map[string]any{"days": "many"},
+		},
+		{
+			name:      "semi normal : invalid arguments are rejected with details",
+			arguments: map[string]any{"days": "3", "unit": "kelvin"},
+			expectedErrors: schema.ValidationErrors{
+				{Pointer: "/city", Keyword: "required", Message: "city is required", Expected: "city"},
+				{Pointer: "/days", Keyword: "type", Message: "must be integer, got string", Expected: "integer", Actual: "string"},
+				{Pointer: "/unit", Keyword: "enum", Message: "must be one of [celsius fahrenheit], got kelvin", Expected: []any{"celsius", "fahrenheit"}, Actual: "kelvin"},
+			},
+		},
+		{
+			name:      "semi normal : coercion does not accept values that cannot be converted",
+			options:   &ToolArgumentValidationOptions{CoerceTypes: true},
+			arguments: map[string]any{"city": "Tokyo", "days": "many"},
+			expectedErrors: schema.ValidationErrors{
+				{Pointer: "/days", Keyword: "type", Message: "must be integer, got string", Expected: "integer", Actual: "string"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sut := NewMcpServer(schema.Implementation{}, &server.ServerOptions{})
+			sut.SetToolArgumentValidation(tt.options)
+			send := newTestConnection(t, sut.Connect).request
+			var gotArgs map[string]any
+			tool, err := sut.Tool("forecast", "weather forecast", nil, nil, func(args map[string]any) (schema.CallToolResultSchema, error) {
+				gotArgs = args
+				return schema.CallToolResultSchema{Content: []schema.ToolContentSchema{}}, nil
+			})
+			if err != nil {
+				t.Fatalf("failed to register tool: %v", err)
+			}
+			inputSchema := schema.Object(schema.PropertySchema{
+				"city": schema.String("city name").WithMinLength(1),
+				"days": schema.Integer("number of days").WithMinimum(1).WithMaximum(7),
+				"unit": schema.Enum("temperature unit", "celsius", "fahrenheit").WithDefault("celsius"),
+			}, "city")
+			tool.Update(ToolUpdates{InputSchema: &inputSchema})
+
+			response := send(&schema.CallToolRequestSchema{
+				MethodName: "tools/call",
+				ParamsData: schema.CallToolRequestParams{Name: "forecast", Arguments: tt.arguments},
+			}, nil)
+			if tt.expectedErrors != nil {
+				errResponse, ok := response.(schema.JsonRpcError)
+				if !ok || errResponse.Error.Code != mcperr.INVALID_PARAMS {
+					t.Fatalf("response = %+v, want INVALID_PARAMS error", response)
+				}
+				if diff := cmp.Diff(tt.expectedErrors, errResponse.Error.Data); diff != "" {
+					t.Errorf("error data mismatch (-want +got):\n%s", diff)
+				}
+				if gotArgs != nil {
+					t.Errorf("callback was called with %v", gotArgs)
+				}
+				return
+			}
+			if _, ok := response.(schema.JsonRpcResponse); !ok {
+				t.Fatalf("response = %+v, want result", response)
+			}
+			if diff := cmp.Diff(tt.expectedArgs, gotArgs); diff != "" {
+				t.Errorf("arguments mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
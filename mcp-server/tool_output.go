@@ -0,0 +1,46 @@
+package mcpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	mcperr "github.com/kakkky/mcp-sdk-go/shared/mcp-err"
+	"github.com/kakkky/mcp-sdk-go/shared/schema"
+)
+
+// ツールの結果のstructuredContentをoutputSchemaで検証し、テキストの代替を補う
+// outputSchemaを持つツールは、エラーの結果を除いてstructuredContentを返す必要がある
+// optionalがtrueの場合は、structuredContentの無い結果も許可する
+// 参照：(https://modelcontextprotocol.io/specification/2025-06-18/server/tools#structured-content)
+func completeStructuredContent(name string, outputSchema *schema.JsonSchema, optional bool, result schema.CallToolResultSchema) (schema.CallToolResultSchema, error) {
+	if result.IsError {
+		return result, nil
+	}
+	if result.StructuredContent == nil {
+		if outputSchema != nil && !optional {
+			return result, mcperr.NewMcpErr(mcperr.INTERNAL_ERROR, fmt.Sprintf("tool %s has an output schema but returned no structured content", name), nil)
+		}
+		return result, nil
+	}
+	if outputSchema != nil {
+		structuredContent, err := outputSchema.Validate(result.StructuredContent, nil)
+		if err != nil {
+			var validationErrs schema.ValidationErrors
+			if errors.As(err, &validationErrs) {
+				return result, mcperr.NewMcpErr(mcperr.INTERNAL_ERROR, fmt.Sprintf("structured content of tool %s does not match the output schema: %v", name, validationErrs), validationErrs)
+			}
+			return result, mcperr.NewMcpErr(mcperr.INTERNAL_ERROR, fmt.Sprintf("invalid structured content of tool %s: %v", name, err), nil)
+		}
+		result.StructuredContent = structuredContent
+	}
+	// 構造化された結果を扱えないクライアントのため、同じ内容をJSONのテキストとしても返す
+	if len(result.Content) == 0 {
+		data, err := json.Marshal(result.StructuredContent)
+		if err != nil {
+			return result, mcperr.NewMcpErr(mcperr.INTERNAL_ERROR, fmt.Sprintf("failed to encode structured content of tool %s", name), err.Error())
+		}
+		result.Content = []schema.ToolContentSchema{&schema.TextContentSchema{Type: "text", Text: string(data)}}
+	}
+	return result, nil
+}
@@ -0,0 +1,106 @@
+package mcpserver
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kakkky/mcp-sdk-go/mcp-server/server"
+	mcperr "github.com/kakkky/mcp-sdk-go/shared/mcp-err"
+	"github.com/kakkky/mcp-sdk-go/shared/schema"
+)
+
+func TestMcpServer_StructuredContent(t *testing.T) {
+	outputSchema := schema.Object(schema.PropertySchema{
+		"temperature": schema.Number("temperature in celsius"),
+	}, "temperature")
+	tests := []struct {
+		name            string
+		outputSchema    *schema.JsonSchema
+		result          schema.CallToolResultSchema
+		expectedResult  *schema.CallToolResultSchema
+		expectedErrCode mcperr.ErrCode
+	}{
+		{
+			name:         "normal : text fallback is added to structured content",
+			outputSchema: &outputSchema,
+			result:       schema.CallToolResultSchema{StructuredContent: map[string]any{"temperature": 22.5}},
+			expectedResult: &schema.CallToolResultSchema{
+				Content:           []schema.ToolContentSchema{&schema.TextContentSchema{Type: "text", Text: `{"temperature":22.5}`}},
+				StructuredContent: map[string]any{"temperature": 22.5},
+			},
+		},
+		{
+			name:         "normal : content returned by the callback is kept",
+			outputSchema: &outputSchema,
+			result: schema.CallToolResultSchema{
+				Content:           []schema.ToolContentSchema{&schema.TextContentSchema{Type: "text", Text: "22.5 degrees"}},
+				StructuredContent: map[string]any{"temperature": 22.5},
+			},
+			expectedResult: &schema.CallToolResultSchema{
+				Content:           []schema.ToolContentSchema{&schema.TextContentSchema{Type: "text", Text: "22.5 degrees"}},
+				StructuredContent: map[string]any{"temperature": 22.5},
+			},
+		},
+		{
+			name:         "normal : error result is not validated",
+			outputSchema: &outputSchema,
+			result: schema.CallToolResultSchema{
+				Content: []schema.ToolContentSchema{&schema.TextContentSchema{Type: "text", Text: "unknown city"}},
+				IsError: true,
+			},
+			expectedResult: &schema.CallToolResultSchema{
+				Content: []schema.ToolContentSchema{&schema.TextContentSchema{Type: "text", Text: "unknown city"}},
+				IsError: true,
+			},
+		},
+		{
+			name:            "semi normal : structured content does not match the output schema",
+			outputSchema:    &outputSchema,
+			result:          schema.CallToolResultSchema{StructuredContent: map[string]any{"temperature": "hot"}},
+			expectedErrCode: mcperr.INTERNAL_ERROR,
+		},
+		{
+			name:            "semi normal : structured content is missing",
+			outputSchema:    &outputSchema,
+			result:          schema.CallToolResultSchema{Content: []schema.ToolContentSchema{}},
+			expectedErrCode: mcperr.INTERNAL_ERROR,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sut := NewMcpServer(schema.Implementation{}, &server.ServerOptions{})
+			send := newTestConnection(t, sut.Connect).request
+			tool, err := sut.Tool("weather", "current weather", schema.PropertySchema{}, nil, func(args map[string]any) (schema.CallToolResultSchema, error) {
+				return tt.result, nil
+			})
+			if err != nil {
+				t.Fatalf("failed to register tool: %v", err)
+			}
+			tool.Update(ToolUpdates{OutputSchema: tt.outputSchema})
+
+			listResponse := send(&schema.ListToolsRequestSchema{MethodName: "tools/list"}, nil).(schema.JsonRpcResponse)
+			if diff := cmp.Diff(tt.outputSchema, listResponse.Result.(*schema.ListToolsResultSchema).Tools[0].OutputSchema); diff != "" {
+				t.Errorf("tools/list output schema mismatch (-want +got):\n%s", diff)
+			}
+
+			response := send(&schema.CallToolRequestSchema{
+				MethodName: "tools/call",
+				ParamsData: schema.CallToolRequestParams{Name: "weather"},
+			}, nil)
+			if tt.expectedErrCode != 0 {
+				errResponse, ok := response.(schema.JsonRpcError)
+				if !ok || errResponse.Error.Code != tt.expectedErrCode {
+					t.Errorf("response = %+v, want error code %d", response, tt.expectedErrCode)
+				}
+				return
+			}
+			result, ok := response.(schema.JsonRpcResponse)
+			if !ok {
+				t.Fatalf("response = %+v, want result", response)
+			}
+			if diff := cmp.Diff(tt.expectedResult, result.Result); diff != "" {
+				t.Errorf("tools/call result mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
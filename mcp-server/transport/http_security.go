@@ -0,0 +1,95 @@
+package transport
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// 全てのOriginを許可する場合にAllowedOriginsに指定する値
+const ALLOW_ANY_ORIGIN = "*"
+
+// HTTPで待ち受けるトランスポート（Streamable HTTP、WebSocket）で、DNSリバインディング攻撃を防ぐための設定
+type HttpSecurityOptions struct {
+	// 許可するHostヘッダーのホスト名
+	// 指定しなかった場合は、ループバックアドレス（localhost、127.0.0.1、::1）のみ許可する
+	AllowedHosts []string
+	// 許可するOrigin（"https://example.com"の形式）。ALLOW_ANY_ORIGINを指定すると全て許可する
+	// 指定しなかった場合は、ループバックアドレスのOriginのみ許可する。Originヘッダーの無いリクエストは常に許可する
+	AllowedOrigins []string
+}
+
+// DNSリバインディング攻撃を防ぐため、HostヘッダーとOriginヘッダーを検証する
+// 不正な場合は、返すべきHTTPステータスコード（403）とメッセージを返す
+// 参照：(https://modelcontextprotocol.io/specification/2025-03-26/basic/transports#security-warning)
+func (o HttpSecurityOptions) validate(r *http.Request) (int, string) {
+	if status, message := o.validateHost(r); status != 0 {
+		return status, message
+	}
+	// ブラウザ以外のクライアントはOriginヘッダーを送らないため、無い場合は許可する
+	origin := r.Header.Get("Origin")
+	if origin != "" && !isAllowedOrigin(origin, o.AllowedOrigins) {
+		return http.StatusForbidden, fmt.Sprintf("origin %q is not allowed", origin)
+	}
+	return 0, ""
+}
+
+func (o HttpSecurityOptions) validateHost(r *http.Request) (int, string) {
+	if !isAllowedHost(r.Host, o.AllowedHosts) {
+		// 意図しないホスト名で到達したリクエスト
+		return http.StatusForbidden, fmt.Sprintf("host %q is not allowed", r.Host)
+	}
+	return 0, ""
+}
+
+// allowedHostsが空の場合は、ループバックアドレスのみ許可する
+func isAllowedHost(hostHeader string, allowedHosts []string) bool {
+	host := stripPort(hostHeader)
+	if host == "" {
+		return false
+	}
+	if len(allowedHosts) == 0 {
+		return isLoopbackHost(host)
+	}
+	return slices.ContainsFunc(allowedHosts, func(allowed string) bool {
+		return strings.EqualFold(stripPort(allowed), host)
+	})
+}
+
+// allowedOriginsが空の場合は、ループバックアドレスのOriginのみ許可する
+func isAllowedOrigin(origin string, allowedOrigins []string) bool {
+	if slices.Contains(allowedOrigins, ALLOW_ANY_ORIGIN) {
+		return true
+	}
+	// サンドボックス化されたiframeなどが送る"null"は許可しない
+	u, err := url.Parse(origin)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return false
+	}
+	if len(allowedOrigins) == 0 {
+		return isLoopbackHost(u.Hostname())
+	}
+	normalized := strings.ToLower(u.Scheme + "://" + u.Host)
+	return slices.ContainsFunc(allowedOrigins, func(allowed string) bool {
+		return strings.EqualFold(strings.TrimSuffix(allowed, "/"), normalized)
+	})
+}
+
+func isLoopbackHost(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// "host:port"、"[::1]:port"からホスト名を取り出す。ポートが無い場合はそのまま返す
+func stripPort(hostport string) string {
+	if host, _, err := net.SplitHostPort(hostport); err == nil {
+		return host
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(hostport, "["), "]")
+}
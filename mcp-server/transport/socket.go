@@ -0,0 +1,307 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/kakkky/mcp-sdk-go/shared/protocol"
+	"github.com/kakkky/mcp-sdk-go/shared/schema"
+	"github.com/kakkky/mcp-sdk-go/shared/schema/jsonrpc"
+	"github.com/kakkky/mcp-sdk-go/shared/transport"
+)
+
+// Unixドメインソケットのデフォルトのファイルパーミッション（所有者のみ読み書き可能）
+const defaultSocketFileMode os.FileMode = 0o600
+
+type SocketServerOptions struct {
+	// Unixドメインソケットのファイルパーミッション
+	// 指定しなかった場合は0600となる。TCPの場合は無視される
+	// ソケットファイルは作成された時点からこのパーミッションとなり、SocketServerのCloseで削除される
+	FileMode os.FileMode
+	// 各セッションの送信キューに溜めておけるメッセージ数
+	// 指定しなかった場合はキューを使わず、送信は書き込みが完了するまでブロックする
+	WriteQueueSize int
+	// メッセージの区切り方。指定しなかった場合はtransport.NewlineFramerとなる
+	Framer transport.Framer
+}
+
+// Unixドメインソケット、またはTCPで接続を待ち受けるサーバー
+// 接続ごとに1つのセッション（トランスポート）を生成する
+// メッセージはデフォルトではstdioと同様に改行区切りで送受信する
+type SocketServer struct {
+	listener       net.Listener
+	writeQueueSize int
+	framer         transport.Framer
+	mu             sync.Mutex
+	isClosed       bool
+
+	// Unixドメインソケットの場合に、Closeで削除するソケットファイルのパス
+	socketPath string
+}
+
+// networkには"unix"または"tcp"を指定する
+// TCPの場合は、"127.0.0.1:0"のようにループバックアドレスを指定することを推奨する
+func NewSocketServer(network string, address string, options *SocketServerOptions) (*SocketServer, error) {
+	switch network {
+	case "unix", "tcp", "tcp4", "tcp6":
+	default:
+		return nil, fmt.Errorf("unsupported network: %s", network)
+	}
+	var listener net.Listener
+	var socketPath string
+	var err error
+	if network == "unix" && !isAbstractSocket(address) {
+		mode := defaultSocketFileMode
+		if options != nil && options.FileMode != 0 {
+			mode = options.FileMode
+		}
+		listener, err = listenUnix(address, mode)
+		socketPath = address
+	} else {
+		listener, err = net.Listen(network, address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s %s: %w", network, address, err)
+	}
+	server := &SocketServer{
+		listener:   listener,
+		socketPath: socketPath,
+	}
+	if options != nil {
+		server.writeQueueSize = options.WriteQueueSize
+		server.framer = options.Framer
+	}
+	return server, nil
+}
+
+// Linuxの抽象名前空間のソケット（"@"で始まるアドレス）はファイルを作成しない
+func isAbstractSocket(address string) bool {
+	return strings.HasPrefix(address, "@")
+}
+
+// 所有者のみがアクセスできる一時ディレクトリの中でソケットを作成してパーミッションを設定した後、addressにハードリンクを作成する
+// net.Listenの直後からos.Chmodまでの間に、デフォルトのパーミッションのソケットファイルが他のユーザーから見えることを防ぐ
+// ハードリンクはaddressに既にファイルがある場合は失敗するため、net.Listenと同様に既存のファイルを上書きしない
+func listenUnix(address string, mode os.FileMode) (net.Listener, error) {
+	dir, err := os.MkdirTemp(filepath.Dir(address), ".mcp-socket-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	// ソケットファイルのパスの長さには上限があるため、短い名前にする
+	tmpPath := filepath.Join(dir, "s")
+	listener, err := net.Listen("unix", tmpPath)
+	if err != nil {
+		return nil, err
+	}
+	// 一時ディレクトリのパスではなく、addressのファイルをCloseで削除する
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	if err := os.Chmod(tmpPath, mode); err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("failed to change socket file mode: %w", err)
+	}
+	if err := os.Link(tmpPath, address); err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+// 接続を受け付け、接続ごとにonSessionを呼び出す
+// onSessionでは、渡されたトランスポートをセッションごとに生成したMcpServerのConnectに渡す
+// Closeが呼ばれるまでブロックする
+func (s *SocketServer) Serve(onSession func(transport protocol.Transport)) error {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			s.mu.Lock()
+			isClosed := s.isClosed
+			s.mu.Unlock()
+			if isClosed {
+				return nil
+			}
+			return fmt.Errorf("failed to accept connection: %w", err)
+		}
+		tr := NewSocketServerTransport(conn)
+		tr.SetWriteQueueSize(s.writeQueueSize)
+		if s.framer != nil {
+			tr.SetFramer(s.framer)
+		}
+		go onSession(tr)
+	}
+}
+
+func (s *SocketServer) Addr() net.Addr {
+	// 待ち受けているソケットは一時ディレクトリの中で作成したものなので、接続先としてaddressを返す
+	if s.socketPath != "" {
+		return &net.UnixAddr{Name: s.socketPath, Net: "unix"}
+	}
+	return s.listener.Addr()
+}
+
+// 待ち受けを終了し、Unixドメインソケットの場合はソケットファイルを削除する
+// 確立済みのセッションは閉じないため、各トランスポートのCloseで閉じる必要がある
+func (s *SocketServer) Close() error {
+	s.mu.Lock()
+	s.isClosed = true
+	s.mu.Unlock()
+	err := s.listener.Close()
+	if s.socketPath != "" {
+		if removeErr := os.Remove(s.socketPath); removeErr != nil && !errors.Is(removeErr, os.ErrNotExist) {
+			err = errors.Join(err, fmt.Errorf("failed to remove socket file: %w", removeErr))
+		}
+	}
+	return err
+}
+
+type socketServerTransport struct {
+	onReceiveMessage func(schema.JsonRpcMessage)
+	onClose          func()
+	onError          func(error)
+
+	conn       net.Conn
+	readBuffer *transport.ReadBuffer
+	framer     transport.Framer
+	writer     *transport.MessageWriter
+	isStarted  bool
+	closeOnce  sync.Once
+}
+
+// 確立済みの接続からトランスポートを生成する
+// SocketServerを使わずに独自に接続を受け付ける場合に使用する
+func NewSocketServerTransport(conn net.Conn) *socketServerTransport {
+	s := &socketServerTransport{
+		conn:       conn,
+		readBuffer: transport.NewReadBuffer(),
+		framer:     transport.NewlineFramer{},
+	}
+	s.writer = transport.NewMessageWriter(conn, nil, s.OnError)
+	return s
+}
+
+// メッセージの区切り方を設定する
+// 指定しなかった場合はtransport.NewlineFramerとなる。Startより前に呼び出す必要がある
+func (s *socketServerTransport) SetFramer(framer transport.Framer) {
+	s.framer = framer
+	s.readBuffer.SetFramer(framer)
+}
+
+// 送信キューのサイズを設定する
+// 0の場合はキューを使わず、SendMessageは書き込みが完了するまでブロックする
+// 1以上の場合はキューに積んだ時点で戻り、キューが一杯の間はブロックする。Startより前に呼び出す必要がある
+func (s *socketServerTransport) SetWriteQueueSize(size int) {
+	s.writer.Close()
+	s.writer = transport.NewMessageWriter(s.conn, &transport.MessageWriterOptions{QueueSize: size}, s.OnError)
+}
+
+// 受信ループを開始する
+// stdioとは異なり、呼び出し元をブロックしない
+func (s *socketServerTransport) Start() error {
+	if s.isStarted {
+		return errors.New("socket server transport is already started. If using Server class, note that connect() calls start() automatically")
+	}
+	s.isStarted = true
+	go s.readLoop()
+	return nil
+}
+
+func (s *socketServerTransport) Close() error {
+	if !s.isStarted {
+		return errors.New("socket server transport is not started")
+	}
+	s.writer.Close()
+	return s.conn.Close()
+}
+
+func (s *socketServerTransport) SendMessage(message schema.JsonRpcMessage) error {
+	return s.SendMessageWithContext(context.Background(), message)
+}
+
+// 書き込みを待っている間にctxがキャンセルされた場合は、送信せずにエラーを返す
+// 並行して呼び出しても、メッセージは1つずつ直列に書き込まれる
+func (s *socketServerTransport) SendMessageWithContext(ctx context.Context, message schema.JsonRpcMessage) error {
+	data, err := jsonrpc.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	// フレームに変換して書き込む
+	if err := s.writer.Write(ctx, s.framer.Encode(data)); err != nil {
+		return fmt.Errorf("failed to write message to socket: %w", err)
+	}
+	return nil
+}
+
+func (s *socketServerTransport) OnClose() {
+	if s.onClose != nil {
+		s.onClose()
+	}
+}
+
+func (s *socketServerTransport) OnError(err error) {
+	if s.onError != nil {
+		s.onError(err)
+	}
+}
+
+func (s *socketServerTransport) SetOnReceiveMessage(onReceiveMessage func(schema.JsonRpcMessage)) {
+	s.onReceiveMessage = onReceiveMessage
+}
+
+func (s *socketServerTransport) SetOnClose(onClose func()) {
+	s.onClose = onClose
+}
+
+func (s *socketServerTransport) SetOnError(onError func(error)) {
+	s.onError = onError
+}
+
+// 接続からデータを読み取り、メッセージ単位でonReceiveMessageコールバックを呼び出す
+// 接続が閉じられるとOnCloseを呼び出す
+func (s *socketServerTransport) readLoop() {
+	defer s.closeOnce.Do(func() {
+		s.readBuffer.Clear()
+		s.writer.Close()
+		s.OnClose()
+	})
+	chunk := make([]byte, readChunkSize)
+	for {
+		n, err := s.conn.Read(chunk)
+		if n > 0 {
+			if err := s.readBuffer.Append(chunk[:n]); err != nil {
+				s.OnError(err)
+				return
+			}
+			s.processReadBuffer()
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				s.OnError(fmt.Errorf("failed to read data from socket: %w", err))
+			}
+			return
+		}
+	}
+}
+
+// バッファ内の全てのメッセージを読み取り、onReceiveMessageコールバックを呼び出す
+func (s *socketServerTransport) processReadBuffer() {
+	for {
+		msg, err := s.readBuffer.ReadMessage()
+		if err != nil {
+			s.OnError(err)
+			continue
+		}
+		if msg == nil {
+			return
+		}
+		s.onReceiveMessage(msg)
+	}
+}
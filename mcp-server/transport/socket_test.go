@@ -0,0 +1,148 @@
+package transport
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kakkky/mcp-sdk-go/shared/protocol"
+	"github.com/kakkky/mcp-sdk-go/shared/schema"
+)
+
+func TestSocketServer(t *testing.T) {
+	tests := []struct {
+		name             string
+		network          string
+		address          func(t *testing.T) string
+		options          *SocketServerOptions
+		expectedFileMode os.FileMode
+	}{
+		{
+			name:    "normal : unix socket with default file mode",
+			network: "unix",
+			address: func(t *testing.T) string {
+				return filepath.Join(t.TempDir(), "mcp.sock")
+			},
+			expectedFileMode: 0o600,
+		},
+		{
+			name:    "normal : unix socket with specified file mode",
+			network: "unix",
+			address: func(t *testing.T) string {
+				return filepath.Join(t.TempDir(), "mcp.sock")
+			},
+			options:          &SocketServerOptions{FileMode: 0o660},
+			expectedFileMode: 0o660,
+		},
+		{
+			name:    "normal : tcp on localhost",
+			network: "tcp",
+			address: func(t *testing.T) string {
+				return "127.0.0.1:0"
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address := tt.address(t)
+			sut, err := NewSocketServer(tt.network, address, tt.options)
+			if err != nil {
+				t.Fatalf("NewSocketServer() error = %v", err)
+			}
+			if tt.network == "unix" {
+				info, err := os.Stat(address)
+				if err != nil {
+					t.Fatalf("failed to stat socket file: %v", err)
+				}
+				if info.Mode().Perm() != tt.expectedFileMode {
+					t.Errorf("socket file mode = %v, want %v", info.Mode().Perm(), tt.expectedFileMode)
+				}
+			}
+
+			// 受信したメッセージをそのまま返すセッションを接続ごとに生成する
+			serveErr := make(chan error, 1)
+			go func() {
+				serveErr <- sut.Serve(func(tr protocol.Transport) {
+					tr.SetOnReceiveMessage(func(msg schema.JsonRpcMessage) {
+						if err := tr.SendMessage(msg); err != nil {
+							t.Errorf("SendMessage() error = %v", err)
+						}
+					})
+					if err := tr.Start(); err != nil {
+						t.Errorf("Start() error = %v", err)
+					}
+				})
+			}()
+
+			// 複数の接続がそれぞれ独立したセッションとして扱われることを確認する
+			msgs := []string{
+				`{"jsonrpc":"2.0","id":1,"method":"ping"}`,
+				`{"jsonrpc":"2.0","id":2,"method":"ping"}`,
+			}
+			for _, msg := range msgs {
+				conn, err := net.Dial(sut.Addr().Network(), sut.Addr().String())
+				if err != nil {
+					t.Fatalf("failed to dial: %v", err)
+				}
+				if _, err := conn.Write([]byte(msg + "\n")); err != nil {
+					t.Fatalf("failed to write: %v", err)
+				}
+				got, err := bufio.NewReader(conn).ReadString('\n')
+				if err != nil {
+					t.Fatalf("failed to read: %v", err)
+				}
+				if diff := cmp.Diff(msg+"\n", got); diff != "" {
+					t.Errorf("response mismatch (-want +got):\n%s", diff)
+				}
+				if err := conn.Close(); err != nil {
+					t.Errorf("failed to close conn: %v", err)
+				}
+			}
+
+			if err := sut.Close(); err != nil {
+				t.Errorf("Close() error = %v", err)
+			}
+			if err := <-serveErr; err != nil {
+				t.Errorf("Serve() error = %v", err)
+			}
+			if tt.network == "unix" {
+				// ソケットファイルも作成時の一時ディレクトリも残らない
+				entries, err := os.ReadDir(filepath.Dir(address))
+				if err != nil {
+					t.Fatalf("failed to read dir: %v", err)
+				}
+				if len(entries) != 0 {
+					t.Errorf("remaining files after Close() = %v, want none", entries)
+				}
+			}
+		})
+	}
+}
+
+func TestSocketServer_ExistingFile(t *testing.T) {
+	address := filepath.Join(t.TempDir(), "mcp.sock")
+	if err := os.WriteFile(address, []byte("not a socket"), 0o600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	// 既存のファイルは上書きせず、エラーとする
+	if _, err := NewSocketServer("unix", address, nil); err == nil {
+		t.Fatal("NewSocketServer() error = nil, want error")
+	}
+	got, err := os.ReadFile(address)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	if diff := cmp.Diff("not a socket", string(got)); diff != "" {
+		t.Errorf("file content mismatch (-want +got):\n%s", diff)
+	}
+	entries, err := os.ReadDir(filepath.Dir(address))
+	if err != nil {
+		t.Fatalf("failed to read dir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("files after NewSocketServer() = %v, want only the existing file", entries)
+	}
+}
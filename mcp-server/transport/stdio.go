@@ -1,66 +1,122 @@
 package transport
 
 import (
-	"bufio"
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
-	"os/signal"
+	"sync"
+	"time"
 
 	"github.com/kakkky/mcp-sdk-go/shared/schema"
 	"github.com/kakkky/mcp-sdk-go/shared/schema/jsonrpc"
 	"github.com/kakkky/mcp-sdk-go/shared/transport"
 )
 
+// 入力を読み取る際のチャンクサイズ
+const readChunkSize = 32 * 1024
+
+// Close時に、送信キューに残っているメッセージの書き込みを待つ最大時間
+const writeDrainTimeout = 2 * time.Second
+
 type stdioServerTransport struct {
 	onReceiveMessage func(schema.JsonRpcMessage)
 	onClose          func()
 	onError          func(error)
 
+	ctx        context.Context
+	stdin      io.Reader
+	stdout     io.Writer
 	readBuffer *transport.ReadBuffer
-	stdScanner *bufio.Scanner
+	framer     transport.Framer
+	writer     *transport.MessageWriter
 	isStarted  bool
+	closeOnce  sync.Once
+	done       chan struct{}
 }
 
+// 標準入出力を使用するトランスポートを生成する
 func NewStdioServerTransport() *stdioServerTransport {
-	return &stdioServerTransport{
+	return NewStdioServerTransportWithIO(context.Background(), os.Stdin, os.Stdout)
+}
+
+// 任意のio.Reader/io.Writerを標準入出力の代わりに使用するトランスポートを生成する
+// パイプやptyのラッパー、テスト用のバッファ上でサーバーを動かす場合に使用する
+// ctxがキャンセルされるとトランスポートを閉じる
+func NewStdioServerTransportWithIO(ctx context.Context, stdin io.Reader, stdout io.Writer) *stdioServerTransport {
+	s := &stdioServerTransport{
+		ctx:        ctx,
+		stdin:      stdin,
+		stdout:     stdout,
 		readBuffer: transport.NewReadBuffer(),
+		framer:     transport.NewlineFramer{},
 		isStarted:  false,
-		stdScanner: bufio.NewScanner(os.Stdin),
+		done:       make(chan struct{}),
 	}
+	s.writer = transport.NewMessageWriter(stdout, nil, s.OnError)
+	return s
 }
+
+// 受信ループを開始する
+// 呼び出し元はブロックされないため、終了を待つ場合はDoneを使用する
 func (s *stdioServerTransport) Start() error {
-	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt)
 	if s.isStarted {
 		return errors.New("stdio server transport is already started. If using Server class, note that connect() calls start() automatically")
 	}
 	s.isStarted = true
 	go func() {
 		s.stdinOnData()
-		s.stdinOnError()
+		// 入力がEOFに達した場合は、クライアントが切断したとみなしてトランスポートを閉じる
+		_ = s.Close()
+		// バッファは受信ループが終了した後に、このgoroutineからのみ解放する
+		s.readBuffer.Clear()
+	}()
+	go func() {
+		select {
+		case <-s.ctx.Done():
+			_ = s.Close()
+		case <-s.done:
+		}
 	}()
-	<-sig
 	return nil
 }
 
+// トランスポートを閉じ、OnCloseを呼び出す
+// 送信キューに残っているメッセージは、writeDrainTimeoutまで書き込みを待ってから閉じる
+// 複数回呼ばれた場合、2回目以降は何もしない
 func (s *stdioServerTransport) Close() error {
 	if !s.isStarted {
 		return errors.New("stdio server transport is not started")
 	}
-	s.readBuffer.Clear()
-	s.onClose()
+	s.closeOnce.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), writeDrainTimeout)
+		_ = s.writer.Shutdown(ctx)
+		cancel()
+		close(s.done)
+		s.OnClose()
+	})
 	return nil
 }
 
+// トランスポートが閉じられた時にcloseされるチャネル
+func (s *stdioServerTransport) Done() <-chan struct{} {
+	return s.done
+}
+
 func (s *stdioServerTransport) SendMessage(message schema.JsonRpcMessage) error {
+	return s.SendMessageWithContext(s.ctx, message)
+}
+
+// 書き込みを待っている間にctxがキャンセルされた場合は、送信せずにエラーを返す
+// 並行して呼び出しても、メッセージは1つずつ直列に書き込まれる
+func (s *stdioServerTransport) SendMessageWithContext(ctx context.Context, message schema.JsonRpcMessage) error {
 	data, err := jsonrpc.Marshal(message)
 	if err != nil {
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
-	// 改行を追加して標準出力に書き込む
-	_, err = os.Stdout.Write(append(data, '\n'))
-	if err != nil {
+	// フレームに変換して標準出力に書き込む
+	if err := s.writer.Write(ctx, s.framer.Encode(data)); err != nil {
 		return fmt.Errorf("failed to write message to stdout: %w", err)
 	}
 	return nil
@@ -90,21 +146,52 @@ func (s *stdioServerTransport) SetOnError(onError func(error)) {
 	s.onError = onError
 }
 
-// 標準入力でデータを\nごとに受け取り、onDataコールバックを呼び出す
+// 1メッセージあたりの最大バイト数を設定する
+// これを超えるメッセージはOnErrorで通知した上で破棄し、後続のメッセージの受信は継続する
+// 0以下を指定した場合は上限なしとなる
+func (s *stdioServerTransport) SetMaxMessageSize(size int) {
+	s.readBuffer.SetMaxMessageSize(size)
+}
+
+// メッセージの区切り方を設定する
+// 指定しなかった場合はtransport.NewlineFramerとなる。Startより前に呼び出す必要がある
+func (s *stdioServerTransport) SetFramer(framer transport.Framer) {
+	s.framer = framer
+	s.readBuffer.SetFramer(framer)
+}
+
+// 送信キューのサイズを設定する
+// 0の場合はキューを使わず、SendMessageは書き込みが完了するまでブロックする
+// 1以上の場合はキューに積んだ時点で戻り、キューが一杯の間はブロックする。Startより前に呼び出す必要がある
+func (s *stdioServerTransport) SetWriteQueueSize(size int) {
+	s.writer.Close()
+	s.writer = transport.NewMessageWriter(s.stdout, &transport.MessageWriterOptions{QueueSize: size}, s.OnError)
+}
+
+// 標準入力からデータをチャンク単位で受け取り、onDataコールバックを呼び出す
+// 1行の長さに上限はなく、メッセージの区切りはReadBufferが改行をもとに判断する
 func (s *stdioServerTransport) stdinOnData() {
-	// 標準入力のスキャナーを使用して、データを読み取る
-	for s.stdScanner.Scan() {
-		data := s.stdScanner.Text()
-		// Scannerは改行を含まないので、改行を追加して
-		if err := s.onData([]byte(data + "\n")); err != nil {
-			s.OnError(fmt.Errorf("failed to read data from stdin: %w", err))
+	chunk := make([]byte, readChunkSize)
+	for {
+		n, err := s.stdin.Read(chunk)
+		// 閉じられた後に受信したデータは破棄する
+		select {
+		case <-s.done:
+			return
+		default:
+		}
+		if n > 0 {
+			if err := s.onData(chunk[:n]); err != nil {
+				s.OnError(fmt.Errorf("failed to read data from stdin: %w", err))
+				return
+			}
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				s.OnError(fmt.Errorf("failed to read data from stdin: %w", err))
+			}
 			return
 		}
-	}
-}
-func (s *stdioServerTransport) stdinOnError() {
-	if s.stdScanner.Err() != nil && s.onError != nil {
-		s.onError(s.stdScanner.Err())
 	}
 }
 
@@ -117,14 +204,18 @@ func (s *stdioServerTransport) onData(chunk []byte) error {
 	return nil
 }
 
-// バッファからメッセージを読み取り、onReceiveMessageコールバックを呼び出す
+// バッファ内の全てのメッセージを読み取り、onReceiveMessageコールバックを呼び出す
+// 最大サイズを超えたメッセージや不正なメッセージはOnErrorで通知し、読み取りを継続する
 func (s *stdioServerTransport) processReadBuffer() {
-	msg, err := s.readBuffer.ReadMessage()
-	if err != nil {
-		s.OnError(err)
-	}
-	if msg == nil {
-		return
+	for {
+		msg, err := s.readBuffer.ReadMessage()
+		if err != nil {
+			s.OnError(err)
+			continue
+		}
+		if msg == nil {
+			return
+		}
+		s.onReceiveMessage(msg)
 	}
-	s.onReceiveMessage(msg)
 }
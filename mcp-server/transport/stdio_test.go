@@ -1,12 +1,19 @@
 package transport
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
 	"io"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/kakkky/mcp-sdk-go/shared/schema"
+	"github.com/kakkky/mcp-sdk-go/shared/transport"
 )
 
 func TestStdioServeTransport(t *testing.T) {
@@ -39,9 +46,6 @@ func TestStdioServeTransport(t *testing.T) {
 			defer func() {
 				os.Stdout = originStdout
 				os.Stdin = originStdin
-				if err := stdinW.Close(); err != nil {
-					t.Errorf("Failed to close stdinW: %v", err)
-				}
 				if err := stdinR.Close(); err != nil {
 					t.Errorf("Failed to close stdinR: %v", err)
 				}
@@ -63,13 +67,10 @@ func TestStdioServeTransport(t *testing.T) {
 				sendMsgDoneChan <- struct{}{}
 			})
 
-			// 非同期でStartを実行
-			go func() {
-				err := sut.Start()
-				if err != nil {
-					t.Errorf("Start() error = %v", err)
-				}
-			}()
+			// Startは呼び出し元をブロックしない
+			if err := sut.Start(); err != nil {
+				t.Errorf("Start() error = %v", err)
+			}
 
 			// テストデータを標準入力に書き込む
 			// 注意: 実際のJSONメッセージを送信する必要がある
@@ -94,11 +95,152 @@ func TestStdioServeTransport(t *testing.T) {
 				t.Errorf("Output mismatch (-want +got):\n%s", diff)
 			}
 
-			// シグナルを送信してStart()を終了させる
-			proc, _ := os.FindProcess(os.Getpid())
-			if err := proc.Signal(os.Interrupt); err != nil {
-				t.Errorf("Failed to send interrupt signal: %v", err)
+			// 標準入力を閉じるとトランスポートが閉じられる
+			if err := stdinW.Close(); err != nil {
+				t.Errorf("Failed to close stdinW: %v", err)
+			}
+			<-sut.Done()
+		})
+	}
+}
+
+func TestStdioServerTransport_Close(t *testing.T) {
+	tests := []struct {
+		name     string
+		shutdown func(stdinW *io.PipeWriter, cancel context.CancelFunc)
+	}{
+		{
+			name: "normal: transport is closed when the reader reaches EOF",
+			shutdown: func(stdinW *io.PipeWriter, cancel context.CancelFunc) {
+				_ = stdinW.Close()
+			},
+		},
+		{
+			name: "normal: transport is closed when the context is canceled",
+			shutdown: func(stdinW *io.PipeWriter, cancel context.CancelFunc) {
+				cancel()
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			stdinR, stdinW := io.Pipe()
+			defer func() {
+				_ = stdinW.Close()
+			}()
+			stdout := &bytes.Buffer{}
+
+			sut := NewStdioServerTransportWithIO(ctx, stdinR, stdout)
+			received := make(chan schema.JsonRpcMessage, 1)
+			sut.SetOnReceiveMessage(func(jrm schema.JsonRpcMessage) {
+				received <- jrm
+			})
+			closed := make(chan struct{})
+			sut.SetOnClose(func() { close(closed) })
+			if err := sut.Start(); err != nil {
+				t.Fatalf("Start() error = %v", err)
+			}
+
+			if _, err := stdinW.Write([]byte(`{"jsonrpc":"2.0","id":1,"method":"ping"}` + "\n")); err != nil {
+				t.Fatalf("Failed to write to stdin: %v", err)
+			}
+			<-received
+
+			tt.shutdown(stdinW, cancel)
+			select {
+			case <-closed:
+			case <-time.After(5 * time.Second):
+				t.Fatal("OnClose was not called")
+			}
+			select {
+			case <-sut.Done():
+			default:
+				t.Error("Done() channel is not closed")
+			}
+			// 2回目以降のCloseは何もしない
+			if err := sut.Close(); err != nil {
+				t.Errorf("Close() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestStdioServerTransport_LargeMessage(t *testing.T) {
+	// 64KiBを大きく超えるメッセージを生成する
+	largeMsg := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"echo","arguments":{"text":"` + strings.Repeat("a", 5*1024*1024) + `"}}}`
+	pingMsg := `{"jsonrpc":"2.0","id":2,"method":"ping"}`
+	tests := []struct {
+		name           string
+		maxMessageSize int
+		input          string
+		wantMsgs       []string
+		wantErr        error
+	}{
+		{
+			name:     "normal: receive a message larger than 64KiB",
+			input:    largeMsg + "\n" + pingMsg + "\n",
+			wantMsgs: []string{largeMsg, pingMsg},
+		},
+		{
+			name:           "semi normal: message exceeding the max size is dropped and the next message is received",
+			maxMessageSize: 1024 * 1024,
+			input:          largeMsg + "\n" + pingMsg + "\n",
+			wantMsgs:       []string{pingMsg},
+			wantErr:        transport.ErrMessageTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdinR, stdinW := io.Pipe()
+			stdoutR, stdoutW := io.Pipe()
+			sut := NewStdioServerTransportWithIO(context.Background(), stdinR, stdoutW)
+			if tt.maxMessageSize != 0 {
+				sut.SetMaxMessageSize(tt.maxMessageSize)
+			}
+			errCh := make(chan error, 1)
+			sut.SetOnError(func(err error) {
+				errCh <- err
+			})
+			// 受信したメッセージをそのまま送信する
+			sut.SetOnReceiveMessage(func(jrm schema.JsonRpcMessage) {
+				if err := sut.SendMessage(jrm); err != nil {
+					t.Errorf("Failed to send message: %v", err)
+				}
+			})
+			if err := sut.Start(); err != nil {
+				t.Fatalf("Start() error = %v", err)
+			}
+			go func() {
+				_, _ = stdinW.Write([]byte(tt.input))
+			}()
+
+			reader := bufio.NewReader(stdoutR)
+			for _, want := range tt.wantMsgs {
+				got, err := reader.ReadString('\n')
+				if err != nil {
+					t.Fatalf("Failed to read from stdout: %v", err)
+				}
+				if diff := cmp.Diff(want+"\n", got); diff != "" {
+					t.Errorf("Output mismatch (-want +got):\n%s", diff)
+				}
+			}
+			if tt.wantErr != nil {
+				select {
+				case err := <-errCh:
+					if !errors.Is(err, tt.wantErr) {
+						t.Errorf("OnError() error = %v, want %v", err, tt.wantErr)
+					}
+				default:
+					t.Error("OnError was not called")
+				}
+			}
+
+			if err := stdinW.Close(); err != nil {
+				t.Errorf("Failed to close stdinW: %v", err)
 			}
+			<-sut.Done()
 		})
 	}
 }
@@ -0,0 +1,754 @@
+package transport
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	mcpauth "github.com/kakkky/mcp-sdk-go/mcp-server/auth"
+	"github.com/kakkky/mcp-sdk-go/shared/auth"
+	mcperr "github.com/kakkky/mcp-sdk-go/shared/mcp-err"
+	"github.com/kakkky/mcp-sdk-go/shared/protocol"
+	"github.com/kakkky/mcp-sdk-go/shared/schema"
+	"github.com/kakkky/mcp-sdk-go/shared/schema/jsonrpc"
+	"github.com/kakkky/mcp-sdk-go/shared/transport"
+)
+
+const (
+	// 受信したメッセージをMcpServerに渡すまで溜めておく数
+	incomingQueueSize = 64
+	// SSEのストリームが無い間に、サーバーから送信するメッセージを溜めておく数
+	maxPendingMessages = 1024
+)
+
+type StreamableHTTPServerOptions struct {
+	// セッションIDを生成する関数
+	// 指定しなかった場合は、暗号論的乱数から生成した32文字の16進数となる
+	SessionIdGenerator func() string
+	// trueの場合、リクエストへの応答をSSEではなくapplication/jsonで返す
+	// この場合、リクエストの処理中にサーバーから送信するメッセージは、GETで開かれたストリームに送られる
+	JSONResponse bool
+	// 1回のPOSTで受け付ける最大バイト数
+	// 指定しなかった場合はtransport.DEFAULT_MAX_MESSAGE_SIZEとなる
+	MaxMessageSize int64
+	// 許可するHostヘッダーとOrigin
+	HttpSecurityOptions
+	// 指定した場合、OAuth 2.1のリソースサーバーとしてBearerトークンを検証する
+	// 検証した認証情報は、受信したリクエストのAuthInfoに設定される
+	Auth *mcpauth.ResourceServerOptions
+}
+
+// Streamable HTTPでMCPクライアントからの接続を受け付けるhttp.Handler
+// initializeリクエストを受け取るたびに1つのセッション（トランスポート）を生成する
+// 参照：(https://modelcontextprotocol.io/specification/2025-03-26/basic/transports#streamable-http)
+type StreamableHTTPHandler struct {
+	onSession func(transport protocol.Transport)
+	options   StreamableHTTPServerOptions
+	serve     http.Handler
+
+	mu       sync.Mutex
+	sessions map[string]*streamableHTTPServerTransport
+	isClosed bool
+}
+
+// onSessionでは、渡されたトランスポートをセッションごとに生成したMcpServerのConnectに渡す
+// onSessionが返るまでに、トランスポートのStartが呼ばれている必要がある
+func NewStreamableHTTPHandler(onSession func(transport protocol.Transport), options *StreamableHTTPServerOptions) *StreamableHTTPHandler {
+	opts := StreamableHTTPServerOptions{}
+	if options != nil {
+		opts = *options
+	}
+	if opts.SessionIdGenerator == nil {
+		opts.SessionIdGenerator = generateSessionId
+	}
+	if opts.MaxMessageSize <= 0 {
+		opts.MaxMessageSize = transport.DEFAULT_MAX_MESSAGE_SIZE
+	}
+	h := &StreamableHTTPHandler{
+		onSession: onSession,
+		options:   opts,
+		sessions:  make(map[string]*streamableHTTPServerTransport),
+	}
+	h.serve = http.HandlerFunc(h.dispatch)
+	if opts.Auth != nil {
+		h.serve = mcpauth.RequireBearerToken(*opts.Auth)(h.serve)
+	}
+	return h
+}
+
+func generateSessionId() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func (h *StreamableHTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if status, message := h.options.HttpSecurityOptions.validate(r); status != 0 {
+		writeJsonRpcError(w, status, mcperr.INVALID_REQUEST, message)
+		return
+	}
+	h.serve.ServeHTTP(w, r)
+}
+
+func (h *StreamableHTTPHandler) dispatch(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		h.handlePost(w, r)
+	case http.MethodGet:
+		h.handleGet(w, r)
+	case http.MethodDelete:
+		h.handleDelete(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// 全てのセッションを閉じ、以降の接続を拒否する
+func (h *StreamableHTTPHandler) Close() error {
+	h.mu.Lock()
+	h.isClosed = true
+	sessions := make([]*streamableHTTPServerTransport, 0, len(h.sessions))
+	for _, session := range h.sessions {
+		sessions = append(sessions, session)
+	}
+	h.mu.Unlock()
+	for _, session := range sessions {
+		_ = session.Close()
+	}
+	return nil
+}
+
+// StreamableHTTPHandlerを1つのパスで待ち受けるHTTPサーバー
+type StreamableHTTPServer struct {
+	listener   net.Listener
+	httpServer *http.Server
+	handler    *StreamableHTTPHandler
+}
+
+// addressのホストを省略した場合（":8080"など）は、全てのインターフェースではなくループバックアドレスで待ち受ける
+// ループバックアドレス以外で待ち受ける場合は、options.AllowedHostsの指定が必要となる
+// options.Auth.Metadataを指定した場合は、保護されたリソースのメタデータも公開する
+func NewStreamableHTTPServer(address string, path string, onSession func(transport protocol.Transport), options *StreamableHTTPServerOptions) (*StreamableHTTPServer, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %q: %w", address, err)
+	}
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	if !isLoopbackHost(host) && (options == nil || len(options.AllowedHosts) == 0) {
+		return nil, fmt.Errorf("listening on non-loopback address %q requires AllowedHosts", host)
+	}
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", address, err)
+	}
+	handler := NewStreamableHTTPHandler(onSession, options)
+	mux := http.NewServeMux()
+	mux.Handle(path, handler)
+	if options != nil && options.Auth != nil && options.Auth.Metadata != nil {
+		metadataURL, err := auth.ProtectedResourceMetadataURL(options.Auth.Metadata.Resource)
+		if err != nil {
+			_ = listener.Close()
+			return nil, err
+		}
+		u, _ := url.Parse(metadataURL)
+		mux.Handle(u.Path, mcpauth.NewProtectedResourceMetadataHandler(*options.Auth.Metadata))
+	}
+	return &StreamableHTTPServer{
+		listener:   listener,
+		httpServer: &http.Server{Handler: mux},
+		handler:    handler,
+	}, nil
+}
+
+// Closeが呼ばれるまでブロックする
+func (s *StreamableHTTPServer) Serve() error {
+	if err := s.httpServer.Serve(s.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *StreamableHTTPServer) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// 全てのセッションを閉じ、待ち受けを終了する
+func (s *StreamableHTTPServer) Close() error {
+	_ = s.handler.Close()
+	return s.httpServer.Close()
+}
+
+func (h *StreamableHTTPHandler) handlePost(w http.ResponseWriter, r *http.Request) {
+	if !acceptsAll(r, transport.CONTENT_TYPE_JSON, transport.CONTENT_TYPE_SSE) {
+		writeJsonRpcError(w, http.StatusNotAcceptable, mcperr.INVALID_REQUEST, "client must accept both application/json and text/event-stream")
+		return
+	}
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), transport.CONTENT_TYPE_JSON) {
+		writeJsonRpcError(w, http.StatusUnsupportedMediaType, mcperr.INVALID_REQUEST, "content type must be application/json")
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, h.options.MaxMessageSize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeJsonRpcError(w, http.StatusRequestEntityTooLarge, mcperr.INVALID_REQUEST, "message exceeds the maximum message size")
+			return
+		}
+		writeJsonRpcError(w, http.StatusBadRequest, mcperr.PARSE_ERROR, "failed to read request body")
+		return
+	}
+	message, err := jsonrpc.Unmarshal(body)
+	if err != nil {
+		writeJsonRpcError(w, http.StatusBadRequest, mcperr.PARSE_ERROR, fmt.Sprintf("failed to parse message: %v", err))
+		return
+	}
+
+	var session *streamableHTTPServerTransport
+	request, isRequest := message.(schema.JsonRpcRequest)
+	authInfo, _ := auth.AuthInfoFromContext(r.Context())
+	if isRequest {
+		request.AuthInfo = authInfo
+		message = request
+	}
+	if isRequest && request.Method() == "initialize" {
+		session, err = h.newSession(authInfo)
+		if err != nil {
+			writeJsonRpcError(w, http.StatusServiceUnavailable, mcperr.INTERNAL_ERROR, err.Error())
+			return
+		}
+	} else {
+		var status int
+		session, status = h.lookupSession(r)
+		if session == nil {
+			writeJsonRpcError(w, status, mcperr.INVALID_REQUEST, http.StatusText(status))
+			return
+		}
+	}
+	w.Header().Set(transport.MCP_SESSION_ID_HEADER, session.sessionId)
+
+	// 通知と応答は受け付けた時点で202を返す
+	if !isRequest {
+		if err := session.deliver(r.Context(), message); err != nil {
+			writeJsonRpcError(w, http.StatusNotFound, mcperr.CONNECTION_CLOSED, err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	stream := session.openRequestStream(request, !h.options.JSONResponse)
+	defer session.closeRequestStream(request.Id)
+	if err := session.deliver(r.Context(), message); err != nil {
+		writeJsonRpcError(w, http.StatusNotFound, mcperr.CONNECTION_CLOSED, err.Error())
+		return
+	}
+	if h.options.JSONResponse {
+		h.respondJSON(w, r, session, stream)
+		return
+	}
+	h.respondSSE(w, r, session, stream)
+}
+
+// リクエストへの応答を待ち、application/jsonで返す
+func (h *StreamableHTTPHandler) respondJSON(w http.ResponseWriter, r *http.Request, session *streamableHTTPServerTransport, stream *sseStream) {
+	select {
+	case data := <-stream.messages:
+		w.Header().Set("Content-Type", transport.CONTENT_TYPE_JSON)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(data)
+	case <-r.Context().Done():
+	case <-session.done:
+		writeJsonRpcError(w, http.StatusNotFound, mcperr.CONNECTION_CLOSED, "session closed")
+	}
+}
+
+// リクエストへの応答まで、関連するメッセージをSSEで送り続ける
+func (h *StreamableHTTPHandler) respondSSE(w http.ResponseWriter, r *http.Request, session *streamableHTTPServerTransport, stream *sseStream) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJsonRpcError(w, http.StatusInternalServerError, mcperr.INTERNAL_ERROR, "streaming is not supported")
+		return
+	}
+	startSSE(w, flusher)
+	for {
+		select {
+		case data := <-stream.messages:
+			if err := transport.WriteSSEEvent(w, data); err != nil {
+				session.OnError(fmt.Errorf("failed to write event: %w", err))
+				return
+			}
+			flusher.Flush()
+		case <-stream.completed:
+			// 応答を書き込んだ後にストリームを閉じる
+			for {
+				select {
+				case data := <-stream.messages:
+					if err := transport.WriteSSEEvent(w, data); err != nil {
+						session.OnError(fmt.Errorf("failed to write event: %w", err))
+						return
+					}
+				default:
+					flusher.Flush()
+					return
+				}
+			}
+		case <-r.Context().Done():
+			return
+		case <-session.done:
+			return
+		}
+	}
+}
+
+// サーバーからクライアントへ任意のタイミングでメッセージを送るためのストリームを開く
+func (h *StreamableHTTPHandler) handleGet(w http.ResponseWriter, r *http.Request) {
+	if !acceptsAll(r, transport.CONTENT_TYPE_SSE) {
+		writeJsonRpcError(w, http.StatusNotAcceptable, mcperr.INVALID_REQUEST, "client must accept text/event-stream")
+		return
+	}
+	session, status := h.lookupSession(r)
+	if session == nil {
+		writeJsonRpcError(w, status, mcperr.INVALID_REQUEST, http.StatusText(status))
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJsonRpcError(w, http.StatusInternalServerError, mcperr.INTERNAL_ERROR, "streaming is not supported")
+		return
+	}
+	stream, ok := session.openStandaloneStream()
+	if !ok {
+		writeJsonRpcError(w, http.StatusConflict, mcperr.INVALID_REQUEST, "stream is already open for this session")
+		return
+	}
+	defer session.closeStandaloneStream(stream)
+	w.Header().Set(transport.MCP_SESSION_ID_HEADER, session.sessionId)
+	startSSE(w, flusher)
+	for {
+		select {
+		case data := <-stream.messages:
+			if err := transport.WriteSSEEvent(w, data); err != nil {
+				session.OnError(fmt.Errorf("failed to write event: %w", err))
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		case <-session.done:
+			return
+		}
+	}
+}
+
+// クライアントがセッションを終了する
+func (h *StreamableHTTPHandler) handleDelete(w http.ResponseWriter, r *http.Request) {
+	session, status := h.lookupSession(r)
+	if session == nil {
+		writeJsonRpcError(w, status, mcperr.INVALID_REQUEST, http.StatusText(status))
+		return
+	}
+	_ = session.Close()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *StreamableHTTPHandler) newSession(authInfo *auth.AuthInfo) (*streamableHTTPServerTransport, error) {
+	h.mu.Lock()
+	if h.isClosed {
+		h.mu.Unlock()
+		return nil, errors.New("server is closed")
+	}
+	session := newStreamableHTTPServerTransport(h.options.SessionIdGenerator(), h)
+	session.owner = principalOf(authInfo)
+	h.sessions[session.sessionId] = session
+	h.mu.Unlock()
+	h.onSession(session)
+	if !session.isStarted() {
+		h.removeSession(session.sessionId)
+		return nil, errors.New("session transport was not started")
+	}
+	return session, nil
+}
+
+// セッションIDヘッダーからセッションを探す
+// 見つからない場合は、返すべきHTTPステータスコードを返す
+func (h *StreamableHTTPHandler) lookupSession(r *http.Request) (*streamableHTTPServerTransport, int) {
+	sessionId := r.Header.Get(transport.MCP_SESSION_ID_HEADER)
+	if sessionId == "" {
+		return nil, http.StatusBadRequest
+	}
+	if version := r.Header.Get(transport.MCP_PROTOCOL_VERSION_HEADER); version != "" && !slices.Contains(schema.SUPPORTED_PROTOCOL_VERSIONS, version) {
+		return nil, http.StatusBadRequest
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	session, ok := h.sessions[sessionId]
+	if !ok {
+		// 終了した、または存在しないセッションの場合、クライアントは新しいセッションを開始する必要がある
+		return nil, http.StatusNotFound
+	}
+	// 他の利用者のトークンでセッションを乗っ取れないよう、セッションを開始した主体と一致することを確認する
+	authInfo, _ := auth.AuthInfoFromContext(r.Context())
+	if session.owner != principalOf(authInfo) {
+		return nil, http.StatusForbidden
+	}
+	return session, http.StatusOK
+}
+
+// セッションを開始した主体の識別子。認証を行っていない場合は空となる
+func principalOf(authInfo *auth.AuthInfo) string {
+	if authInfo == nil {
+		return ""
+	}
+	return authInfo.ClientId + "\x00" + authInfo.Subject
+}
+
+func (h *StreamableHTTPHandler) removeSession(sessionId string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.sessions, sessionId)
+}
+
+func acceptsAll(r *http.Request, contentTypes ...string) bool {
+	accept := r.Header.Get("Accept")
+	for _, contentType := range contentTypes {
+		if !strings.Contains(accept, contentType) && !strings.Contains(accept, "*/*") {
+			return false
+		}
+	}
+	return true
+}
+
+func startSSE(w http.ResponseWriter, flusher http.Flusher) {
+	w.Header().Set("Content-Type", transport.CONTENT_TYPE_SSE)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+}
+
+// HTTPのエラーとともに、JSON-RPCのエラーを本文として返す
+func writeJsonRpcError(w http.ResponseWriter, status int, code mcperr.ErrCode, message string) {
+	body, _ := json.Marshal(struct {
+		Jsonrpc string       `json:"jsonrpc"`
+		Id      *int         `json:"id"`
+		Error   schema.Error `json:"error"`
+	}{
+		Jsonrpc: schema.JSON_RPC_VERSION,
+		Error:   schema.Error{Code: code, Message: message},
+	})
+	w.Header().Set("Content-Type", transport.CONTENT_TYPE_JSON)
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+// 1つのSSEストリーム（またはapplication/jsonの応答）に送るメッセージ
+type sseStream struct {
+	messages     chan []byte
+	completed    chan struct{} // リクエストへの応答が送られた時にcloseされる
+	completeOnce sync.Once
+	closed       chan struct{} // HTTPリクエストの処理が終わった時にcloseされる
+	closeOnce    sync.Once
+	sse          bool // falseの場合は応答のみを送る
+}
+
+func newSSEStream(sse bool) *sseStream {
+	return &sseStream{
+		messages:  make(chan []byte, incomingQueueSize),
+		completed: make(chan struct{}),
+		closed:    make(chan struct{}),
+		sse:       sse,
+	}
+}
+
+func (s *sseStream) complete() {
+	s.completeOnce.Do(func() { close(s.completed) })
+}
+
+func (s *sseStream) close() {
+	s.closeOnce.Do(func() { close(s.closed) })
+}
+
+// Streamable HTTPの1セッションに対応するトランスポート
+type streamableHTTPServerTransport struct {
+	onReceiveMessage func(schema.JsonRpcMessage)
+	onClose          func()
+	onError          func(error)
+
+	sessionId string
+	owner     string
+	handler   *StreamableHTTPHandler
+	incoming  chan schema.JsonRpcMessage
+
+	mu             sync.Mutex
+	started        bool
+	requestStreams map[int]*sseStream
+	requestOrder   []int          // SSEで応答中のリクエストIDを開始順に並べたもの
+	progressTokens map[string]int // progressTokenと、それを指定したリクエストIDの対応
+	standalone     *sseStream
+	pending        [][]byte // ストリームが無い間に送信されたメッセージ
+	closeOnce      sync.Once
+	done           chan struct{}
+}
+
+func newStreamableHTTPServerTransport(sessionId string, handler *StreamableHTTPHandler) *streamableHTTPServerTransport {
+	return &streamableHTTPServerTransport{
+		sessionId:      sessionId,
+		handler:        handler,
+		incoming:       make(chan schema.JsonRpcMessage, incomingQueueSize),
+		requestStreams: make(map[int]*sseStream),
+		progressTokens: make(map[string]int),
+		done:           make(chan struct{}),
+	}
+}
+
+// 受信したメッセージを1つずつMcpServerに渡すループを開始する
+func (s *streamableHTTPServerTransport) Start() error {
+	s.mu.Lock()
+	if s.started {
+		s.mu.Unlock()
+		return errors.New("streamable http server transport is already started. If using Server class, note that connect() calls start() automatically")
+	}
+	s.started = true
+	s.mu.Unlock()
+	go func() {
+		for {
+			select {
+			case message := <-s.incoming:
+				s.onReceiveMessage(message)
+			case <-s.done:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+func (s *streamableHTTPServerTransport) isStarted() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.started
+}
+
+// セッションを終了し、開いている全てのストリームを閉じる
+func (s *streamableHTTPServerTransport) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.handler.removeSession(s.sessionId)
+		s.OnClose()
+	})
+	return nil
+}
+
+// セッションが終了した時にcloseされるチャネル
+func (s *streamableHTTPServerTransport) Done() <-chan struct{} {
+	return s.done
+}
+
+// セッションIDを返す
+func (s *streamableHTTPServerTransport) SessionId() string {
+	return s.sessionId
+}
+
+func (s *streamableHTTPServerTransport) SendMessage(message schema.JsonRpcMessage) error {
+	return s.SendMessageWithContext(context.Background(), message)
+}
+
+// リクエストへの応答と、そのリクエストのprogressTokenを指定した進捗通知は、リクエストを受け付けたストリームに送る
+// それ以外のメッセージはGETで開かれたストリームに送り、無い場合は応答中のストリームに送る
+// どちらも無い場合は、ストリームが開かれるまで溜めておく
+func (s *streamableHTTPServerTransport) SendMessageWithContext(ctx context.Context, message schema.JsonRpcMessage) error {
+	data, err := jsonrpc.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	s.mu.Lock()
+	var stream *sseStream
+	isResponse := false
+	switch m := message.(type) {
+	case schema.JsonRpcResponse:
+		stream, isResponse = s.requestStreams[m.Id], true
+	case schema.JsonRpcError:
+		stream, isResponse = s.requestStreams[m.Id], true
+	case schema.JsonRpcNotification:
+		if progress, ok := m.Notification.(*schema.ProgressNotificationSchema); ok {
+			if id, ok := s.progressTokens[fmt.Sprint(progress.ParamsData.ProgressToken)]; ok {
+				if requestStream := s.requestStreams[id]; requestStream != nil && requestStream.sse {
+					stream = requestStream
+				}
+			}
+		}
+	}
+	if isResponse && stream == nil {
+		s.mu.Unlock()
+		// 応答を待っていたHTTPリクエストが切断された場合
+		return errors.New("no pending request for the response")
+	}
+	if stream == nil {
+		stream = s.streamForServerMessage()
+	}
+	if stream == nil {
+		if len(s.pending) >= maxPendingMessages {
+			s.mu.Unlock()
+			return errors.New("too many pending messages: no stream is open")
+		}
+		s.pending = append(s.pending, data)
+		s.mu.Unlock()
+		return nil
+	}
+	s.mu.Unlock()
+
+	select {
+	case stream.messages <- data:
+	case <-stream.closed:
+		return errors.New("stream is closed before the message is sent")
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-s.done:
+		return errors.New("session is closed")
+	}
+	if isResponse {
+		stream.complete()
+	}
+	return nil
+}
+
+// s.muをロックした状態で呼び出す
+func (s *streamableHTTPServerTransport) streamForServerMessage() *sseStream {
+	if s.standalone != nil {
+		return s.standalone
+	}
+	for i := len(s.requestOrder) - 1; i >= 0; i-- {
+		if stream := s.requestStreams[s.requestOrder[i]]; stream != nil && stream.sse {
+			return stream
+		}
+	}
+	return nil
+}
+
+// HTTPリクエストで受け取ったメッセージを、受信ループに渡す
+func (s *streamableHTTPServerTransport) deliver(ctx context.Context, message schema.JsonRpcMessage) error {
+	select {
+	case s.incoming <- message:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-s.done:
+		return errors.New("session is closed")
+	}
+}
+
+func (s *streamableHTTPServerTransport) openRequestStream(request schema.JsonRpcRequest, sse bool) *sseStream {
+	stream := newSSEStream(sse)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requestStreams[request.Id] = stream
+	if sse {
+		s.requestOrder = append(s.requestOrder, request.Id)
+		s.flushPending(stream)
+	}
+	if token := progressToken(request.Request); token != nil {
+		s.progressTokens[fmt.Sprint(token)] = request.Id
+	}
+	return stream
+}
+
+func (s *streamableHTTPServerTransport) closeRequestStream(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if stream := s.requestStreams[id]; stream != nil {
+		stream.close()
+	}
+	delete(s.requestStreams, id)
+	s.requestOrder = slices.DeleteFunc(s.requestOrder, func(requestId int) bool { return requestId == id })
+	for token, requestId := range s.progressTokens {
+		if requestId == id {
+			delete(s.progressTokens, token)
+		}
+	}
+}
+
+func (s *streamableHTTPServerTransport) openStandaloneStream() (*sseStream, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.standalone != nil {
+		return nil, false
+	}
+	s.standalone = newSSEStream(true)
+	s.flushPending(s.standalone)
+	return s.standalone, true
+}
+
+func (s *streamableHTTPServerTransport) closeStandaloneStream(stream *sseStream) {
+	stream.close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.standalone == stream {
+		s.standalone = nil
+	}
+}
+
+// 溜めておいたメッセージをストリームに移す。s.muをロックした状態で呼び出す
+func (s *streamableHTTPServerTransport) flushPending(stream *sseStream) {
+	for len(s.pending) > 0 {
+		select {
+		case stream.messages <- s.pending[0]:
+			s.pending = s.pending[1:]
+		default:
+			return
+		}
+	}
+}
+
+// リクエストのparamsの"_meta"に含まれるprogressTokenを返す
+func progressToken(request schema.Request) any {
+	var meta *schema.RequestMetaSchema
+	switch r := request.(type) {
+	case *schema.CallToolRequestSchema:
+		meta = r.ParamsData.Meta
+	case *schema.GetPromptRequestSchema:
+		meta = r.ParamsData.Meta
+	case *schema.ReadResourceRequestSchema:
+		meta = r.ParamsData.Meta
+	}
+	if meta == nil {
+		return nil
+	}
+	return meta.ProgressToken
+}
+
+func (s *streamableHTTPServerTransport) OnClose() {
+	if s.onClose != nil {
+		s.onClose()
+	}
+}
+
+func (s *streamableHTTPServerTransport) OnError(err error) {
+	if s.onError != nil {
+		s.onError(err)
+	}
+}
+
+func (s *streamableHTTPServerTransport) SetOnReceiveMessage(onReceiveMessage func(schema.JsonRpcMessage)) {
+	s.onReceiveMessage = onReceiveMessage
+}
+
+func (s *streamableHTTPServerTransport) SetOnClose(onClose func()) {
+	s.onClose = onClose
+}
+
+func (s *streamableHTTPServerTransport) SetOnError(onError func(error)) {
+	s.onError = onError
+}
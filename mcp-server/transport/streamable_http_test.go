@@ -0,0 +1,309 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	mcpserver "github.com/kakkky/mcp-sdk-go/mcp-server"
+	mcpauth "github.com/kakkky/mcp-sdk-go/mcp-server/auth"
+	"github.com/kakkky/mcp-sdk-go/mcp-server/server"
+	"github.com/kakkky/mcp-sdk-go/shared/auth"
+	"github.com/kakkky/mcp-sdk-go/shared/protocol"
+	"github.com/kakkky/mcp-sdk-go/shared/schema"
+	"github.com/kakkky/mcp-sdk-go/shared/transport"
+)
+
+const initializeRequestBody = `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test-client","version":"1.0.0"}}}`
+
+func TestStreamableHTTPHandler_ValidateHostAndOrigin(t *testing.T) {
+	tests := []struct {
+		name           string
+		options        *StreamableHTTPServerOptions
+		host           string
+		origin         string
+		expectedStatus int
+	}{
+		{
+			name:           "normal : loopback host without origin",
+			host:           "127.0.0.1:8080",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "normal : localhost with loopback origin",
+			host:           "localhost:8080",
+			origin:         "http://localhost:3000",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "normal : ipv6 loopback host",
+			host:           "[::1]:8080",
+			origin:         "http://[::1]:3000",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "normal : configured host and origin",
+			options:        &StreamableHTTPServerOptions{HttpSecurityOptions: HttpSecurityOptions{AllowedHosts: []string{"mcp.example.com"}, AllowedOrigins: []string{"https://app.example.com"}}},
+			host:           "MCP.example.com:443",
+			origin:         "https://app.example.com",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "normal : any origin is allowed",
+			options:        &StreamableHTTPServerOptions{HttpSecurityOptions: HttpSecurityOptions{AllowedOrigins: []string{ALLOW_ANY_ORIGIN}}},
+			host:           "127.0.0.1:8080",
+			origin:         "https://anywhere.example.com",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "semi normal : rebinded host is rejected",
+			host:           "evil.example.com",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "semi normal : rebinded host with port is rejected",
+			host:           "attacker.com:8080",
+			origin:         "http://attacker.com:8080",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "semi normal : loopback host is rejected when other hosts are configured",
+			options:        &StreamableHTTPServerOptions{HttpSecurityOptions: HttpSecurityOptions{AllowedHosts: []string{"mcp.example.com"}}},
+			host:           "127.0.0.1:8080",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "semi normal : hostile origin is rejected",
+			host:           "127.0.0.1:8080",
+			origin:         "http://evil.example.com",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "semi normal : null origin is rejected",
+			host:           "127.0.0.1:8080",
+			origin:         "null",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "semi normal : origin not in configured list is rejected",
+			options:        &StreamableHTTPServerOptions{HttpSecurityOptions: HttpSecurityOptions{AllowedOrigins: []string{"https://app.example.com"}}},
+			host:           "127.0.0.1:8080",
+			origin:         "https://app.example.com.evil.com",
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := &StreamableHTTPServerOptions{JSONResponse: true}
+			if tt.options != nil {
+				options.HttpSecurityOptions = tt.options.HttpSecurityOptions
+			}
+			// initializeに応答するだけのセッションを生成する
+			sut := NewStreamableHTTPHandler(func(tr protocol.Transport) {
+				tr.SetOnReceiveMessage(func(msg schema.JsonRpcMessage) {
+					request, ok := msg.(schema.JsonRpcRequest)
+					if !ok {
+						return
+					}
+					if err := tr.SendMessage(schema.JsonRpcResponse{
+						BaseMessage: schema.BaseMessage{Jsonrpc: schema.JSON_RPC_VERSION, Id: request.Id},
+						Result:      &schema.InitializeResultSchema{ProtocolVersion: schema.LATEST_PROTOCOL_VERSION},
+					}); err != nil {
+						t.Errorf("SendMessage() error = %v", err)
+					}
+				})
+				if err := tr.Start(); err != nil {
+					t.Errorf("Start() error = %v", err)
+				}
+			}, options)
+			defer sut.Close()
+
+			req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(initializeRequestBody))
+			req.Host = tt.host
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Accept", "application/json, text/event-stream")
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			rec := httptest.NewRecorder()
+			sut.ServeHTTP(rec, req)
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("status = %d, want %d (body: %s)", rec.Code, tt.expectedStatus, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestNewStreamableHTTPServer(t *testing.T) {
+	tests := []struct {
+		name         string
+		address      string
+		options      *StreamableHTTPServerOptions
+		wantErr      bool
+		wantLoopback bool
+	}{
+		{
+			name:         "normal : omitted host binds to loopback",
+			address:      ":0",
+			wantLoopback: true,
+		},
+		{
+			name:         "normal : explicit loopback address",
+			address:      "127.0.0.1:0",
+			wantLoopback: true,
+		},
+		{
+			name:    "normal : non-loopback address with allowed hosts",
+			address: "0.0.0.0:0",
+			options: &StreamableHTTPServerOptions{HttpSecurityOptions: HttpSecurityOptions{AllowedHosts: []string{"mcp.example.com"}}},
+		},
+		{
+			name:    "semi normal : non-loopback address without allowed hosts",
+			address: "0.0.0.0:0",
+			wantErr: true,
+		},
+		{
+			name:    "semi normal : invalid address",
+			address: "127.0.0.1",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sut, err := NewStreamableHTTPServer(tt.address, "/mcp", func(protocol.Transport) {}, tt.options)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewStreamableHTTPServer() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			serveErr := make(chan error, 1)
+			go func() { serveErr <- sut.Serve() }()
+			if tt.wantLoopback && !isLoopbackHost(stripPort(sut.Addr().String())) {
+				t.Errorf("Addr() = %v, want loopback address", sut.Addr())
+			}
+			if err := sut.Close(); err != nil {
+				t.Errorf("Close() error = %v", err)
+			}
+			if err := <-serveErr; err != nil {
+				t.Errorf("Serve() error = %v", err)
+			}
+		})
+	}
+}
+
+// トークンと認証情報の対応を持つTokenVerifier
+type stubVerifier map[string]*auth.AuthInfo
+
+func (v stubVerifier) VerifyAccessToken(ctx context.Context, token string) (*auth.AuthInfo, error) {
+	info, ok := v[token]
+	if !ok {
+		return nil, mcpauth.ErrInvalidToken
+	}
+	return info, nil
+}
+
+func TestStreamableHTTPHandler_WithAuth(t *testing.T) {
+	verifier := stubVerifier{
+		"alice-token":   {Subject: "alice", Scopes: []string{"mcp", "admin"}, Claims: map[string]any{"sub": "alice", "tenant": "a"}},
+		"mallory-token": {Subject: "mallory", Scopes: []string{"mcp"}, Claims: map[string]any{"sub": "mallory"}},
+	}
+	sut := NewStreamableHTTPHandler(func(tr protocol.Transport) {
+		mcpServer := mcpserver.NewMcpServer(schema.Implementation{Name: "auth-server", Version: "1.0.0"}, &server.ServerOptions{
+			Capabilities: schema.ServerCapabilities{Tools: &schema.Tools{ListChanged: true}},
+		})
+		// コールバックのコンテキストから呼び出し元のクレームを参照する
+		if _, err := mcpServer.ToolWithExchange("whoami", "returns the caller", schema.PropertySchema{}, nil,
+			func(ctx context.Context, exchange *mcpserver.Exchange, args map[string]any) (schema.CallToolResultSchema, error) {
+				info, ok := auth.AuthInfoFromContext(ctx)
+				if !ok || !info.HasScopes("admin") {
+					return schema.CallToolResultSchema{
+						Content: []schema.ToolContentSchema{&schema.TextContentSchema{Type: "text", Text: "forbidden"}},
+						IsError: true,
+					}, nil
+				}
+				return schema.CallToolResultSchema{
+					Content: []schema.ToolContentSchema{&schema.TextContentSchema{Type: "text", Text: info.Subject + "@" + info.Claims["tenant"].(string)}},
+				}, nil
+			},
+		); err != nil {
+			t.Errorf("failed to register tool: %v", err)
+		}
+		if err := mcpServer.Connect(tr); err != nil {
+			t.Errorf("failed to connect server: %v", err)
+		}
+	}, &StreamableHTTPServerOptions{
+		JSONResponse: true,
+		Auth: &mcpauth.ResourceServerOptions{
+			Verifier:       verifier,
+			RequiredScopes: []string{"mcp"},
+			Metadata:       &auth.ProtectedResourceMetadata{Resource: "http://127.0.0.1/mcp"},
+		},
+	})
+	defer sut.Close()
+
+	post := func(token string, sessionId string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(body))
+		req.Host = "127.0.0.1"
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json, text/event-stream")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		if sessionId != "" {
+			req.Header.Set(transport.MCP_SESSION_ID_HEADER, sessionId)
+		}
+		rec := httptest.NewRecorder()
+		sut.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// トークンが無い場合は、メタデータの場所を伝えて401を返す
+	rec := post("", "", initializeRequestBody)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status without token = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if got, want := rec.Header().Get("WWW-Authenticate"), `Bearer resource_metadata="http://127.0.0.1/.well-known/oauth-protected-resource/mcp"`; got != want {
+		t.Errorf("WWW-Authenticate = %q, want %q", got, want)
+	}
+	if rec := post("forged-token", "", initializeRequestBody); rec.Code != http.StatusUnauthorized {
+		t.Errorf("status with forged token = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	rec = post("alice-token", "", initializeRequestBody)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("initialize status = %d, want %d (body: %s)", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	sessionId := rec.Header().Get(transport.MCP_SESSION_ID_HEADER)
+	if rec := post("alice-token", sessionId, `{"jsonrpc":"2.0","method":"notifications/initialized"}`); rec.Code != http.StatusAccepted {
+		t.Fatalf("initialized status = %d, want %d", rec.Code, http.StatusAccepted)
+	}
+
+	// 他の利用者のトークンではセッションを使えない
+	callTool := `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"whoami","arguments":{}}}`
+	if rec := post("mallory-token", sessionId, callTool); rec.Code != http.StatusForbidden {
+		t.Errorf("status with another user's token = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	rec = post("alice-token", sessionId, callTool)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("tools/call status = %d, want %d (body: %s)", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var response struct {
+		Result struct {
+			Content []struct {
+				Text string `json:"text"`
+			} `json:"content"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if diff := cmp.Diff("alice@a", response.Result.Content[0].Text); diff != "" {
+		t.Errorf("tool result mismatch (-want +got):\n%s", diff)
+	}
+}
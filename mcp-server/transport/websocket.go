@@ -0,0 +1,179 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+
+	"github.com/gorilla/websocket"
+	mcpauth "github.com/kakkky/mcp-sdk-go/mcp-server/auth"
+	"github.com/kakkky/mcp-sdk-go/shared/auth"
+	mcperr "github.com/kakkky/mcp-sdk-go/shared/mcp-err"
+	"github.com/kakkky/mcp-sdk-go/shared/schema"
+	"github.com/kakkky/mcp-sdk-go/shared/transport"
+)
+
+type WebSocketServerOptions struct {
+	transport.WebSocketOptions
+	// 許可するHostヘッダーとOrigin。アップグレードの前に検証する
+	HttpSecurityOptions
+	// Originヘッダーを検証する関数
+	// 指定した場合は、AllowedOriginsの代わりにこの関数でOriginを検証する（Hostヘッダーは常にAllowedHostsで検証する）
+	CheckOrigin func(r *http.Request) bool
+}
+
+type websocketServerTransport struct {
+	onReceiveMessage func(schema.JsonRpcMessage)
+	onClose          func()
+	onError          func(error)
+
+	conn      *transport.WebSocketConn
+	isStarted bool
+	// ハンドシェイク時に検証された認証情報。接続中の全てのリクエストに設定する
+	authInfo *auth.AuthInfo
+	// 受信したリクエストごとに適用するレート制限。ハンドシェイク時のリクエストのcontextから取り出す
+	rateLimiter mcpauth.MessageRateLimiter
+	// レート制限の検証に使用するcontext。ハンドシェイクの完了後も取り消されない
+	ctx context.Context
+}
+
+// HTTPリクエストをWebSocketにアップグレードし、1つの接続（セッション）に対応するトランスポートを返す
+// http.Handler内で呼び出し、返されたトランスポートをMcpServer.Connectに渡す
+// mcpauth.RequireBearerTokenでラップしたハンドラー内で呼び出した場合、検証した認証情報を受信したリクエストのAuthInfoに設定する
+// VerifierがMessageRateLimiterを実装している場合（APIKeyVerifierなど）は、受信したリクエストごとにレート制限を適用する
+// HostヘッダーやOriginが許可されていない場合は、403を返してエラーを返す
+func NewWebSocketServerTransport(w http.ResponseWriter, r *http.Request, options *WebSocketServerOptions) (*websocketServerTransport, error) {
+	opts := WebSocketServerOptions{}
+	if options != nil {
+		opts = *options
+	}
+	validate := opts.HttpSecurityOptions.validate
+	if opts.CheckOrigin != nil {
+		validate = opts.HttpSecurityOptions.validateHost
+	}
+	if status, message := validate(r); status != 0 {
+		http.Error(w, message, status)
+		return nil, fmt.Errorf("failed to upgrade to websocket: %s", message)
+	}
+	upgrader := websocket.Upgrader{
+		Subprotocols: []string{transport.WEBSOCKET_SUBPROTOCOL},
+		CheckOrigin:  opts.CheckOrigin,
+	}
+	if upgrader.CheckOrigin == nil {
+		// Originは検証済みのため、gorilla/websocketの同一オリジンの検証は行わない
+		upgrader.CheckOrigin = func(r *http.Request) bool { return true }
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upgrade to websocket: %w", err)
+	}
+	authInfo, _ := auth.AuthInfoFromContext(r.Context())
+	rateLimiter, _ := mcpauth.MessageRateLimiterFromContext(r.Context())
+	return &websocketServerTransport{
+		conn:        transport.NewWebSocketConn(conn, &opts.WebSocketOptions),
+		authInfo:    authInfo,
+		rateLimiter: rateLimiter,
+		ctx:         context.WithoutCancel(r.Context()),
+	}, nil
+}
+
+// 受信ループを開始する
+// stdioとは異なり、呼び出し元をブロックしない
+func (s *websocketServerTransport) Start() error {
+	if s.isStarted {
+		return errors.New("websocket server transport is already started. If using Server class, note that connect() calls start() automatically")
+	}
+	s.isStarted = true
+	s.conn.Start(s.receiveMessage, s.OnError, s.OnClose)
+	return nil
+}
+
+func (s *websocketServerTransport) receiveMessage(message schema.JsonRpcMessage) {
+	if request, ok := message.(schema.JsonRpcRequest); ok && s.authInfo != nil {
+		if s.rateLimiter != nil {
+			if err := s.rateLimiter.AllowMessage(s.ctx, s.authInfo); err != nil {
+				s.rejectRequest(request, err)
+				return
+			}
+		}
+		request.AuthInfo = s.authInfo
+		message = request
+	}
+	if s.onReceiveMessage != nil {
+		s.onReceiveMessage(message)
+	}
+}
+
+// レート制限を超えた、または接続中にキーが無効になったリクエストを、処理せずにエラーで応答する
+func (s *websocketServerTransport) rejectRequest(request schema.JsonRpcRequest, err error) {
+	var code mcperr.ErrCode = mcperr.INTERNAL_ERROR
+	var data any
+	var rateLimitErr *mcpauth.RateLimitError
+	switch {
+	case errors.As(err, &rateLimitErr):
+		code = mcperr.RATE_LIMITED
+		data = map[string]any{"retryAfter": int(math.Ceil(rateLimitErr.RetryAfter.Seconds()))}
+	case errors.Is(err, mcpauth.ErrInvalidToken):
+		code = mcperr.PERMISSION_DENIED
+	}
+	if err := s.SendMessage(schema.JsonRpcError{
+		BaseMessage: schema.BaseMessage{
+			Jsonrpc: schema.JSON_RPC_VERSION,
+			Id:      request.Id,
+		},
+		Error: schema.Error{
+			Code:    code,
+			Message: err.Error(),
+			Data:    data,
+		},
+	}); err != nil {
+		s.OnError(fmt.Errorf("failed to send error response: %w", err))
+	}
+}
+
+func (s *websocketServerTransport) Close() error {
+	if !s.isStarted {
+		return errors.New("websocket server transport is not started")
+	}
+	return s.conn.Close()
+}
+
+func (s *websocketServerTransport) SendMessage(message schema.JsonRpcMessage) error {
+	return s.SendMessageWithContext(context.Background(), message)
+}
+
+// 書き込みを待っている間にctxがキャンセルされた場合は、送信せずにエラーを返す
+func (s *websocketServerTransport) SendMessageWithContext(ctx context.Context, message schema.JsonRpcMessage) error {
+	return s.conn.WriteMessage(ctx, message)
+}
+
+// 接続が終了した時にcloseされるチャネル
+func (s *websocketServerTransport) Done() <-chan struct{} {
+	return s.conn.Done()
+}
+
+func (s *websocketServerTransport) OnClose() {
+	if s.onClose != nil {
+		s.onClose()
+	}
+}
+
+func (s *websocketServerTransport) OnError(err error) {
+	if s.onError != nil {
+		s.onError(err)
+	}
+}
+
+func (s *websocketServerTransport) SetOnReceiveMessage(onReceiveMessage func(schema.JsonRpcMessage)) {
+	s.onReceiveMessage = onReceiveMessage
+}
+
+func (s *websocketServerTransport) SetOnClose(onClose func()) {
+	s.onClose = onClose
+}
+
+func (s *websocketServerTransport) SetOnError(onError func(error)) {
+	s.onError = onError
+}
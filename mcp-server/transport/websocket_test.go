@@ -0,0 +1,164 @@
+package transport
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/gorilla/websocket"
+	mcpauth "github.com/kakkky/mcp-sdk-go/mcp-server/auth"
+	mcperr "github.com/kakkky/mcp-sdk-go/shared/mcp-err"
+	"github.com/kakkky/mcp-sdk-go/shared/schema"
+	"github.com/kakkky/mcp-sdk-go/shared/transport"
+)
+
+func TestNewWebSocketServerTransport_ValidateHostAndOrigin(t *testing.T) {
+	tests := []struct {
+		name           string
+		options        *WebSocketServerOptions
+		host           string
+		origin         string
+		expectedStatus int
+	}{
+		{
+			name:           "normal : loopback host without origin",
+			expectedStatus: http.StatusSwitchingProtocols,
+		},
+		{
+			name:           "normal : configured host and origin",
+			options:        &WebSocketServerOptions{HttpSecurityOptions: HttpSecurityOptions{AllowedHosts: []string{"mcp.example.com"}, AllowedOrigins: []string{"https://app.example.com"}}},
+			host:           "mcp.example.com",
+			origin:         "https://app.example.com",
+			expectedStatus: http.StatusSwitchingProtocols,
+		},
+		{
+			name:           "normal : CheckOrigin replaces the origin check",
+			options:        &WebSocketServerOptions{CheckOrigin: func(r *http.Request) bool { return true }},
+			origin:         "https://anywhere.example.com",
+			expectedStatus: http.StatusSwitchingProtocols,
+		},
+		{
+			name:           "semi normal : rebinded host is rejected",
+			host:           "attacker.com:8080",
+			origin:         "http://attacker.com:8080",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "semi normal : host is checked even with CheckOrigin",
+			options:        &WebSocketServerOptions{CheckOrigin: func(r *http.Request) bool { return true }},
+			host:           "evil.example.com",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "semi normal : hostile origin is rejected",
+			origin:         "http://evil.example.com",
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				sut, err := NewWebSocketServerTransport(w, r, tt.options)
+				if err != nil {
+					return
+				}
+				if err := sut.Start(); err != nil {
+					t.Errorf("Start() error = %v", err)
+					return
+				}
+				_ = sut.Close()
+			}))
+			defer httpServer.Close()
+
+			header := http.Header{}
+			if tt.host != "" {
+				header.Set("Host", tt.host)
+			}
+			if tt.origin != "" {
+				header.Set("Origin", tt.origin)
+			}
+			dialer := websocket.Dialer{Subprotocols: []string{transport.WEBSOCKET_SUBPROTOCOL}}
+			conn, resp, err := dialer.Dial("ws"+strings.TrimPrefix(httpServer.URL, "http"), header)
+			if conn != nil {
+				_ = conn.Close()
+			}
+			if resp == nil {
+				t.Fatalf("Dial() error = %v", err)
+			}
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.expectedStatus)
+			}
+		})
+	}
+}
+
+func TestWebSocketServerTransport_RateLimitPerMessage(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	// ハンドシェイクで1回、以降のリクエストで2回まで受け付ける
+	verifier := mcpauth.NewAPIKeyVerifier(mcpauth.StaticAPIKeyStore{
+		{Key: "limited", Name: "service-a", RateLimit: &mcpauth.RateLimit{Requests: 3, Per: time.Minute}},
+	}, &mcpauth.APIKeyVerifierOptions{Now: func() time.Time { return now }})
+	received := make(chan schema.JsonRpcRequest, 3)
+	handler := mcpauth.RequireBearerToken(mcpauth.ResourceServerOptions{Verifier: verifier})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sut, err := NewWebSocketServerTransport(w, r, nil)
+		if err != nil {
+			t.Errorf("NewWebSocketServerTransport() error = %v", err)
+			return
+		}
+		sut.SetOnReceiveMessage(func(message schema.JsonRpcMessage) {
+			request := message.(schema.JsonRpcRequest)
+			received <- request
+			_ = sut.SendMessage(schema.JsonRpcResponse{
+				BaseMessage: schema.BaseMessage{Jsonrpc: schema.JSON_RPC_VERSION, Id: request.Id},
+				Result:      &schema.EmptyResultSchema{},
+			})
+		})
+		if err := sut.Start(); err != nil {
+			t.Errorf("Start() error = %v", err)
+		}
+	}))
+	httpServer := httptest.NewServer(handler)
+	defer httpServer.Close()
+
+	header := http.Header{"Authorization": []string{"Bearer limited"}}
+	dialer := websocket.Dialer{Subprotocols: []string{transport.WEBSOCKET_SUBPROTOCOL}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(httpServer.URL, "http"), header)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+
+	var gotErrors []any
+	for id := 1; id <= 3; id++ {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"ping"}`, id))); err != nil {
+			t.Fatalf("WriteMessage() error = %v", err)
+		}
+		var response struct {
+			Id    int            `json:"id"`
+			Error map[string]any `json:"error"`
+		}
+		if err := conn.ReadJSON(&response); err != nil {
+			t.Fatalf("ReadJSON() error = %v", err)
+		}
+		if response.Id != id {
+			t.Fatalf("response id = %d, want %d", response.Id, id)
+		}
+		gotErrors = append(gotErrors, response.Error)
+	}
+	want := []any{
+		map[string]any(nil),
+		map[string]any(nil),
+		map[string]any{"code": float64(mcperr.RATE_LIMITED), "message": "rate limit exceeded: retry after 20s", "data": map[string]any{"retryAfter": float64(20)}},
+	}
+	if diff := cmp.Diff(want, gotErrors); diff != "" {
+		t.Errorf("responses mismatch (-want +got):\n%s", diff)
+	}
+	// レート制限を超えたリクエストはサーバーに渡されない
+	if len(received) != 2 {
+		t.Errorf("received %d requests, want 2", len(received))
+	}
+}
@@ -0,0 +1,335 @@
+package mcpserver
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	mcperr "github.com/kakkky/mcp-sdk-go/shared/mcp-err"
+	"github.com/kakkky/mcp-sdk-go/shared/schema"
+)
+
+// 構造体の引数と戻り値を持つ関数をツールとして登録する
+// 入力のスキーマはInのフィールドとタグから生成する
+//   - json: 引数名。"-"のフィールドは含めない。omitemptyを指定しないフィールドは必須となる
+//   - description: 引数の説明
+//   - enum: カンマ区切りの取りうる値
+//   - required: "true"または"false"で、omitemptyによる判定を上書きする
+//   - default: 引数が省略された場合の値
+//   - minimum, maximum: 数値の範囲
+//   - format, pattern: 文字列の形式と正規表現
+//
+// 構造体、スライス、mapのフィールドは、入れ子のスキーマとなる
+// 引数は入力スキーマで検証し、既定値を設定した上でInにデコードする
+// ctxからは、auth.AuthInfoFromContextで呼び出し元の認証情報を、ExchangeFromContextでExchangeを取得できる
+// Outがstringの場合はそのまま、schema.CallToolResultSchemaの場合はそのまま結果とし、それ以外はJSONのテキストとして返す
+// Outが構造体の場合は、出力のスキーマをoutputSchemaとして公開し、結果をstructuredContentとしても返す
+func AddTool[In, Out any](m *McpServer, name string, description string, handler func(ctx context.Context, in In) (Out, error)) (*RegisteredTool, error) {
+	if handler == nil {
+		return nil, errors.New("handler is required")
+	}
+	inType := indirect(reflect.TypeFor[In]())
+	if inType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("input type of tool %s must be a struct, got %s", name, inType)
+	}
+	fields, err := toolFieldsOf(inType, map[reflect.Type]bool{inType: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate input schema of tool %s: %w", name, err)
+	}
+	inputSchema := objectSchemaOf(fields)
+	outputSchema, err := outputSchemaOf(reflect.TypeFor[Out]())
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate output schema of tool %s: %w", name, err)
+	}
+	return m.registerTool(name, RegisteredTool{
+		description:    description,
+		propertySchema: inputSchema.Properties,
+		inputSchema:    &inputSchema,
+		outputSchema:   outputSchema,
+		// Outがポインタの場合、nilは構造化された結果が無いことを表す
+		structuredContentOptional: reflect.TypeFor[Out]().Kind() == reflect.Pointer,
+		callback: func(ctx context.Context, exchange *Exchange, args map[string]any) (schema.CallToolResultSchema, error) {
+			in, err := decodeToolArguments[In](name, inputSchema, args)
+			if err != nil {
+				return schema.CallToolResultSchema{}, err
+			}
+			out, err := handler(ctx, in)
+			if err != nil {
+				return schema.CallToolResultSchema{}, err
+			}
+			return toolResultOf(out)
+		},
+	})
+}
+
+// Outが構造体の場合は、結果をstructuredContentとしても返すため、出力のスキーマを生成する
+func outputSchemaOf(t reflect.Type) (*schema.JsonSchema, error) {
+	t = indirect(t)
+	if t.Kind() != reflect.Struct || t == timeType || t == reflect.TypeFor[schema.CallToolResultSchema]() {
+		return nil, nil
+	}
+	outputSchema, err := jsonSchemaOf(t, map[reflect.Type]bool{})
+	if err != nil {
+		return nil, err
+	}
+	return &outputSchema, nil
+}
+
+// 入力の構造体の1つのフィールド
+type toolField struct {
+	name     string
+	property schema.PropertyInfoSchema
+	required bool
+}
+
+func objectSchemaOf(fields []toolField) schema.JsonSchema {
+	properties := make(schema.PropertySchema, len(fields))
+	var required []string
+	for _, field := range fields {
+		properties[field.name] = field.property
+		if field.required {
+			required = append(required, field.name)
+		}
+	}
+	return schema.Object(properties, required...)
+}
+
+// visitingは再帰的な型で無限に展開しないよう、展開中の構造体の型を持つ
+func toolFieldsOf(t reflect.Type, visiting map[reflect.Type]bool) ([]toolField, error) {
+	var fields []toolField
+	for i := range t.NumField() {
+		structField := t.Field(i)
+		jsonTag := structField.Tag.Get("json")
+		if jsonTag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(jsonTag, ",")
+		// encoding/jsonと同様に、名前の無い埋め込み構造体のフィールドは非公開の型でも展開する
+		isEmbeddedStruct := structField.Anonymous && name == "" && indirect(structField.Type).Kind() == reflect.Struct
+		if !structField.IsExported() && !isEmbeddedStruct {
+			continue
+		}
+		if isEmbeddedStruct {
+			embedded, err := toolFieldsOf(indirect(structField.Type), visiting)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, embedded...)
+			continue
+		}
+		if name == "" {
+			name = structField.Name
+		}
+		field, err := toolFieldOf(name, structField, slices.Contains(strings.Split(options, ","), "omitempty"), visiting)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", structField.Name, err)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+func toolFieldOf(name string, structField reflect.StructField, omitempty bool, visiting map[reflect.Type]bool) (toolField, error) {
+	property, err := jsonSchemaOf(structField.Type, visiting)
+	if err != nil {
+		return toolField{}, err
+	}
+	property.Description = structField.Tag.Get("description")
+	property.Format = cmp.Or(structField.Tag.Get("format"), property.Format)
+	property.Pattern = structField.Tag.Get("pattern")
+	jsonType := property.Type
+	field := toolField{
+		name:     name,
+		property: property,
+		required: !omitempty,
+	}
+	if required, ok := structField.Tag.Lookup("required"); ok {
+		isRequired, err := strconv.ParseBool(required)
+		if err != nil {
+			return toolField{}, fmt.Errorf("invalid required tag %q", required)
+		}
+		field.required = isRequired
+	}
+	if enum, ok := structField.Tag.Lookup("enum"); ok {
+		for _, value := range strings.Split(enum, ",") {
+			enumValue, err := parseTagValue(jsonType, value)
+			if err != nil {
+				return toolField{}, fmt.Errorf("invalid enum tag: %w", err)
+			}
+			field.property.Enum = append(field.property.Enum, enumValue)
+		}
+	}
+	if value, ok := structField.Tag.Lookup("default"); ok {
+		defaultValue, err := parseTagValue(jsonType, value)
+		if err != nil {
+			return toolField{}, fmt.Errorf("invalid default tag: %w", err)
+		}
+		field.property.Default = defaultValue
+	}
+	for tag, target := range map[string]**float64{"minimum": &field.property.Minimum, "maximum": &field.property.Maximum} {
+		value, ok := structField.Tag.Lookup(tag)
+		if !ok {
+			continue
+		}
+		bound, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return toolField{}, fmt.Errorf("invalid %s tag %q", tag, value)
+		}
+		*target = &bound
+	}
+	return field, nil
+}
+
+var timeType = reflect.TypeFor[time.Time]()
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// Goの型に対応するJSON Schema
+func jsonSchemaOf(t reflect.Type, visiting map[reflect.Type]bool) (schema.JsonSchema, error) {
+	t = indirect(t)
+	switch {
+	case t == timeType:
+		return schema.JsonSchema{Type: "string", Format: "date-time"}, nil
+	case t.Kind() == reflect.Interface:
+		// 任意の値を受け付ける
+		return schema.JsonSchema{}, nil
+	case t.Kind() == reflect.Struct:
+		if visiting[t] {
+			return schema.JsonSchema{Type: "object"}, nil
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+		fields, err := toolFieldsOf(t, visiting)
+		if err != nil {
+			return schema.JsonSchema{}, err
+		}
+		return objectSchemaOf(fields), nil
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		// encoding/jsonと同様に、[]byteはbase64の文字列とする
+		return schema.JsonSchema{Type: "string", ContentEncoding: "base64"}, nil
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		items, err := jsonSchemaOf(t.Elem(), visiting)
+		if err != nil {
+			return schema.JsonSchema{}, err
+		}
+		return schema.Array("", items), nil
+	case t.Kind() == reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return schema.JsonSchema{}, fmt.Errorf("map key must be a string, got %s", t.Key())
+		}
+		values, err := jsonSchemaOf(t.Elem(), visiting)
+		if err != nil {
+			return schema.JsonSchema{}, err
+		}
+		return schema.JsonSchema{Type: "object"}.WithAdditionalPropertiesSchema(values), nil
+	default:
+		return schema.JsonSchema{Type: jsonTypeOf(t)}, nil
+	}
+}
+
+// Goの型に対応するJSON Schemaの型
+func jsonTypeOf(t reflect.Type) string {
+	t = indirect(t)
+	if t == timeType {
+		return "string"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// タグに書かれた値を、フィールドの型に合わせて変換する
+func parseTagValue(jsonType string, value string) (any, error) {
+	switch jsonType {
+	case "string":
+		return value, nil
+	case "boolean":
+		return strconv.ParseBool(value)
+	case "integer":
+		return strconv.ParseInt(value, 10, 64)
+	case "number":
+		return strconv.ParseFloat(value, 64)
+	default:
+		var parsed any
+		if err := json.Unmarshal([]byte(value), &parsed); err != nil {
+			return nil, err
+		}
+		return parsed, nil
+	}
+}
+
+// 入力スキーマで検証し、省略された引数に既定値を設定してからデコードする
+func decodeToolArguments[In any](name string, inputSchema schema.InputSchema, args map[string]any) (In, error) {
+	var in In
+	values, err := validateToolArguments(name, inputSchema, args, &schema.ValidateOptions{ApplyDefaults: true})
+	if err != nil {
+		return in, err
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return in, err
+	}
+	if err := json.Unmarshal(data, &in); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return in, mcperr.NewMcpErr(mcperr.INVALID_PARAMS, fmt.Sprintf("invalid arguments for tool %s: %s must be %s, got %s", name, typeErr.Field, jsonTypeOf(typeErr.Type), typeErr.Value), nil)
+		}
+		return in, mcperr.NewMcpErr(mcperr.INVALID_PARAMS, fmt.Sprintf("invalid arguments for tool %s: %v", name, err), nil)
+	}
+	return in, nil
+}
+
+func toolResultOf[Out any](out Out) (schema.CallToolResultSchema, error) {
+	switch v := any(out).(type) {
+	case schema.CallToolResultSchema:
+		return v, nil
+	case *schema.CallToolResultSchema:
+		if v == nil {
+			return schema.CallToolResultSchema{Content: []schema.ToolContentSchema{}}, nil
+		}
+		return *v, nil
+	case string:
+		return schema.CallToolResultSchema{
+			Content: []schema.ToolContentSchema{&schema.TextContentSchema{Type: "text", Text: v}},
+		}, nil
+	}
+	// nilのポインタはstructuredContentとせず、型付きのnilを検証しないようにする
+	if v := reflect.ValueOf(any(out)); !v.IsValid() || (v.Kind() == reflect.Pointer && v.IsNil()) {
+		return schema.CallToolResultSchema{Content: []schema.ToolContentSchema{}}, nil
+	}
+	data, err := json.Marshal(out)
+	if err != nil {
+		return schema.CallToolResultSchema{}, fmt.Errorf("failed to encode tool result: %w", err)
+	}
+	result := schema.CallToolResultSchema{
+		Content: []schema.ToolContentSchema{&schema.TextContentSchema{Type: "text", Text: string(data)}},
+	}
+	if t := indirect(reflect.TypeFor[Out]()); t.Kind() == reflect.Struct && t != timeType {
+		result.StructuredContent = out
+	}
+	return result, nil
+}
@@ -0,0 +1,195 @@
+package mcpserver
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kakkky/mcp-sdk-go/mcp-server/server"
+	"github.com/kakkky/mcp-sdk-go/shared/auth"
+	mcperr "github.com/kakkky/mcp-sdk-go/shared/mcp-err"
+	"github.com/kakkky/mcp-sdk-go/shared/schema"
+)
+
+type searchPaging struct {
+	Limit int `json:"limit,omitempty" description:"max results" default:"10" minimum:"1" maximum:"100"`
+}
+
+type searchInput struct {
+	Query  string        `json:"query" description:"search query"`
+	Sort   string        `json:"sort,omitempty" description:"sort order" enum:"asc,desc"`
+	Tags   []string      `json:"tags,omitempty"`
+	Filter *searchFilter `json:"filter,omitempty"`
+	Debug  bool          `json:"-"`
+	searchPaging
+}
+
+type searchFilter struct {
+	From time.Time         `json:"from"`
+	Meta map[string]string `json:"meta,omitempty"`
+}
+
+type searchOutput struct {
+	Query   string `json:"query"`
+	Limit   int    `json:"limit"`
+	Sort    string `json:"sort,omitempty"`
+	Subject string `json:"subject,omitempty"`
+}
+
+func TestAddTool(t *testing.T) {
+	sut := NewMcpServer(schema.Implementation{}, &server.ServerOptions{})
+	conn := newTestConnection(t, sut.Connect)
+	tool, err := AddTool(sut, "search", "search documents", func(ctx context.Context, in searchInput) (searchOutput, error) {
+		out := searchOutput{Query: in.Query, Limit: in.Limit, Sort: in.Sort}
+		if info, ok := auth.AuthInfoFromContext(ctx); ok {
+			out.Subject = info.Subject
+		}
+		return out, nil
+	})
+	if err != nil {
+		t.Fatalf("AddTool() error = %v", err)
+	}
+
+	wantInputSchema := &schema.InputSchema{
+		Type: "object",
+		Properties: schema.PropertySchema{
+			"query": schema.String("search query"),
+			"sort":  schema.String("sort order").WithEnum("asc", "desc"),
+			"tags":  schema.Array("", schema.JsonSchema{Type: "string"}),
+			"limit": schema.Integer("max results").WithDefault(int64(10)).WithMinimum(1).WithMaximum(100),
+			"filter": schema.Object(schema.PropertySchema{
+				"from": {Type: "string", Format: "date-time"},
+				"meta": schema.JsonSchema{Type: "object"}.WithAdditionalPropertiesSchema(schema.JsonSchema{Type: "string"}),
+			}, "from"),
+		},
+		Required: []string{"query"},
+	}
+	if diff := cmp.Diff(wantInputSchema, tool.inputSchema); diff != "" {
+		t.Errorf("input schema mismatch (-want +got):\n%s", diff)
+	}
+
+	tests := []struct {
+		name            string
+		arguments       map[string]any
+		authInfo        *auth.AuthInfo
+		expectedText    string
+		expectedErrCode int
+		isExpectedErr   bool
+	}{
+		{
+			name:         "normal : default is applied to omitted argument",
+			arguments:    map[string]any{"query": "mcp"},
+			expectedText: `{"query":"mcp","limit":10}`,
+		},
+		{
+			name:         "normal : all arguments with auth info in context",
+			arguments:    map[string]any{"query": "mcp", "sort": "desc", "limit": float64(5)},
+			authInfo:     &auth.AuthInfo{Subject: "alice"},
+			expectedText: `{"query":"mcp","limit":5,"sort":"desc","subject":"alice"}`,
+		},
+		{
+			name:          "semi normal : missing required argument",
+			arguments:     map[string]any{},
+			isExpectedErr: true,
+		},
+		{
+			name:          "semi normal : value not in enum",
+			arguments:     map[string]any{"query": "mcp", "sort": "random"},
+			isExpectedErr: true,
+		},
+		{
+			name:          "semi normal : value out of range",
+			arguments:     map[string]any{"query": "mcp", "limit": float64(1000)},
+			isExpectedErr: true,
+		},
+		{
+			name:          "semi normal : wrong type",
+			arguments:     map[string]any{"query": float64(1)},
+			isExpectedErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := conn.request(&schema.CallToolRequestSchema{
+				MethodName: "tools/call",
+				ParamsData: schema.CallToolRequestParams{Name: "search", Arguments: tt.arguments},
+			}, tt.authInfo)
+			if tt.isExpectedErr {
+				errResponse, ok := response.(schema.JsonRpcError)
+				if !ok || errResponse.Error.Code != mcperr.INVALID_PARAMS {
+					t.Errorf("response = %+v, want INVALID_PARAMS error", response)
+				}
+				return
+			}
+			result, ok := response.(schema.JsonRpcResponse)
+			if !ok {
+				t.Fatalf("response = %+v, want result", response)
+			}
+			content := result.Result.(*schema.CallToolResultSchema).Content
+			if got := fmt.Sprint(content[0].(*schema.TextContentSchema).Text); got != tt.expectedText {
+				t.Errorf("result = %s, want %s", got, tt.expectedText)
+			}
+		})
+	}
+}
+
+func TestAddTool_InvalidInputType(t *testing.T) {
+	sut := NewMcpServer(schema.Implementation{}, &server.ServerOptions{})
+	if _, err := AddTool(sut, "invalid", "input is not a struct", func(ctx context.Context, in string) (string, error) {
+		return in, nil
+	}); err == nil {
+		t.Error("AddTool() error = nil, want error")
+	}
+}
+
+func TestAddTool_PointerOutput(t *testing.T) {
+	sut := NewMcpServer(schema.Implementation{}, &server.ServerOptions{})
+	conn := newTestConnection(t, sut.Connect)
+	tool, err := AddTool(sut, "find", "find a document", func(ctx context.Context, in searchInput) (*searchOutput, error) {
+		if in.Query == "missing" {
+			return nil, nil
+		}
+		return &searchOutput{Query: in.Query, Limit: in.Limit}, nil
+	})
+	if err != nil {
+		t.Fatalf("AddTool() error = %v", err)
+	}
+	// 登録した時点で、入力と出力のスキーマが設定されている
+	if tool.inputSchema == nil || tool.outputSchema == nil {
+		t.Fatalf("schemas are not set on registration: input = %v, output = %v", tool.inputSchema, tool.outputSchema)
+	}
+
+	tests := []struct {
+		name                      string
+		query                     string
+		expectedStructuredContent any
+	}{
+		{
+			name:                      "normal : returned struct is structured content",
+			query:                     "mcp",
+			expectedStructuredContent: map[string]any{"query": "mcp", "limit": float64(10)},
+		},
+		{
+			name:                      "normal : nil is returned without structured content",
+			query:                     "missing",
+			expectedStructuredContent: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := conn.request(&schema.CallToolRequestSchema{
+				MethodName: "tools/call",
+				ParamsData: schema.CallToolRequestParams{Name: "find", Arguments: map[string]any{"query": tt.query}},
+			}, nil)
+			result, ok := response.(schema.JsonRpcResponse)
+			if !ok {
+				t.Fatalf("response = %+v, want result", response)
+			}
+			if diff := cmp.Diff(tt.expectedStructuredContent, result.Result.(*schema.CallToolResultSchema).StructuredContent); diff != "" {
+				t.Errorf("structured content mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+// 検証済みのアクセストークンから得られる認証情報
+type AuthInfo struct {
+	// 検証したアクセストークン
+	Token string
+	// トークンを発行されたクライアントのID（client_id、azp）
+	ClientId string
+	// トークンの主体（sub）
+	Subject string
+	// トークンに付与されたスコープ
+	Scopes []string
+	// トークンの有効期限。ゼロ値の場合は期限なし
+	ExpiresAt time.Time
+	// トークンに含まれる全てのクレーム
+	Claims map[string]any
+}
+
+// 指定した全てのスコープを持つかどうか
+func (a *AuthInfo) HasScopes(scopes ...string) bool {
+	if a == nil {
+		return len(scopes) == 0
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(a.Scopes, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+type authInfoKey struct{}
+
+// 認証情報を持つcontextを返す
+func WithAuthInfo(ctx context.Context, info *AuthInfo) context.Context {
+	return context.WithValue(ctx, authInfoKey{}, info)
+}
+
+// WithAuthInfoで設定した認証情報を取り出す
+func AuthInfoFromContext(ctx context.Context) (*AuthInfo, bool) {
+	info, ok := ctx.Value(authInfoKey{}).(*AuthInfo)
+	return info, ok && info != nil
+}
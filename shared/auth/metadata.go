@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// 保護されたリソースのメタデータを公開するパス
+const PROTECTED_RESOURCE_METADATA_PATH = "/.well-known/oauth-protected-resource"
+
+// 保護されたリソース（MCPサーバー）のメタデータ
+// 参照：(https://datatracker.ietf.org/doc/html/rfc9728#section-2)
+type ProtectedResourceMetadata struct {
+	// リソースの識別子。MCPエンドポイントのURLを指定する
+	Resource string `json:"resource"`
+	// トークンを発行する認可サーバーの識別子（issuer）
+	AuthorizationServers   []string `json:"authorization_servers,omitempty"`
+	JwksUri                string   `json:"jwks_uri,omitempty"`
+	ScopesSupported        []string `json:"scopes_supported,omitempty"`
+	BearerMethodsSupported []string `json:"bearer_methods_supported,omitempty"`
+	ResourceName           string   `json:"resource_name,omitempty"`
+	ResourceDocumentation  string   `json:"resource_documentation,omitempty"`
+}
+
+// リソースの識別子から、メタデータを公開するURLを返す
+// "https://example.com/mcp"の場合は"https://example.com/.well-known/oauth-protected-resource/mcp"となる
+func ProtectedResourceMetadataURL(resource string) (string, error) {
+	return wellKnownURL(resource, PROTECTED_RESOURCE_METADATA_PATH)
+}
+
+// ホストとパスの間にwell-knownのパスを挿入する
+// 参照：(https://datatracker.ietf.org/doc/html/rfc8615)
+func wellKnownURL(identifier string, wellKnownPath string) (string, error) {
+	u, err := url.Parse(identifier)
+	if err != nil {
+		return "", fmt.Errorf("invalid identifier %q: %w", identifier, err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("identifier must be an absolute URL: %q", identifier)
+	}
+	u.Path = wellKnownPath + strings.TrimSuffix(u.Path, "/")
+	u.RawPath = ""
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String(), nil
+}
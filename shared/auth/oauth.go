@@ -0,0 +1,81 @@
+package auth
+
+import "time"
+
+// 認可サーバーのメタデータを公開するパス
+const (
+	AUTHORIZATION_SERVER_METADATA_PATH = "/.well-known/oauth-authorization-server"
+	OPENID_CONFIGURATION_PATH          = "/.well-known/openid-configuration"
+)
+
+// 認可サーバーのメタデータ
+// 参照：(https://datatracker.ietf.org/doc/html/rfc8414#section-2)
+type AuthorizationServerMetadata struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	RegistrationEndpoint              string   `json:"registration_endpoint,omitempty"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint,omitempty"`
+	RevocationEndpoint                string   `json:"revocation_endpoint,omitempty"`
+	JwksUri                           string   `json:"jwks_uri,omitempty"`
+	ScopesSupported                   []string `json:"scopes_supported,omitempty"`
+	ResponseTypesSupported            []string `json:"response_types_supported,omitempty"`
+	GrantTypesSupported               []string `json:"grant_types_supported,omitempty"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported,omitempty"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported,omitempty"`
+}
+
+// 動的クライアント登録で送るクライアントのメタデータ
+// 参照：(https://datatracker.ietf.org/doc/html/rfc7591#section-2)
+type OAuthClientMetadata struct {
+	RedirectUris            []string `json:"redirect_uris"`
+	TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method,omitempty"`
+	GrantTypes              []string `json:"grant_types,omitempty"`
+	ResponseTypes           []string `json:"response_types,omitempty"`
+	ClientName              string   `json:"client_name,omitempty"`
+	ClientUri               string   `json:"client_uri,omitempty"`
+	Scope                   string   `json:"scope,omitempty"`
+	SoftwareId              string   `json:"software_id,omitempty"`
+	SoftwareVersion         string   `json:"software_version,omitempty"`
+}
+
+// 認可サーバーに登録されたクライアントの情報
+// 参照：(https://datatracker.ietf.org/doc/html/rfc7591#section-3.2.1)
+type ClientInformation struct {
+	OAuthClientMetadata
+	ClientId              string `json:"client_id"`
+	ClientSecret          string `json:"client_secret,omitempty"`
+	ClientIdIssuedAt      int64  `json:"client_id_issued_at,omitempty"`
+	ClientSecretExpiresAt int64  `json:"client_secret_expires_at,omitempty"`
+}
+
+// トークンエンドポイントの応答
+// 参照：(https://datatracker.ietf.org/doc/html/rfc6749#section-5.1)
+type Tokens struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+	// ExpiresInから求めた有効期限。ゼロ値の場合は期限なし
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+}
+
+// トークンエンドポイントなどが返すエラー
+// 参照：(https://datatracker.ietf.org/doc/html/rfc6749#section-5.2)
+type OAuthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func (e *OAuthError) Error() string {
+	if e.Description == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Description
+}
+
+// 認可サーバーの識別子（issuer）から、メタデータを公開するURLを返す
+func AuthorizationServerMetadataURL(issuer string) (string, error) {
+	return wellKnownURL(issuer, AUTHORIZATION_SERVER_METADATA_PATH)
+}
@@ -28,6 +28,10 @@ const (
 	// Custom error codes
 	CONNECTION_CLOSED = -32000
 	REQUEST_TIMEOUT   = -32001
+	// 呼び出し元に権限が無い
+	PERMISSION_DENIED = -32003
+	// 呼び出し元のレート制限を超えた
+	RATE_LIMITED = -32004
 
 	// Standard JSON-RPC error codes
 	PARSE_ERROR      = -32700
@@ -1,6 +1,7 @@
 package protocol
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
@@ -24,13 +25,17 @@ func (p *Protocol) onReceiveMessage(message schema.JsonRpcMessage) {
 }
 
 func (p *Protocol) onRequest(request schema.JsonRpcRequest) {
-	handler := p.handlers.requestHandlers[request.Method()]
-	if handler == nil && p.handlers.fallbackRequestHandler != nil {
-		p.handlers.fallbackRequestHandler()
+	handler, fallback := p.handlers.requestHandlerOf(request.Method())
+	if handler == nil && fallback != nil {
+		fallback()
 		return
 	}
 	if handler == nil {
-		err := p.transport.SendMessage(
+		transport := p.Transport()
+		if transport == nil {
+			return
+		}
+		err := transport.SendMessage(
 			schema.JsonRpcError{
 				BaseMessage: schema.BaseMessage{
 					Jsonrpc: schema.JSON_RPC_VERSION,
@@ -47,12 +52,43 @@ func (p *Protocol) onRequest(request schema.JsonRpcRequest) {
 		}
 		return
 	}
+	ctx, cancel := context.WithCancel(context.Background())
+	request.Context = ctx
+	p.inFlightMu.Lock()
+	p.inFlightRequests[request.Id] = cancel
+	p.inFlightMu.Unlock()
+
+	// リクエストは受信した順に1つずつ処理し、応答を書き終えてから次のメッセージを受信する
+	// ただし、ハンドラーがリクエストを送信して応答を待ち始めた場合は、その応答やnotifications/cancelledを受信できるよう受信ループを再開する
+	done := make(chan struct{})
+	awaitingResponse := p.awaitingResponseCh()
+	go func() {
+		defer close(done)
+		defer p.finishInFlightRequest(request.Id, cancel)
+		p.handleRequest(ctx, handler, request)
+	}()
+	select {
+	case <-done:
+	case <-awaitingResponse:
+	}
+}
+
+func (p *Protocol) handleRequest(ctx context.Context, handler requestHandler, request schema.JsonRpcRequest) {
 	result, err := handler(request)
+	// 取り消されたリクエストには応答しない
+	// 参照：(https://modelcontextprotocol.io/specification/2025-06-18/basic/utilities/cancellation)
+	if ctx.Err() != nil {
+		return
+	}
+	transport := p.Transport()
+	if transport == nil {
+		return
+	}
 	if err != nil {
 		// MCPエラー
 		if mcpErr, ok := err.(*mcperr.McpErr); ok {
 			code := mcpErr.Code
-			if err := p.transport.SendMessage(
+			if err := transport.SendMessage(
 				schema.JsonRpcError{
 					BaseMessage: schema.BaseMessage{
 						Jsonrpc: schema.JSON_RPC_VERSION,
@@ -61,6 +97,7 @@ func (p *Protocol) onRequest(request schema.JsonRpcRequest) {
 					Error: schema.Error{
 						Code:    code,
 						Message: err.Error(),
+						Data:    mcpErr.Data,
 					},
 				},
 			); err != nil {
@@ -69,7 +106,7 @@ func (p *Protocol) onRequest(request schema.JsonRpcRequest) {
 			return
 		}
 		// MCPエラーではないエラー
-		if err := p.transport.SendMessage(
+		if err := transport.SendMessage(
 			schema.JsonRpcError{
 				BaseMessage: schema.BaseMessage{
 					Jsonrpc: schema.JSON_RPC_VERSION,
@@ -85,7 +122,7 @@ func (p *Protocol) onRequest(request schema.JsonRpcRequest) {
 		}
 		return
 	}
-	if err := p.transport.SendMessage(schema.JsonRpcResponse{
+	if err := transport.SendMessage(schema.JsonRpcResponse{
 		BaseMessage: schema.BaseMessage{
 			Jsonrpc: schema.JSON_RPC_VERSION,
 			Id:      request.Id,
@@ -98,41 +135,48 @@ func (p *Protocol) onRequest(request schema.JsonRpcRequest) {
 
 func (p *Protocol) onResponse(response schema.JsonRpcResponse) {
 	messageId := response.Id
-	handler := p.handlers.responseHandlers[messageId]
-	if handler == nil {
+	handler, respCh, ok := p.takeResponseHandler(messageId)
+	if !ok {
 		err := fmt.Errorf("received a response for an unknown message ID: %d", messageId)
 		p.onError(err)
 		return
 	}
-	defer delete(p.handlers.responseHandlers, messageId)
+	// 結果の型が一致しない場合なども、応答を待っているリクエストにエラーを返す
 	result, err := handler(&response, nil)
-	if err != nil {
-		p.onError(err)
-		return
-	}
-
-	p.respCh <- result
-
+	p.deliverResponse(respCh, result, err)
 }
 
 func (p *Protocol) onErrResponse(errResponse schema.JsonRpcError) {
 	messageId := errResponse.Id
-	handler := p.handlers.responseHandlers[messageId]
-	if handler == nil {
+	handler, respCh, ok := p.takeResponseHandler(messageId)
+	if !ok {
 		p.onError(fmt.Errorf("received a response for an unknown message ID: %d", messageId))
 		return
 	}
-	defer delete(p.handlers.responseHandlers, messageId)
-	err := mcperr.NewMcpErr(errResponse.Error.Code, errResponse.Error.Message, errResponse.Error.Data)
-
-	p.errRespCh <- err
+	result, err := handler(nil, mcperr.NewMcpErr(errResponse.Error.Code, errResponse.Error.Message, errResponse.Error.Data))
+	p.deliverResponse(respCh, result, err)
+}
 
+// 応答を待っているリクエストに結果を渡す
+// チャネルはリクエストごとに容量1で作成され、1度だけ送信するため、受信ループはブロックされない
+func (p *Protocol) deliverResponse(respCh chan responseResult, result schema.Result, err error) {
+	if respCh == nil {
+		// SetResponseHandlerで直接登録したハンドラーには、結果を待っているリクエストが無い
+		if err != nil {
+			p.onError(err)
+		}
+		return
+	}
+	respCh <- responseResult{result: result, err: err}
 }
 
 func (p *Protocol) onNotification(notification schema.JsonRpcNotification) {
-	handler := p.handlers.notificationHandlers[notification.Method()]
-	if handler == nil && p.handlers.fallbackNotificationHandler != nil {
-		p.handlers.fallbackNotificationHandler()
+	if cancelled, ok := notification.Notification.(*schema.CancelledNotificationSchema); ok {
+		p.cancelInFlightRequest(cancelled.ParamsData.RequestId)
+	}
+	handler, fallback := p.handlers.notificationHandlerOf(notification.Method())
+	if handler == nil && fallback != nil {
+		fallback()
 		return
 	}
 	if handler == nil {
@@ -142,3 +186,27 @@ func (p *Protocol) onNotification(notification schema.JsonRpcNotification) {
 		p.onError(err)
 	}
 }
+
+// 処理中のリクエストのコンテキストを取り消す
+func (p *Protocol) cancelInFlightRequest(requestId int) {
+	p.inFlightMu.Lock()
+	defer p.inFlightMu.Unlock()
+	if cancel, ok := p.inFlightRequests[requestId]; ok {
+		cancel()
+	}
+}
+
+func (p *Protocol) cancelInFlightRequests() {
+	p.inFlightMu.Lock()
+	defer p.inFlightMu.Unlock()
+	for _, cancel := range p.inFlightRequests {
+		cancel()
+	}
+}
+
+func (p *Protocol) finishInFlightRequest(requestId int, cancel context.CancelFunc) {
+	p.inFlightMu.Lock()
+	defer p.inFlightMu.Unlock()
+	cancel()
+	delete(p.inFlightRequests, requestId)
+}
@@ -2,16 +2,20 @@ package protocol
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/kakkky/mcp-sdk-go/shared/schema"
 )
 
 type handlers struct {
+	// 接続中にもハンドラーを登録できるよう、受信ループからの参照と登録を保護する
+	mu                          sync.RWMutex
 	requestHandlers             map[string]requestHandler
 	notificationHandlers        map[string]notificationHandler
-	responseHandlers            map[int]responseHandler
 	fallbackNotificationHandler func()
 	fallbackRequestHandler      func()
+	// Protocol.muで保護する
+	responseHandlers map[int]responseHandler
 }
 
 type requestHandler func(request schema.JsonRpcRequest) (schema.Result, error)
@@ -29,27 +33,53 @@ func (p *Protocol) SetRequestHandler(requestSchema schema.Request, handler func(
 		}
 	}
 	// TODO: ここで、指定されたmethodをすでに登録していないか確認
+	p.handlers.mu.Lock()
+	defer p.handlers.mu.Unlock()
 	p.handlers.requestHandlers[method()] = handler
 }
 
 func (p *Protocol) SetNotificationHandler(notificationSchema schema.Notification, handler func(notification schema.JsonRpcNotification) error) {
 	method := notificationSchema.Method
+	p.handlers.mu.Lock()
+	defer p.handlers.mu.Unlock()
 	p.handlers.notificationHandlers[method()] = handler
 }
 
 // リクエスト送信の際に、対応するレスポンスハンドラを登録する
 func (p *Protocol) SetResponseHandler(messageId int, handler func(response *schema.JsonRpcResponse, mcpErr error) (schema.Result, error)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	p.handlers.responseHandlers[messageId] = handler
 }
 
 func (p *Protocol) SetFallbackNotificationHandler(handler func(), notification schema.Notification) {
+	p.handlers.mu.Lock()
+	defer p.handlers.mu.Unlock()
 	p.handlers.fallbackNotificationHandler = handler
 }
 func (p *Protocol) SetFallbackRequestHandler(handler func(), request schema.Request) {
+	p.handlers.mu.Lock()
+	defer p.handlers.mu.Unlock()
 	p.handlers.fallbackRequestHandler = handler
 }
 
+// methodのリクエストハンドラーと、フォールバックのハンドラーを返す
+func (h *handlers) requestHandlerOf(method string) (requestHandler, func()) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.requestHandlers[method], h.fallbackRequestHandler
+}
+
+// methodの通知ハンドラーと、フォールバックのハンドラーを返す
+func (h *handlers) notificationHandlerOf(method string) (notificationHandler, func()) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.notificationHandlers[method], h.fallbackNotificationHandler
+}
+
 func (p *Protocol) ValidateCanSetRequestHandler(method string) error {
+	p.handlers.mu.RLock()
+	defer p.handlers.mu.RUnlock()
 	if p.handlers.requestHandlers[method] != nil {
 		return fmt.Errorf("request handler for method %s already exists , which would be overridden", method)
 	}
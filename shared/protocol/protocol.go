@@ -1,24 +1,41 @@
 package protocol
 
 import (
+	"context"
 	"fmt"
 	"reflect"
+	"sync"
 
 	mcperr "github.com/kakkky/mcp-sdk-go/shared/mcp-err"
 	"github.com/kakkky/mcp-sdk-go/shared/schema"
 )
 
 type Protocol struct {
-	transport            Transport
 	handlers             *handlers
-	requestMessageId     int
 	onClose              func()
 	onError              func(error)
 	options              *ProtocolOptions
 	capabilityValidators *capabilityValidators
 
-	respCh    chan schema.Result
-	errRespCh chan error
+	// transport、requestMessageId、応答を待っているリクエストを保護する
+	// transportは接続が終了するとonCloseでnilになるため、受信ループ以外のゴルーチンからはTransport()で参照する
+	mu               sync.Mutex
+	transport        Transport
+	requestMessageId int
+	// 応答を待っている送信したリクエストのIDと、その応答を受け取るチャネル
+	responseChs map[int]chan responseResult
+	// 受信ループがハンドラーの完了を待っている間に、送信したリクエストの応答を待ち始めたことを伝えるチャネル
+	awaitingResponse chan struct{}
+
+	// 処理中の受信したリクエストのIDと、そのコンテキストを取り消す関数
+	inFlightMu       sync.Mutex
+	inFlightRequests map[int]context.CancelFunc
+}
+
+// 送信したリクエストに対する応答の結果
+type responseResult struct {
+	result schema.Result
+	err    error
 }
 
 func NewProtocol(options *ProtocolOptions) *Protocol {
@@ -35,17 +52,28 @@ func NewProtocol(options *ProtocolOptions) *Protocol {
 			validateNotificationCapability:   nil,
 			validateRequestHandlerCapability: nil,
 		},
-		respCh:    make(chan schema.Result, 1),
-		errRespCh: make(chan error, 1),
+		responseChs:      make(map[int]chan responseResult),
+		inFlightRequests: make(map[int]context.CancelFunc),
 	}
 	p.onClose = func() {
+		p.mu.Lock()
 		responseHandlers := p.handlers.responseHandlers
-		for _, handler := range responseHandlers {
-			_, _ = handler(nil, mcperr.NewMcpErr(mcperr.CONNECTION_CLOSED, "connection closed", nil))
-		}
+		responseChs := p.responseChs
 		p.handlers.responseHandlers = make(map[int]responseHandler)
+		p.responseChs = make(map[int]chan responseResult)
 		p.transport = nil
+		p.mu.Unlock()
+		for messageId, handler := range responseHandlers {
+			// 応答を待っているリクエストを接続断のエラーで終了させる
+			result, err := handler(nil, mcperr.NewMcpErr(mcperr.CONNECTION_CLOSED, "connection closed", nil))
+			if respCh := responseChs[messageId]; respCh != nil {
+				respCh <- responseResult{result: result, err: err}
+			}
+		}
+		p.cancelInFlightRequests()
 	}
+	// SetOnErrorが呼ばれていない場合も、受信中のエラーでパニックしないようにする
+	p.onError = func(error) {}
 
 	p.SetRequestHandler(&schema.PingRequestSchema{MethodName: "ping"}, func(request schema.JsonRpcRequest) (schema.Result, error) {
 		return &schema.EmptyResultSchema{}, nil
@@ -68,32 +96,47 @@ func (p *Protocol) SetOnError(onError func(error)) {
 }
 
 func (p *Protocol) Connect(transport Transport) error {
+	p.mu.Lock()
 	p.transport = transport
-	p.transport.SetOnClose(p.onClose)
-	p.transport.SetOnError(p.onError)
-	p.transport.SetOnReceiveMessage(p.onReceiveMessage)
-	if err := p.transport.Start(); err != nil {
+	p.mu.Unlock()
+	transport.SetOnClose(p.onClose)
+	transport.SetOnError(p.onError)
+	transport.SetOnReceiveMessage(p.onReceiveMessage)
+	if err := transport.Start(); err != nil {
 		return err
 	}
 	return nil
 }
 
 func (p *Protocol) Close() error {
-	if p.transport == nil {
+	transport := p.Transport()
+	if transport == nil {
 		return fmt.Errorf("not connected")
 	}
-	if err := p.transport.Close(); err != nil {
+	if err := transport.Close(); err != nil {
 		return err
 	}
 	return nil
 }
 
+// 接続中のトランスポートを返す。接続が終了した後はnilを返す
 func (p *Protocol) Transport() Transport {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	return p.transport
 }
 
 func (p *Protocol) Request(request schema.Request, resultSchema any) (schema.Result, error) {
-	if p.transport == nil {
+	return p.RequestWithContext(context.Background(), request, resultSchema)
+}
+
+// ctxが取り消された場合は応答を待たずにctx.Err()を返し、相手にnotifications/cancelledを送る
+// 参照：(https://modelcontextprotocol.io/specification/2025-06-18/basic/utilities/cancellation)
+func (p *Protocol) RequestWithContext(ctx context.Context, request schema.Request, resultSchema any) (schema.Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if p.Transport() == nil {
 		return nil, fmt.Errorf("not connected")
 	}
 
@@ -103,17 +146,22 @@ func (p *Protocol) Request(request schema.Request, resultSchema any) (schema.Res
 		}
 
 	}
+	// 応答はリクエストごとのチャネルで受け取る。受信ループが1度だけ送信するため、容量は1とする
+	respCh := make(chan responseResult, 1)
+	// 接続の終了と競合しないよう、接続中であることの確認と応答の待機の登録を同じロックの中で行う
+	p.mu.Lock()
+	transport := p.transport
+	if transport == nil {
+		p.mu.Unlock()
+		return nil, fmt.Errorf("not connected")
+	}
 	p.requestMessageId += 1
 	messageId := p.requestMessageId
-	jsonRpcRequest := schema.JsonRpcRequest{
-		BaseMessage: schema.BaseMessage{
-			Jsonrpc: schema.JSON_RPC_VERSION,
-			Id:      messageId,
-		},
-		Request: request,
-	}
 	// リクエストに紐づくレスポンスハンドラを登録する
-	p.SetResponseHandler(messageId, func(response *schema.JsonRpcResponse, mcpErr error) (schema.Result, error) {
+	p.handlers.responseHandlers[messageId] = func(response *schema.JsonRpcResponse, mcpErr error) (schema.Result, error) {
+		if mcpErr != nil {
+			return nil, mcpErr
+		}
 		// レスポンスの型をチェック
 		result := response.Result
 		resultT := reflect.TypeOf(result)
@@ -122,22 +170,76 @@ func (p *Protocol) Request(request schema.Request, resultSchema any) (schema.Res
 			return nil, fmt.Errorf("result type mismatch: expected %s, got %s", schemaT, resultT)
 		}
 		return result, nil
-	})
+	}
+	p.responseChs[messageId] = respCh
+	p.mu.Unlock()
+
+	jsonRpcRequest := schema.JsonRpcRequest{
+		BaseMessage: schema.BaseMessage{
+			Jsonrpc: schema.JSON_RPC_VERSION,
+			Id:      messageId,
+		},
+		Request: request,
+	}
 	// リクエストの送信
-	if err := p.transport.SendMessage(jsonRpcRequest); err != nil {
+	if err := transport.SendMessage(jsonRpcRequest); err != nil {
+		p.takeResponseHandler(messageId)
 		return nil, err
 	}
-	// 登録したレスポンスハンドラーからの結果を待つ
+	// 応答を受信できるよう、ハンドラーの完了を待っている受信ループを再開させてから結果を待つ
+	p.releaseReceiveLoop()
 	select {
-	case result := <-p.respCh:
-		return result, nil
-	case err := <-p.errRespCh:
-		return nil, err
+	case response := <-respCh:
+		return response.result, response.err
+	case <-ctx.Done():
+		if _, _, ok := p.takeResponseHandler(messageId); ok {
+			_ = p.Notificate(&schema.CancelledNotificationSchema{
+				MethodName: "notifications/cancelled",
+				ParamsData: schema.CancelledNotificationParams{
+					RequestId: messageId,
+					Reason:    ctx.Err().Error(),
+				},
+			})
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// ハンドラーの完了を待つ受信ループが、送信したリクエストの応答を待ち始めるまでの間待機するチャネルを返す
+func (p *Protocol) awaitingResponseCh() <-chan struct{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.awaitingResponse == nil {
+		p.awaitingResponse = make(chan struct{})
 	}
+	return p.awaitingResponse
+}
+
+// ハンドラーの完了を待っている受信ループを再開させる
+func (p *Protocol) releaseReceiveLoop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.awaitingResponse != nil {
+		close(p.awaitingResponse)
+		p.awaitingResponse = nil
+	}
+}
+
+// 応答を受信したリクエストのレスポンスハンドラーと、応答を待っているチャネルを取り出して登録を解除する
+// SetResponseHandlerで直接登録したハンドラーの場合、チャネルはnilとなる
+func (p *Protocol) takeResponseHandler(messageId int) (responseHandler, chan responseResult, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	handler, ok := p.handlers.responseHandlers[messageId]
+	respCh := p.responseChs[messageId]
+	delete(p.handlers.responseHandlers, messageId)
+	delete(p.responseChs, messageId)
+	return handler, respCh, ok
 }
 
 func (p *Protocol) Notificate(notification schema.Notification) error {
-	if p.transport == nil {
+	transport := p.Transport()
+	if transport == nil {
 		return fmt.Errorf("not connected")
 	}
 	if p.capabilityValidators.validateNotificationCapability != nil {
@@ -149,7 +251,7 @@ func (p *Protocol) Notificate(notification schema.Notification) error {
 		Jsonrpc:      schema.JSON_RPC_VERSION,
 		Notification: notification,
 	}
-	if err := p.transport.SendMessage(jsonRpcNotification); err != nil {
+	if err := transport.SendMessage(jsonRpcNotification); err != nil {
 		return err
 	}
 	return nil
@@ -3,6 +3,7 @@ package protocol
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	mcperr "github.com/kakkky/mcp-sdk-go/shared/mcp-err"
@@ -283,3 +284,137 @@ func TestProtocol_Close(t *testing.T) {
 		})
 	}
 }
+
+func TestProtocol_RequestFailsOnClose(t *testing.T) {
+	client := NewProtocol(nil)
+	clientToServerCh := make(chan []byte, 1)
+	clientTransport := mock.NewMockChannelClientTransport(clientToServerCh, make(chan []byte, 1))
+	if err := client.Connect(clientTransport); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := client.Request(&schema.PingRequestSchema{MethodName: "ping"}, &schema.EmptyResultSchema{})
+		errCh <- err
+	}()
+	// リクエストが送信されたのを確認してから、応答を返さずに接続を閉じる
+	<-clientToServerCh
+	if err := clientTransport.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	err := <-errCh
+	e, ok := err.(*mcperr.McpErr)
+	if !ok {
+		t.Fatalf("Request() got error = %v, want McpErr", err)
+	}
+	if e.Code != mcperr.CONNECTION_CLOSED {
+		t.Errorf("Request() got error code = %v, want %v", e.Code, mcperr.CONNECTION_CLOSED)
+	}
+}
+
+func TestProtocol_RequestReceivesUnexpectedResult(t *testing.T) {
+	server := NewProtocol(nil)
+	client := NewProtocol(nil)
+	serverToClientCh := make(chan []byte, 1)
+	clientToServerCh := make(chan []byte, 1)
+	serverTransport := mock.NewMockChannelServerTransport(clientToServerCh, serverToClientCh)
+	clientTransport := mock.NewMockChannelClientTransport(clientToServerCh, serverToClientCh)
+	if err := server.Connect(serverTransport); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	if err := client.Connect(clientTransport); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer func() {
+		_ = client.Close()
+		_ = server.Close()
+	}()
+
+	// pingの結果（空のオブジェクト）は、initializeの結果として解釈できない
+	if _, err := client.Request(&schema.PingRequestSchema{MethodName: "ping"}, &schema.InitializeResultSchema{}); err == nil {
+		t.Fatal("Request() should return an error for a result of the wrong type")
+	}
+	// 受信が止まっていなければ、後続のリクエストは応答を受け取れる
+	got, err := client.Request(&schema.PingRequestSchema{MethodName: "ping"}, &schema.EmptyResultSchema{})
+	if err != nil {
+		t.Fatalf("Request() error = %v", err)
+	}
+	if diff := cmp.Diff(&schema.EmptyResultSchema{}, got); diff != "" {
+		t.Errorf("Request() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestProtocol_ConcurrentRequests(t *testing.T) {
+	server := NewProtocol(nil)
+	client := NewProtocol(nil)
+	serverToClientCh := make(chan []byte, 1)
+	clientToServerCh := make(chan []byte, 1)
+	serverTransport := mock.NewMockChannelServerTransport(clientToServerCh, serverToClientCh)
+	clientTransport := mock.NewMockChannelClientTransport(clientToServerCh, serverToClientCh)
+
+	// tools/listは、クライアントへpingを送信して応答を待つ。クライアントは後から送られたresources/listが処理されるまでpingに応答しない
+	listing, released := make(chan struct{}), make(chan struct{})
+	server.SetRequestHandler(&schema.ListToolsRequestSchema{MethodName: "tools/list"}, func(request schema.JsonRpcRequest) (schema.Result, error) {
+		if _, err := server.Request(&schema.PingRequestSchema{MethodName: "ping"}, &schema.EmptyResultSchema{}); err != nil {
+			return nil, err
+		}
+		return &schema.ListToolsResultSchema{Tools: []schema.ToolSchema{{Name: "slow"}}}, nil
+	})
+	server.SetRequestHandler(&schema.ListResourceRequestSchema{MethodName: "resources/list"}, func(request schema.JsonRpcRequest) (schema.Result, error) {
+		close(released)
+		return &schema.ListResourcesResultSchema{Resources: []schema.ResourceSchema{}}, nil
+	})
+	client.SetRequestHandler(&schema.PingRequestSchema{MethodName: "ping"}, func(request schema.JsonRpcRequest) (schema.Result, error) {
+		close(listing)
+		<-released
+		return &schema.EmptyResultSchema{}, nil
+	})
+	if err := server.Connect(serverTransport); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	if err := client.Connect(clientTransport); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer func() {
+		_ = client.Close()
+		_ = server.Close()
+	}()
+
+	type response struct {
+		result schema.Result
+		err    error
+	}
+	request := func(request schema.Request, resultSchema schema.Result) <-chan response {
+		done := make(chan response, 1)
+		go func() {
+			result, err := client.Request(request, resultSchema)
+			done <- response{result, err}
+		}()
+		return done
+	}
+	listDone := request(&schema.ListToolsRequestSchema{MethodName: "tools/list"}, &schema.ListToolsResultSchema{})
+	<-listing
+	// ハンドラーが送信したリクエストの応答を待っている間も後続のリクエストは処理され、応答の順序がリクエストと異なっても、それぞれに対応する応答を受け取る
+	resourcesDone := request(&schema.ListResourceRequestSchema{MethodName: "resources/list"}, &schema.ListResourcesResultSchema{})
+	for _, tt := range []struct {
+		done <-chan response
+		want schema.Result
+	}{
+		{done: resourcesDone, want: &schema.ListResourcesResultSchema{Resources: []schema.ResourceSchema{}}},
+		{done: listDone, want: &schema.ListToolsResultSchema{Tools: []schema.ToolSchema{{Name: "slow"}}}},
+	} {
+		select {
+		case got := <-tt.done:
+			if got.err != nil {
+				t.Fatalf("Request() error = %v", got.err)
+			}
+			if diff := cmp.Diff(tt.want, got.result); diff != "" {
+				t.Errorf("Request() mismatch (-want +got):\n%s", diff)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Request() did not receive the response")
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package shared
 
 import (
+	"context"
+
 	"github.com/kakkky/mcp-sdk-go/shared/protocol"
 	"github.com/kakkky/mcp-sdk-go/shared/schema"
 )
@@ -21,5 +23,6 @@ type Protocol interface {
 	Close() error
 
 	Request(request schema.Request, resultSchema any) (schema.Result, error)
+	RequestWithContext(ctx context.Context, request schema.Request, resultSchema any) (schema.Result, error)
 	Notificate(notification schema.Notification) error
 }
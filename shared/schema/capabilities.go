@@ -31,10 +31,13 @@ type ClientCapabilities struct {
 	Experimental any `json:"experimental,omitempty"`
 	*Sampling    `json:"sampling,omitempty"`
 	*Roots       `json:"roots,omitempty"`
+	*Elicitation `json:"elicitation,omitempty"`
 }
 
 type Sampling struct{}
 
+type Elicitation struct{}
+
 type Roots struct {
 	ListChanged bool `json:"listChanged,omitempty"`
 }
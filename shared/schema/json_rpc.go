@@ -1,5 +1,11 @@
 package schema
 
+import (
+	"context"
+
+	"github.com/kakkky/mcp-sdk-go/shared/auth"
+)
+
 // Request , Notification, Response の抽象型。
 // JsonRpcMessage()メソッド自体は意味をなさない。
 type JsonRpcMessage interface {
@@ -14,6 +20,10 @@ type BaseMessage struct {
 type JsonRpcRequest struct {
 	BaseMessage
 	Request
+	// HTTPのトランスポートで検証したアクセストークンの認証情報。送受信されるメッセージには含まれない
+	AuthInfo *auth.AuthInfo `json:"-"`
+	// 受信したリクエストの処理中に有効なコンテキスト。notifications/cancelledを受信するか、接続が閉じられると取り消される
+	Context context.Context `json:"-"`
 }
 
 type JsonRpcNotification struct {
@@ -0,0 +1,345 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// JSON Schema（draft 2020-12）のサブセット
+// ツールの入力スキーマなど、MCPでやり取りするスキーマを表す
+// 参照：(https://json-schema.org/draft/2020-12/json-schema-core)
+type JsonSchema struct {
+	Schema      string                `json:"$schema,omitempty"`
+	Id          string                `json:"$id,omitempty"`
+	Ref         string                `json:"$ref,omitempty"`
+	Defs        map[string]JsonSchema `json:"$defs,omitempty"`
+	Title       string                `json:"title,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Type        string                `json:"type,omitempty"`
+	Enum        []any                 `json:"enum,omitempty"`
+	Const       any                   `json:"const,omitempty"`
+	Default     any                   `json:"default,omitempty"`
+	Examples    []any                 `json:"examples,omitempty"`
+
+	// 文字列
+	MinLength       *int   `json:"minLength,omitempty"`
+	MaxLength       *int   `json:"maxLength,omitempty"`
+	Pattern         string `json:"pattern,omitempty"`
+	Format          string `json:"format,omitempty"`
+	ContentEncoding string `json:"contentEncoding,omitempty"`
+
+	// 数値
+	Minimum          *float64 `json:"minimum,omitempty"`
+	Maximum          *float64 `json:"maximum,omitempty"`
+	ExclusiveMinimum *float64 `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum *float64 `json:"exclusiveMaximum,omitempty"`
+	MultipleOf       *float64 `json:"multipleOf,omitempty"`
+
+	// 配列
+	Items       *JsonSchema `json:"items,omitempty"`
+	MinItems    *int        `json:"minItems,omitempty"`
+	MaxItems    *int        `json:"maxItems,omitempty"`
+	UniqueItems bool        `json:"uniqueItems,omitempty"`
+
+	// オブジェクト
+	Properties           PropertySchema `json:"properties,omitempty"`
+	Required             []string       `json:"required,omitempty"`
+	AdditionalProperties *JsonSchema    `json:"additionalProperties,omitempty"`
+	MinProperties        *int           `json:"minProperties,omitempty"`
+	MaxProperties        *int           `json:"maxProperties,omitempty"`
+
+	// スキーマの組み合わせ
+	AllOf []JsonSchema `json:"allOf,omitempty"`
+	AnyOf []JsonSchema `json:"anyOf,omitempty"`
+	OneOf []JsonSchema `json:"oneOf,omitempty"`
+	Not   *JsonSchema  `json:"not,omitempty"`
+
+	// trueまたはfalseのスキーマ（例："additionalProperties": false）
+	// nil以外の場合は、他のフィールドを無視してtrue/falseとしてエンコードする
+	Bool *bool `json:"-"`
+	// typeが配列の場合の型（例："type": ["string", "null"]）
+	// 空でない場合は、Typeの代わりにエンコードする
+	Types []string `json:"-"`
+	// constがnullの場合はtrue（Constのnilは、constが無いことを表す）
+	ConstNull bool `json:"-"`
+	// このモデルで扱わないキーワード（prefixItems、if/then/else、$commentなど）
+	// 受け取ったスキーマを再びエンコードする際に、そのまま書き出す
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+type jsonSchemaAlias JsonSchema
+
+// jsonSchemaAliasのフィールドとしてエンコードされるキーワード
+var knownJsonSchemaKeywords = func() map[string]bool {
+	keywords := make(map[string]bool)
+	t := reflect.TypeOf(jsonSchemaAlias{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			keywords[name] = true
+		}
+	}
+	return keywords
+}()
+
+func (s JsonSchema) MarshalJSON() ([]byte, error) {
+	if s.Bool != nil {
+		return json.Marshal(*s.Bool)
+	}
+	alias := jsonSchemaAlias(s)
+	extra := make(map[string]json.RawMessage, len(s.Extra)+2)
+	for keyword, value := range s.Extra {
+		if !knownJsonSchemaKeywords[keyword] {
+			extra[keyword] = value
+		}
+	}
+	if len(s.Types) > 0 {
+		alias.Type = ""
+		types, err := json.Marshal(s.Types)
+		if err != nil {
+			return nil, err
+		}
+		extra["type"] = types
+	}
+	if s.ConstNull && s.Const == nil {
+		extra["const"] = json.RawMessage("null")
+	}
+	data, err := json.Marshal(alias)
+	if err != nil || len(extra) == 0 {
+		return data, err
+	}
+	// 構造体のフィールドの後に、キーワード名の順で追加する
+	keywords := make([]string, 0, len(extra))
+	for keyword := range extra {
+		keywords = append(keywords, keyword)
+	}
+	slices.Sort(keywords)
+	var b bytes.Buffer
+	b.Write(data[:len(data)-1])
+	for i, keyword := range keywords {
+		if i > 0 || len(data) > 2 {
+			b.WriteByte(',')
+		}
+		name, err := json.Marshal(keyword)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(name)
+		b.WriteByte(':')
+		b.Write(extra[keyword])
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+func (s *JsonSchema) UnmarshalJSON(data []byte) error {
+	trimmed := bytes.TrimSpace(data)
+	if bytes.Equal(trimmed, []byte("true")) || bytes.Equal(trimmed, []byte("false")) {
+		boolean := bytes.Equal(trimmed, []byte("true"))
+		*s = JsonSchema{Bool: &boolean}
+		return nil
+	}
+	var keywords map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keywords); err != nil {
+		return err
+	}
+	var types []string
+	if value, ok := keywords["type"]; ok && bytes.HasPrefix(bytes.TrimSpace(value), []byte("[")) {
+		if err := json.Unmarshal(value, &types); err != nil {
+			return err
+		}
+		delete(keywords, "type")
+	}
+	var constNull bool
+	if value, ok := keywords["const"]; ok && bytes.Equal(bytes.TrimSpace(value), []byte("null")) {
+		constNull = true
+		delete(keywords, "const")
+	}
+	var extra map[string]json.RawMessage
+	for keyword, value := range keywords {
+		if knownJsonSchemaKeywords[keyword] {
+			continue
+		}
+		if extra == nil {
+			extra = make(map[string]json.RawMessage)
+		}
+		extra[keyword] = value
+		delete(keywords, keyword)
+	}
+	known, err := json.Marshal(keywords)
+	if err != nil {
+		return err
+	}
+	var alias jsonSchemaAlias
+	if err := json.Unmarshal(known, &alias); err != nil {
+		return err
+	}
+	*s = JsonSchema(alias)
+	s.Types = types
+	s.ConstNull = constNull
+	s.Extra = extra
+	return nil
+}
+
+// 以下はスキーマを手で記述するためのビルダー
+// 値を返すため、With〜のメソッドをつなげて記述できる
+//
+//	schema.Object(schema.PropertySchema{
+//		"name": schema.String("user name").WithMinLength(1),
+//		"tags": schema.Array("tags", schema.String("tag")),
+//	}, "name").WithAdditionalProperties(false)
+
+func String(description string) JsonSchema {
+	return JsonSchema{Type: "string", Description: description}
+}
+
+func Number(description string) JsonSchema {
+	return JsonSchema{Type: "number", Description: description}
+}
+
+func Integer(description string) JsonSchema {
+	return JsonSchema{Type: "integer", Description: description}
+}
+
+func Boolean(description string) JsonSchema {
+	return JsonSchema{Type: "boolean", Description: description}
+}
+
+func Array(description string, items JsonSchema) JsonSchema {
+	return JsonSchema{Type: "array", Description: description, Items: &items}
+}
+
+func Object(properties PropertySchema, required ...string) JsonSchema {
+	return JsonSchema{Type: "object", Properties: properties, Required: required}
+}
+
+func Enum(description string, values ...any) JsonSchema {
+	return JsonSchema{Description: description, Enum: values}
+}
+
+func OneOf(schemas ...JsonSchema) JsonSchema {
+	return JsonSchema{OneOf: schemas}
+}
+
+func AnyOf(schemas ...JsonSchema) JsonSchema {
+	return JsonSchema{AnyOf: schemas}
+}
+
+// 別のスキーマ（$defsなど）への参照
+func Ref(ref string) JsonSchema {
+	return JsonSchema{Ref: ref}
+}
+
+func (s JsonSchema) WithTitle(title string) JsonSchema {
+	s.Title = title
+	return s
+}
+
+func (s JsonSchema) WithEnum(values ...any) JsonSchema {
+	s.Enum = values
+	return s
+}
+
+// valueがnilの場合は、"const": nullとしてエンコードする
+func (s JsonSchema) WithConst(value any) JsonSchema {
+	s.Const = value
+	s.ConstNull = value == nil
+	return s
+}
+
+// 複数の型を許可する（例：WithTypes("string", "null")）
+func (s JsonSchema) WithTypes(types ...string) JsonSchema {
+	s.Type = ""
+	s.Types = types
+	return s
+}
+
+func (s JsonSchema) WithDefault(value any) JsonSchema {
+	s.Default = value
+	return s
+}
+
+func (s JsonSchema) WithExamples(examples ...any) JsonSchema {
+	s.Examples = examples
+	return s
+}
+
+func (s JsonSchema) WithMinLength(minLength int) JsonSchema {
+	s.MinLength = &minLength
+	return s
+}
+
+func (s JsonSchema) WithMaxLength(maxLength int) JsonSchema {
+	s.MaxLength = &maxLength
+	return s
+}
+
+func (s JsonSchema) WithPattern(pattern string) JsonSchema {
+	s.Pattern = pattern
+	return s
+}
+
+func (s JsonSchema) WithFormat(format string) JsonSchema {
+	s.Format = format
+	return s
+}
+
+func (s JsonSchema) WithMinimum(minimum float64) JsonSchema {
+	s.Minimum = &minimum
+	return s
+}
+
+func (s JsonSchema) WithMaximum(maximum float64) JsonSchema {
+	s.Maximum = &maximum
+	return s
+}
+
+func (s JsonSchema) WithExclusiveMinimum(minimum float64) JsonSchema {
+	s.ExclusiveMinimum = &minimum
+	return s
+}
+
+func (s JsonSchema) WithExclusiveMaximum(maximum float64) JsonSchema {
+	s.ExclusiveMaximum = &maximum
+	return s
+}
+
+func (s JsonSchema) WithMultipleOf(multipleOf float64) JsonSchema {
+	s.MultipleOf = &multipleOf
+	return s
+}
+
+func (s JsonSchema) WithMinItems(minItems int) JsonSchema {
+	s.MinItems = &minItems
+	return s
+}
+
+func (s JsonSchema) WithMaxItems(maxItems int) JsonSchema {
+	s.MaxItems = &maxItems
+	return s
+}
+
+func (s JsonSchema) WithUniqueItems() JsonSchema {
+	s.UniqueItems = true
+	return s
+}
+
+// falseの場合は、propertiesに無いプロパティを許可しない
+func (s JsonSchema) WithAdditionalProperties(allowed bool) JsonSchema {
+	s.AdditionalProperties = &JsonSchema{Bool: &allowed}
+	return s
+}
+
+// propertiesに無いプロパティの値のスキーマ（map[string]Tなど）
+func (s JsonSchema) WithAdditionalPropertiesSchema(values JsonSchema) JsonSchema {
+	s.AdditionalProperties = &values
+	return s
+}
+
+func (s JsonSchema) WithDefs(defs map[string]JsonSchema) JsonSchema {
+	s.Defs = defs
+	return s
+}
@@ -0,0 +1,108 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestJsonSchema_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name         string
+		schema       JsonSchema
+		expectedJSON string
+	}{
+		{
+			name: "normal : nested object built with builders",
+			schema: Object(PropertySchema{
+				"name": String("user name").WithMinLength(1).WithPattern("^[a-z]+$"),
+				"age":  Integer("age").WithMinimum(0).WithMaximum(150).WithDefault(float64(20)),
+				"tags": Array("tags", String("tag")).WithUniqueItems(),
+				"role": Enum("role", "admin", "member"),
+			}, "name").WithAdditionalProperties(false),
+			expectedJSON: `{"type":"object","properties":{"age":{"description":"age","type":"integer","default":20,"minimum":0,"maximum":150},"name":{"description":"user name","type":"string","minLength":1,"pattern":"^[a-z]+$"},"role":{"description":"role","enum":["admin","member"]},"tags":{"description":"tags","type":"array","items":{"description":"tag","type":"string"},"uniqueItems":true}},"required":["name"],"additionalProperties":false}`,
+		},
+		{
+			name: "normal : combinators and references",
+			schema: Object(PropertySchema{
+				"value": OneOf(Number(""), String("").WithFormat("uuid")),
+				"owner": Ref("#/$defs/user"),
+			}).WithDefs(map[string]JsonSchema{"user": Object(PropertySchema{"id": String("")})}),
+			expectedJSON: `{"$defs":{"user":{"type":"object","properties":{"id":{"type":"string"}}}},"type":"object","properties":{"owner":{"$ref":"#/$defs/user"},"value":{"oneOf":[{"type":"number"},{"type":"string","format":"uuid"}]}}}`,
+		},
+		{
+			name: "normal : type array and null const",
+			schema: Object(PropertySchema{
+				"nickname": String("nickname").WithTypes("string", "null"),
+				"deleted":  JsonSchema{}.WithConst(nil),
+			}),
+			expectedJSON: `{"type":"object","properties":{"deleted":{"const":null},"nickname":{"description":"nickname","type":["string","null"]}}}`,
+		},
+		{
+			name: "normal : unknown keywords are kept",
+			schema: Array("pair", String("")).WithMinItems(1).withExtra(map[string]json.RawMessage{
+				"$comment":    json.RawMessage(`"first item is a name"`),
+				"prefixItems": json.RawMessage(`[{"type":"string"}]`),
+			}),
+			expectedJSON: `{"description":"pair","type":"array","items":{"type":"string"},"minItems":1,"$comment":"first item is a name","prefixItems":[{"type":"string"}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.schema)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if string(data) != tt.expectedJSON {
+				t.Errorf("Marshal() = %s, want %s", data, tt.expectedJSON)
+			}
+			var got JsonSchema
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.schema, got); diff != "" {
+				t.Errorf("round trip mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func (s JsonSchema) withExtra(extra map[string]json.RawMessage) JsonSchema {
+	s.Extra = extra
+	return s
+}
+
+func TestJsonSchema_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected JsonSchema
+	}{
+		{
+			name:     "normal : type array",
+			data:     `{"type":["string","null"]}`,
+			expected: JsonSchema{Types: []string{"string", "null"}},
+		},
+		{
+			name: "normal : conditional keywords are kept as they are",
+			data: `{"type":"object","if":{"properties":{"kind":{"const":"a"}}},"then":{"required":["a"]},"dependentRequired":{"a":["b"]}}`,
+			expected: JsonSchema{Type: "object", Extra: map[string]json.RawMessage{
+				"if":                json.RawMessage(`{"properties":{"kind":{"const":"a"}}}`),
+				"then":              json.RawMessage(`{"required":["a"]}`),
+				"dependentRequired": json.RawMessage(`{"a":["b"]}`),
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got JsonSchema
+			if err := json.Unmarshal([]byte(tt.data), &got); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.expected, got); diff != "" {
+				t.Errorf("Unmarshal() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,476 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type ValidateOptions struct {
+	// "10"と10、"true"とtrueのような単純な型の不一致を、スキーマの型に変換する
+	CoerceTypes bool
+	// 省略されたプロパティに、スキーマのdefaultの値を設定する
+	ApplyDefaults bool
+}
+
+// スキーマに一致しなかった1つの箇所
+type ValidationError struct {
+	// 値の中の位置を示すJSON Pointer（例："/filter/from"）
+	// 参照：(https://datatracker.ietf.org/doc/html/rfc6901)
+	Pointer  string `json:"pointer"`
+	Keyword  string `json:"keyword"`
+	Message  string `json:"message"`
+	Expected any    `json:"expected,omitempty"`
+	Actual   any    `json:"actual,omitempty"`
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", pointerOrRoot(e.Pointer), e.Message)
+}
+
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, validationErr := range e {
+		messages[i] = validationErr.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+func pointerOrRoot(pointer string) string {
+	if pointer == "" {
+		return "/"
+	}
+	return pointer
+}
+
+// valueがスキーマに一致するかを検証する
+// 一致しない場合は、全ての箇所をValidationErrorsとして返す
+// 戻り値はJSONとして正規化した値で、optionsに応じて型の変換と既定値の設定を行ったもの
+// 引数のvalueは変更しない
+func (s JsonSchema) Validate(value any, options *ValidateOptions) (any, error) {
+	normalized, err := normalizeJSON(value)
+	if err != nil {
+		return nil, fmt.Errorf("value is not representable as JSON: %w", err)
+	}
+	v := &validator{root: s}
+	if options != nil {
+		v.options = *options
+	}
+	result := v.validate(s, normalized, "")
+	if len(v.errors) > 0 {
+		return nil, v.errors
+	}
+	return result, nil
+}
+
+// JSONのエンコード・デコードを通し、値をmap[string]any、[]any、float64などに揃える
+func normalizeJSON(value any) (any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var normalized any
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+type validator struct {
+	root    JsonSchema
+	options ValidateOptions
+	errors  ValidationErrors
+	// $refの解決が無限に続かないよう、同じ位置で展開中の参照を持つ
+	resolving map[string]bool
+}
+
+func (v *validator) addError(pointer, keyword string, expected, actual any, format string, args ...any) {
+	v.errors = append(v.errors, ValidationError{
+		Pointer:  pointer,
+		Keyword:  keyword,
+		Message:  fmt.Sprintf(format, args...),
+		Expected: expected,
+		Actual:   actual,
+	})
+}
+
+// エラーを記録せずに一致するかを調べる（anyOf、oneOf、notで使う）
+func (v *validator) matches(s JsonSchema, value any, pointer string) (any, bool) {
+	trial := &validator{root: v.root, options: v.options, resolving: v.resolving}
+	result := trial.validate(s, value, pointer)
+	return result, len(trial.errors) == 0
+}
+
+func (v *validator) validate(s JsonSchema, value any, pointer string) any {
+	if s.Bool != nil {
+		if !*s.Bool {
+			v.addError(pointer, "false", nil, value, "no value is allowed")
+		}
+		return value
+	}
+	if s.Ref != "" {
+		value = v.validateRef(s.Ref, value, pointer)
+	}
+
+	if s.Type != "" {
+		coerced, ok := v.validateType(s.Type, value, pointer)
+		if !ok {
+			return value
+		}
+		value = coerced
+	}
+	if len(s.Types) > 0 {
+		coerced, ok := v.validateTypes(s.Types, value, pointer)
+		if !ok {
+			return value
+		}
+		value = coerced
+	}
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(enumValue any) bool { return jsonEqual(enumValue, value) }) {
+		v.addError(pointer, "enum", s.Enum, value, "must be one of %v, got %v", s.Enum, value)
+	}
+	if s.ConstNull && value != nil {
+		v.addError(pointer, "const", nil, value, "must be null, got %v", value)
+	}
+	if s.Const != nil && !jsonEqual(s.Const, value) {
+		v.addError(pointer, "const", s.Const, value, "must be %v, got %v", s.Const, value)
+	}
+
+	switch typed := value.(type) {
+	case string:
+		v.validateString(s, typed, pointer)
+	case float64:
+		v.validateNumber(s, typed, pointer)
+	case []any:
+		value = v.validateArray(s, typed, pointer)
+	case map[string]any:
+		value = v.validateObject(s, typed, pointer)
+	}
+
+	for _, sub := range s.AllOf {
+		value = v.validate(sub, value, pointer)
+	}
+	if len(s.AnyOf) > 0 {
+		matched := false
+		for _, sub := range s.AnyOf {
+			if result, ok := v.matches(sub, value, pointer); ok {
+				value, matched = result, true
+				break
+			}
+		}
+		if !matched {
+			v.addError(pointer, "anyOf", nil, value, "must match at least one of the schemas in anyOf")
+		}
+	}
+	if len(s.OneOf) > 0 {
+		var matchedCount int
+		var matchedValue any
+		for _, sub := range s.OneOf {
+			if result, ok := v.matches(sub, value, pointer); ok {
+				if matchedCount == 0 {
+					matchedValue = result
+				}
+				matchedCount++
+			}
+		}
+		if matchedCount == 1 {
+			value = matchedValue
+		} else {
+			v.addError(pointer, "oneOf", 1, matchedCount, "must match exactly one of the schemas in oneOf, matched %d", matchedCount)
+		}
+	}
+	if s.Not != nil {
+		if _, ok := v.matches(*s.Not, value, pointer); ok {
+			v.addError(pointer, "not", nil, value, "must not match the schema in not")
+		}
+	}
+	return value
+}
+
+// "#"と"#/$defs/..."の形式の参照のみ解決する
+func (v *validator) validateRef(ref string, value any, pointer string) any {
+	key := ref + "@" + pointer
+	if v.resolving[key] {
+		return value
+	}
+	var target JsonSchema
+	switch {
+	case ref == "#":
+		target = v.root
+	case strings.HasPrefix(ref, "#/$defs/"):
+		def, ok := v.root.Defs[unescapePointerToken(strings.TrimPrefix(ref, "#/$defs/"))]
+		if !ok {
+			v.addError(pointer, "$ref", ref, nil, "unresolvable reference %s", ref)
+			return value
+		}
+		target = def
+	default:
+		v.addError(pointer, "$ref", ref, nil, "unsupported reference %s", ref)
+		return value
+	}
+	if v.resolving == nil {
+		v.resolving = make(map[string]bool)
+	}
+	v.resolving[key] = true
+	defer delete(v.resolving, key)
+	return v.validate(target, value, pointer)
+}
+
+func (v *validator) validateType(expected string, value any, pointer string) (any, bool) {
+	actual := jsonTypeName(value)
+	if actual == expected || (expected == "number" && actual == "integer") {
+		return value, true
+	}
+	if v.options.CoerceTypes {
+		if coerced, ok := coerce(expected, value); ok {
+			return coerced, true
+		}
+	}
+	v.addError(pointer, "type", expected, actual, "must be %s, got %s", expected, actual)
+	return value, false
+}
+
+// typeが配列の場合は、いずれかの型に一致すればよい
+// 型の変換は、値がどの型にも一致しない場合に、配列の順に試す
+func (v *validator) validateTypes(expected []string, value any, pointer string) (any, bool) {
+	actual := jsonTypeName(value)
+	if slices.Contains(expected, actual) || (actual == "integer" && slices.Contains(expected, "number")) {
+		return value, true
+	}
+	if v.options.CoerceTypes {
+		for _, t := range expected {
+			if coerced, ok := coerce(t, value); ok {
+				return coerced, true
+			}
+		}
+	}
+	v.addError(pointer, "type", expected, actual, "must be one of %v, got %s", expected, actual)
+	return value, false
+}
+
+// JSONの値の型名（整数の数値は"integer"とする）
+func jsonTypeName(value any) string {
+	switch typed := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if typed == math.Trunc(typed) && !math.IsInf(typed, 0) {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func coerce(expected string, value any) (any, bool) {
+	switch typed := value.(type) {
+	case string:
+		trimmed := strings.TrimSpace(typed)
+		switch expected {
+		case "number":
+			return parseFiniteNumber(trimmed)
+		case "integer":
+			number, ok := parseFiniteNumber(trimmed)
+			return number, ok && number == math.Trunc(number)
+		case "boolean":
+			boolean, err := strconv.ParseBool(trimmed)
+			return boolean, err == nil
+		case "null":
+			return nil, trimmed == "null"
+		}
+	case float64:
+		if expected == "string" {
+			return strconv.FormatFloat(typed, 'f', -1, 64), true
+		}
+	case bool:
+		if expected == "string" {
+			return strconv.FormatBool(typed), true
+		}
+	}
+	return nil, false
+}
+
+// "NaN"や"Inf"はJSONの数値として表せないため変換しない
+func parseFiniteNumber(value string) (float64, bool) {
+	number, err := strconv.ParseFloat(value, 64)
+	return number, err == nil && !math.IsNaN(number) && !math.IsInf(number, 0)
+}
+
+func (v *validator) validateString(s JsonSchema, value string, pointer string) {
+	length := utf8.RuneCountInString(value)
+	if s.MinLength != nil && length < *s.MinLength {
+		v.addError(pointer, "minLength", *s.MinLength, length, "must be at least %d characters, got %d", *s.MinLength, length)
+	}
+	if s.MaxLength != nil && length > *s.MaxLength {
+		v.addError(pointer, "maxLength", *s.MaxLength, length, "must be at most %d characters, got %d", *s.MaxLength, length)
+	}
+	if s.Pattern != "" {
+		pattern, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			v.addError(pointer, "pattern", s.Pattern, value, "invalid pattern %q in schema", s.Pattern)
+		} else if !pattern.MatchString(value) {
+			v.addError(pointer, "pattern", s.Pattern, value, "must match pattern %q", s.Pattern)
+		}
+	}
+	if s.Format != "" && !matchesFormat(s.Format, value) {
+		v.addError(pointer, "format", s.Format, value, "must be a valid %s", s.Format)
+	}
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// 未知のformatは注釈として扱い、検証しない
+func matchesFormat(format string, value string) bool {
+	switch format {
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, value)
+		return err == nil
+	case "date":
+		_, err := time.Parse(time.DateOnly, value)
+		return err == nil
+	case "email":
+		address, err := mail.ParseAddress(value)
+		return err == nil && address.Address == value
+	case "uri":
+		parsed, err := url.Parse(value)
+		return err == nil && parsed.Scheme != ""
+	case "uuid":
+		return uuidPattern.MatchString(value)
+	default:
+		return true
+	}
+}
+
+func (v *validator) validateNumber(s JsonSchema, value float64, pointer string) {
+	if s.Minimum != nil && value < *s.Minimum {
+		v.addError(pointer, "minimum", *s.Minimum, value, "must be >= %v, got %v", *s.Minimum, value)
+	}
+	if s.Maximum != nil && value > *s.Maximum {
+		v.addError(pointer, "maximum", *s.Maximum, value, "must be <= %v, got %v", *s.Maximum, value)
+	}
+	if s.ExclusiveMinimum != nil && value <= *s.ExclusiveMinimum {
+		v.addError(pointer, "exclusiveMinimum", *s.ExclusiveMinimum, value, "must be > %v, got %v", *s.ExclusiveMinimum, value)
+	}
+	if s.ExclusiveMaximum != nil && value >= *s.ExclusiveMaximum {
+		v.addError(pointer, "exclusiveMaximum", *s.ExclusiveMaximum, value, "must be < %v, got %v", *s.ExclusiveMaximum, value)
+	}
+	if s.MultipleOf != nil && *s.MultipleOf > 0 {
+		quotient := value / *s.MultipleOf
+		if math.Abs(quotient-math.Round(quotient)) > 1e-9 {
+			v.addError(pointer, "multipleOf", *s.MultipleOf, value, "must be a multiple of %v, got %v", *s.MultipleOf, value)
+		}
+	}
+}
+
+func (v *validator) validateArray(s JsonSchema, value []any, pointer string) []any {
+	if s.MinItems != nil && len(value) < *s.MinItems {
+		v.addError(pointer, "minItems", *s.MinItems, len(value), "must have at least %d items, got %d", *s.MinItems, len(value))
+	}
+	if s.MaxItems != nil && len(value) > *s.MaxItems {
+		v.addError(pointer, "maxItems", *s.MaxItems, len(value), "must have at most %d items, got %d", *s.MaxItems, len(value))
+	}
+	if s.UniqueItems {
+		for i := range value {
+			for j := i + 1; j < len(value); j++ {
+				if jsonEqual(value[i], value[j]) {
+					v.addError(pointer, "uniqueItems", nil, value[j], "items %d and %d must be unique", i, j)
+				}
+			}
+		}
+	}
+	if s.Items == nil {
+		return value
+	}
+	items := make([]any, len(value))
+	for i, item := range value {
+		items[i] = v.validate(*s.Items, item, pointer+"/"+strconv.Itoa(i))
+	}
+	return items
+}
+
+func (v *validator) validateObject(s JsonSchema, value map[string]any, pointer string) map[string]any {
+	object := make(map[string]any, len(value))
+	for key, propertyValue := range value {
+		object[key] = propertyValue
+	}
+	if v.options.ApplyDefaults {
+		for name, property := range s.Properties {
+			if _, ok := object[name]; !ok && property.Default != nil {
+				if defaultValue, err := normalizeJSON(property.Default); err == nil {
+					object[name] = defaultValue
+				}
+			}
+		}
+	}
+	for _, name := range s.Required {
+		if _, ok := object[name]; !ok {
+			v.addError(pointer+"/"+escapePointerToken(name), "required", name, nil, "%s is required", name)
+		}
+	}
+	if s.MinProperties != nil && len(object) < *s.MinProperties {
+		v.addError(pointer, "minProperties", *s.MinProperties, len(object), "must have at least %d properties, got %d", *s.MinProperties, len(object))
+	}
+	if s.MaxProperties != nil && len(object) > *s.MaxProperties {
+		v.addError(pointer, "maxProperties", *s.MaxProperties, len(object), "must have at most %d properties, got %d", *s.MaxProperties, len(object))
+	}
+	// エラーの順序を一定にするため、名前順に検証する
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		propertyPointer := pointer + "/" + escapePointerToken(name)
+		if property, ok := s.Properties[name]; ok {
+			object[name] = v.validate(property, object[name], propertyPointer)
+			continue
+		}
+		if s.AdditionalProperties == nil {
+			continue
+		}
+		if s.AdditionalProperties.Bool != nil && !*s.AdditionalProperties.Bool {
+			v.addError(propertyPointer, "additionalProperties", nil, name, "unknown property %s", name)
+			continue
+		}
+		object[name] = v.validate(*s.AdditionalProperties, object[name], propertyPointer)
+	}
+	return object
+}
+
+// JSONとして同じ値か（float64(10)とint64(10)なども等しいとする）
+func jsonEqual(a, b any) bool {
+	aData, aErr := json.Marshal(a)
+	bData, bErr := json.Marshal(b)
+	if aErr != nil || bErr != nil {
+		return false
+	}
+	return string(aData) == string(bData)
+}
+
+func escapePointerToken(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+func unescapePointerToken(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+}
@@ -0,0 +1,110 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestJsonSchema_Validate(t *testing.T) {
+	userSchema := Object(PropertySchema{
+		"name":  String("user name").WithMinLength(1),
+		"age":   Integer("age").WithMinimum(0).WithDefault(20),
+		"role":  Enum("role", "admin", "member").WithDefault("member"),
+		"admin": Boolean("is admin"),
+		"tags":  Array("tags", String("tag")).WithMaxItems(2).WithUniqueItems(),
+		"owner": Ref("#/$defs/user"),
+		"id":    OneOf(Integer(""), String("").WithFormat("uuid")),
+		"alias": String("alias").WithTypes("string", "null"),
+		"gone":  JsonSchema{}.WithConst(nil),
+	}, "name").WithAdditionalProperties(false).WithDefs(map[string]JsonSchema{
+		"user": Object(PropertySchema{"email": String("").WithFormat("email")}, "email"),
+	})
+	tests := []struct {
+		name           string
+		value          any
+		options        *ValidateOptions
+		expectedValue  any
+		expectedErrors ValidationErrors
+	}{
+		{
+			name:          "normal : valid value is returned as normalized JSON",
+			value:         map[string]any{"name": "alice", "age": 30, "tags": []string{"a", "b"}, "id": 1},
+			expectedValue: map[string]any{"name": "alice", "age": float64(30), "tags": []any{"a", "b"}, "id": float64(1)},
+		},
+		{
+			name:          "normal : defaults are applied to omitted properties",
+			value:         map[string]any{"name": "alice"},
+			options:       &ValidateOptions{ApplyDefaults: true},
+			expectedValue: map[string]any{"name": "alice", "age": float64(20), "role": "member"},
+		},
+		{
+			name:          "normal : simple type mismatches are coerced",
+			value:         map[string]any{"name": 42, "age": "30", "admin": "true"},
+			options:       &ValidateOptions{CoerceTypes: true},
+			expectedValue: map[string]any{"name": "42", "age": float64(30), "admin": true},
+		},
+		{
+			name:  "semi normal : every violation is reported with its pointer",
+			value: map[string]any{"age": 1.5, "role": "guest", "tags": []any{"a", "a", "b"}, "owner": map[string]any{"email": "not-an-email"}, "extra": true},
+			expectedErrors: ValidationErrors{
+				{Pointer: "/name", Keyword: "required", Message: "name is required", Expected: "name"},
+				{Pointer: "/age", Keyword: "type", Message: "must be integer, got number", Expected: "integer", Actual: "number"},
+				{Pointer: "/extra", Keyword: "additionalProperties", Message: "unknown property extra", Actual: "extra"},
+				{Pointer: "/owner/email", Keyword: "format", Message: "must be a valid email", Expected: "email", Actual: "not-an-email"},
+				{Pointer: "/role", Keyword: "enum", Message: "must be one of [admin member], got guest", Expected: []any{"admin", "member"}, Actual: "guest"},
+				{Pointer: "/tags", Keyword: "maxItems", Message: "must have at most 2 items, got 3", Expected: 2, Actual: 3},
+				{Pointer: "/tags", Keyword: "uniqueItems", Message: "items 0 and 1 must be unique", Actual: "a"},
+			},
+		},
+		{
+			name:          "normal : type array and null const accept null",
+			value:         map[string]any{"name": "alice", "alias": nil, "gone": nil},
+			expectedValue: map[string]any{"name": "alice", "alias": nil, "gone": nil},
+		},
+		{
+			name:  "semi normal : value matches none of the types in the type array",
+			value: map[string]any{"name": "alice", "alias": 1, "gone": "x"},
+			expectedErrors: ValidationErrors{
+				{Pointer: "/alias", Keyword: "type", Message: "must be one of [string null], got integer", Expected: []string{"string", "null"}, Actual: "integer"},
+				{Pointer: "/gone", Keyword: "const", Message: "must be null, got x", Actual: "x"},
+			},
+		},
+		{
+			name:  "semi normal : type is not coerced without the option",
+			value: map[string]any{"name": "alice", "age": "30", "id": "123"},
+			expectedErrors: ValidationErrors{
+				{Pointer: "/age", Keyword: "type", Message: "must be integer, got string", Expected: "integer", Actual: "string"},
+				{Pointer: "/id", Keyword: "oneOf", Message: "must match exactly one of the schemas in oneOf, matched 0", Expected: 1, Actual: 0},
+			},
+		},
+		{
+			name:  "semi normal : value of the wrong type at the root",
+			value: []any{"alice"},
+			expectedErrors: ValidationErrors{
+				{Pointer: "", Keyword: "type", Message: "must be object, got array", Expected: "object", Actual: "array"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := userSchema.Validate(tt.value, tt.options)
+			if tt.expectedErrors != nil {
+				validationErrs, ok := err.(ValidationErrors)
+				if !ok {
+					t.Fatalf("Validate() error = %v, want ValidationErrors", err)
+				}
+				if diff := cmp.Diff(tt.expectedErrors, validationErrs); diff != "" {
+					t.Errorf("Validate() errors mismatch (-want +got):\n%s", diff)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.expectedValue, got); diff != "" {
+				t.Errorf("Validate() value mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -50,6 +50,31 @@ func unmarshalNotification(message *Message) (schema.Notification, error) {
 			MethodName: message.Method,
 		}, nil
 
+	case "notifications/roots/list_changed":
+		return &schema.RootsListChangedNotificationSchema{
+			MethodName: message.Method,
+		}, nil
+
+	case "notifications/cancelled":
+		params := schema.CancelledNotificationParams{}
+		if err := json.Unmarshal(message.Params, &params); err != nil {
+			return nil, err
+		}
+		return &schema.CancelledNotificationSchema{
+			MethodName: message.Method,
+			ParamsData: params,
+		}, nil
+
+	case "notifications/progress":
+		params := schema.ProgressNotificationParams{}
+		if err := json.Unmarshal(message.Params, &params); err != nil {
+			return nil, err
+		}
+		return &schema.ProgressNotificationSchema{
+			MethodName: message.Method,
+			ParamsData: params,
+		}, nil
+
 	// その他の通知タイプはここに追加
 
 	default:
@@ -36,6 +36,26 @@ func unmarshalRequest(message *Message) (schema.Request, error) {
 			MethodName: message.Method,
 			ParamsData: *params,
 		}, nil
+	case "resources/subscribe":
+		params := &schema.SubscribeRequestParams{}
+		if err := json.Unmarshal(message.Params, &params); err != nil {
+			return nil, err
+		}
+		return &schema.SubscribeRequestSchema{
+			MethodName: message.Method,
+			ParamsData: *params,
+		}, nil
+	case "resources/unsubscribe":
+		params := &schema.UnsubscribeRequestParams{}
+		if err := json.Unmarshal(message.Params, &params); err != nil {
+			return nil, err
+		}
+		return &schema.UnsubscribeRequestSchema{
+			MethodName: message.Method,
+			ParamsData: *params,
+		}, nil
+	case "sampling/createMessage":
+		return unmarshalCreateMessageRequest(message)
 	case "resources/templates/list":
 		return &schema.ListResourceTemplatesRequestSchema{
 			MethodName: message.Method,
@@ -88,6 +108,15 @@ func unmarshalRequest(message *Message) (schema.Request, error) {
 		return &schema.ListRootsRequestSchema{
 			MethodName: message.Method,
 		}, nil
+	case "elicitation/create":
+		params := &schema.ElicitRequestParams{}
+		if err := json.Unmarshal(message.Params, &params); err != nil {
+			return nil, err
+		}
+		return &schema.ElicitRequestSchema{
+			MethodName: message.Method,
+			ParamsData: *params,
+		}, nil
 	case "logging/setLevel":
 		params := &schema.SetLoggingLevelRequestParams{}
 		if err := json.Unmarshal(message.Params, &params); err != nil {
@@ -127,3 +156,43 @@ func unmarshalRequest(message *Message) (schema.Request, error) {
 
 	return nil, fmt.Errorf("unknown method: %s", message.Method)
 }
+
+// sampling/createMessageはメッセージのcontentの型によって型パラメータが異なる
+// 全てのメッセージのcontentは先頭のメッセージと同じ型であるものとする
+func unmarshalCreateMessageRequest(message *Message) (schema.Request, error) {
+	params := struct {
+		Messages []struct {
+			Content struct {
+				Type string `json:"type"`
+			} `json:"content"`
+		} `json:"messages"`
+	}{}
+	if err := json.Unmarshal(message.Params, &params); err != nil {
+		return nil, err
+	}
+	contentType := "text"
+	if len(params.Messages) > 0 {
+		contentType = params.Messages[0].Content.Type
+	}
+	switch contentType {
+	case "text":
+		return unmarshalCreateMessageRequestWith[schema.TextContentSchema](message)
+	case "image":
+		return unmarshalCreateMessageRequestWith[schema.ImageContentSchema](message)
+	case "audio":
+		return unmarshalCreateMessageRequestWith[schema.AudioContentSchema](message)
+	default:
+		return nil, fmt.Errorf("unknown content type: %s", contentType)
+	}
+}
+
+func unmarshalCreateMessageRequestWith[T schema.ContentSchema](message *Message) (schema.Request, error) {
+	params := &schema.CreateMessageRequestParams[T]{}
+	if err := json.Unmarshal(message.Params, &params); err != nil {
+		return nil, err
+	}
+	return &schema.CreateMessageRequestSchema[T]{
+		MethodName: message.Method,
+		ParamsData: *params,
+	}, nil
+}
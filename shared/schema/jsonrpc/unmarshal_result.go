@@ -21,6 +21,12 @@ func unmarshalResult(message *Message) (schema.Result, error) {
 		return &result, nil
 	case isEmptyResult(rawResult):
 		return &schema.EmptyResultSchema{}, nil
+	case isElicitResult(rawResult):
+		var result schema.ElicitResultSchema
+		if err := json.Unmarshal(message.Result, &result); err != nil {
+			return nil, err
+		}
+		return &result, nil
 	case isCreateMessageResult(rawResult):
 		result := struct {
 			Model      string         `json:"model"`
@@ -154,8 +160,9 @@ func unmarshalResult(message *Message) (schema.Result, error) {
 			isError = false // デフォルトはエラーではない
 		}
 		return &schema.CallToolResultSchema{
-			Content: contents,
-			IsError: isError.(bool),
+			Content:           contents,
+			StructuredContent: rawResult["structuredContent"],
+			IsError:           isError.(bool),
 		}, nil
 	case isListPromptsResult(rawResult):
 		var result schema.ListPromptsResultSchema
@@ -183,6 +190,9 @@ func isEmptyResult(data map[string]any) bool {
 func isCreateMessageResult(data map[string]any) bool {
 	return hasResultFields(data, "model", "role", "content")
 }
+func isElicitResult(data map[string]any) bool {
+	return hasResultFields(data, "action")
+}
 func isListRootsResult(data map[string]any) bool {
 	return hasResultFields(data, "roots")
 }
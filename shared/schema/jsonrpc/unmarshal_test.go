@@ -511,6 +511,102 @@ func TestUnmarshal(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "normal : able to unmarshal cancelled notification",
+			jsonStr: `{
+				"jsonrpc": "2.0",
+				"method": "notifications/cancelled",
+				"params": {
+					"requestId": 3,
+					"reason": "user requested"
+				}
+			}`,
+			expected: schema.JsonRpcNotification{
+				Jsonrpc: schema.JSON_RPC_VERSION,
+				Notification: &schema.CancelledNotificationSchema{
+					MethodName: "notifications/cancelled",
+					ParamsData: schema.CancelledNotificationParams{
+						RequestId: 3,
+						Reason:    "user requested",
+					},
+				},
+			},
+		},
+		{
+			name: "normal : able to unmarshal progress notification",
+			jsonStr: `{
+				"jsonrpc": "2.0",
+				"method": "notifications/progress",
+				"params": {
+					"progressToken": "token-1",
+					"progress": 50,
+					"total": 100
+				}
+			}`,
+			expected: schema.JsonRpcNotification{
+				Jsonrpc: schema.JSON_RPC_VERSION,
+				Notification: &schema.ProgressNotificationSchema{
+					MethodName: "notifications/progress",
+					ParamsData: schema.ProgressNotificationParams{
+						ProgressToken: "token-1",
+						Progress:      50,
+						Total:         100,
+					},
+				},
+			},
+		},
+		{
+			name: "normal : able to unmarshal tools/call request with progress token",
+			jsonStr: `{
+				"jsonrpc": "2.0",
+				"id": 4,
+				"method": "tools/call",
+				"params": {
+					"name": "slow",
+					"_meta": {"progressToken": 1}
+				}
+			}`,
+			expected: schema.JsonRpcRequest{
+				BaseMessage: schema.BaseMessage{
+					Jsonrpc: schema.JSON_RPC_VERSION,
+					Id:      4,
+				},
+				Request: &schema.CallToolRequestSchema{
+					MethodName: "tools/call",
+					ParamsData: schema.CallToolRequestParams{
+						Name: "slow",
+						Meta: &schema.RequestMetaSchema{ProgressToken: float64(1)},
+					},
+				},
+			},
+		},
+		{
+			name: "normal : able to unmarshal sampling/createMessage request",
+			jsonStr: `{
+				"jsonrpc": "2.0",
+				"id": 5,
+				"method": "sampling/createMessage",
+				"params": {
+					"messages": [{"role": "user", "content": {"type": "text", "text": "hello"}}],
+					"maxTokens": 100
+				}
+			}`,
+			expected: schema.JsonRpcRequest{
+				BaseMessage: schema.BaseMessage{
+					Jsonrpc: schema.JSON_RPC_VERSION,
+					Id:      5,
+				},
+				Request: &schema.CreateMessageRequestSchema[schema.TextContentSchema]{
+					MethodName: "sampling/createMessage",
+					ParamsData: schema.CreateMessageRequestParams[schema.TextContentSchema]{
+						Messages: []schema.SamplingMessageSchema[schema.TextContentSchema]{
+							{Role: "user", Content: schema.TextContentSchema{Type: "text", Text: "hello"}},
+						},
+						MaxTokens: 100,
+					},
+				},
+			},
+		},
 		{
 			name: "normal : able to unmarshal error response with simple error message",
 			jsonStr: `{
@@ -110,3 +110,45 @@ func (n *RootsListChangedNotificationSchema) Method() string {
 func (n *RootsListChangedNotificationSchema) Params() any {
 	return nil
 }
+
+// notifications/cancelled
+// 送信済みのリクエストを取り消すことを相手に伝える
+type CancelledNotificationSchema struct {
+	MethodName string                      `json:"method"`
+	ParamsData CancelledNotificationParams `json:"params"`
+}
+
+type CancelledNotificationParams struct {
+	RequestId int    `json:"requestId"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+func (n *CancelledNotificationSchema) Method() string {
+	return n.MethodName
+}
+
+func (n *CancelledNotificationSchema) Params() any {
+	return n.ParamsData
+}
+
+// notifications/progress
+// リクエストの_meta.progressTokenで指定されたトークンに紐づく進捗を伝える
+type ProgressNotificationSchema struct {
+	MethodName string                     `json:"method"`
+	ParamsData ProgressNotificationParams `json:"params"`
+}
+
+type ProgressNotificationParams struct {
+	ProgressToken any     `json:"progressToken"` // stringまたはnumber
+	Progress      float64 `json:"progress"`
+	Total         float64 `json:"total,omitempty"`
+	Message       string  `json:"message,omitempty"`
+}
+
+func (n *ProgressNotificationSchema) Method() string {
+	return n.MethodName
+}
+
+func (n *ProgressNotificationSchema) Params() any {
+	return n.ParamsData
+}
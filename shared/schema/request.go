@@ -76,6 +76,27 @@ func (r *ListRootsRequestSchema) Params() any {
 	return nil
 }
 
+// elicitation/create
+// サーバーがクライアントを通して、ユーザーに追加の情報の入力を求める
+type ElicitRequestSchema struct {
+	MethodName string              `json:"method"`
+	ParamsData ElicitRequestParams `json:"params"`
+}
+
+type ElicitRequestParams struct {
+	Message string `json:"message"`
+	// 入力を求める値のスキーマ。プロパティが文字列、数値、真偽値、列挙値のみのオブジェクトとする
+	RequestedSchema JsonSchema `json:"requestedSchema"`
+}
+
+func (r *ElicitRequestSchema) Method() string {
+	return r.MethodName
+}
+
+func (r *ElicitRequestSchema) Params() any {
+	return r.ParamsData
+}
+
 // resources/list
 type ListResourceRequestSchema struct {
 	MethodName string `json:"method"`
@@ -108,7 +129,8 @@ type ReadResourceRequestSchema struct {
 }
 
 type ReadResourceRequestParams struct {
-	Uri string `json:"uri"`
+	Uri  string             `json:"uri"`
+	Meta *RequestMetaSchema `json:"_meta,omitempty"`
 }
 
 func (r *ReadResourceRequestSchema) Method() string {
@@ -203,8 +225,9 @@ type GetPromptRequestSchema struct {
 	ParamsData GetPromptRequestParams `json:"params"`
 }
 type GetPromptRequestParams struct {
-	Name      string            `json:"name"`
-	Arguments map[string]string `json:"arguments,omitempty"` // 変数名と値のマップ
+	Name      string             `json:"name"`
+	Arguments map[string]string  `json:"arguments,omitempty"` // 変数名と値のマップ
+	Meta      *RequestMetaSchema `json:"_meta,omitempty"`
 }
 
 func (r *GetPromptRequestSchema) Method() string {
@@ -232,8 +255,9 @@ type CallToolRequestSchema struct {
 	ParamsData CallToolRequestParams `json:"params"`
 }
 type CallToolRequestParams struct {
-	Name      string         `json:"name"`
-	Arguments map[string]any `json:"arguments,omitempty"` // 変数名と値のマップ
+	Name      string             `json:"name"`
+	Arguments map[string]any     `json:"arguments,omitempty"` // 変数名と値のマップ
+	Meta      *RequestMetaSchema `json:"_meta,omitempty"`
 }
 
 func (r *CallToolRequestSchema) Method() string {
@@ -242,3 +266,9 @@ func (r *CallToolRequestSchema) Method() string {
 func (r *CallToolRequestSchema) Params() any {
 	return r.ParamsData
 }
+
+// リクエストのparamsに含まれる"_meta"
+type RequestMetaSchema struct {
+	// 指定した場合、受信側はこのトークンを付けたnotifications/progressで進捗を通知できる
+	ProgressToken any `json:"progressToken,omitempty"` // stringまたはnumber
+}
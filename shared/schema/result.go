@@ -44,6 +44,24 @@ func (r *ListRootsResultSchema) Result() any {
 	return r
 }
 
+// elicitation/create
+type ElicitResultSchema struct {
+	Action  ElicitAction   `json:"action"`
+	Content map[string]any `json:"content,omitempty"` // actionがacceptの場合に、requestedSchemaに一致する値
+}
+
+func (r *ElicitResultSchema) Result() any {
+	return r
+}
+
+type ElicitAction string
+
+const (
+	ELICIT_ACTION_ACCEPT  ElicitAction = "accept"
+	ELICIT_ACTION_DECLINE ElicitAction = "decline"
+	ELICIT_ACTION_CANCEL  ElicitAction = "cancel"
+)
+
 // resources/read
 type ReadResourceResultSchema struct {
 	Contents []ResourceContentSchema `json:"contents"`
@@ -110,9 +128,11 @@ func (r *ListToolsResultSchema) Result() any {
 
 // tools/call
 type CallToolResultSchema struct {
-	Content                           []ToolContentSchema `json:"content"`
-	IsError                           bool                `json:"isError,omitempty"`
-	CompatibilityCallToolResultSchema                     // Deprecated: use Content instead
+	Content []ToolContentSchema `json:"content"`
+	// ツールのoutputSchemaに一致するJSONのオブジェクト（map[string]anyや構造体）
+	StructuredContent                 any  `json:"structuredContent,omitempty"`
+	IsError                           bool `json:"isError,omitempty"`
+	CompatibilityCallToolResultSchema      // Deprecated: use Content instead
 }
 
 func (r *CallToolResultSchema) Result() any {
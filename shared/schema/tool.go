@@ -1,24 +1,21 @@
 package schema
 
 type ToolSchema struct {
-	Name        string                `json:"name"`                  // Name of the tool
-	Description string                `json:"description,omitempty"` // Description of the tool
-	InputSchema InputSchema           `json:"inputSchema"`           // Schema for the input to the tool
-	Annotations *ToolAnotationsSchema `json:"annotations,omitempty"` // Annotations for the tool
+	Name         string                `json:"name"`                   // Name of the tool
+	Description  string                `json:"description,omitempty"`  // Description of the tool
+	InputSchema  InputSchema           `json:"inputSchema"`            // Schema for the input to the tool
+	OutputSchema *JsonSchema           `json:"outputSchema,omitempty"` // Schema for the structuredContent of the tool result
+	Annotations  *ToolAnotationsSchema `json:"annotations,omitempty"`  // Annotations for the tool
 }
 
-type InputSchema struct {
-	Type       string         `json:"type"`               // "object"
-	Properties PropertySchema `json:"properties"`         // Properties of the input object
-	Required   []string       `json:"required,omitempty"` // Required properties
-}
+// ツールの入力スキーマ。typeは"object"となる
+type InputSchema = JsonSchema
 
-type PropertySchema map[string]PropertyInfoSchema
-type PropertyInfoSchema struct {
-	Type        string   `json:"type"`
-	Description string   `json:"description"`
-	Enum        []string `json:"enum,omitempty"` // Optional, for enum types
-}
+// プロパティ名とスキーマの対応
+type PropertySchema map[string]JsonSchema
+
+// 以前のプロパティのスキーマの型名。JsonSchemaの別名で、Enumは[]stringから[]anyに変わっている
+type PropertyInfoSchema = JsonSchema
 
 type ToolAnotationsSchema struct {
 	Title           string `json:"title,omitempty"`           // Title of the tool
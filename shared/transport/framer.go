@@ -0,0 +1,131 @@
+package transport
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ストリーム上でJSON-RPCメッセージの区切りを決める方式
+// ReadBufferと、stdio、ソケットのトランスポートで使用する
+type Framer interface {
+	// dataの先頭から1フレームを取り出し、メッセージ本体と読み進めるバイト数nを返す
+	// フレームが揃っていない場合はn=0を返す
+	// エラーの場合もnバイトを読み捨てる。nがdataより長い場合は、後から届くデータも含めて読み捨てる
+	// nが負の場合は、Resyncが次のフレームの開始位置を見つけるまで読み捨てる
+	// maxSizeが0より大きく、メッセージ本体がmaxSizeを超える場合はErrMessageTooLargeを返す
+	Decode(data []byte, maxSize int) (payload []byte, n int, err error)
+	// メッセージ本体をフレームに変換する
+	Encode(payload []byte) []byte
+	// 読み捨てている途中のdataから次のフレームの開始位置を探す。見つからない場合は-1を返す
+	// チャンクの境目で分かれた開始位置も見つけられるように、dataの先頭には前回のdataの末尾（最大RESYNC_OVERLAPバイト）が含まれる
+	Resync(data []byte) int
+}
+
+// メッセージを改行で区切る方式（MCPのstdioトランスポートの標準）
+// 参照：(https://modelcontextprotocol.io/specification/draft/basic/transports#stdio)
+type NewlineFramer struct{}
+
+func (NewlineFramer) Decode(data []byte, maxSize int) ([]byte, int, error) {
+	index := bytes.IndexByte(data, '\n')
+	if index == -1 {
+		// 改行が見つからないまま最大サイズを超えた場合は、次の改行まで読み捨てる
+		if exceedsMaxSize(len(data), maxSize) {
+			return nil, -1, fmt.Errorf("%w: more than %d bytes (limit %d bytes)", ErrMessageTooLarge, len(data), maxSize)
+		}
+		return nil, 0, nil
+	}
+	if exceedsMaxSize(index, maxSize) {
+		return nil, index + 1, fmt.Errorf("%w: %d bytes (limit %d bytes)", ErrMessageTooLarge, index, maxSize)
+	}
+	line := data[:index]
+	// CRLFを考慮（Windowsの場合）
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	return line, index + 1, nil
+}
+
+func (NewlineFramer) Encode(payload []byte) []byte {
+	return append(payload, '\n')
+}
+
+func (NewlineFramer) Resync(data []byte) int {
+	index := bytes.IndexByte(data, '\n')
+	if index == -1 {
+		return -1
+	}
+	return index + 1
+}
+
+// LSPと同様に、"Content-Length"ヘッダーでメッセージの長さを示す方式
+// 参照：(https://microsoft.github.io/language-server-protocol/specifications/base/0.9/specification/#headerPart)
+type ContentLengthFramer struct{}
+
+const (
+	contentLengthHeader = "Content-Length"
+	// ヘッダー部の最大バイト数。これを超えてもヘッダーの終端が見つからない場合は不正なフレームとする
+	maxHeaderSize = 8 * 1024
+)
+
+var headerTerminator = []byte("\r\n\r\n")
+
+// Content-Lengthヘッダーが欠けている、または不正な場合のエラー
+var ErrInvalidFrameHeader = errors.New("invalid frame header")
+
+func (ContentLengthFramer) Decode(data []byte, maxSize int) ([]byte, int, error) {
+	headerEnd := bytes.Index(data, headerTerminator)
+	if headerEnd == -1 {
+		if len(data) > maxHeaderSize {
+			return nil, -1, fmt.Errorf("%w: header exceeds %d bytes", ErrInvalidFrameHeader, maxHeaderSize)
+		}
+		return nil, 0, nil
+	}
+	bodyStart := headerEnd + len(headerTerminator)
+	length := -1
+	for _, line := range strings.Split(string(data[:headerEnd]), "\r\n") {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, bodyStart, fmt.Errorf("%w: %q", ErrInvalidFrameHeader, line)
+		}
+		// Content-Type等の他のヘッダーは無視する
+		if !strings.EqualFold(strings.TrimSpace(name), contentLengthHeader) {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || n < 0 {
+			return nil, bodyStart, fmt.Errorf("%w: invalid Content-Length %q", ErrInvalidFrameHeader, value)
+		}
+		length = n
+	}
+	if length == -1 {
+		return nil, bodyStart, fmt.Errorf("%w: missing Content-Length", ErrInvalidFrameHeader)
+	}
+	if exceedsMaxSize(length, maxSize) {
+		// 本体がまだ届いていなくても、長さが分かっているためその分を読み捨てる
+		return nil, bodyStart + length, fmt.Errorf("%w: %d bytes (limit %d bytes)", ErrMessageTooLarge, length, maxSize)
+	}
+	if len(data) < bodyStart+length {
+		return nil, 0, nil
+	}
+	return data[bodyStart : bodyStart+length], bodyStart + length, nil
+}
+
+func (ContentLengthFramer) Encode(payload []byte) []byte {
+	header := fmt.Sprintf("%s: %d\r\n\r\n", contentLengthHeader, len(payload))
+	return append([]byte(header), payload...)
+}
+
+// Decodeと同様に、ヘッダー名の大文字と小文字は区別しない
+func (ContentLengthFramer) Resync(data []byte) int {
+	for i := 0; i+len(contentLengthHeader) <= len(data); i++ {
+		if bytes.EqualFold(data[i:i+len(contentLengthHeader)], []byte(contentLengthHeader)) {
+			return i
+		}
+	}
+	return -1
+}
+
+func exceedsMaxSize(size int, maxSize int) bool {
+	return maxSize > 0 && size > maxSize
+}
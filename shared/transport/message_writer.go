@@ -0,0 +1,169 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// MessageWriterが閉じられた後に書き込もうとした場合に返すエラー
+var ErrWriterClosed = errors.New("message writer is closed")
+
+type MessageWriterOptions struct {
+	// 送信キューに溜めておけるメッセージ数
+	// 0の場合はキューを使わず、呼び出し元のゴルーチンで書き込みが完了するまでブロックする
+	// 1以上の場合はキューに積んだ時点で戻り、キューが一杯の間は空きができるまでブロックする（バックプレッシャー）
+	QueueSize int
+}
+
+// 1つのio.Writerへの書き込みを直列化する
+// 複数のゴルーチンから同時にWriteを呼び出しても、メッセージ同士が混ざることはない
+type MessageWriter struct {
+	w       io.Writer
+	onError func(error)
+
+	// 直接書き込む場合に、書き込み権を受け渡すためのセマフォ
+	// sync.Mutexと異なり、待機中にcontextのキャンセルを検知できる
+	sem chan struct{}
+	// キューを使う場合の送信キュー
+	queue chan []byte
+
+	closeOnce sync.Once
+	done      chan struct{}
+
+	// closeされると、書き込みループはキューに残っているメッセージを破棄して終了する
+	abortOnce sync.Once
+	abort     chan struct{}
+	// 書き込みループが終了した時にcloseされる
+	stopped chan struct{}
+}
+
+// onErrorには、キューを使う場合に非同期の書き込みで発生したエラーが渡される
+func NewMessageWriter(w io.Writer, options *MessageWriterOptions, onError func(error)) *MessageWriter {
+	m := &MessageWriter{
+		w:       w,
+		onError: onError,
+		sem:     make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		abort:   make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	if options != nil && options.QueueSize > 0 {
+		m.queue = make(chan []byte, options.QueueSize)
+		go m.writeLoop()
+	} else {
+		close(m.stopped)
+	}
+	return m
+}
+
+// dataをそのまま1回のWriteで書き込む
+// ctxがキャンセルされた場合は、書き込み権やキューの空きを待つのをやめてctx.Err()を返す
+// 既に開始された書き込みは中断されない
+func (m *MessageWriter) Write(ctx context.Context, data []byte) error {
+	if m.queue != nil {
+		select {
+		case <-m.done:
+			return ErrWriterClosed
+		default:
+		}
+		select {
+		case m.queue <- data:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-m.done:
+			return ErrWriterClosed
+		}
+	}
+
+	select {
+	case m.sem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-m.done:
+		return ErrWriterClosed
+	}
+	defer func() { <-m.sem }()
+	// 待機中に閉じられていた場合は書き込まない
+	select {
+	case <-m.done:
+		return ErrWriterClosed
+	default:
+	}
+	_, err := m.w.Write(data)
+	return err
+}
+
+// キューに積まれたメッセージを順番に書き込む
+// 閉じられた後は、中断されない限りキューに残っているメッセージを書き込んでから終了する
+func (m *MessageWriter) writeLoop() {
+	defer close(m.stopped)
+	for {
+		select {
+		case data := <-m.queue:
+			m.writeQueued(data)
+		case <-m.done:
+			for {
+				select {
+				case <-m.abort:
+					return
+				default:
+				}
+				select {
+				case data := <-m.queue:
+					m.writeQueued(data)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (m *MessageWriter) writeQueued(data []byte) {
+	if _, err := m.w.Write(data); err != nil && m.onError != nil {
+		m.onError(err)
+	}
+}
+
+// 以降の書き込みを受け付けないようにする
+// 書き込み待ちのWriteはErrWriterClosedを返し、キューに残っているメッセージは破棄される
+// 複数回呼ばれた場合、2回目以降は何もしない
+func (m *MessageWriter) Close() {
+	m.closeOnce.Do(func() {
+		close(m.done)
+	})
+	m.abortOnce.Do(func() {
+		close(m.abort)
+	})
+}
+
+// 以降の書き込みを受け付けないようにした上で、キューに残っているメッセージと実行中の書き込みが完了するまで待つ
+// ctxが先に終了した場合は残りのメッセージを破棄してctx.Err()を返す。実行中の書き込みは中断されない
+// Closeの後に呼び出した場合は、キューに残っているメッセージは既に破棄されている
+func (m *MessageWriter) Shutdown(ctx context.Context) error {
+	m.closeOnce.Do(func() {
+		close(m.done)
+	})
+	if m.queue == nil {
+		// 実行中の書き込みが終わるまで書き込み権を待つ
+		select {
+		case m.sem <- struct{}{}:
+			<-m.sem
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	select {
+	case <-m.stopped:
+		return nil
+	case <-ctx.Done():
+		m.abortOnce.Do(func() {
+			close(m.abort)
+		})
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,264 @@
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 1回のWriteを2回に分けて書き込むWriter
+// 書き込みが直列化されていなければ、並行したメッセージ同士が混ざる
+type splitWriter struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (w *splitWriter) Write(p []byte) (int, error) {
+	half := len(p) / 2
+	w.mu.Lock()
+	w.buf.Write(p[:half])
+	w.mu.Unlock()
+	runtime.Gosched()
+	w.mu.Lock()
+	w.buf.Write(p[half:])
+	w.mu.Unlock()
+	return len(p), nil
+}
+
+func TestMessageWriter_Serialize(t *testing.T) {
+	tests := []struct {
+		name      string
+		queueSize int
+	}{
+		{
+			name:      "normal: concurrent writes are not interleaved without queue",
+			queueSize: 0,
+		},
+		{
+			name:      "normal: concurrent writes are not interleaved with queue",
+			queueSize: 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &splitWriter{}
+			sut := NewMessageWriter(w, &MessageWriterOptions{QueueSize: tt.queueSize}, func(err error) {
+				t.Errorf("unexpected error: %v", err)
+			})
+			defer sut.Close()
+
+			const count = 100
+			wantLines := make(map[string]bool, count)
+			var wg sync.WaitGroup
+			for i := range count {
+				line := fmt.Sprintf(`{"jsonrpc":"2.0","method":"notifications/message","params":{"data":"%s"}}`, strings.Repeat(fmt.Sprint(i%10), 64))
+				wantLines[line] = true
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if err := sut.Write(context.Background(), []byte(line+"\n")); err != nil {
+						t.Errorf("Write() error = %v", err)
+					}
+				}()
+			}
+			wg.Wait()
+
+			// キューを使う場合は書き込みが非同期のため、全て書き込まれるまで待つ
+			deadline := time.Now().Add(5 * time.Second)
+			for {
+				w.mu.Lock()
+				n := strings.Count(w.buf.String(), "\n")
+				w.mu.Unlock()
+				if n == count || time.Now().After(deadline) {
+					break
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			w.mu.Lock()
+			defer w.mu.Unlock()
+			scanner := bufio.NewScanner(&w.buf)
+			got := 0
+			for scanner.Scan() {
+				if !wantLines[scanner.Text()] {
+					t.Fatalf("interleaved line: %q", scanner.Text())
+				}
+				got++
+			}
+			if got != count {
+				t.Errorf("written lines = %d, want %d", got, count)
+			}
+		})
+	}
+}
+
+func TestMessageWriter_Backpressure(t *testing.T) {
+	tests := []struct {
+		name      string
+		queueSize int
+		// 書き込み先がブロックした状態で、ブロックせずに受け付けられるWriteの回数
+		accepted int
+	}{
+		{
+			name:      "semi normal: write waiting for the in-flight write is canceled by context",
+			queueSize: 0,
+			accepted:  0,
+		},
+		{
+			name:      "semi normal: write waiting for a free queue slot is canceled by context",
+			queueSize: 2,
+			// 書き込みループが1件取り出してブロックするため、キューのサイズより1件多く受け付ける
+			accepted: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 読み取り側がいないため、書き込みはブロックし続ける
+			pr, pw := io.Pipe()
+			defer func() {
+				_ = pr.Close()
+			}()
+			sut := NewMessageWriter(pw, &MessageWriterOptions{QueueSize: tt.queueSize}, nil)
+
+			if tt.queueSize == 0 {
+				// 書き込み権を保持したままブロックするWrite
+				go func() {
+					_ = sut.Write(context.Background(), []byte("blocked\n"))
+				}()
+				time.Sleep(10 * time.Millisecond)
+			}
+			for i := range tt.accepted {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+				err := sut.Write(ctx, []byte("queued\n"))
+				cancel()
+				if err != nil {
+					t.Fatalf("Write() #%d error = %v", i, err)
+				}
+				// 書き込みループがキューから取り出すのを待つ
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			if err := sut.Write(ctx, []byte("overflow\n")); !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("Write() error = %v, want %v", err, context.DeadlineExceeded)
+			}
+
+			// 閉じると、待機中のWriteはErrWriterClosedを返す
+			errCh := make(chan error, 1)
+			go func() {
+				errCh <- sut.Write(context.Background(), []byte("waiting\n"))
+			}()
+			time.Sleep(10 * time.Millisecond)
+			sut.Close()
+			select {
+			case err := <-errCh:
+				if !errors.Is(err, ErrWriterClosed) {
+					t.Errorf("Write() error = %v, want %v", err, ErrWriterClosed)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Write() was not unblocked by Close()")
+			}
+		})
+	}
+}
+
+// 1回の書き込みに時間がかかるWriter
+type slowWriter struct {
+	mu    sync.Mutex
+	delay time.Duration
+	lines []string
+}
+
+func (w *slowWriter) Write(p []byte) (int, error) {
+	time.Sleep(w.delay)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lines = append(w.lines, string(p))
+	return len(p), nil
+}
+
+func TestMessageWriter_Shutdown(t *testing.T) {
+	tests := []struct {
+		name      string
+		queueSize int
+		delay     time.Duration
+		timeout   time.Duration
+		wantErr   error
+		// 書き込まれていることを期待する先頭のメッセージ数
+		wantWritten int
+	}{
+		{
+			name:        "normal : queued messages are written before shutdown returns",
+			queueSize:   16,
+			delay:       5 * time.Millisecond,
+			timeout:     5 * time.Second,
+			wantWritten: 16,
+		},
+		{
+			name:        "normal : shutdown without queue returns immediately",
+			queueSize:   0,
+			timeout:     5 * time.Second,
+			wantWritten: 16,
+		},
+		{
+			name:      "semi normal : remaining messages are discarded when the timeout is exceeded",
+			queueSize: 16,
+			delay:     50 * time.Millisecond,
+			timeout:   20 * time.Millisecond,
+			wantErr:   context.DeadlineExceeded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &slowWriter{delay: tt.delay}
+			sut := NewMessageWriter(w, &MessageWriterOptions{QueueSize: tt.queueSize}, nil)
+
+			const count = 16
+			for i := range count {
+				if err := sut.Write(context.Background(), []byte(fmt.Sprintf("%d\n", i))); err != nil {
+					t.Fatalf("Write() #%d error = %v", i, err)
+				}
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			if err := sut.Shutdown(ctx); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Shutdown() error = %v, want %v", err, tt.wantErr)
+			}
+			if err := sut.Write(context.Background(), []byte("after shutdown\n")); !errors.Is(err, ErrWriterClosed) {
+				t.Errorf("Write() error = %v, want %v", err, ErrWriterClosed)
+			}
+
+			w.mu.Lock()
+			written := append([]string(nil), w.lines...)
+			w.mu.Unlock()
+			if tt.wantErr != nil {
+				// 打ち切られた後は、実行中の書き込みを除いて書き込まれない
+				time.Sleep(3 * tt.delay)
+				w.mu.Lock()
+				n := len(w.lines)
+				w.mu.Unlock()
+				if n >= count {
+					t.Errorf("written %d messages, want remaining messages to be discarded", n)
+				}
+				return
+			}
+			if len(written) != tt.wantWritten {
+				t.Fatalf("written %d messages, want %d", len(written), tt.wantWritten)
+			}
+			for i, line := range written {
+				if want := fmt.Sprintf("%d\n", i); line != want {
+					t.Errorf("message #%d = %q, want %q", i, line, want)
+				}
+			}
+		})
+	}
+}
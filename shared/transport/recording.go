@@ -0,0 +1,113 @@
+package transport
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/kakkky/mcp-sdk-go/shared/protocol"
+	"github.com/kakkky/mcp-sdk-go/shared/schema"
+	"github.com/kakkky/mcp-sdk-go/shared/schema/jsonrpc"
+)
+
+// 記録したメッセージの向き（記録したクライアント、またはサーバーから見た向き）
+type Direction string
+
+const (
+	DIRECTION_IN  Direction = "in"  // 相手から受信したメッセージ
+	DIRECTION_OUT Direction = "out" // 相手に送信したメッセージ
+)
+
+// JSONLの1行として記録される1メッセージ
+type Record struct {
+	Time      time.Time       `json:"time"`
+	Direction Direction       `json:"direction"`
+	Message   json.RawMessage `json:"message"`
+}
+
+// 送受信した全てのメッセージを、時刻と向きとともにJSONL形式で書き出すトランスポートのデコレーター
+// 記録したファイルはReadRecordsで読み込み、ReplayTransportで再生できる
+type RecordingTransport struct {
+	protocol.Transport
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewRecordingTransport(inner protocol.Transport, w io.Writer) *RecordingTransport {
+	return &RecordingTransport{
+		Transport: inner,
+		w:         w,
+	}
+}
+
+func (r *RecordingTransport) SendMessage(message schema.JsonRpcMessage) error {
+	r.record(DIRECTION_OUT, message)
+	return r.Transport.SendMessage(message)
+}
+
+func (r *RecordingTransport) SetOnReceiveMessage(onReceiveMessage func(schema.JsonRpcMessage)) {
+	r.Transport.SetOnReceiveMessage(func(message schema.JsonRpcMessage) {
+		r.record(DIRECTION_IN, message)
+		onReceiveMessage(message)
+	})
+}
+
+// 記録に失敗しても通信は継続し、エラーはOnErrorで通知する
+func (r *RecordingTransport) record(direction Direction, message schema.JsonRpcMessage) {
+	data, err := jsonrpc.Marshal(message)
+	if err != nil {
+		r.OnError(fmt.Errorf("failed to record message: %w", err))
+		return
+	}
+	line, err := json.Marshal(Record{
+		Time:      time.Now(),
+		Direction: direction,
+		Message:   data,
+	})
+	if err != nil {
+		r.OnError(fmt.Errorf("failed to record message: %w", err))
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.w.Write(append(line, '\n')); err != nil {
+		r.OnError(fmt.Errorf("failed to record message: %w", err))
+	}
+}
+
+// RecordingTransportが書き出したJSONLを読み込む
+func ReadRecords(r io.Reader) ([]Record, error) {
+	var records []Record
+	reader := bufio.NewReader(r)
+	for lineNo := 1; ; lineNo++ {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 && !isBlank(line) {
+			var record Record
+			if err := json.Unmarshal(line, &record); err != nil {
+				return nil, fmt.Errorf("invalid record at line %d: %w", lineNo, err)
+			}
+			if record.Direction != DIRECTION_IN && record.Direction != DIRECTION_OUT {
+				return nil, fmt.Errorf("invalid record at line %d: unknown direction %q", lineNo, record.Direction)
+			}
+			records = append(records, record)
+		}
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read records: %w", err)
+		}
+	}
+}
+
+func isBlank(line []byte) bool {
+	for _, b := range line {
+		if b != ' ' && b != '\t' && b != '\r' && b != '\n' {
+			return false
+		}
+	}
+	return true
+}
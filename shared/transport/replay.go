@@ -0,0 +1,287 @@
+package transport
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kakkky/mcp-sdk-go/shared/schema"
+	"github.com/kakkky/mcp-sdk-go/shared/schema/jsonrpc"
+)
+
+const defaultReplayTimeout = 5 * time.Second
+
+type ReplayOptions struct {
+	// 記録された送信メッセージが実際に送信されるまで待つ時間。指定しなかった場合は5秒となる
+	Timeout time.Duration
+	// Start時に呼ばれる
+	// client.Clientに渡す場合は、client/transportのNewReplayClientTransportを使用する
+	OnStarted func()
+}
+
+// 記録と実際の送信メッセージの食い違い
+type ReplayMismatch struct {
+	// 記録の何番目のメッセージか（0始まり）。記録の終了後に送信された場合は記録の件数となる
+	Index    int
+	Expected json.RawMessage // 記録されていたメッセージ。記録の終了後に送信された場合はnil
+	Actual   json.RawMessage // 実際に送信されたメッセージ。送信されなかった場合はnil
+	Diff     string
+}
+
+func (m ReplayMismatch) String() string {
+	switch {
+	case m.Expected == nil:
+		return fmt.Sprintf("record %d: unexpected message was sent: %s", m.Index, m.Actual)
+	case m.Actual == nil:
+		return fmt.Sprintf("record %d: expected message was not sent: %s", m.Index, m.Expected)
+	default:
+		return fmt.Sprintf("record %d: sent message does not match the recording (-recorded +actual):\n%s", m.Index, m.Diff)
+	}
+}
+
+// 記録と実際の送信メッセージが食い違った場合にErrで返すエラー
+var ErrReplayMismatch = errors.New("replayed session does not match the recording")
+
+// RecordingTransportで記録したセッションを再生するトランスポート
+// 記録した側（クライアント、またはサーバー）に渡すと、受信メッセージを記録の順に届け、
+// 送信メッセージが記録と一致するかを検証する
+// 記録の時刻は再生に影響せず、メッセージの順序のみで再生する
+type ReplayTransport struct {
+	records []Record
+	options ReplayOptions
+
+	onReceiveMessage func(schema.JsonRpcMessage)
+	onClose          func()
+	onError          func(error)
+
+	sent       chan json.RawMessage
+	mu         sync.Mutex
+	mismatches []ReplayMismatch
+	isStarted  bool
+	isFinished bool
+	closeOnce  sync.Once
+	closed     chan struct{}
+	done       chan struct{}
+}
+
+func NewReplayTransport(records []Record, options *ReplayOptions) *ReplayTransport {
+	opts := ReplayOptions{}
+	if options != nil {
+		opts = *options
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultReplayTimeout
+	}
+	return &ReplayTransport{
+		records: records,
+		options: opts,
+		sent:    make(chan json.RawMessage, len(records)+1),
+		closed:  make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+func (r *ReplayTransport) Start() error {
+	r.mu.Lock()
+	if r.isStarted {
+		r.mu.Unlock()
+		return errors.New("replay transport is already started")
+	}
+	r.isStarted = true
+	r.mu.Unlock()
+	go r.replayLoop()
+	if r.options.OnStarted != nil {
+		r.options.OnStarted()
+	}
+	return nil
+}
+
+func (r *ReplayTransport) replayLoop() {
+	defer close(r.done)
+	for i, record := range r.records {
+		switch record.Direction {
+		case DIRECTION_IN:
+			message, err := jsonrpc.Unmarshal(record.Message)
+			if err != nil {
+				r.OnError(fmt.Errorf("failed to replay record %d: %w", i, err))
+				continue
+			}
+			r.onReceiveMessage(message)
+		case DIRECTION_OUT:
+			select {
+			case actual := <-r.sent:
+				r.compare(i, record.Message, actual)
+			case <-time.After(r.options.Timeout):
+				r.addMismatch(ReplayMismatch{Index: i, Expected: record.Message})
+			case <-r.closed:
+				// 閉じられた以降の送信メッセージは全て送信されなかったものとする
+				for j := i; j < len(r.records); j++ {
+					if r.records[j].Direction == DIRECTION_OUT {
+						r.addMismatch(ReplayMismatch{Index: j, Expected: r.records[j].Message})
+					}
+				}
+				return
+			}
+		}
+	}
+	r.mu.Lock()
+	r.isFinished = true
+	r.mu.Unlock()
+	// 再生が終わるまでに送信された余分なメッセージ
+	for {
+		select {
+		case actual := <-r.sent:
+			r.addMismatch(ReplayMismatch{Index: len(r.records), Actual: actual})
+		default:
+			return
+		}
+	}
+}
+
+func (r *ReplayTransport) compare(index int, expected, actual json.RawMessage) {
+	var expectedValue, actualValue any
+	if err := json.Unmarshal(expected, &expectedValue); err != nil {
+		r.OnError(fmt.Errorf("failed to replay record %d: %w", index, err))
+		return
+	}
+	if err := json.Unmarshal(actual, &actualValue); err != nil {
+		r.OnError(fmt.Errorf("failed to replay record %d: %w", index, err))
+		return
+	}
+	// キーの順序や空白の違いは無視し、値として比較する
+	if reflect.DeepEqual(expectedValue, actualValue) {
+		return
+	}
+	r.addMismatch(ReplayMismatch{Index: index, Expected: expected, Actual: actual, Diff: diffJson(expectedValue, actualValue)})
+}
+
+// キーを並べて整形したJSONを行ごとに比較し、記録のみの行を"-"、実際の送信のみの行を"+"で示す
+func diffJson(expected, actual any) string {
+	expectedJson, _ := json.MarshalIndent(expected, "", "  ")
+	actualJson, _ := json.MarshalIndent(actual, "", "  ")
+	return diffLines(strings.Split(string(expectedJson), "\n"), strings.Split(string(actualJson), "\n"))
+}
+
+// 最長共通部分列をもとに、行単位の差分を返す
+func diffLines(a, b []string) string {
+	// lcs[i][j]はa[i:]とb[j:]の最長共通部分列の長さ
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var sb strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			sb.WriteString("  " + a[i] + "\n")
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			sb.WriteString("- " + a[i] + "\n")
+			i++
+		default:
+			sb.WriteString("+ " + b[j] + "\n")
+			j++
+		}
+	}
+	return sb.String()
+}
+
+func (r *ReplayTransport) addMismatch(mismatch ReplayMismatch) {
+	r.mu.Lock()
+	r.mismatches = append(r.mismatches, mismatch)
+	r.mu.Unlock()
+	r.OnError(fmt.Errorf("%w: %s", ErrReplayMismatch, mismatch))
+}
+
+func (r *ReplayTransport) SendMessage(message schema.JsonRpcMessage) error {
+	data, err := jsonrpc.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	r.mu.Lock()
+	isFinished := r.isFinished
+	r.mu.Unlock()
+	if isFinished {
+		r.addMismatch(ReplayMismatch{Index: len(r.records), Actual: data})
+		return nil
+	}
+	select {
+	case r.sent <- data:
+	default:
+		// 記録の件数を超えて送信された場合
+		r.addMismatch(ReplayMismatch{Index: len(r.records), Actual: data})
+	}
+	return nil
+}
+
+func (r *ReplayTransport) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.closed)
+		r.OnClose()
+	})
+	return nil
+}
+
+// 記録の再生が終わった時にcloseされるチャネル
+func (r *ReplayTransport) Done() <-chan struct{} {
+	return r.done
+}
+
+// これまでに検出した食い違いを返す
+func (r *ReplayTransport) Mismatches() []ReplayMismatch {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]ReplayMismatch{}, r.mismatches...)
+}
+
+// 食い違いがあればErrReplayMismatchをラップしたエラーを返す
+func (r *ReplayTransport) Err() error {
+	mismatches := r.Mismatches()
+	if len(mismatches) == 0 {
+		return nil
+	}
+	errs := make([]error, 0, len(mismatches))
+	for _, mismatch := range mismatches {
+		errs = append(errs, fmt.Errorf("%w: %s", ErrReplayMismatch, mismatch))
+	}
+	return errors.Join(errs...)
+}
+
+func (r *ReplayTransport) OnClose() {
+	if r.onClose != nil {
+		r.onClose()
+	}
+}
+
+func (r *ReplayTransport) OnError(err error) {
+	if r.onError != nil {
+		r.onError(err)
+	}
+}
+
+func (r *ReplayTransport) SetOnReceiveMessage(onReceiveMessage func(schema.JsonRpcMessage)) {
+	r.onReceiveMessage = onReceiveMessage
+}
+
+func (r *ReplayTransport) SetOnClose(onClose func()) {
+	r.onClose = onClose
+}
+
+func (r *ReplayTransport) SetOnError(onError func(error)) {
+	r.onError = onError
+}
@@ -0,0 +1,48 @@
+package transport
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDiffJson(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected any
+		actual   any
+		want     string
+	}{
+		{
+			name:     "normal : changed value is shown as removed and added lines",
+			expected: map[string]any{"id": 1.0, "method": "ping"},
+			actual:   map[string]any{"id": 2.0, "method": "ping"},
+			want: `  {
+-   "id": 1,
++   "id": 2,
+    "method": "ping"
+  }
+`,
+		},
+		{
+			name:     "normal : missing and extra keys",
+			expected: map[string]any{"a": 1.0, "b": 2.0},
+			actual:   map[string]any{"b": 2.0, "c": 3.0},
+			want: `  {
+-   "a": 1,
+-   "b": 2
++   "b": 2,
++   "c": 3
+  }
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diffJson(tt.expected, tt.actual)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("diffJson() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
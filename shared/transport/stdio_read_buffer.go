@@ -2,14 +2,32 @@ package transport
 
 import (
 	"bytes"
-	"strings"
+	"errors"
 
 	"github.com/kakkky/mcp-sdk-go/shared/schema"
 	"github.com/kakkky/mcp-sdk-go/shared/schema/jsonrpc"
 )
 
+// 1メッセージあたりのデフォルトの最大バイト数
+const DEFAULT_MAX_MESSAGE_SIZE = 32 * 1024 * 1024
+
+// Resyncで次のフレームの開始位置が見つからなかった場合に、次のチャンクと合わせて探すために残しておくバイト数
+// フレームの開始を示す文字列（"Content-Length"など）より長くする必要がある
+const RESYNC_OVERLAP = 64
+
+// メッセージが最大サイズを超えた場合にReadMessageが返すエラー
+var ErrMessageTooLarge = errors.New("message exceeds the maximum message size")
+
 type ReadBuffer struct {
-	buffer *bytes.Buffer
+	buffer         *bytes.Buffer
+	framer         Framer
+	maxMessageSize int
+	// 最大サイズを超えたメッセージの残りのうち、まだ届いていないために読み捨てるバイト数
+	skip int
+	// 最大サイズを超えたメッセージの残りを、次のフレームの開始位置まで読み捨てている最中かどうか
+	discarding bool
+	// 読み捨てている最中に受け取ったデータの末尾。次のチャンクと合わせてResyncに渡す
+	resyncTail []byte
 }
 
 func NewReadBuffer() *ReadBuffer {
@@ -17,16 +35,55 @@ func NewReadBuffer() *ReadBuffer {
 		buffer: bytes.NewBuffer(
 			[]byte{},
 		),
+		framer:         NewlineFramer{},
+		maxMessageSize: DEFAULT_MAX_MESSAGE_SIZE,
 	}
 }
 
+// 1メッセージあたりの最大バイト数を設定する
+// 0以下を指定した場合は上限なしとなる
+func (r *ReadBuffer) SetMaxMessageSize(size int) {
+	r.maxMessageSize = size
+}
+
+// メッセージの区切り方を設定する
+// 指定しなかった場合はNewlineFramerとなる
+func (r *ReadBuffer) SetFramer(framer Framer) {
+	r.framer = framer
+}
+
 // バッファにチャンクを追加する
 func (r *ReadBuffer) Append(chunk []byte) error {
+	if r.skip > 0 {
+		n := min(r.skip, len(chunk))
+		r.skip -= n
+		chunk = chunk[n:]
+	}
+	if r.discarding {
+		chunk = r.resync(chunk)
+	}
 	_, err := r.buffer.Write(chunk)
 	return err
 }
 
-// バッファから一行分メッセージを読み取る
+// 次のフレームの開始位置を探し、そこから後のデータを返す
+// 見つからない場合はnilを返し、末尾を次のチャンクのために残しておく
+func (r *ReadBuffer) resync(chunk []byte) []byte {
+	data := append(r.resyncTail, chunk...)
+	index := r.framer.Resync(data)
+	if index == -1 {
+		r.resyncTail = bytes.Clone(data[max(0, len(data)-RESYNC_OVERLAP):])
+		return nil
+	}
+	// 最大サイズを超えたメッセージの終端まで読み捨てる
+	r.discarding = false
+	r.resyncTail = nil
+	return data[index:]
+}
+
+// バッファから1メッセージを読み取る
+// メッセージが最大サイズを超えた場合はErrMessageTooLargeを返し、そのメッセージは破棄する
+// 後続のメッセージは引き続き読み取ることができる
 func (r *ReadBuffer) ReadMessage() (schema.JsonRpcMessage, error) {
 	// バッファが空の場合
 	if r.buffer.Len() == 0 {
@@ -36,28 +93,24 @@ func (r *ReadBuffer) ReadMessage() (schema.JsonRpcMessage, error) {
 	// バッファ内容を取得（コピーせず）
 	data := r.buffer.Bytes()
 
-	// 改行を探す
-	index := bytes.IndexByte(data, '\n')
-	if index == -1 {
+	payload, n, err := r.framer.Decode(data, r.maxMessageSize)
+	if err != nil {
+		r.discard(n)
+		return nil, err
+	}
+	if n == 0 {
 		return nil, nil
 	}
 
-	// 改行までの部分を取り出す
-	// JSONRPCメッセージは改行ごとに区切られている
-	// 参照：(https://modelcontextprotocol.io/specification/draft/basic/transports#stdio)
-	line := make([]byte, index)
-	copy(line, data[:index])
-
-	// CRLFを考慮（Windowsの場合）
-	if strings.HasSuffix(string(line), "\r") {
-		line = line[:len(line)-1] // CRを削除
-	}
+	// バッファを更新する前にコピーする
+	frame := make([]byte, len(payload))
+	copy(frame, payload)
 
 	// バッファを更新（読み取った部分を削除）
-	r.buffer.Next(index + 1)
+	r.buffer.Next(n)
 
 	// JSONメッセージにデシリアライズ
-	message, err := jsonrpc.Unmarshal(line)
+	message, err := jsonrpc.Unmarshal(frame)
 	if err != nil {
 		return nil, err
 	}
@@ -65,7 +118,30 @@ func (r *ReadBuffer) ReadMessage() (schema.JsonRpcMessage, error) {
 	return message, nil
 }
 
+// 不正なフレームをnバイト読み捨てる
+// nが負の場合は、次のフレームの開始位置まで読み捨てる
+func (r *ReadBuffer) discard(n int) {
+	size := r.buffer.Len()
+	switch {
+	case n < 0:
+		// 先頭のフレームは不正なため、2バイト目以降から次のフレームの開始位置を探す
+		rest := bytes.Clone(r.buffer.Bytes()[min(1, size):])
+		r.buffer.Reset()
+		r.discarding = true
+		r.resyncTail = nil
+		r.buffer.Write(r.resync(rest))
+	case n > size:
+		r.buffer.Reset()
+		r.skip = n - size
+	default:
+		r.buffer.Next(n)
+	}
+}
+
 // バッファをクリアする
 func (r *ReadBuffer) Clear() {
 	r.buffer.Reset()
+	r.skip = 0
+	r.discarding = false
+	r.resyncTail = nil
 }
@@ -1,6 +1,8 @@
 package transport
 
 import (
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -131,3 +133,176 @@ func TestReadBuffer(t *testing.T) {
 		})
 	}
 }
+
+func TestReadBuffer_MaxMessageSize(t *testing.T) {
+	ping := `{"jsonrpc":"2.0","id":1,"method":"ping"}`
+	tests := []struct {
+		name   string
+		chunks [][]byte
+	}{
+		{
+			name: "seminormal: message exceeding the limit is dropped and the next message can be read",
+			chunks: [][]byte{
+				[]byte(`{"jsonrpc":"2.0","id":1,"method":"ping","params":{"padding":"` + strings.Repeat("a", 128) + `"}}` + "\n" + ping + "\n"),
+			},
+		},
+		{
+			name: "seminormal: message exceeding the limit without newline is discarded until the next newline",
+			chunks: [][]byte{
+				[]byte(`{"jsonrpc":"2.0","id":1,"method":"ping","params":{"padding":"` + strings.Repeat("a", 128)),
+				[]byte(strings.Repeat("a", 128)),
+				[]byte(`"}}` + "\n" + ping + "\n"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sut := NewReadBuffer()
+			sut.SetMaxMessageSize(64)
+
+			var gotErr error
+			var got []schema.JsonRpcMessage
+			for _, chunk := range tt.chunks {
+				if err := sut.Append(chunk); err != nil {
+					t.Fatalf("Append() error = %v", err)
+				}
+				for {
+					msg, err := sut.ReadMessage()
+					if err != nil {
+						gotErr = err
+						continue
+					}
+					if msg == nil {
+						break
+					}
+					got = append(got, msg)
+				}
+			}
+			if !errors.Is(gotErr, ErrMessageTooLarge) {
+				t.Errorf("ReadMessage() error = %v, want %v", gotErr, ErrMessageTooLarge)
+			}
+			expected := []schema.JsonRpcMessage{
+				schema.JsonRpcRequest{
+					BaseMessage: schema.BaseMessage{Jsonrpc: schema.JSON_RPC_VERSION, Id: 1},
+					Request:     &schema.PingRequestSchema{MethodName: "ping"},
+				},
+			}
+			if diff := cmp.Diff(got, expected); diff != "" {
+				t.Errorf("ReadMessage() mismatch (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReadBuffer_LargeMessage(t *testing.T) {
+	// 64KiBを大きく超えるメッセージも分割されたチャンクから読み取れる
+	blob := strings.Repeat("x", 8*1024*1024)
+	line := []byte(`{"jsonrpc":"2.0","id":1,"method":"resources/read","params":{"uri":"` + blob + `"}}` + "\n")
+	sut := NewReadBuffer()
+	for i := 0; i < len(line); i += 32 * 1024 {
+		end := min(i+32*1024, len(line))
+		if err := sut.Append(line[i:end]); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+	msg, err := sut.ReadMessage()
+	if err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+	request, ok := msg.(schema.JsonRpcRequest)
+	if !ok {
+		t.Fatalf("ReadMessage() got %T, want schema.JsonRpcRequest", msg)
+	}
+	if got := request.Params().(schema.ReadResourceRequestParams).Uri; got != blob {
+		t.Errorf("ReadMessage() uri length = %d, want %d", len(got), len(blob))
+	}
+}
+
+func TestReadBuffer_ContentLengthFramer(t *testing.T) {
+	ping := `{"jsonrpc":"2.0","id":1,"method":"ping"}`
+	frame := func(body string) string {
+		return string(ContentLengthFramer{}.Encode([]byte(body)))
+	}
+	oversize := `{"jsonrpc":"2.0","id":1,"method":"ping","params":{"padding":"` + strings.Repeat("a", 128) + `"}}`
+	tests := []struct {
+		name      string
+		chunks    []string
+		wantCount int
+		wantErr   error
+	}{
+		{
+			name:      "normal: can read a frame split across chunks",
+			chunks:    []string{"Content-Len", "gth: 40\r\n", "\r\n" + ping[:10], ping[10:]},
+			wantCount: 1,
+		},
+		{
+			name:      "normal: can read multiple frames with other headers in one chunk",
+			chunks:    []string{"Content-Length: 40\r\nContent-Type: application/vscode-jsonrpc; charset=utf-8\r\n\r\n" + ping + frame(ping)},
+			wantCount: 2,
+		},
+		{
+			name:      "normal: newline in the body does not split the frame",
+			chunks:    []string{frame("{\n\"jsonrpc\":\"2.0\",\n\"id\":1,\n\"method\":\"ping\"\n}")},
+			wantCount: 1,
+		},
+		{
+			name:      "seminormal: frame exceeding the limit is skipped even before the body arrives",
+			chunks:    []string{frame(oversize)[:40], frame(oversize)[40:100], frame(oversize)[100:] + frame(ping)},
+			wantCount: 1,
+			wantErr:   ErrMessageTooLarge,
+		},
+		{
+			name:      "seminormal: after a header without terminator, the next header is found even if it is split across chunks",
+			chunks:    []string{"Content-Length: 40\r\nX-Padding: " + strings.Repeat("a", 9*1024), strings.Repeat("a", 10) + "\r\ncontent-len", "gth: 40\r\n\r\n" + ping},
+			wantCount: 1,
+			wantErr:   ErrInvalidFrameHeader,
+		},
+		{
+			name:      "seminormal: frame without Content-Length is dropped and the next frame can be read",
+			chunks:    []string{"Content-Type: application/json\r\n\r\n" + frame(ping)},
+			wantCount: 1,
+			wantErr:   ErrInvalidFrameHeader,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sut := NewReadBuffer()
+			sut.SetFramer(ContentLengthFramer{})
+			sut.SetMaxMessageSize(64)
+
+			var gotErr error
+			var got []schema.JsonRpcMessage
+			for _, chunk := range tt.chunks {
+				if err := sut.Append([]byte(chunk)); err != nil {
+					t.Fatalf("Append() error = %v", err)
+				}
+				for {
+					msg, err := sut.ReadMessage()
+					if err != nil {
+						gotErr = err
+						continue
+					}
+					if msg == nil {
+						break
+					}
+					got = append(got, msg)
+				}
+			}
+			if !errors.Is(gotErr, tt.wantErr) {
+				t.Errorf("ReadMessage() error = %v, want %v", gotErr, tt.wantErr)
+			}
+			if len(got) != tt.wantCount {
+				t.Fatalf("ReadMessage() got %d messages, want %d", len(got), tt.wantCount)
+			}
+			expected := schema.JsonRpcRequest{
+				BaseMessage: schema.BaseMessage{Jsonrpc: schema.JSON_RPC_VERSION, Id: 1},
+				Request:     &schema.PingRequestSchema{MethodName: "ping"},
+			}
+			for _, msg := range got {
+				if diff := cmp.Diff(msg, schema.JsonRpcMessage(expected)); diff != "" {
+					t.Errorf("ReadMessage() mismatch (-got +want):\n%s", diff)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Streamable HTTPトランスポートで使用するHTTPヘッダー
+// 参照：(https://modelcontextprotocol.io/specification/2025-03-26/basic/transports#streamable-http)
+const (
+	MCP_SESSION_ID_HEADER       = "Mcp-Session-Id"
+	MCP_PROTOCOL_VERSION_HEADER = "Mcp-Protocol-Version"
+)
+
+const (
+	CONTENT_TYPE_JSON = "application/json"
+	CONTENT_TYPE_SSE  = "text/event-stream"
+)
+
+// SSEでJSON-RPCメッセージを運ぶイベントの種類
+const sseMessageEvent = "message"
+
+// SSEのイベントとして、1つのJSON-RPCメッセージを書き込む
+func WriteSSEEvent(w io.Writer, data []byte) error {
+	var buf bytes.Buffer
+	buf.WriteString("event: " + sseMessageEvent + "\n")
+	// データに改行が含まれる場合は、行ごとにdataフィールドに分ける
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		buf.WriteString("data: ")
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// SSEのストリームからJSON-RPCメッセージを1つずつ読み取る
+type SSEReader struct {
+	reader *bufio.Reader
+}
+
+func NewSSEReader(r io.Reader) *SSEReader {
+	return &SSEReader{reader: bufio.NewReader(r)}
+}
+
+// 次の"message"イベントのデータを返す
+// ストリームが終了した場合はio.EOFを返す。他の種類のイベントとコメントは読み飛ばす
+func (s *SSEReader) Next() ([]byte, error) {
+	event := ""
+	var data [][]byte
+	for {
+		line, err := s.reader.ReadBytes('\n')
+		if err != nil {
+			if errors.Is(err, io.EOF) && len(bytes.TrimSpace(line)) > 0 {
+				return nil, fmt.Errorf("unexpected end of event stream: %w", io.ErrUnexpectedEOF)
+			}
+			return nil, err
+		}
+		line = bytes.TrimSuffix(bytes.TrimSuffix(line, []byte("\n")), []byte("\r"))
+		// 空行でイベントが確定する
+		if len(line) == 0 {
+			if data != nil && (event == "" || event == sseMessageEvent) {
+				return bytes.Join(data, []byte("\n")), nil
+			}
+			event = ""
+			data = nil
+			continue
+		}
+		// ":"から始まる行はコメント
+		if line[0] == ':' {
+			continue
+		}
+		field, value, _ := strings.Cut(string(line), ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event = value
+		case "data":
+			data = append(data, []byte(value))
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/kakkky/mcp-sdk-go/shared/schema"
+	"github.com/kakkky/mcp-sdk-go/shared/schema/jsonrpc"
+)
+
+// WebSocketのサブプロトコル名
+const WEBSOCKET_SUBPROTOCOL = "mcp"
+
+const (
+	defaultPingInterval   = 30 * time.Second
+	defaultPongWait       = 60 * time.Second
+	defaultWriteWait      = 10 * time.Second
+	defaultMaxMessageSize = 4 * 1024 * 1024
+)
+
+type WebSocketOptions struct {
+	// pingを送信する間隔
+	PingInterval time.Duration
+	// pongを待つ時間。この時間内にpongもメッセージも受信できなければ接続を切断する
+	// PingIntervalより長い値である必要がある
+	PongWait time.Duration
+	// 1回の書き込みにかけられる時間
+	WriteWait time.Duration
+	// 1フレームあたりの最大バイト数。これを超えるメッセージを受信した場合は1009で接続を閉じる
+	MaxMessageSize int64
+	// 送信キューに溜めておけるメッセージ数
+	// 指定しなかった場合はキューを使わず、送信は書き込みが完了するまでブロックする
+	WriteQueueSize int
+}
+
+// 未指定の項目をデフォルト値で埋めたオプションを返す
+func (o *WebSocketOptions) withDefaults() WebSocketOptions {
+	opts := WebSocketOptions{}
+	if o != nil {
+		opts = *o
+	}
+	if opts.PingInterval <= 0 {
+		opts.PingInterval = defaultPingInterval
+	}
+	if opts.PongWait <= 0 {
+		opts.PongWait = defaultPongWait
+	}
+	if opts.PongWait <= opts.PingInterval {
+		opts.PongWait = opts.PingInterval * 2
+	}
+	if opts.WriteWait <= 0 {
+		opts.WriteWait = defaultWriteWait
+	}
+	if opts.MaxMessageSize <= 0 {
+		opts.MaxMessageSize = defaultMaxMessageSize
+	}
+	return opts
+}
+
+// クライアント・サーバー双方のWebSocketトランスポートで共有する接続
+// 1つのテキストフレームに1つのJSON-RPCメッセージを載せて送受信する
+type WebSocketConn struct {
+	conn    *websocket.Conn
+	options WebSocketOptions
+
+	// gorilla/websocketは並行書き込みをサポートしないため、テキストフレームの書き込みは直列化する
+	// ping、クローズフレームの送信（WriteControl）は他の書き込みと並行して呼び出せる
+	writer    *MessageWriter
+	closeOnce sync.Once
+	closing   bool // ローカルからCloseしたかどうか
+	closingMu sync.Mutex
+	done      chan struct{}
+}
+
+func NewWebSocketConn(conn *websocket.Conn, options *WebSocketOptions) *WebSocketConn {
+	return &WebSocketConn{
+		conn:    conn,
+		options: options.withDefaults(),
+		done:    make(chan struct{}),
+	}
+}
+
+// 受信ループとpingループを開始する
+// 接続が終了すると、正常なクローズ（1000, 1001）であればonCloseのみ、
+// それ以外の場合はonErrorを呼び出した後にonCloseを呼び出す
+func (c *WebSocketConn) Start(onReceiveMessage func(schema.JsonRpcMessage), onError func(error), onClose func()) {
+	c.conn.SetReadLimit(c.options.MaxMessageSize)
+	_ = c.conn.SetReadDeadline(time.Now().Add(c.options.PongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(c.options.PongWait))
+	})
+	c.writer = NewMessageWriter(&websocketFrameWriter{c: c}, &MessageWriterOptions{QueueSize: c.options.WriteQueueSize}, onError)
+	go c.pingLoop(onError)
+	go c.readLoop(onReceiveMessage, onError, onClose)
+}
+
+func (c *WebSocketConn) readLoop(onReceiveMessage func(schema.JsonRpcMessage), onError func(error), onClose func()) {
+	defer func() {
+		c.closeOnce.Do(func() { close(c.done) })
+		c.writer.Close()
+		_ = c.conn.Close()
+		if onClose != nil {
+			onClose()
+		}
+	}()
+	for {
+		messageType, data, err := c.conn.ReadMessage()
+		if err != nil {
+			if closeErr := c.closeError(err); closeErr != nil && onError != nil {
+				onError(closeErr)
+			}
+			return
+		}
+		_ = c.conn.SetReadDeadline(time.Now().Add(c.options.PongWait))
+		if messageType != websocket.TextMessage {
+			if onError != nil {
+				onError(fmt.Errorf("unsupported websocket message type: %d", messageType))
+			}
+			continue
+		}
+		msg, err := jsonrpc.Unmarshal(data)
+		if err != nil {
+			if onError != nil {
+				onError(err)
+			}
+			continue
+		}
+		onReceiveMessage(msg)
+	}
+}
+
+// 受信ループの終了原因をエラーに変換する
+// 正常なクローズの場合はnilを返す
+func (c *WebSocketConn) closeError(err error) error {
+	c.closingMu.Lock()
+	closing := c.closing
+	c.closingMu.Unlock()
+	if closing {
+		return nil
+	}
+	if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+		return nil
+	}
+	if errors.Is(err, websocket.ErrReadLimit) {
+		return fmt.Errorf("websocket message exceeds the maximum size of %d bytes: %w", c.options.MaxMessageSize, err)
+	}
+	var closeErr *websocket.CloseError
+	if errors.As(err, &closeErr) {
+		return fmt.Errorf("websocket closed by peer with code %d: %w", closeErr.Code, err)
+	}
+	return fmt.Errorf("websocket connection lost: %w", err)
+}
+
+func (c *WebSocketConn) pingLoop(onError func(error)) {
+	ticker := time.NewTicker(c.options.PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(c.options.WriteWait))
+			if err != nil {
+				if onError != nil && !errors.Is(err, websocket.ErrCloseSent) {
+					onError(fmt.Errorf("failed to send ping: %w", err))
+				}
+				return
+			}
+		}
+	}
+}
+
+// 1つのテキストフレームとしてメッセージを送信する
+// 書き込みを待っている間にctxがキャンセルされた場合は、送信せずにエラーを返す
+func (c *WebSocketConn) WriteMessage(ctx context.Context, message schema.JsonRpcMessage) error {
+	if c.writer == nil {
+		return errors.New("websocket connection is not started")
+	}
+	data, err := jsonrpc.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	if int64(len(data)) > c.options.MaxMessageSize {
+		return fmt.Errorf("message size %d exceeds the maximum size of %d bytes", len(data), c.options.MaxMessageSize)
+	}
+	if err := c.writer.Write(ctx, data); err != nil {
+		return fmt.Errorf("failed to write message to websocket: %w", err)
+	}
+	return nil
+}
+
+// 1回のWriteを1つのテキストフレームとして書き込むio.Writer
+type websocketFrameWriter struct {
+	c *WebSocketConn
+}
+
+func (w *websocketFrameWriter) Write(p []byte) (int, error) {
+	if err := w.c.conn.SetWriteDeadline(time.Now().Add(w.c.options.WriteWait)); err != nil {
+		return 0, err
+	}
+	if err := w.c.conn.WriteMessage(websocket.TextMessage, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// 1000(Normal Closure)でクローズフレームを送信し、接続を閉じる
+func (c *WebSocketConn) Close() error {
+	c.closingMu.Lock()
+	c.closing = true
+	c.closingMu.Unlock()
+
+	if c.writer != nil {
+		c.writer.Close()
+	}
+	err := c.conn.WriteControl(
+		websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+		time.Now().Add(c.options.WriteWait),
+	)
+	if err != nil && !errors.Is(err, websocket.ErrCloseSent) {
+		_ = c.conn.Close()
+		return fmt.Errorf("failed to send close frame: %w", err)
+	}
+	return c.conn.Close()
+}
+
+// 接続が終了した時にcloseされるチャネル
+func (c *WebSocketConn) Done() <-chan struct{} {
+	return c.done
+}